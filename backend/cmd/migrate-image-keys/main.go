@@ -0,0 +1,83 @@
+// Command migrate-image-keys rewrites events.image_url values that hold full
+// URLs into storage keys for the configured upload backend. URLs that do not
+// point at that backend, such as external images, are left as they are.
+//
+// Usage:
+//
+//	go run ./cmd/migrate-image-keys [-dry-run]
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/net1io/zenbali/internal/config"
+	"github.com/net1io/zenbali/internal/database"
+	"github.com/net1io/zenbali/internal/repository"
+	"github.com/net1io/zenbali/internal/storage"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report the rewrites without applying them")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	store, err := storage.New(ctx, cfg.Upload)
+	if err != nil {
+		log.Fatalf("Failed to initialize %s storage: %v", cfg.Upload.Backend, err)
+	}
+
+	events := repository.NewEventRepository(db.Pool)
+	refs, err := events.ListImageRefs(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list event images: %v", err)
+	}
+
+	var rewritten, alreadyKeys, external int
+	for id, ref := range refs {
+		if !storage.IsURL(ref) {
+			alreadyKeys++
+			continue
+		}
+
+		key, ok := store.KeyFromURL(ref)
+		if !ok {
+			external++
+			log.Printf("skip %s: %s is not in %s storage", id, ref, cfg.Upload.Backend)
+			continue
+		}
+
+		if *dryRun {
+			log.Printf("would rewrite %s: %s -> %s", id, ref, key)
+			rewritten++
+			continue
+		}
+
+		updated, err := events.ReplaceImageRef(ctx, id, ref, key)
+		if err != nil {
+			log.Fatalf("Failed to rewrite %s: %v", id, err)
+		}
+		if updated {
+			log.Printf("rewrote %s: %s -> %s", id, ref, key)
+			rewritten++
+		}
+	}
+
+	verb := "Rewrote"
+	if *dryRun {
+		verb = "Would rewrite"
+	}
+	log.Printf("%s %d image URLs; %d already keys, %d left unchanged", verb, rewritten, alreadyKeys, external)
+}
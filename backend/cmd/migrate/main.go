@@ -0,0 +1,42 @@
+// Command migrate applies and rolls back database migrations. The server
+// binary runs the same commands as `zenbali migrate ...`.
+//
+// Usage:
+//
+//	go run ./cmd/migrate up|down [n]|status|redo|baseline <n>
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/net1io/zenbali/internal/config"
+	"github.com/net1io/zenbali/internal/database"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	err = database.RunMigrateCommand(context.Background(), db, os.Args[1:], os.Stdout)
+	if errors.Is(err, database.ErrMigrateUsage) {
+		fmt.Fprint(os.Stderr, database.MigrateUsage)
+		db.Close()
+		os.Exit(2)
+	}
+	if err != nil {
+		db.Close()
+		log.Fatalf("Migration failed: %v", err)
+	}
+}
@@ -2,16 +2,24 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/net1io/zenbali/internal/config"
 	"github.com/net1io/zenbali/internal/database"
+	"github.com/net1io/zenbali/internal/geoip"
 	"github.com/net1io/zenbali/internal/handlers"
+	"github.com/net1io/zenbali/internal/imaging"
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/openapi"
 	"github.com/net1io/zenbali/internal/repository"
 	"github.com/net1io/zenbali/internal/services"
 
@@ -35,38 +43,66 @@ func main() {
 	}
 	defer db.Close()
 
+	// `zenbali migrate ...` runs a migration command instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := database.RunMigrateCommand(context.Background(), db, os.Args[2:], os.Stdout)
+		if errors.Is(err, database.ErrMigrateUsage) {
+			fmt.Fprint(os.Stderr, database.MigrateUsage)
+			db.Close()
+			os.Exit(2)
+		}
+		if err != nil {
+			db.Close()
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
 	// Run migrations
-	// if err := db.RunMigrations(); err != nil {
-	// 	log.Fatalf("Failed to run migrations: %v", err)
-	// }
+	if cfg.Database.MigrateOnStart {
+		if err := db.RunMigrations(); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+	}
 
 	// Initialize Stripe
 	stripe.Key = cfg.Stripe.SecretKey
 
 	// Initialize repositories
-	repos := &repository.Repositories{
-		Creator:      repository.NewCreatorRepository(db.Pool),
-		Event:        repository.NewEventRepository(db.Pool),
-		Payment:      repository.NewPaymentRepository(db.Pool),
-		Admin:        repository.NewAdminRepository(db.Pool),
-		Location:     repository.NewLocationRepository(db.Pool),
-		EventType:    repository.NewEventTypeRepository(db.Pool),
-		EntranceType: repository.NewEntranceTypeRepository(db.Pool),
-		Visitor:      repository.NewVisitorRepository(db.Pool),
-	}
+	repos := repository.New(db.Pool)
 
 	// Initialize services
-	uploadService, err := services.NewUploadService(context.Background(), cfg.Upload)
+	uploadService, err := services.NewUploadService(context.Background(), cfg.Upload, repos.Upload)
 	if err != nil {
 		log.Fatalf("Failed to initialize upload service: %v", err)
 	}
+	models.SetImageURLResolver(uploadService.URL)
+	imaging.RegisterHEIFDecoder(imaging.DecodeHEIFWASM)
+
+	var geoLocator *geoip.Locator
+	if cfg.GeoIP.DBPath != "" {
+		geoReader, err := geoip.Open(cfg.GeoIP.DBPath)
+		if err != nil {
+			log.Printf("WARN failed to open GeoIP database %s: %v; visitor locations will be recorded as Unknown", cfg.GeoIP.DBPath, err)
+		} else {
+			geoLocator = geoip.NewLocator(geoReader, cfg.GeoIP.CacheSize)
+		}
+	} else {
+		log.Println("GEOIP_DB_PATH not set; visitor locations will be recorded as Unknown")
+	}
 
+	visitorService := services.NewVisitorService(repos, geoLocator, cfg.BaseURL)
 	svcs := &services.Services{
-		Auth:    services.NewAuthService(repos, cfg.JWT),
-		Event:   services.NewEventService(repos, uploadService),
-		Payment: services.NewPaymentService(repos, cfg.Stripe),
-		Upload:  uploadService,
-		Visitor: services.NewVisitorService(repos),
+		Auth:             services.NewAuthService(repos, cfg.JWT, cfg.BaseURL),
+		AgentKey:         services.NewAgentKeyService(repos, cfg.Agent),
+		Event:            services.NewEventService(repos, uploadService),
+		EventStats:       services.NewEventStatsService(repos, visitorService),
+		Payment:          services.NewPaymentService(repos, cfg.Stripe),
+		Report:           services.NewReportService(repos),
+		Upload:           uploadService,
+		UploadSweeper:    services.NewUploadSweeper(repos, uploadService, time.Duration(cfg.Upload.OrphanGraceHours)*time.Hour),
+		Visitor:          visitorService,
+		VisitorRetention: services.NewVisitorRetention(repos, cfg.Visitor.RetentionDays),
 	}
 
 	if err := svcs.Auth.EnsureDefaultAdmin(context.Background(), cfg.Admin.Email, cfg.Admin.Password); err != nil {
@@ -80,11 +116,64 @@ func main() {
 	h := handlers.New(svcs, repos, cfg)
 
 	// Setup router
+	r := newRouter(cfg, h, repos)
+
+	// Create server
+	server := &http.Server{
+		Addr:         ":" + cfg.Port,
+		Handler:      r,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Sweep orphaned uploads and roll up visitor stats in the background
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if cfg.Upload.SweepIntervalMinutes > 0 {
+		go svcs.UploadSweeper.Run(jobsCtx, time.Duration(cfg.Upload.SweepIntervalMinutes)*time.Minute)
+	}
+	if cfg.Visitor.RollupIntervalMinutes > 0 {
+		go svcs.VisitorRetention.Run(jobsCtx, time.Duration(cfg.Visitor.RollupIntervalMinutes)*time.Minute)
+	}
+
+	// Start server in goroutine
+	go func() {
+		log.Printf("🌴 Zen Bali server starting on port %s", cfg.Port)
+		log.Printf("📍 Environment: %s", cfg.Env)
+		log.Printf("🌐 Base URL: %s", cfg.BaseURL)
+
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server error: %v", err)
+		}
+	}()
+
+	// Graceful shutdown
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down server...")
+	stopJobs()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Fatalf("Server forced to shutdown: %v", err)
+	}
+
+	log.Println("Server exited gracefully")
+}
+
+// newRouter mounts the middleware, the API routes and the static files. Every
+// /api route must be described in internal/openapi/openapi.json.
+func newRouter(cfg *config.Config, h *handlers.Handlers, repos *repository.Repositories) *chi.Mux {
 	r := chi.NewRouter()
 
 	// Middleware
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(handlers.ClientIPMiddleware(cfg.TrustedProxies))
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(60 * time.Second))
@@ -92,8 +181,8 @@ func main() {
 	// CORS
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Idempotency-Key"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300,
@@ -104,6 +193,10 @@ func main() {
 		// Health check
 		r.Get("/health", h.HealthCheck)
 
+		// API description and its docs page
+		r.Get("/openapi.json", openapi.ServeSpec)
+		r.Get("/docs", openapi.ServeDocs)
+
 		// Public routes
 		r.Get("/events", h.Public.ListEvents)
 		r.Get("/locations", h.Public.ListLocations)
@@ -114,7 +207,13 @@ func main() {
 		r.With(h.Auth.OptionalCreatorAuthMiddleware).Get("/events/{id}", h.Public.GetEvent)
 
 		// Visitor tracking
-		r.Post("/visitors", h.Visitor.TrackVisitor)
+		r.With(handlers.RateLimitByIP(cfg.Visitor.TrackRateLimit, time.Minute)).Post("/visitors", h.Visitor.TrackVisitor)
+		r.With(handlers.RateLimitByIP(cfg.Visitor.TrackRateLimit, time.Minute)).Post("/events/{id}/interactions", h.Visitor.TrackEventInteraction)
+
+		// Signed direct uploads, local storage only; the query is the auth
+		if cfg.Upload.Backend == "" || cfg.Upload.Backend == "local" {
+			r.Put("/uploads/direct", h.Public.ReceiveDirectUpload)
+		}
 		r.Get("/visitors/stats", h.Visitor.GetStats)
 
 		// Creator authentication
@@ -129,11 +228,19 @@ func main() {
 			r.Get("/creator/profile", h.Creator.GetProfile)
 			r.Put("/creator/profile", h.Creator.UpdateProfile)
 			r.Get("/creator/events", h.Creator.ListEvents)
+			r.Get("/creator/stats", h.Creator.GetStats)
 			r.Post("/creator/events", h.Creator.CreateEvent)
 			r.Get("/creator/events/{id}", h.Creator.GetEvent)
+			r.Get("/creator/events/{id}/stats", h.Creator.GetEventStats)
 			r.Put("/creator/events/{id}", h.Creator.UpdateEvent)
 			r.Delete("/creator/events/{id}", h.Creator.DeleteEvent)
 			r.Post("/creator/events/{id}/upload-image", h.Creator.UploadEventImage)
+			r.Post("/creator/events/{id}/images", h.Creator.AddEventImage)
+			r.Post("/creator/events/{id}/images/upload-url", h.Creator.CreateEventImageUpload)
+			r.Post("/creator/events/{id}/images/confirm", h.Creator.ConfirmEventImageUpload)
+			r.Put("/creator/events/{id}/images/order", h.Creator.ReorderEventImages)
+			r.Patch("/creator/events/{id}/images/{imageID}", h.Creator.UpdateEventImage)
+			r.Delete("/creator/events/{id}/images/{imageID}", h.Creator.DeleteEventImage)
 			r.Post("/creator/events/{id}/pay", h.Creator.CreatePaymentSession)
 			r.Get("/creator/events/{id}/verify-payment", h.Creator.VerifyPaymentSession)
 			r.Get("/creator/payments", h.Creator.ListPayments)
@@ -147,10 +254,20 @@ func main() {
 			r.Use(h.Auth.AdminAuthMiddleware)
 
 			r.Get("/admin/dashboard", h.Admin.Dashboard)
+			r.Get("/admin/reports/timeseries", h.Admin.TimeSeriesReport)
+			r.Get("/admin/reports/sources", h.Admin.SourceReport)
+			r.Get("/admin/reports/bots", h.Admin.BotReport)
 			r.Get("/admin/events", h.Admin.ListEvents)
 			r.Post("/admin/events", h.Admin.CreateEvent)
+			r.Post("/admin/events/import", h.Admin.ImportEvents)
 			r.Put("/admin/events/{id}", h.Admin.UpdateEvent)
 			r.Delete("/admin/events/{id}", h.Admin.DeleteEvent)
+			r.Post("/admin/events/{id}/images", h.Admin.AddEventImage)
+			r.Post("/admin/events/{id}/images/upload-url", h.Admin.CreateEventImageUpload)
+			r.Post("/admin/events/{id}/images/confirm", h.Admin.ConfirmEventImageUpload)
+			r.Put("/admin/events/{id}/images/order", h.Admin.ReorderEventImages)
+			r.Patch("/admin/events/{id}/images/{imageID}", h.Admin.UpdateEventImage)
+			r.Delete("/admin/events/{id}/images/{imageID}", h.Admin.DeleteEventImage)
 			r.Get("/admin/creators", h.Admin.ListCreators)
 			r.Post("/admin/creators", h.Admin.CreateCreator)
 			r.Put("/admin/creators/{id}", h.Admin.UpdateCreator)
@@ -163,14 +280,41 @@ func main() {
 			r.Get("/admin/settings/event-types", h.Admin.ListEventTypes)
 			r.Post("/admin/settings/event-types", h.Admin.CreateEventType)
 			r.Put("/admin/settings/event-types/{id}", h.Admin.UpdateEventType)
+			r.Get("/admin/agent-keys", h.Admin.ListAgentKeys)
+			r.Post("/admin/agent-keys", h.Admin.CreateAgentKey)
+			r.Delete("/admin/agent-keys/{id}", h.Admin.RevokeAgentKey)
+			r.Get("/admin/uploads/orphans", h.Admin.ListOrphanedUploads)
+			r.Post("/admin/uploads/sweep", h.Admin.SweepUploads)
 		})
 
 		// Agent protected routes
 		r.Group(func(r chi.Router) {
 			r.Use(h.Auth.AgentAuthMiddleware)
 
-			r.Post("/agent/uploads/event-image", h.Agent.UploadEventImage)
-			r.Post("/agent/events", h.Agent.CreateEvent)
+			r.With(h.Auth.RequireAgentScope(models.AgentScopeImagesUpload)).Post("/agent/uploads/event-image", h.Agent.UploadEventImage)
+
+			r.Group(func(r chi.Router) {
+				r.Use(h.Auth.RequireAgentScope(models.AgentScopeEventsRead))
+
+				r.Get("/agent/events", h.Agent.ListEvents)
+				r.Get("/agent/events/external/{externalID}", h.Agent.GetEventByExternalID)
+				r.Get("/agent/events/{id}", h.Agent.GetEvent)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(h.Auth.RequireAgentScope(models.AgentScopeEventsWrite))
+
+				r.With(handlers.IdempotencyMiddleware(repos)).Post("/agent/events", h.Agent.CreateEvent)
+				r.With(handlers.IdempotencyMiddleware(repos)).Post("/agent/events/batch", h.Agent.ImportEvents)
+				r.Patch("/agent/events/{id}", h.Agent.UpdateEvent)
+				r.Delete("/agent/events/{id}", h.Agent.DeleteEvent)
+				r.With(h.Auth.RequireAgentScope(models.AgentScopeImagesUpload)).Post("/agent/events/{id}/images", h.Agent.AddEventImage)
+				r.With(h.Auth.RequireAgentScope(models.AgentScopeImagesUpload)).Post("/agent/events/{id}/images/upload-url", h.Agent.CreateEventImageUpload)
+				r.With(h.Auth.RequireAgentScope(models.AgentScopeImagesUpload)).Post("/agent/events/{id}/images/confirm", h.Agent.ConfirmEventImageUpload)
+				r.Put("/agent/events/{id}/images/order", h.Agent.ReorderEventImages)
+				r.Patch("/agent/events/{id}/images/{imageID}", h.Agent.UpdateEventImage)
+				r.Delete("/agent/events/{id}/images/{imageID}", h.Agent.DeleteEventImage)
+			})
 		})
 
 		// Stripe webhook
@@ -178,47 +322,20 @@ func main() {
 	})
 
 	// Serve uploaded files when local storage is enabled.
-	if cfg.Upload.Backend != "gcs" {
-		fileServer := http.FileServer(http.Dir(cfg.Upload.Dir))
-		r.Handle("/uploads/*", http.StripPrefix("/uploads/", fileServer))
+	if cfg.Upload.Backend == "" || cfg.Upload.Backend == "local" {
+		fileServer := http.StripPrefix("/uploads/", http.FileServer(http.Dir(cfg.Upload.Dir)))
+		r.Handle("/uploads/*", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Direct uploads are unvalidated until confirmed and never served
+			if services.IsStagingKey(strings.TrimPrefix(path.Clean(r.URL.Path), "/uploads/")) {
+				http.NotFound(w, r)
+				return
+			}
+			fileServer.ServeHTTP(w, r)
+		}))
 	}
 
 	// Serve static frontend files
 	r.Handle("/*", http.FileServer(http.Dir("../frontend/public")))
 
-	// Create server
-	server := &http.Server{
-		Addr:         ":" + cfg.Port,
-		Handler:      r,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
-	}
-
-	// Start server in goroutine
-	go func() {
-		log.Printf("🌴 Zen Bali server starting on port %s", cfg.Port)
-		log.Printf("📍 Environment: %s", cfg.Env)
-		log.Printf("🌐 Base URL: %s", cfg.BaseURL)
-
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
-		}
-	}()
-
-	// Graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-
-	log.Println("Shutting down server...")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
-	}
-
-	log.Println("Server exited gracefully")
+	return r
 }
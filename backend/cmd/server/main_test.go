@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/net1io/zenbali/internal/config"
+	"github.com/net1io/zenbali/internal/handlers"
+	"github.com/net1io/zenbali/internal/openapi"
+	"github.com/net1io/zenbali/internal/repository"
+	"github.com/net1io/zenbali/internal/services"
+)
+
+// TestRoutesMatchOpenAPI checks that the OpenAPI document describes every
+// /api route of the router, and nothing else
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	// Local storage mounts the direct upload route too
+	cfg := &config.Config{Upload: config.UploadConfig{Backend: "local"}}
+	r := newRouter(cfg, handlers.New(&services.Services{}, &repository.Repositories{}, cfg), &repository.Repositories{})
+
+	routes := map[string]bool{}
+	err := chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if strings.HasPrefix(route, "/api/") {
+			routes[strings.ToLower(method)+" "+route] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openapi.Spec, &doc); err != nil {
+		t.Fatal(err)
+	}
+	documented := map[string]bool{}
+	for path, item := range doc.Paths {
+		for method := range item {
+			switch method {
+			case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+				documented[method+" "+path] = true
+			}
+		}
+	}
+
+	for _, route := range sortedKeys(routes) {
+		if !documented[route] {
+			t.Errorf("%s is not in the OpenAPI document", route)
+		}
+	}
+	for _, route := range sortedKeys(documented) {
+		if !routes[route] {
+			t.Errorf("%s is documented but not routed", route)
+		}
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
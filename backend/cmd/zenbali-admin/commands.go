@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+// parseFlags parses args into fs, reporting any flag in required that was
+// left empty
+func parseFlags(fs *flag.FlagSet, args []string, required ...string) error {
+	fs.SetOutput(os.Stderr)
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errUsage
+	}
+	for _, name := range required {
+		if fs.Lookup(name).Value.String() == "" {
+			fmt.Fprintf(os.Stderr, "-%s is required\n", name)
+			fs.Usage()
+			return errUsage
+		}
+	}
+	return nil
+}
+
+// readPassword returns password, or reads one line from a.in when it is empty
+func (a *app) readPassword(password string) (string, error) {
+	if password != "" {
+		return password, nil
+	}
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(a.in).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func createAdmin(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := fs.String("email", "", "admin email")
+	name := fs.String("name", "", "admin name")
+	password := fs.String("password", "", "admin password (read from stdin when omitted)")
+	if err := parseFlags(fs, args, "email", "name"); err != nil {
+		return err
+	}
+
+	pw, err := a.readPassword(*password)
+	if err != nil {
+		return err
+	}
+	admin, err := a.services.Auth.CreateAdmin(ctx, strings.TrimSpace(*email), strings.TrimSpace(*name), pw)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "Created admin %s (%s)\n", admin.Email, admin.ID)
+	return nil
+}
+
+func resetPassword(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	email := fs.String("email", "", "account email")
+	password := fs.String("password", "", "new password (read from stdin when omitted)")
+	creator := fs.Bool("creator", false, "reset a creator's password instead of an admin's")
+	if err := parseFlags(fs, args, "email"); err != nil {
+		return err
+	}
+
+	pw, err := a.readPassword(*password)
+	if err != nil {
+		return err
+	}
+	if *creator {
+		err = a.services.Auth.ResetCreatorPassword(ctx, strings.TrimSpace(*email), pw)
+	} else {
+		err = a.services.Auth.ResetAdminPassword(ctx, strings.TrimSpace(*email), pw)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "Password reset for %s\n", *email)
+	return nil
+}
+
+func listUsers(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("list-users", flag.ContinueOnError)
+	userType := fs.String("type", "admins", "admins or creators")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	switch *userType {
+	case "admins":
+		admins, err := a.repos.Admin.List(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "ID\tEMAIL\tNAME\tACTIVE\tCREATED")
+		for _, admin := range admins {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\n", admin.ID, admin.Email, admin.Name, admin.IsActive, admin.CreatedAt.Format("2006-01-02"))
+		}
+
+	case "creators":
+		fmt.Fprintln(w, "ID\tEMAIL\tNAME\tORGANIZATION\tVERIFIED\tACTIVE\tCREATED")
+		err := eachCreator(ctx, a, func(creator *models.Creator) error {
+			_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%t\t%s\n", creator.ID, creator.Email, creator.Name,
+				creator.OrganizationName, creator.IsVerified, creator.IsActive, creator.CreatedAt.Format("2006-01-02"))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown user type %q\n", *userType)
+		return errUsage
+	}
+	return w.Flush()
+}
+
+func reseed(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("reseed", flag.ContinueOnError)
+	withDemoData := fs.Bool("with-demo-data", false, "also restore the demo admin and creator, whose passwords are public, and the sample event")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if err := a.db.Reseed(ctx, *withDemoData); err != nil {
+		return err
+	}
+	if *withDemoData {
+		fmt.Fprintln(a.out, "Reference and demo data restored")
+	} else {
+		fmt.Fprintln(a.out, "Reference data restored")
+	}
+	return nil
+}
+
+func publishPaid(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("publish-paid", flag.ContinueOnError)
+	eventID := fs.String("event", "", "event ID")
+	reference := fs.String("reference", "", "reference of the manual payment, such as a bank transfer ID")
+	amountCents := fs.Int64("amount-cents", 0, "amount paid in cents (defaults to the posting fee)")
+	if err := parseFlags(fs, args, "event", "reference"); err != nil {
+		return err
+	}
+	id, err := uuid.Parse(*eventID)
+	if err != nil || *amountCents < 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	payment, err := a.services.Payment.RecordManualPayment(ctx, id, *amountCents, strings.TrimSpace(*reference))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "Recorded payment %s of %d %s cents and published event %s\n",
+		payment.ID, payment.AmountCents, payment.Currency, id)
+	return nil
+}
+
+func issueAgentKey(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("issue-agent-key", flag.ContinueOnError)
+	creatorID := fs.String("creator", "", "ID of the creator the agent posts as")
+	name := fs.String("name", "", "name of the key")
+	scopes := fs.String("scopes", strings.Join(models.AgentScopes, ","), "comma-separated scopes")
+	expiresInDays := fs.Int("expires-days", 0, "days until the key expires (0 never expires)")
+	if err := parseFlags(fs, args, "creator", "name", "scopes"); err != nil {
+		return err
+	}
+
+	key, secret, err := a.services.AgentKey.Issue(ctx, &models.AgentAPIKeyCreateRequest{
+		Name:          *name,
+		CreatorID:     *creatorID,
+		Scopes:        strings.Split(*scopes, ","),
+		ExpiresInDays: *expiresInDays,
+	}, uuid.Nil)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "Issued key %s (%s) with scopes %s\n", key.ID, key.Name, strings.Join(key.Scopes, ","))
+	if key.ExpiresAt != nil {
+		fmt.Fprintf(a.out, "Expires %s\n", key.ExpiresAt.Format("2006-01-02"))
+	}
+	fmt.Fprintf(a.out, "Secret (shown once): %s\n", secret)
+	return nil
+}
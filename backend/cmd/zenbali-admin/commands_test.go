@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/config"
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/repository"
+	"github.com/net1io/zenbali/internal/repository/memory"
+	"github.com/net1io/zenbali/internal/services"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTestApp runs the commands against in-memory repositories, with stdin
+// reading from in and the report written to the returned buffer
+func newTestApp(t *testing.T, in string) (*app, *bytes.Buffer) {
+	t.Helper()
+	repos := memory.New().Repositories()
+	out := &bytes.Buffer{}
+	return &app{
+		repos: repos,
+		services: &services.Services{
+			Auth:     services.NewAuthService(repos, config.JWTConfig{Secret: "test-secret", ExpiryHours: 1}, "http://localhost"),
+			AgentKey: services.NewAgentKeyService(repos, config.AgentConfig{}),
+			Payment:  services.NewPaymentService(repos, config.StripeConfig{PriceCents: 100}),
+		},
+		in:  strings.NewReader(in),
+		out: out,
+	}, out
+}
+
+func createCreator(t *testing.T, repos *repository.Repositories) *models.Creator {
+	t.Helper()
+	creator := &models.Creator{Name: "Made", Email: "made@example.com", PasswordHash: "x"}
+	if err := repos.Creator.Create(context.Background(), creator); err != nil {
+		t.Fatal(err)
+	}
+	return creator
+}
+
+func createEvent(t *testing.T, repos *repository.Repositories, creatorID uuid.UUID) *models.Event {
+	t.Helper()
+	event := &models.Event{
+		CreatorID: creatorID, Title: "Sound Healing", EventDate: time.Now().AddDate(0, 0, 3),
+		LocationID: 1, EventTypeID: 1, EntranceTypeID: 1,
+	}
+	if err := repos.Event.Create(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func checkPassword(t *testing.T, hash, password string) {
+	t.Helper()
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		t.Errorf("stored hash does not match %q", password)
+	}
+}
+
+func TestCreateAdmin(t *testing.T) {
+	ctx := context.Background()
+	a, out := newTestApp(t, "from-stdin-pw\n")
+
+	if err := createAdmin(ctx, a, []string{"-email", " ops@example.com ", "-name", "Ops"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "Created admin ops@example.com (") {
+		t.Errorf("output = %q", out.String())
+	}
+	admin, _ := a.repos.Admin.GetByEmail(ctx, "ops@example.com")
+	if admin == nil || admin.Name != "Ops" || !admin.IsActive {
+		t.Fatalf("admin = %+v", admin)
+	}
+	checkPassword(t, admin.PasswordHash, "from-stdin-pw")
+
+	if err := createAdmin(ctx, a, []string{"-email", "ops@example.com", "-name", "Ops", "-password", "another-pw"}); !errors.Is(err, services.ErrEmailExists) {
+		t.Errorf("creating twice: err = %v, want ErrEmailExists", err)
+	}
+	if err := createAdmin(ctx, a, []string{"-email", "new@example.com", "-name", "New", "-password", "short"}); !errors.Is(err, services.ErrPasswordTooShort) {
+		t.Errorf("short password: err = %v, want ErrPasswordTooShort", err)
+	}
+	if err := createAdmin(ctx, a, []string{"-email", "new@example.com"}); !errors.Is(err, errUsage) {
+		t.Errorf("without -name: err = %v, want errUsage", err)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	ctx := context.Background()
+	a, out := newTestApp(t, "")
+	admin := &models.Admin{Email: "ops@example.com", Name: "Ops", PasswordHash: "x", IsActive: true}
+	if err := a.repos.Admin.Create(ctx, admin); err != nil {
+		t.Fatal(err)
+	}
+	creator := createCreator(t, a.repos)
+
+	if err := resetPassword(ctx, a, []string{"-email", admin.Email, "-password", "new-admin-pw"}); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := a.repos.Admin.GetByEmail(ctx, admin.Email)
+	checkPassword(t, stored.PasswordHash, "new-admin-pw")
+
+	if err := resetPassword(ctx, a, []string{"-email", creator.Email, "-password", "new-creator-pw", "-creator"}); err != nil {
+		t.Fatal(err)
+	}
+	storedCreator, _ := a.repos.Creator.GetByEmail(ctx, creator.Email)
+	checkPassword(t, storedCreator.PasswordHash, "new-creator-pw")
+	if got := out.String(); got != "Password reset for ops@example.com\nPassword reset for made@example.com\n" {
+		t.Errorf("output = %q", got)
+	}
+
+	// Without -creator the email is looked up among admins only
+	if err := resetPassword(ctx, a, []string{"-email", creator.Email, "-password", "new-creator-pw"}); !errors.Is(err, services.ErrAdminNotFound) {
+		t.Errorf("creator email as admin: err = %v, want ErrAdminNotFound", err)
+	}
+	if err := resetPassword(ctx, a, []string{"-email", admin.Email, "-password", "short"}); !errors.Is(err, services.ErrPasswordTooShort) {
+		t.Errorf("short password: err = %v, want ErrPasswordTooShort", err)
+	}
+}
+
+func TestPublishPaid(t *testing.T) {
+	ctx := context.Background()
+	a, out := newTestApp(t, "")
+	event := createEvent(t, a.repos, createCreator(t, a.repos).ID)
+
+	if err := publishPaid(ctx, a, []string{"-event", event.ID.String(), "-reference", " BANK-42 "}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "of 100 USD cents and published event "+event.ID.String()) {
+		t.Errorf("output = %q", out.String())
+	}
+	stored, _ := a.repos.Event.GetByID(ctx, event.ID)
+	if !stored.IsPaid || !stored.IsPublished {
+		t.Errorf("event paid %t, published %t; want both", stored.IsPaid, stored.IsPublished)
+	}
+
+	// An event that is already paid for is not charged again
+	if err := publishPaid(ctx, a, []string{"-event", event.ID.String(), "-reference", "BANK-43", "-amount-cents", "500"}); !errors.Is(err, services.ErrAlreadyPaid) {
+		t.Errorf("paying twice: err = %v, want ErrAlreadyPaid", err)
+	}
+	payments, total, _ := a.repos.Payment.ListAll(ctx, 1, 10, "")
+	if total != 1 || payments[0].StripePaymentIntentID != "BANK-42" || payments[0].Status != models.PaymentStatusCompleted {
+		t.Errorf("payments = %d, first %+v; want one completed with reference BANK-42", total, payments[0])
+	}
+
+	if err := publishPaid(ctx, a, []string{"-event", uuid.NewString(), "-reference", "BANK-44"}); !errors.Is(err, services.ErrEventNotFound) {
+		t.Errorf("unknown event: err = %v, want ErrEventNotFound", err)
+	}
+	if err := publishPaid(ctx, a, []string{"-event", "not-a-uuid", "-reference", "BANK-44"}); !errors.Is(err, errUsage) {
+		t.Errorf("malformed event ID: err = %v, want errUsage", err)
+	}
+	if err := publishPaid(ctx, a, []string{"-event", event.ID.String(), "-reference", "BANK-44", "-amount-cents", "-5"}); !errors.Is(err, errUsage) {
+		t.Errorf("negative amount: err = %v, want errUsage", err)
+	}
+}
+
+func TestIssueAgentKey(t *testing.T) {
+	ctx := context.Background()
+	a, out := newTestApp(t, "")
+	creator := createCreator(t, a.repos)
+
+	tests := []struct {
+		name   string
+		args   []string
+		want   error
+		scopes string
+	}{
+		{"default scopes", nil, nil, strings.Join(models.AgentScopes, ",")},
+		{"normalized scopes", []string{"-scopes", " Events:Read,events:read "}, nil, "events:read"},
+		{"unknown scope", []string{"-scopes", "events:read,events:delete"}, services.ErrInvalidAgentScope, ""},
+		{"empty scope", []string{"-scopes", "events:read,"}, services.ErrInvalidAgentScope, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out.Reset()
+			args := append([]string{"-creator", creator.ID.String(), "-name", "Import bot"}, tt.args...)
+			err := issueAgentKey(ctx, a, args)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if tt.want != nil {
+				if out.Len() != 0 {
+					t.Errorf("printed %q for a refused key", out.String())
+				}
+				return
+			}
+			if !strings.Contains(out.String(), "with scopes "+tt.scopes+"\n") || !strings.Contains(out.String(), "Secret (shown once): ") {
+				t.Errorf("output = %q", out.String())
+			}
+		})
+	}
+
+	keys, err := a.repos.AgentKey.List(ctx)
+	if err != nil || len(keys) != 2 {
+		t.Errorf("stored %d keys (%v), want 2", len(keys), err)
+	}
+	if err := issueAgentKey(ctx, a, []string{"-creator", uuid.NewString(), "-name", "Bot"}); !errors.Is(err, services.ErrCreatorNotFound) {
+		t.Errorf("unknown creator: err = %v, want ErrCreatorNotFound", err)
+	}
+	if err := issueAgentKey(ctx, a, []string{"-creator", creator.ID.String(), "-name", "Bot", "-scopes", ""}); !errors.Is(err, errUsage) {
+		t.Errorf("empty -scopes: err = %v, want errUsage", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	a, out := newTestApp(t, "")
+	creator := createCreator(t, a.repos)
+	event := createEvent(t, a.repos, creator.ID)
+	if _, err := a.services.Payment.RecordManualPayment(ctx, event.ID, 250, "BANK-42"); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(t *testing.T, data string) [][]string {
+		t.Helper()
+		records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+		if err != nil {
+			t.Fatalf("export is not CSV: %v", err)
+		}
+		return records
+	}
+
+	tests := []struct {
+		exportType string
+		column     string
+		want       string
+	}{
+		{"events", "title", "Sound Healing"},
+		{"creators", "email", "made@example.com"},
+		{"payments", "amount_cents", "250"},
+		{"payments", "stripe_payment_intent_id", "BANK-42"},
+	}
+	for _, tt := range tests {
+		out.Reset()
+		if err := export(ctx, a, []string{"-type", tt.exportType}); err != nil {
+			t.Fatalf("%s: %v", tt.exportType, err)
+		}
+		records := read(t, out.String())
+		if len(records) != 2 {
+			t.Fatalf("%s: %d rows, want a header and one row", tt.exportType, len(records))
+		}
+		column := -1
+		for i, name := range records[0] {
+			if name == tt.column {
+				column = i
+			}
+		}
+		if column < 0 || records[1][column] != tt.want {
+			t.Errorf("%s.%s = %v, want %q", tt.exportType, tt.column, records[1], tt.want)
+		}
+	}
+
+	// -out writes the file instead of standard output
+	out.Reset()
+	path := filepath.Join(t.TempDir(), "creators.csv")
+	if err := export(ctx, a, []string{"-type", "creators", "-out", path}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 || len(read(t, string(data))) != 2 {
+		t.Errorf("wrote %d bytes to out and %q to the file", out.Len(), data)
+	}
+
+	if err := export(ctx, a, []string{"-type", "visitors"}); !errors.Is(err, errUsage) {
+		t.Errorf("unknown type: err = %v, want errUsage", err)
+	}
+}
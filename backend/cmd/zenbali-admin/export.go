@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/net1io/zenbali/internal/models"
+)
+
+// exportPageSize is how many rows are read from the database at a time
+const exportPageSize = 500
+
+func export(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	exportType := fs.String("type", "", "events, creators or payments")
+	outPath := fs.String("out", "", "CSV file to write (defaults to standard output)")
+	if err := parseFlags(fs, args, "type"); err != nil {
+		return err
+	}
+
+	var write func(context.Context, *app, *csv.Writer) error
+	switch *exportType {
+	case "events":
+		write = exportEvents
+	case "creators":
+		write = exportCreators
+	case "payments":
+		write = exportPayments
+	default:
+		fmt.Fprintf(os.Stderr, "unknown export type %q\n", *exportType)
+		return errUsage
+	}
+
+	var out io.Writer = a.out
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	w := csv.NewWriter(out)
+	if err := write(ctx, a, w); err != nil {
+		return err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	if file, ok := out.(*os.File); ok && *outPath != "" {
+		return file.Close()
+	}
+	return nil
+}
+
+func exportEvents(ctx context.Context, a *app, w *csv.Writer) error {
+	w.Write([]string{"id", "title", "event_date", "event_time", "location", "event_type", "entrance_type",
+		"entrance_fee", "creator_id", "creator_name", "organization_name", "contact_email", "is_paid", "is_published", "created_at"})
+
+	for page := 1; ; page++ {
+		events, total, err := a.repos.Event.List(ctx, models.EventListFilter{IncludePast: true, Page: page, Limit: exportPageSize})
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			w.Write([]string{
+				event.ID.String(),
+				event.Title,
+				event.EventDate.Format("2006-01-02"),
+				stringValue(event.EventTime),
+				event.LocationName,
+				event.EventTypeName,
+				event.EntranceTypeName,
+				strconv.FormatFloat(event.EntranceFee, 'f', -1, 64),
+				event.CreatorID.String(),
+				event.CreatorName,
+				event.OrganizationName,
+				event.ContactEmail,
+				strconv.FormatBool(event.IsPaid),
+				strconv.FormatBool(event.IsPublished),
+				event.CreatedAt.Format(time.RFC3339),
+			})
+		}
+		if page*exportPageSize >= total {
+			return nil
+		}
+	}
+}
+
+func exportCreators(ctx context.Context, a *app, w *csv.Writer) error {
+	w.Write([]string{"id", "name", "organization_name", "email", "mobile", "is_verified", "is_active", "source", "created_at"})
+
+	return eachCreator(ctx, a, func(creator *models.Creator) error {
+		return w.Write([]string{
+			creator.ID.String(),
+			creator.Name,
+			creator.OrganizationName,
+			creator.Email,
+			creator.Mobile,
+			strconv.FormatBool(creator.IsVerified),
+			strconv.FormatBool(creator.IsActive),
+			creator.Attribution.Source,
+			creator.CreatedAt.Format(time.RFC3339),
+		})
+	})
+}
+
+func exportPayments(ctx context.Context, a *app, w *csv.Writer) error {
+	w.Write([]string{"id", "event_id", "event_title", "creator_id", "creator_name", "amount_cents", "currency",
+		"status", "stripe_session_id", "stripe_payment_intent_id", "created_at"})
+
+	for page := 1; ; page++ {
+		payments, total, err := a.repos.Payment.ListAll(ctx, page, exportPageSize, "")
+		if err != nil {
+			return err
+		}
+		for _, payment := range payments {
+			w.Write([]string{
+				payment.ID.String(),
+				payment.EventID.String(),
+				payment.EventTitle,
+				payment.CreatorID.String(),
+				payment.CreatorName,
+				strconv.Itoa(payment.AmountCents),
+				payment.Currency,
+				payment.Status,
+				payment.StripeSessionID,
+				payment.StripePaymentIntentID,
+				payment.CreatedAt.Format(time.RFC3339),
+			})
+		}
+		if page*exportPageSize >= total {
+			return nil
+		}
+	}
+}
+
+// eachCreator calls fn for every creator, a page at a time
+func eachCreator(ctx context.Context, a *app, fn func(*models.Creator) error) error {
+	for page := 1; ; page++ {
+		creators, total, err := a.repos.Creator.List(ctx, page, exportPageSize)
+		if err != nil {
+			return err
+		}
+		for _, creator := range creators {
+			if err := fn(creator); err != nil {
+				return err
+			}
+		}
+		if page*exportPageSize >= total {
+			return nil
+		}
+	}
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,106 @@
+// Command zenbali-admin runs operator tasks against the database through the
+// same repositories and services as the server: managing accounts, restoring
+// reference data, recording manual payments, issuing agent keys and exporting data.
+//
+// Usage:
+//
+//	go run ./cmd/zenbali-admin <command> [flags]
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/net1io/zenbali/internal/config"
+	"github.com/net1io/zenbali/internal/database"
+	"github.com/net1io/zenbali/internal/repository"
+	"github.com/net1io/zenbali/internal/services"
+)
+
+const usage = `usage: zenbali-admin <command> [flags]
+
+commands:
+  create-admin      -email <email> -name <name> [-password <password>]
+  reset-password    -email <email> [-password <password>] [-creator]
+  list-users        [-type admins|creators]
+  reseed            [--with-demo-data]
+                    restore locations and types; with --with-demo-data also
+                    the demo accounts and sample event (never in production)
+  publish-paid      -event <id> -reference <ref> [-amount-cents <n>]
+                    record a manually confirmed payment and publish the event
+  issue-agent-key   -creator <id> -name <name> [-scopes a,b] [-expires-days <n>]
+  export            -type events|creators|payments [-out <file>]
+
+Passwords that are not given as flags are read from standard input.
+Run "zenbali-admin <command> -h" for the flags of a command.
+`
+
+// errUsage is returned for a command line that cannot be run
+var errUsage = errors.New("invalid command")
+
+// app holds what the commands run against
+type app struct {
+	db       *database.Database
+	repos    *repository.Repositories
+	services *services.Services
+	in       io.Reader
+	out      io.Writer
+}
+
+type command func(ctx context.Context, a *app, args []string) error
+
+var commands = map[string]command{
+	"create-admin":    createAdmin,
+	"reset-password":  resetPassword,
+	"list-users":      listUsers,
+	"reseed":          reseed,
+	"publish-paid":    publishPaid,
+	"issue-agent-key": issueAgentKey,
+	"export":          export,
+}
+
+func main() {
+	if len(os.Args) < 2 || commands[os.Args[1]] == nil {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	run := commands[os.Args[1]]
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	db, err := database.New(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	repos := repository.New(db.Pool)
+	a := &app{
+		db:    db,
+		repos: repos,
+		services: &services.Services{
+			Auth:     services.NewAuthService(repos, cfg.JWT, cfg.BaseURL),
+			AgentKey: services.NewAgentKeyService(repos, cfg.Agent),
+			Payment:  services.NewPaymentService(repos, cfg.Stripe),
+		},
+		in:  os.Stdin,
+		out: os.Stdout,
+	}
+
+	err = run(context.Background(), a, os.Args[2:])
+	if errors.Is(err, errUsage) {
+		db.Close()
+		os.Exit(2)
+	}
+	if err != nil {
+		db.Close()
+		log.Fatalf("%s failed: %v", os.Args[1], err)
+	}
+}
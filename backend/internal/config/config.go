@@ -2,8 +2,10 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -19,6 +21,11 @@ type Config struct {
 	Admin    AdminConfig
 	Creator  CreatorConfig
 	Agent    AgentConfig
+	GeoIP    GeoIPConfig
+	Visitor  VisitorConfig
+	// Reverse proxies whose X-Forwarded-For and X-Real-IP headers are
+	// believed when finding a client's address
+	TrustedProxies []netip.Prefix
 }
 
 type DatabaseConfig struct {
@@ -30,6 +37,8 @@ type DatabaseConfig struct {
 	SSLMode        string
 	MaxConnections int
 	MaxIdleConns   int
+	// Apply pending migrations when the server starts
+	MigrateOnStart bool
 }
 
 type JWTConfig struct {
@@ -45,13 +54,36 @@ type StripeConfig struct {
 }
 
 type UploadConfig struct {
-	Backend       string
-	Dir           string
-	MaxSizeMB     int
-	AllowedExt    []string
+	Backend    string
+	Dir        string
+	MaxSizeMB  int
+	AllowedExt []string
+
+	// Unattached uploads older than the grace period are deleted by a sweep
+	// every SweepIntervalMinutes; 0 disables the background sweep
+	OrphanGraceHours     int
+	SweepIntervalMinutes int
+
+	// Direct uploads go from the client straight to storage through a signed
+	// URL, so they are not bound by MaxSizeMB or the server's timeouts
+	DirectMaxSizeMB     int
+	DirectURLTTLMinutes int
+	// The local backend emulates signed URLs with an API endpoint
+	DirectUploadURL    string
+	DirectUploadSecret string
+
 	GCSBucket     string
 	GCSPrefix     string
 	GCSPublicBase string
+
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3Prefix          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3PublicBase      string
+	S3PathStyle       bool
 }
 
 type AdminConfig struct {
@@ -64,6 +96,25 @@ type CreatorConfig struct {
 	Password string
 }
 
+// GeoIPConfig points at a MaxMind DB (GeoLite2 City or Country) file used to
+// locate visitors. Without one, visitor locations are recorded as Unknown.
+type GeoIPConfig struct {
+	DBPath    string
+	CacheSize int
+}
+
+// VisitorConfig controls how long raw visits are kept. Daily rollups are kept
+// for good.
+type VisitorConfig struct {
+	// 0 keeps raw visits forever
+	RetentionDays int
+	// How often rollups are recomputed and old visits and salts deleted; 0
+	// disables the job
+	RollupIntervalMinutes int
+	// Tracking requests allowed per client IP per minute; 0 disables the limit
+	TrackRateLimit int
+}
+
 type AgentConfig struct {
 	Token        string
 	CreatorEmail string
@@ -89,6 +140,7 @@ func Load() (*Config, error) {
 			SSLMode:        getEnv("DB_SSL_MODE", "disable"),
 			MaxConnections: getEnvInt("DB_MAX_CONNECTIONS", 25),
 			MaxIdleConns:   getEnvInt("DB_MAX_IDLE_CONNECTIONS", 5),
+			MigrateOnStart: getEnv("DB_MIGRATE_ON_START", "true") == "true",
 		},
 		JWT: JWTConfig{
 			Secret:      getEnv("JWT_SECRET", "default-dev-secret-change-in-production-min-32-chars"),
@@ -101,13 +153,30 @@ func Load() (*Config, error) {
 			PriceCents:     int64(getEnvInt("STRIPE_PRICE_CENTS", 100)),
 		},
 		Upload: UploadConfig{
-			Backend:       getEnv("UPLOAD_BACKEND", "local"),
-			Dir:           getEnv("UPLOAD_DIR", "./uploads"),
-			MaxSizeMB:     getEnvInt("MAX_UPLOAD_SIZE_MB", 5),
-			AllowedExt:    []string{".jpg", ".jpeg", ".png", ".webp"},
+			Backend:    getEnv("UPLOAD_BACKEND", "local"),
+			Dir:        getEnv("UPLOAD_DIR", "./uploads"),
+			MaxSizeMB:  getEnvInt("MAX_UPLOAD_SIZE_MB", 5),
+			AllowedExt: []string{".jpg", ".jpeg", ".png", ".webp", ".heic", ".heif"},
+
+			OrphanGraceHours:     getEnvInt("UPLOAD_ORPHAN_GRACE_HOURS", 24),
+			SweepIntervalMinutes: getEnvInt("UPLOAD_SWEEP_INTERVAL_MINUTES", 60),
+
+			DirectMaxSizeMB:     getEnvInt("DIRECT_UPLOAD_MAX_SIZE_MB", 25),
+			DirectURLTTLMinutes: getEnvInt("DIRECT_UPLOAD_URL_TTL_MINUTES", 15),
+			DirectUploadSecret:  getEnv("DIRECT_UPLOAD_SECRET", ""),
+
 			GCSBucket:     getEnv("GCS_BUCKET", ""),
 			GCSPrefix:     getEnv("GCS_PREFIX", ""),
 			GCSPublicBase: getEnv("GCS_PUBLIC_BASE_URL", ""),
+
+			S3Endpoint:        getEnv("S3_ENDPOINT", ""),
+			S3Region:          getEnv("S3_REGION", "us-east-1"),
+			S3Bucket:          getEnv("S3_BUCKET", ""),
+			S3Prefix:          getEnv("S3_PREFIX", ""),
+			S3AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+			S3SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+			S3PublicBase:      getEnv("S3_PUBLIC_BASE_URL", ""),
+			S3PathStyle:       getEnv("S3_PATH_STYLE", "true") == "true",
 		},
 		Admin: AdminConfig{
 			Email:    getEnv("ADMIN_EMAIL", "admin@zenbali.org"),
@@ -121,6 +190,31 @@ func Load() (*Config, error) {
 			Token:        getEnv("AGENT_API_TOKEN", ""),
 			CreatorEmail: getEnv("AGENT_CREATOR_EMAIL", getEnv("CREATOR_EMAIL", "creator@zenbali.org")),
 		},
+		GeoIP: GeoIPConfig{
+			DBPath:    getEnv("GEOIP_DB_PATH", ""),
+			CacheSize: getEnvInt("GEOIP_CACHE_SIZE", 10000),
+		},
+		Visitor: VisitorConfig{
+			RetentionDays:         getEnvInt("VISITOR_RETENTION_DAYS", 90),
+			RollupIntervalMinutes: getEnvInt("VISITOR_ROLLUP_INTERVAL_MINUTES", 60),
+			TrackRateLimit:        getEnvInt("VISITOR_TRACK_RATE_LIMIT", 30),
+		},
+	}
+
+	trusted, err := parsePrefixes(getEnv("TRUSTED_PROXIES", "127.0.0.0/8,::1/128"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	cfg.TrustedProxies = trusted
+
+	// Signed local uploads are sent to our API and checked with their own key,
+	// so that it can be rotated apart from JWT_SECRET
+	cfg.Upload.DirectUploadURL = strings.TrimSuffix(cfg.BaseURL, "/") + "/api/uploads/direct"
+	if cfg.Upload.DirectUploadSecret == "" {
+		if cfg.Env == "production" {
+			return nil, fmt.Errorf("DIRECT_UPLOAD_SECRET is required when ENV=production")
+		}
+		cfg.Upload.DirectUploadSecret = "default-dev-upload-secret-change-in-production"
 	}
 
 	if cfg.Upload.Backend == "gcs" && cfg.Upload.GCSBucket == "" {
@@ -144,6 +238,32 @@ func (d *DatabaseConfig) DSN() string {
 	)
 }
 
+// parsePrefixes reads a comma-separated list of CIDR prefixes; a bare address
+// stands for itself
+func parsePrefixes(value string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
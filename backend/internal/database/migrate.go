@@ -0,0 +1,612 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration has been modified")
+	ErrNoDownMigration  = errors.New("migration has no down file")
+	ErrUnknownMigration = errors.New("applied migration is not in this build")
+	ErrUntrackedSchema  = errors.New("database has tables but no migration history; run `zenbali migrate baseline <version>` first")
+	ErrAlreadyTracked   = errors.New("database already has migration history")
+)
+
+// migrationLockID keys the advisory lock held while migrating, so instances
+// started together take turns and the later ones find nothing to do
+const migrationLockID = 7_317_001
+
+const createMigrationsTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum CHAR(64) NOT NULL,
+		applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+	)
+`
+
+// Migration is one numbered schema change, read from NNN_name.up.sql and an
+// optional NNN_name.down.sql
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	// Checksum is the SHA-256 of Up, recorded when it is applied
+	Checksum string
+}
+
+// MigrationStatus is a migration and whether it has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	State     string
+	AppliedAt *time.Time
+}
+
+// Migration states
+const (
+	MigrationApplied  = "applied"
+	MigrationPending  = "pending"
+	MigrationModified = "modified"
+	// Missing migrations were applied but are no longer in this build
+	MigrationMissing = "missing"
+)
+
+var migrationFile = regexp.MustCompile(`^(\d+)_([A-Za-z0-9_]+)\.(up|down)\.sql$`)
+
+// LoadMigrations reads the migrations in dir, ordered by version. Two
+// migrations with the same version, a down file without an up file and
+// unrecognised .sql files are errors.
+func LoadMigrations(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration file %s is not named NNN_name.up.sql or NNN_name.down.sql", entry.Name())
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("migration file %s: %w", entry.Name(), err)
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, migration.Name, match[2])
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+			sum := sha256.Sum256(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Checksum == "" {
+			return nil, fmt.Errorf("migration %s has a down file but no up file", migration)
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func (m *Migration) String() string {
+	return fmt.Sprintf("%03d_%s", m.Version, m.Name)
+}
+
+// Migrator applies and rolls back the embedded migrations. Every operation
+// that writes holds a Postgres advisory lock, so it is safe to run from
+// several instances at once.
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []*Migration
+}
+
+func NewMigrator(pool *pgxpool.Pool) (*Migrator, error) {
+	migrations, err := LoadMigrations(migrationsFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{pool: pool, migrations: migrations}, nil
+}
+
+// querier is the part of a connection or transaction used to read rows
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Up applies every pending migration in version order and returns them.
+// Nothing is applied if an applied migration's file has changed.
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	var done []*Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			version, err := untrackedVersion(ctx, conn)
+			if err != nil {
+				return err
+			}
+			for _, migration := range m.migrations {
+				if migration.Version > version {
+					break
+				}
+				if err := record(ctx, conn, migration); err != nil {
+					return err
+				}
+				applied[migration.Version] = appliedMigration{name: migration.Name, checksum: migration.Checksum}
+			}
+			if version > 0 {
+				log.Printf("Baselined untracked schema at migration %03d", version)
+			}
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the last steps applied migrations, newest first, and
+// returns them
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	var done []*Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, version := range newestFirst(applied) {
+			if len(done) == steps {
+				break
+			}
+			migration := m.find(version)
+			if migration == nil {
+				return fmt.Errorf("%w: version %d", ErrUnknownMigration, version)
+			}
+			if err := revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Redo rolls back the last applied migration and applies it again. It
+// returns nil if nothing has been applied.
+func (m *Migrator) Redo(ctx context.Context) (*Migration, error) {
+	var migration *Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		versions := newestFirst(applied)
+		if len(versions) == 0 {
+			return nil
+		}
+
+		migration = m.find(versions[0])
+		if migration == nil {
+			return fmt.Errorf("%w: version %d", ErrUnknownMigration, versions[0])
+		}
+		if err := revert(ctx, conn, migration); err != nil {
+			return err
+		}
+		return apply(ctx, conn, migration)
+	})
+	return migration, err
+}
+
+// Status lists every migration in this build and every applied one that is
+// not, in version order. It only reads, so it takes no lock and leaves a
+// missing or old-style schema_migrations table as it is.
+func (m *Migrator) Status(ctx context.Context) ([]*MigrationStatus, error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	applied, err := m.appliedReadOnly(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []*MigrationStatus
+	for _, migration := range m.migrations {
+		status := &MigrationStatus{Version: migration.Version, Name: migration.Name, State: MigrationPending}
+		if record, ok := applied[migration.Version]; ok {
+			status.State = MigrationApplied
+			if record.checksum != migration.Checksum {
+				status.State = MigrationModified
+			}
+			appliedAt := record.appliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	for version, record := range applied {
+		if m.find(version) == nil {
+			appliedAt := record.appliedAt
+			statuses = append(statuses, &MigrationStatus{
+				Version: version, Name: record.name, State: MigrationMissing, AppliedAt: &appliedAt,
+			})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Baseline records the migrations up to version as applied without running
+// them, for databases whose schema was created before migrations were
+// tracked. It refuses if any migration is already recorded.
+func (m *Migrator) Baseline(ctx context.Context, version int) ([]*Migration, error) {
+	var done []*Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if len(applied) > 0 {
+			return ErrAlreadyTracked
+		}
+
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+			if err := record(ctx, conn, migration); err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// withLock runs fn on one connection while holding the migration lock, once
+// the schema_migrations table exists
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			log.Printf("ERROR releasing migration lock: %v", err)
+		}
+	}()
+
+	if err := m.prepareTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// prepareTable creates schema_migrations, converting the table of the
+// previous runner, which keyed rows by file name and kept no checksums
+func (m *Migrator) prepareTable(ctx context.Context, conn *pgxpool.Conn) error {
+	var legacy bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'schema_migrations'
+				AND column_name = 'version' AND data_type <> 'integer'
+		)
+	`
+	if err := conn.QueryRow(ctx, query).Scan(&legacy); err != nil {
+		return fmt.Errorf("failed to inspect migrations table: %w", err)
+	}
+	if !legacy {
+		if _, err := conn.Exec(ctx, createMigrationsTable); err != nil {
+			return fmt.Errorf("failed to create migrations table: %w", err)
+		}
+		return nil
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	old, err := legacyApplied(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DROP TABLE schema_migrations`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, createMigrationsTable); err != nil {
+		return err
+	}
+	for version, appliedAt := range old {
+		migration := m.find(version)
+		if migration == nil {
+			return fmt.Errorf("%w: version %d", ErrUnknownMigration, version)
+		}
+		query := `INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)`
+		if _, err := tx.Exec(ctx, query, migration.Version, migration.Name, migration.Checksum, appliedAt); err != nil {
+			return err
+		}
+	}
+	log.Printf("Converted schema_migrations: %d migrations recorded", len(old))
+	return tx.Commit(ctx)
+}
+
+// legacyApplied reads the previous runner's schema_migrations, which keyed
+// rows by file name, into applied times by version
+func legacyApplied(ctx context.Context, q querier) (map[int]time.Time, error) {
+	rows, err := q.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	old := map[int]time.Time{}
+	for rows.Next() {
+		var name string
+		var appliedAt time.Time
+		if err := rows.Scan(&name, &appliedAt); err != nil {
+			return nil, err
+		}
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("unrecognised migration %q in schema_migrations", name)
+		}
+		old[version] = appliedAt
+	}
+	return old, rows.Err()
+}
+
+// appliedReadOnly is applied for a table that may not have been prepared.
+// With no table every migration is pending; the rows of an old-style table
+// count as applied with this build's checksums, as converting it records.
+func (m *Migrator) appliedReadOnly(ctx context.Context, conn *pgxpool.Conn) (map[int]appliedMigration, error) {
+	var versionType *string
+	query := `
+		SELECT max(data_type) FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'schema_migrations' AND column_name = 'version'
+	`
+	if err := conn.QueryRow(ctx, query).Scan(&versionType); err != nil {
+		return nil, fmt.Errorf("failed to inspect migrations table: %w", err)
+	}
+	switch {
+	case versionType == nil:
+		return map[int]appliedMigration{}, nil
+	case *versionType == "integer":
+		return m.applied(ctx, conn)
+	}
+
+	old, err := legacyApplied(ctx, conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	applied := map[int]appliedMigration{}
+	for version, appliedAt := range old {
+		record := appliedMigration{appliedAt: appliedAt}
+		if migration := m.find(version); migration != nil {
+			record.name, record.checksum = migration.Name, migration.Checksum
+		}
+		applied[version] = record
+	}
+	return applied, nil
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *pgxpool.Conn) (map[int]appliedMigration, error) {
+	rows, err := conn.Query(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]appliedMigration{}
+	for rows.Next() {
+		var version int
+		var record appliedMigration
+		if err := rows.Scan(&version, &record.name, &record.checksum, &record.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = record
+	}
+	return applied, rows.Err()
+}
+
+// verify checks that no applied migration in this build has changed since
+func (m *Migrator) verify(applied map[int]appliedMigration) error {
+	for _, migration := range m.migrations {
+		if record, ok := applied[migration.Version]; ok && record.checksum != migration.Checksum {
+			return fmt.Errorf("%w: %s", ErrChecksumMismatch, migration)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) find(version int) *Migration {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return nil
+}
+
+// legacyMarkers tell which of the migrations that predate tracking a database
+// has. Those were applied by piping the files into psql, with nothing
+// recorded, so each is recognised by what it left behind.
+var legacyMarkers = []struct {
+	version int
+	query   string
+}{
+	{1, `SELECT to_regclass('creators') IS NOT NULL`},
+	{2, `SELECT EXISTS (SELECT 1 FROM entrance_types)`},
+	{3, `SELECT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'events' AND column_name = 'lead_by'
+	)`},
+	{4, `SELECT EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'events' AND column_name = 'venue'
+	)`},
+}
+
+// untrackedVersion returns the last migration an untracked database already
+// has, or 0 if it is empty. A schema with tables from after tracking began
+// cannot be placed and needs an explicit baseline.
+func untrackedVersion(ctx context.Context, conn *pgxpool.Conn) (int, error) {
+	var tracked bool
+	if err := conn.QueryRow(ctx, `SELECT to_regclass('agent_api_keys') IS NOT NULL`).Scan(&tracked); err != nil {
+		return 0, err
+	}
+	if tracked {
+		return 0, ErrUntrackedSchema
+	}
+
+	version := 0
+	for _, marker := range legacyMarkers {
+		var present bool
+		if err := conn.QueryRow(ctx, marker.query).Scan(&present); err != nil {
+			return 0, fmt.Errorf("failed to inspect untracked schema: %w", err)
+		}
+		if present {
+			version = marker.version
+		} else if version == 0 {
+			// Without creators there is nothing else to find
+			return 0, nil
+		}
+	}
+	return version, nil
+}
+
+func apply(ctx context.Context, conn *pgxpool.Conn, migration *Migration) error {
+	log.Printf("Applying migration: %s", migration)
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, migration.Up); err != nil {
+		return fmt.Errorf("failed to apply migration %s: %w", migration, err)
+	}
+	query := `INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(ctx, query, migration.Version, migration.Name, migration.Checksum); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", migration, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit migration %s: %w", migration, err)
+	}
+
+	log.Printf("✅ Applied migration: %s", migration)
+	return nil
+}
+
+func revert(ctx context.Context, conn *pgxpool.Conn, migration *Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("%w: %s", ErrNoDownMigration, migration)
+	}
+	log.Printf("Rolling back migration: %s", migration)
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, migration.Down); err != nil {
+		return fmt.Errorf("failed to roll back migration %s: %w", migration, err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version); err != nil {
+		return fmt.Errorf("failed to unrecord migration %s: %w", migration, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit rollback of %s: %w", migration, err)
+	}
+
+	log.Printf("✅ Rolled back migration: %s", migration)
+	return nil
+}
+
+func record(ctx context.Context, conn *pgxpool.Conn, migration *Migration) error {
+	query := `INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`
+	if _, err := conn.Exec(ctx, query, migration.Version, migration.Name, migration.Checksum); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", migration, err)
+	}
+	return nil
+}
+
+func newestFirst(applied map[int]appliedMigration) []int {
+	versions := make([]int, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+	return versions
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// ErrMigrateUsage is returned for a migrate command line that cannot be run
+var ErrMigrateUsage = errors.New("invalid migrate command")
+
+// MigrateUsage describes the migrate subcommands
+const MigrateUsage = `usage: migrate <command>
+
+commands:
+  up              apply all pending migrations
+  down [n]        roll back the last n migrations (default 1)
+  status          list migrations and whether they are applied
+  redo            roll back the last migration and apply it again
+  baseline <n>    record migrations up to n as applied without running them,
+                  for databases created before migrations were tracked
+`
+
+// RunMigrateCommand runs a migrate subcommand (args excludes "migrate")
+// against db, writing its report to out
+func RunMigrateCommand(ctx context.Context, db *Database, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return ErrMigrateUsage
+	}
+
+	migrator, err := NewMigrator(db.Pool)
+	if err != nil {
+		return err
+	}
+
+	switch command := args[0]; {
+	case command == "up" && len(args) == 1:
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "No pending migrations")
+		}
+		for _, migration := range applied {
+			fmt.Fprintf(out, "Applied %s\n", migration)
+		}
+
+	case command == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return ErrMigrateUsage
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Fprintln(out, "No applied migrations")
+		}
+		for _, migration := range reverted {
+			fmt.Fprintf(out, "Rolled back %s\n", migration)
+		}
+
+	case command == "redo" && len(args) == 1:
+		migration, err := migrator.Redo(ctx)
+		if err != nil {
+			return err
+		}
+		if migration == nil {
+			fmt.Fprintln(out, "No applied migrations")
+			return nil
+		}
+		fmt.Fprintf(out, "Redid %s\n", migration)
+
+	case command == "status" && len(args) == 1:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "-"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(w, "%03d\t%s\t%s\t%s\n", status.Version, status.Name, status.State, appliedAt)
+		}
+		return w.Flush()
+
+	case command == "baseline" && len(args) == 2:
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 1 {
+			return ErrMigrateUsage
+		}
+		recorded, err := migrator.Baseline(ctx, version)
+		if err != nil {
+			return err
+		}
+		for _, migration := range recorded {
+			fmt.Fprintf(out, "Recorded %s\n", migration)
+		}
+
+	default:
+		return ErrMigrateUsage
+	}
+	return nil
+}
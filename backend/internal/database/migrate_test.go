@@ -0,0 +1,121 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/002_add_venue.up.sql":   {Data: []byte("ALTER TABLE events ADD COLUMN venue TEXT;")},
+		"m/002_add_venue.down.sql": {Data: []byte("ALTER TABLE events DROP COLUMN venue;")},
+		"m/001_init.up.sql":        {Data: []byte("CREATE TABLE events (id INT);")},
+		"m/README.md":              {Data: []byte("not a migration")},
+	}
+
+	migrations, err := LoadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("got %d migrations, want 2", len(migrations))
+	}
+
+	first, second := migrations[0], migrations[1]
+	if first.String() != "001_init" || second.String() != "002_add_venue" {
+		t.Fatalf("migrations = %s, %s; want them in version order", first, second)
+	}
+	if first.Down != "" || second.Down == "" {
+		t.Fatalf("down files not paired with their migrations")
+	}
+	if len(first.Checksum) != 64 || first.Checksum == second.Checksum {
+		t.Fatalf("checksums %q and %q", first.Checksum, second.Checksum)
+	}
+}
+
+func TestLoadMigrationsRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{
+			"duplicate version",
+			fstest.MapFS{
+				"m/004_add_venue.up.sql":           {Data: []byte("SELECT 1;")},
+				"m/004_add_venue_to_events.up.sql": {Data: []byte("SELECT 1;")},
+			},
+			"duplicate migration version 4",
+		},
+		{
+			"down without up",
+			fstest.MapFS{"m/003_drop_things.down.sql": {Data: []byte("SELECT 1;")}},
+			"no up file",
+		},
+		{
+			"badly named file",
+			fstest.MapFS{"m/add_venue.sql": {Data: []byte("SELECT 1;")}},
+			"is not named",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadMigrations(tt.files, "m")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("LoadMigrations() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(migrationsFS, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("migration %s: want version %d, versions must not skip", migration, i+1)
+		}
+		if migration.Down == "" {
+			t.Errorf("migration %s has no down file", migration)
+		}
+	}
+}
+
+// TestSeedsMatchSeedMigration checks that the reseed files restore what
+// migration 002 inserted, and that only the demo file holds accounts
+func TestSeedsMatchSeedMigration(t *testing.T) {
+	migrations, err := LoadMigrations(migrationsFS, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed := migrations[1]
+	if seed.Version != 2 {
+		t.Fatalf("second migration is %s, want 002_seed_data", seed)
+	}
+
+	tests := []struct {
+		file     string
+		accounts bool
+	}{
+		{referenceSeed, false},
+		{demoSeed, true},
+	}
+	for _, tt := range tests {
+		content, err := seedsFS.ReadFile(tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The statements follow the file's own header
+		_, body, ok := strings.Cut(string(content), "\n\n")
+		if !ok || !strings.Contains(seed.Up, body) {
+			t.Errorf("%s differs from the statements of %s", tt.file, seed)
+		}
+		if got := strings.Contains(body, "INSERT INTO admins"); got != tt.accounts {
+			t.Errorf("%s inserts admins: %t, want %t", tt.file, got, tt.accounts)
+		}
+	}
+}
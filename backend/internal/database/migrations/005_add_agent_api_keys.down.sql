@@ -0,0 +1,6 @@
+-- ===========================================
+-- Remove agent API keys
+-- ===========================================
+
+DROP TRIGGER IF EXISTS update_agent_api_keys_updated_at ON agent_api_keys;
+DROP TABLE IF EXISTS agent_api_keys;
@@ -0,0 +1,24 @@
+-- ===========================================
+-- Agent API keys (admin-issued, bound to a creator)
+-- ===========================================
+
+CREATE TABLE agent_api_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    key_prefix VARCHAR(20) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    creator_id UUID NOT NULL REFERENCES creators(id) ON DELETE CASCADE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_by UUID REFERENCES admins(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_agent_api_keys_creator ON agent_api_keys(creator_id);
+
+CREATE TRIGGER update_agent_api_keys_updated_at
+    BEFORE UPDATE ON agent_api_keys
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,8 @@
+-- ===========================================
+-- Remove external_id from events
+-- ===========================================
+
+DROP INDEX IF EXISTS idx_events_creator_external_id;
+
+ALTER TABLE events
+DROP COLUMN IF EXISTS external_id;
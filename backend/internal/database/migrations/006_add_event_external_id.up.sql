@@ -0,0 +1,10 @@
+-- ===========================================
+-- Add agent-supplied external_id to events
+-- ===========================================
+
+ALTER TABLE events
+ADD COLUMN external_id VARCHAR(255);
+
+CREATE UNIQUE INDEX idx_events_creator_external_id
+    ON events(creator_id, external_id)
+    WHERE external_id IS NOT NULL;
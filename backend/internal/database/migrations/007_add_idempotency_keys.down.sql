@@ -0,0 +1,6 @@
+-- ===========================================
+-- Remove idempotency key storage
+-- ===========================================
+
+DROP INDEX IF EXISTS idx_events_date_location;
+DROP TABLE IF EXISTS idempotency_keys;
@@ -1,6 +1,5 @@
 -- ===========================================
--- Add venue field to events table
+-- Remove the uploads registry
 -- ===========================================
 
-ALTER TABLE events
-ADD COLUMN venue VARCHAR(50);
+DROP TABLE IF EXISTS uploads;
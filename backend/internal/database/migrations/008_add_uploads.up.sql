@@ -0,0 +1,20 @@
+-- ===========================================
+-- Uploads registry: who stored each image and which event uses it
+-- ===========================================
+
+CREATE TABLE uploads (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    storage_key VARCHAR(512) NOT NULL UNIQUE,
+    source VARCHAR(20) NOT NULL,
+    creator_id UUID REFERENCES creators(id) ON DELETE SET NULL,
+    agent_key_id UUID REFERENCES agent_api_keys(id) ON DELETE SET NULL,
+    event_id UUID REFERENCES events(id) ON DELETE SET NULL,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    attached_at TIMESTAMP WITH TIME ZONE,
+    detached_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- The sweeper only ever looks at unattached uploads
+CREATE INDEX idx_uploads_unattached ON uploads(created_at) WHERE event_id IS NULL;
+CREATE INDEX idx_uploads_event ON uploads(event_id);
@@ -0,0 +1,6 @@
+-- ===========================================
+-- Remove event image galleries
+-- ===========================================
+
+DROP TRIGGER IF EXISTS update_event_images_updated_at ON event_images;
+DROP TABLE IF EXISTS event_images;
@@ -0,0 +1,29 @@
+-- ===========================================
+-- Event image galleries
+-- ===========================================
+
+CREATE TABLE event_images (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    image_url VARCHAR(512) NOT NULL,
+    caption VARCHAR(500),
+    alt_text VARCHAR(500),
+    position INTEGER NOT NULL DEFAULT 0,
+    is_cover BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_event_images_event ON event_images(event_id, position);
+CREATE INDEX idx_event_images_image_url ON event_images(image_url);
+CREATE UNIQUE INDEX idx_event_images_one_cover ON event_images(event_id) WHERE is_cover;
+
+CREATE TRIGGER update_event_images_updated_at
+    BEFORE UPDATE ON event_images
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Existing single images become the cover of a one-image gallery
+INSERT INTO event_images (event_id, image_url, position, is_cover)
+SELECT id, image_url, 0, TRUE
+FROM events
+WHERE image_url IS NOT NULL AND image_url <> '';
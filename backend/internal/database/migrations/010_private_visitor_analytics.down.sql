@@ -0,0 +1,17 @@
+-- ===========================================
+-- Restore raw visitor columns. Dropped IPs and user agents cannot be recovered.
+-- ===========================================
+
+DROP TABLE IF EXISTS visitor_daily_segments;
+DROP TABLE IF EXISTS visitor_daily_stats;
+DROP TABLE IF EXISTS visitor_salts;
+
+DROP INDEX IF EXISTS idx_visitors_hash;
+
+ALTER TABLE visitors
+    DROP COLUMN IF EXISTS visitor_hash,
+    DROP COLUMN IF EXISTS browser,
+    DROP COLUMN IF EXISTS os,
+    DROP COLUMN IF EXISTS device,
+    ADD COLUMN ip_address VARCHAR(50),
+    ADD COLUMN user_agent TEXT;
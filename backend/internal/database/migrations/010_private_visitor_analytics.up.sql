@@ -0,0 +1,58 @@
+-- ===========================================
+-- Private visitor analytics: no raw IPs or user agents
+-- ===========================================
+
+-- One random salt per day. Visitor hashes are only comparable within a day,
+-- and once a day's salt is deleted they cannot be linked back to an address.
+CREATE TABLE visitor_salts (
+    day DATE PRIMARY KEY,
+    salt BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+ALTER TABLE visitors
+    ADD COLUMN visitor_hash CHAR(64),
+    ADD COLUMN browser VARCHAR(50),
+    ADD COLUMN os VARCHAR(50),
+    ADD COLUMN device VARCHAR(20);
+
+CREATE INDEX idx_visitors_hash ON visitors(visitor_hash, visited_at);
+
+-- Daily totals, kept after raw visits pass the retention period
+CREATE TABLE visitor_daily_stats (
+    day DATE PRIMARY KEY,
+    visits INT NOT NULL DEFAULT 0,
+    unique_visitors INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Daily visits by country, browser, os and device
+CREATE TABLE visitor_daily_segments (
+    day DATE NOT NULL,
+    dimension VARCHAR(20) NOT NULL,
+    value VARCHAR(100) NOT NULL,
+    visits INT NOT NULL DEFAULT 0,
+    unique_visitors INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, dimension, value)
+);
+
+-- Roll up what is already recorded
+INSERT INTO visitor_daily_stats (day, visits, unique_visitors)
+SELECT visited_at::date, COUNT(*), COUNT(DISTINCT ip_address)
+FROM visitors
+GROUP BY visited_at::date;
+
+INSERT INTO visitor_daily_segments (day, dimension, value, visits, unique_visitors)
+SELECT visited_at::date, 'country', COALESCE(NULLIF(country, ''), 'Unknown'), COUNT(*), COUNT(DISTINCT ip_address)
+FROM visitors
+GROUP BY visited_at::date, COALESCE(NULLIF(country, ''), 'Unknown');
+
+-- Hash existing visits with a salt that is thrown away, then drop the raw data.
+-- Their browser, os and device stay unknown.
+UPDATE visitors v
+SET visitor_hash = encode(sha256(convert_to(s.salt || v.visited_at::date || COALESCE(v.ip_address, '') || COALESCE(v.user_agent, ''), 'UTF8')), 'hex')
+FROM (SELECT md5(random()::text || clock_timestamp()::text) AS salt) s;
+
+ALTER TABLE visitors
+    DROP COLUMN ip_address,
+    DROP COLUMN user_agent;
@@ -0,0 +1,6 @@
+-- ===========================================
+-- Remove per-event stats
+-- ===========================================
+
+DROP TABLE IF EXISTS event_daily_stats;
+DROP TABLE IF EXISTS event_interactions;
@@ -0,0 +1,26 @@
+-- ===========================================
+-- Per-event views, contact clicks and shares
+-- ===========================================
+
+-- Who did what today, only to count each visitor once per event, action and
+-- day. Rows are deleted with the day's salt, once the hashes mean nothing.
+CREATE TABLE event_interactions (
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    day DATE NOT NULL DEFAULT CURRENT_DATE,
+    action VARCHAR(20) NOT NULL,
+    visitor_hash CHAR(64) NOT NULL,
+    PRIMARY KEY (event_id, day, action, visitor_hash)
+);
+
+CREATE INDEX idx_event_interactions_day ON event_interactions(day);
+
+-- Daily counts of distinct visitors per action
+CREATE TABLE event_daily_stats (
+    event_id UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    views INT NOT NULL DEFAULT 0,
+    email_clicks INT NOT NULL DEFAULT 0,
+    whatsapp_clicks INT NOT NULL DEFAULT 0,
+    share_clicks INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (event_id, day)
+);
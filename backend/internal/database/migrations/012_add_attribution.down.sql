@@ -0,0 +1,27 @@
+-- ===========================================
+-- Remove attribution
+-- ===========================================
+
+DELETE FROM visitor_daily_segments WHERE dimension = 'source';
+
+DROP INDEX IF EXISTS idx_creators_source;
+
+ALTER TABLE creators
+    DROP COLUMN IF EXISTS source,
+    DROP COLUMN IF EXISTS referrer,
+    DROP COLUMN IF EXISTS landing_path,
+    DROP COLUMN IF EXISTS utm_source,
+    DROP COLUMN IF EXISTS utm_medium,
+    DROP COLUMN IF EXISTS utm_campaign,
+    DROP COLUMN IF EXISTS utm_term,
+    DROP COLUMN IF EXISTS utm_content;
+
+ALTER TABLE visitors
+    DROP COLUMN IF EXISTS source,
+    DROP COLUMN IF EXISTS referrer,
+    DROP COLUMN IF EXISTS landing_path,
+    DROP COLUMN IF EXISTS utm_source,
+    DROP COLUMN IF EXISTS utm_medium,
+    DROP COLUMN IF EXISTS utm_campaign,
+    DROP COLUMN IF EXISTS utm_term,
+    DROP COLUMN IF EXISTS utm_content;
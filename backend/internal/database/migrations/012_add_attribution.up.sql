@@ -0,0 +1,28 @@
+-- ===========================================
+-- Referrer and UTM attribution of visits and creator signups
+-- ===========================================
+
+-- source is derived from utm_source or the referring site. Referrers are
+-- stored without their query string.
+ALTER TABLE visitors
+    ADD COLUMN source VARCHAR(100),
+    ADD COLUMN referrer VARCHAR(512),
+    ADD COLUMN landing_path VARCHAR(512),
+    ADD COLUMN utm_source VARCHAR(100),
+    ADD COLUMN utm_medium VARCHAR(100),
+    ADD COLUMN utm_campaign VARCHAR(100),
+    ADD COLUMN utm_term VARCHAR(100),
+    ADD COLUMN utm_content VARCHAR(100);
+
+-- Creators keep where they first arrived from before signing up
+ALTER TABLE creators
+    ADD COLUMN source VARCHAR(100),
+    ADD COLUMN referrer VARCHAR(512),
+    ADD COLUMN landing_path VARCHAR(512),
+    ADD COLUMN utm_source VARCHAR(100),
+    ADD COLUMN utm_medium VARCHAR(100),
+    ADD COLUMN utm_campaign VARCHAR(100),
+    ADD COLUMN utm_term VARCHAR(100),
+    ADD COLUMN utm_content VARCHAR(100);
+
+CREATE INDEX idx_creators_source ON creators(source);
@@ -0,0 +1,13 @@
+-- ===========================================
+-- Remove the bot flag
+-- ===========================================
+
+DELETE FROM visitor_daily_segments WHERE dimension = 'bot';
+
+ALTER TABLE visitor_daily_stats
+    DROP COLUMN IF EXISTS bot_visits,
+    DROP COLUMN IF EXISTS bot_unique_visitors;
+
+-- Without the flag the rollups would count bot visits as people
+DELETE FROM visitors WHERE is_bot;
+ALTER TABLE visitors DROP COLUMN IF EXISTS is_bot;
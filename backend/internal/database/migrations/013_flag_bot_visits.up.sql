@@ -0,0 +1,12 @@
+-- ===========================================
+-- Flag visits by crawlers and scripts
+-- ===========================================
+
+-- Bots are kept out of visits and unique_visitors, and counted apart. For a
+-- bot, browser holds the crawler's name. Past visits cannot be classified:
+-- their user agents were never kept.
+ALTER TABLE visitors ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE visitor_daily_stats
+    ADD COLUMN bot_visits INT NOT NULL DEFAULT 0,
+    ADD COLUMN bot_unique_visitors INT NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+-- ===========================================
+-- Remove the payment completion time
+-- ===========================================
+
+DROP INDEX IF EXISTS idx_payments_completed_at;
+ALTER TABLE payments DROP COLUMN IF EXISTS completed_at;
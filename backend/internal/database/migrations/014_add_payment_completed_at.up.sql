@@ -0,0 +1,12 @@
+-- ===========================================
+-- Record when a payment completed
+-- ===========================================
+
+-- Reports count revenue on the day a payment completed, not the day its
+-- checkout was opened. updated_at is the closest record of that for past
+-- payments.
+ALTER TABLE payments ADD COLUMN completed_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE payments SET completed_at = updated_at WHERE status = 'completed';
+
+CREATE INDEX idx_payments_completed_at ON payments(completed_at) WHERE status = 'completed';
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+)
+
+//go:embed seeds/*.sql
+var seedsFS embed.FS
+
+// Seed files, kept apart from migration 002 so that reference data can be
+// restored without its demo accounts
+const (
+	referenceSeed = "seeds/reference.sql"
+	demoSeed      = "seeds/demo.sql"
+)
+
+// Reseed restores the locations, event types and entrance types that were
+// deleted. With withDemoData it also restores the demo admin and creator,
+// whose passwords are public, and the sample event. Rows that still exist
+// are left as they are.
+func (db *Database) Reseed(ctx context.Context, withDemoData bool) error {
+	files := []string{referenceSeed}
+	if withDemoData {
+		files = append(files, demoSeed)
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, file := range files {
+		content, err := seedsFS.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, string(content)); err != nil {
+			return fmt.Errorf("seed %s: %w", file, err)
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,45 @@
+-- ===========================================
+-- Zen Bali Demo Data
+-- ===========================================
+-- Well-known accounts and a sample event for local development. Restored
+-- only by `zenbali-admin reseed --with-demo-data`: the passwords below are
+-- public, so this must never run against a production database.
+
+-- ===========================================
+-- Admin User (password: Teameditor@123)
+-- ===========================================
+
+INSERT INTO admins (email, password_hash, name, is_active) VALUES
+    ('admin@zenbali.org', '$2y$10$7ANIKiUEOuV3Su0j6leAAODblimGW6vsP0KfiMGQYOr0OK1Omr6pu', 'Admin', true)
+ON CONFLICT (email) DO NOTHING;
+
+-- ===========================================
+-- Test Creator (password: admin123)
+-- ===========================================
+
+INSERT INTO creators (id, name, organization_name, email, mobile, password_hash, is_verified, is_active) VALUES
+    ('98d34804-bf30-49b1-b578-af23b6b7c124', 'Test Creator', 'Test Organization', 'creator@zenbali.org', '+628123456789', '$2a$10$7E1R11SN79ghwafaxYckH./LZWgy4TagcjIgMJE94ByW5WACFTCD.', true, true)
+ON CONFLICT (email) DO NOTHING;
+
+-- ===========================================
+-- Sample Paid & Published Event
+-- ===========================================
+
+INSERT INTO events (creator_id, title, event_date, event_time, location_id, event_type_id, duration, entrance_type_id, entrance_fee, contact_email, contact_mobile, notes, image_url, is_paid, is_published)
+SELECT
+    '98d34804-bf30-49b1-b578-af23b6b7c124'::uuid,
+    'Sample Yoga Session in Ubud',
+    CURRENT_DATE + INTERVAL '1 day',
+    '09:00:00',
+    (SELECT id FROM locations WHERE slug = 'ubud'),
+    (SELECT id FROM event_types WHERE slug = 'yoga'),
+    '2 hours',
+    (SELECT id FROM entrance_types WHERE slug = 'prepaid-online'),
+    150000,
+    'creator@zenbali.org',
+    '+628123456789',
+    'This is a sample yoga session for testing purposes.',
+    '/uploads/af0b57c0-2cfb-4285-9edc-97e3da7c5c5a.png',
+    true,
+    true
+WHERE NOT EXISTS (SELECT 1 FROM events WHERE title = 'Sample Yoga Session in Ubud');
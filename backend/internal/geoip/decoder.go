@@ -0,0 +1,219 @@
+package geoip
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var errCorrupt = errors.New("geoip: corrupt database")
+
+// MMDB data section field types
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// decoder reads values from an MMDB data section. Pointers are offsets from
+// the start of buf.
+type decoder struct {
+	buf []byte
+}
+
+// decode returns the value at offset and the offset just past it. Maps decode
+// to map[string]any, arrays to []any, integers to uint64 or int32, and
+// uint128 values to their 16 raw bytes.
+func (d *decoder) decode(offset uint) (any, uint, error) {
+	return d.decodeDepth(offset, 0)
+}
+
+// maxDepth bounds nesting, so a database with pointer loops cannot recurse
+// forever
+const maxDepth = 64
+
+func (d *decoder) decodeDepth(offset uint, depth int) (any, uint, error) {
+	if depth > maxDepth {
+		return nil, 0, errCorrupt
+	}
+	ctrl, err := d.byteAt(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset++
+
+	kind := uint(ctrl >> 5)
+	if kind == typePointer {
+		target, next, err := d.pointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decodeDepth(target, depth+1)
+		return value, next, err
+	}
+	if kind == typeExtended {
+		ext, err := d.byteAt(offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		kind = 7 + uint(ext)
+		offset++
+	}
+
+	size, offset, err := d.size(ctrl, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch kind {
+	case typeMap:
+		values := make(map[string]any, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := d.decodeDepth(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, errCorrupt
+			}
+			value, next, err := d.decodeDepth(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			values[name] = value
+			offset = next
+		}
+		return values, offset, nil
+	case typeArray:
+		values := make([]any, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := d.decodeDepth(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			values = append(values, value)
+			offset = next
+		}
+		return values, offset, nil
+	case typeBool:
+		// The value is the size itself; there is no payload
+		return size != 0, offset, nil
+	}
+
+	payload, err := d.slice(offset, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	next := offset + size
+
+	switch kind {
+	case typeString:
+		return string(payload), next, nil
+	case typeBytes, typeUint128:
+		return append([]byte(nil), payload...), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errCorrupt
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(payload)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errCorrupt
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(payload)), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, errCorrupt
+		}
+		return uintFrom(payload), next, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, errCorrupt
+		}
+		return int32(uint32(uintFrom(payload))), next, nil
+	default:
+		return nil, 0, errCorrupt
+	}
+}
+
+// size reads the payload size encoded in ctrl and the bytes that follow it
+func (d *decoder) size(ctrl byte, offset uint) (uint, uint, error) {
+	size := uint(ctrl & 0x1f)
+	if size < 29 {
+		return size, offset, nil
+	}
+
+	extra := size - 28
+	bytes, err := d.slice(offset, extra)
+	if err != nil {
+		return 0, 0, err
+	}
+	value := uint(uintFrom(bytes))
+	switch extra {
+	case 1:
+		value += 29
+	case 2:
+		value += 285
+	case 3:
+		value += 65821
+	}
+	return value, offset + extra, nil
+}
+
+// pointer resolves a pointer's target and returns it with the offset past the
+// pointer
+func (d *decoder) pointer(ctrl byte, offset uint) (uint, uint, error) {
+	extra := uint((ctrl>>3)&0x3) + 1
+	bytes, err := d.slice(offset, extra)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	high := uint(ctrl & 0x7)
+	value := uint(uintFrom(bytes))
+	switch extra {
+	case 1:
+		value |= high << 8
+	case 2:
+		value = (value | high<<16) + 2048
+	case 3:
+		value = (value | high<<24) + 526336
+	}
+	return value, offset + extra, nil
+}
+
+func (d *decoder) byteAt(offset uint) (byte, error) {
+	if offset >= uint(len(d.buf)) {
+		return 0, errCorrupt
+	}
+	return d.buf[offset], nil
+}
+
+func (d *decoder) slice(offset, size uint) ([]byte, error) {
+	if offset > uint(len(d.buf)) || size > uint(len(d.buf))-offset {
+		return nil, errCorrupt
+	}
+	return d.buf[offset : offset+size], nil
+}
+
+func uintFrom(bytes []byte) uint64 {
+	var value uint64
+	for _, b := range bytes {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
@@ -0,0 +1,89 @@
+package geoip
+
+import (
+	"container/list"
+	"net/netip"
+	"sync"
+)
+
+// Locator answers lookups from a Reader through an LRU cache, since visitors
+// tend to come back from the same few addresses
+type Locator struct {
+	reader *Reader
+
+	mu      sync.Mutex
+	size    int
+	order   *list.List // most recently used first
+	entries map[netip.Addr]*list.Element
+}
+
+type cacheEntry struct {
+	addr  netip.Addr
+	loc   Location
+	found bool
+}
+
+// NewLocator caches up to cacheSize lookups; 0 or less disables the cache
+func NewLocator(reader *Reader, cacheSize int) *Locator {
+	return &Locator{
+		reader:  reader,
+		size:    cacheSize,
+		order:   list.New(),
+		entries: make(map[netip.Addr]*list.Element),
+	}
+}
+
+// Lookup is Reader.Lookup with caching. Misses are cached too; errors are not.
+func (l *Locator) Lookup(addr netip.Addr) (Location, bool, error) {
+	addr = addr.Unmap()
+	if entry, ok := l.get(addr); ok {
+		return entry.loc, entry.found, nil
+	}
+
+	loc, found, err := l.reader.Lookup(addr)
+	if err != nil {
+		return Location{}, false, err
+	}
+	l.put(&cacheEntry{addr: addr, loc: loc, found: found})
+	return loc, found, nil
+}
+
+func (l *Locator) get(addr netip.Addr) (*cacheEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.entries[addr]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry), true
+}
+
+func (l *Locator) put(entry *cacheEntry) {
+	if l.size <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.entries[entry.addr]; ok {
+		elem.Value = entry
+		l.order.MoveToFront(elem)
+		return
+	}
+	l.entries[entry.addr] = l.order.PushFront(entry)
+	if l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*cacheEntry).addr)
+	}
+}
+
+// Len returns the number of cached lookups
+func (l *Locator) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
@@ -0,0 +1,209 @@
+// Package geoip looks up where IP addresses are in a local MaxMind DB
+// (GeoLite2/GeoIP2 City or Country) file.
+package geoip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+)
+
+// metadataStart marks the metadata map at the end of an MMDB file
+var metadataStart = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// dataSectionGap is the run of zero bytes between the search tree and the
+// data section
+const dataSectionGap = 16
+
+// Location is what the database knows about an address. Names are in
+// English; fields the database lacks are empty.
+type Location struct {
+	Country     string
+	CountryCode string
+	City        string
+}
+
+// Reader searches an MMDB file held in memory. It is safe for concurrent use.
+type Reader struct {
+	tree       []byte
+	data       decoder
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	// ipv4Start is the node IPv4 lookups begin at in an IPv6 tree
+	ipv4Start uint
+}
+
+// Open reads the database at path
+func Open(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := FromBytes(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return reader, nil
+}
+
+// FromBytes parses a database already in memory. buf must not be modified
+// afterwards.
+func FromBytes(buf []byte) (*Reader, error) {
+	start := bytes.LastIndex(buf, metadataStart)
+	if start < 0 {
+		return nil, errors.New("geoip: not a MaxMind DB file")
+	}
+
+	meta := decoder{buf: buf[start+len(metadataStart):]}
+	value, _, err := meta.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := value.(map[string]any)
+	if !ok {
+		return nil, errCorrupt
+	}
+
+	r := &Reader{
+		nodeCount:  metaUint(fields, "node_count"),
+		recordSize: metaUint(fields, "record_size"),
+		ipVersion:  metaUint(fields, "ip_version"),
+	}
+	if major := metaUint(fields, "binary_format_major_version"); major != 2 {
+		return nil, fmt.Errorf("geoip: unsupported format version %d", major)
+	}
+	switch r.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("geoip: unsupported record size %d", r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("geoip: unsupported IP version %d", r.ipVersion)
+	}
+
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+dataSectionGap > uint(start) {
+		return nil, errCorrupt
+	}
+	r.tree = buf[:treeSize]
+	r.data = decoder{buf: buf[treeSize+dataSectionGap : start]}
+
+	if r.ipVersion == 6 {
+		// IPv4 addresses live under ::/96
+		node := uint(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// Lookup returns the location recorded for addr. found is false when the
+// database has no entry for it.
+func (r *Reader) Lookup(addr netip.Addr) (loc Location, found bool, err error) {
+	offset, found, err := r.find(addr)
+	if err != nil || !found {
+		return Location{}, false, err
+	}
+
+	value, _, err := r.data.decode(offset)
+	if err != nil {
+		return Location{}, false, err
+	}
+	record, ok := value.(map[string]any)
+	if !ok {
+		return Location{}, false, errCorrupt
+	}
+
+	country := child(record, "country")
+	loc = Location{
+		Country:     englishName(country),
+		CountryCode: stringField(country, "iso_code"),
+		City:        englishName(child(record, "city")),
+	}
+	return loc, true, nil
+}
+
+// find walks the search tree for addr and returns the data section offset of
+// its record
+func (r *Reader) find(addr netip.Addr) (uint, bool, error) {
+	if !addr.IsValid() {
+		return 0, false, nil
+	}
+
+	var ip []byte
+	node := uint(0)
+	switch {
+	case addr.Is4() || addr.Is4In6():
+		v4 := addr.Unmap().As4()
+		ip = v4[:]
+		if r.ipVersion == 6 {
+			node = r.ipv4Start
+		}
+	case r.ipVersion == 4:
+		return 0, false, nil
+	default:
+		v6 := addr.As16()
+		ip = v6[:]
+	}
+
+	for i := 0; i < len(ip)*8 && node < r.nodeCount; i++ {
+		bit := uint(ip[i/8]>>(7-i%8)) & 1
+		node = r.record(node, bit)
+	}
+
+	switch {
+	case node == r.nodeCount:
+		return 0, false, nil
+	case node > r.nodeCount:
+		offset := node - r.nodeCount - dataSectionGap
+		if offset >= uint(len(r.data.buf)) {
+			return 0, false, errCorrupt
+		}
+		return offset, true, nil
+	default:
+		// Ran out of address bits inside the tree
+		return 0, false, errCorrupt
+	}
+}
+
+// record reads the left (bit 0) or right (bit 1) record of a node
+func (r *Reader) record(node, bit uint) uint {
+	switch r.recordSize {
+	case 24:
+		b := r.tree[node*6+bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		b := r.tree[node*7:]
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		b := r.tree[node*8+bit*4:]
+		return uint(b[0])<<24 | uint(b[1])<<16 | uint(b[2])<<8 | uint(b[3])
+	}
+}
+
+func metaUint(fields map[string]any, name string) uint {
+	value, _ := fields[name].(uint64)
+	return uint(value)
+}
+
+func child(record map[string]any, name string) map[string]any {
+	value, _ := record[name].(map[string]any)
+	return value
+}
+
+func stringField(record map[string]any, name string) string {
+	value, _ := record[name].(string)
+	return value
+}
+
+func englishName(record map[string]any) string {
+	return stringField(child(record, "names"), "en")
+}
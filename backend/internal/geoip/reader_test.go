@@ -0,0 +1,278 @@
+package geoip
+
+import (
+	"bytes"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// pointer encodes as an MMDB pointer to a data section offset
+type pointer uint
+
+// encode writes v in the MMDB data format
+func encode(buf *bytes.Buffer, v any) {
+	switch v := v.(type) {
+	case pointer:
+		buf.WriteByte(0x20 | byte(v>>8))
+		buf.WriteByte(byte(v))
+	case string:
+		writeControl(buf, typeString, len(v))
+		buf.WriteString(v)
+	case uint64:
+		var payload []byte
+		for n := v; n > 0; n >>= 8 {
+			payload = append([]byte{byte(n)}, payload...)
+		}
+		writeControl(buf, typeUint32, len(payload))
+		buf.Write(payload)
+	case bool:
+		size := 0
+		if v {
+			size = 1
+		}
+		writeControl(buf, typeBool, size)
+	case []any:
+		writeControl(buf, typeArray, len(v))
+		for _, item := range v {
+			encode(buf, item)
+		}
+	case map[string]any:
+		writeControl(buf, typeMap, len(v))
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			encode(buf, key)
+			encode(buf, v[key])
+		}
+	default:
+		panic(fmt.Sprintf("cannot encode %T", v))
+	}
+}
+
+func writeControl(buf *bytes.Buffer, kind, size int) {
+	sizeBits, extra := size, []byte(nil)
+	if size >= 29 {
+		sizeBits, extra = 29, []byte{byte(size - 29)}
+	}
+	if kind > 7 {
+		buf.WriteByte(byte(sizeBits))
+		buf.WriteByte(byte(kind - 7))
+	} else {
+		buf.WriteByte(byte(kind<<5 | sizeBits))
+	}
+	buf.Write(extra)
+}
+
+// buildDB writes an MMDB file mapping each prefix to the record encoded
+// at the same index of records
+func buildDB(t *testing.T, ipVersion, recordSize int, prefixes []string, records []any) []byte {
+	t.Helper()
+
+	var data bytes.Buffer
+	offsets := make([]int, len(records))
+	for i, record := range records {
+		offsets[i] = data.Len()
+		encode(&data, record)
+	}
+
+	// A record is a node index, -1 for no data, or -(i+2) for records[i]
+	nodes := [][2]int{{-1, -1}}
+	for i, text := range prefixes {
+		prefix := netip.MustParsePrefix(text)
+		ip := prefix.Addr().AsSlice()
+		bits := prefix.Bits()
+		if ipVersion == 6 && prefix.Addr().Is4() {
+			ip = append(make([]byte, 12), ip...)
+			bits += 96
+		}
+
+		node := 0
+		for bit := 0; bit < bits; bit++ {
+			side := int(ip[bit/8]>>(7-bit%8)) & 1
+			if bit == bits-1 {
+				nodes[node][side] = -(i + 2)
+				break
+			}
+			if nodes[node][side] < 0 {
+				nodes = append(nodes, [2]int{-1, -1})
+				nodes[node][side] = len(nodes) - 1
+			}
+			node = nodes[node][side]
+		}
+	}
+
+	nodeCount := len(nodes)
+	value := func(record int) uint64 {
+		switch {
+		case record >= 0:
+			return uint64(record)
+		case record == -1:
+			return uint64(nodeCount)
+		default:
+			return uint64(nodeCount + dataSectionGap + offsets[-record-2])
+		}
+	}
+
+	var file bytes.Buffer
+	for _, node := range nodes {
+		left, right := value(node[0]), value(node[1])
+		switch recordSize {
+		case 24:
+			file.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left)})
+			file.Write([]byte{byte(right >> 16), byte(right >> 8), byte(right)})
+		case 28:
+			file.Write([]byte{byte(left >> 16), byte(left >> 8), byte(left)})
+			file.WriteByte(byte(left>>24)<<4 | byte(right>>24)&0x0f)
+			file.Write([]byte{byte(right >> 16), byte(right >> 8), byte(right)})
+		case 32:
+			file.Write([]byte{byte(left >> 24), byte(left >> 16), byte(left >> 8), byte(left)})
+			file.Write([]byte{byte(right >> 24), byte(right >> 16), byte(right >> 8), byte(right)})
+		}
+	}
+	file.Write(make([]byte, dataSectionGap))
+	file.Write(data.Bytes())
+	file.Write(metadataStart)
+	encode(&file, map[string]any{
+		"binary_format_major_version": uint64(2),
+		"binary_format_minor_version": uint64(0),
+		"database_type":               "Test-City",
+		"ip_version":                  uint64(ipVersion),
+		"languages":                   []any{"en"},
+		"node_count":                  uint64(nodeCount),
+		"record_size":                 uint64(recordSize),
+	})
+	return file.Bytes()
+}
+
+func cityRecord(country, code, city string) map[string]any {
+	return map[string]any{
+		"city": map[string]any{"names": map[string]any{"en": city}},
+		"country": map[string]any{
+			"iso_code":             code,
+			"names":                map[string]any{"en": country},
+			"is_in_european_union": false,
+		},
+	}
+}
+
+func testDB(t *testing.T, ipVersion, recordSize int) *Reader {
+	t.Helper()
+
+	country := map[string]any{"names": map[string]any{"en": "Indonesia"}}
+	prefixes := []string{"81.2.69.0/24", "103.10.0.0/16"}
+	records := []any{
+		cityRecord("United Kingdom", "GB", "London"),
+		// A long name, and an ISO code behind a pointer
+		map[string]any{
+			"city":    map[string]any{"names": map[string]any{"en": strings.Repeat("Denpasar ", 5)}},
+			"country": country,
+		},
+	}
+	if ipVersion == 6 {
+		prefixes = append(prefixes, "2001:db8::/32")
+		records = append(records, cityRecord("Indonesia", "ID", "Ubud"))
+	}
+
+	// The pointer target is a string after the records the tree points to
+	country["iso_code"] = pointer(0)
+	var data bytes.Buffer
+	for _, record := range records {
+		encode(&data, record)
+	}
+	country["iso_code"] = pointer(data.Len())
+	records = append(records, "ID")
+
+	reader, err := FromBytes(buildDB(t, ipVersion, recordSize, prefixes, records))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reader
+}
+
+func TestReaderLookup(t *testing.T) {
+	for _, ipVersion := range []int{4, 6} {
+		for _, recordSize := range []int{24, 28, 32} {
+			t.Run(fmt.Sprintf("ipv%d/%d", ipVersion, recordSize), func(t *testing.T) {
+				reader := testDB(t, ipVersion, recordSize)
+
+				tests := []struct {
+					addr  string
+					found bool
+					want  Location
+				}{
+					{"81.2.69.160", true, Location{Country: "United Kingdom", CountryCode: "GB", City: "London"}},
+					{"::ffff:81.2.69.1", true, Location{Country: "United Kingdom", CountryCode: "GB", City: "London"}},
+					{"81.2.70.1", false, Location{}},
+					{"2001:db8::1", ipVersion == 6, Location{}},
+					{"2001:db9::1", false, Location{}},
+				}
+				if ipVersion == 6 {
+					tests[3].want = Location{Country: "Indonesia", CountryCode: "ID", City: "Ubud"}
+				}
+
+				for _, tt := range tests {
+					got, found, err := reader.Lookup(netip.MustParseAddr(tt.addr))
+					if err != nil {
+						t.Fatalf("Lookup(%s): %v", tt.addr, err)
+					}
+					if found != tt.found || got != tt.want {
+						t.Errorf("Lookup(%s) = %+v, %v; want %+v, %v", tt.addr, got, found, tt.want, tt.found)
+					}
+				}
+
+				got, found, err := reader.Lookup(netip.MustParseAddr("103.10.4.4"))
+				if err != nil || !found {
+					t.Fatalf("Lookup(103.10.4.4) = %v, %v", found, err)
+				}
+				if got.City != strings.Repeat("Denpasar ", 5) || got.Country != "Indonesia" || got.CountryCode != "ID" {
+					t.Errorf("Lookup(103.10.4.4) = %+v", got)
+				}
+			})
+		}
+	}
+}
+
+func TestFromBytesRejectsOtherFiles(t *testing.T) {
+	if _, err := FromBytes([]byte("not a database")); err == nil {
+		t.Fatal("expected an error for a file without metadata")
+	}
+
+	buf := buildDB(t, 4, 24, []string{"81.2.69.0/24"}, []any{cityRecord("United Kingdom", "GB", "London")})
+	// Cut into the search tree
+	truncated := append([]byte(nil), buf[:3]...)
+	truncated = append(truncated, buf[bytes.LastIndex(buf, metadataStart):]...)
+	if _, err := FromBytes(truncated); err == nil {
+		t.Fatal("expected an error for a truncated tree")
+	}
+}
+
+func TestLocatorEvictsLeastRecentlyUsed(t *testing.T) {
+	locator := NewLocator(testDB(t, 6, 24), 2)
+
+	lookup := func(addr string) {
+		t.Helper()
+		if _, _, err := locator.Lookup(netip.MustParseAddr(addr)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lookup("81.2.69.1")
+	lookup("192.0.2.1") // misses are cached too
+	lookup("::ffff:81.2.69.1")
+	lookup("2001:db8::1")
+
+	if locator.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", locator.Len())
+	}
+	if _, ok := locator.entries[netip.MustParseAddr("192.0.2.1")]; ok {
+		t.Error("least recently used lookup was not evicted")
+	}
+	if _, ok := locator.entries[netip.MustParseAddr("81.2.69.1")]; !ok {
+		t.Error("mapped lookup did not share the IPv4 cache entry")
+	}
+}
@@ -49,7 +49,8 @@ func (h *AdminHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
 	}
 
 	totalVisitors, _ := h.repos.Visitor.GetTotalCount(ctx)
-	todayVisitors, _ := h.repos.Visitor.GetTodayCount(ctx)
+	todayVisitors, todayUniqueVisitors, todayBotVisits, _ := h.repos.Visitor.GetToday(ctx)
+	visitorSegments, _ := h.repos.Visitor.ListSegments(ctx, 30, 5)
 	recentEvents, _ := h.repos.Event.GetRecent(ctx, 5)
 	recentPayments, _ := h.repos.Payment.GetRecent(ctx, 5)
 
@@ -59,19 +60,25 @@ func (h *AdminHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
 	if recentPayments == nil {
 		recentPayments = []*models.Payment{}
 	}
+	if visitorSegments == nil {
+		visitorSegments = []*models.VisitorSegment{}
+	}
 
 	stats := models.DashboardStats{
-		TotalEvents:     totalEvents,
-		PublishedEvents: publishedEvents,
-		UpcomingEvents:  upcomingEvents,
-		TotalCreators:   totalCreators,
-		ActiveCreators:  activeCreators,
-		TotalPayments:   totalPayments,
-		TotalRevenue:    totalRevenue,
-		TotalVisitors:   totalVisitors,
-		TodayVisitors:   todayVisitors,
-		RecentEvents:    recentEvents,
-		RecentPayments:  recentPayments,
+		TotalEvents:         totalEvents,
+		PublishedEvents:     publishedEvents,
+		UpcomingEvents:      upcomingEvents,
+		TotalCreators:       totalCreators,
+		ActiveCreators:      activeCreators,
+		TotalPayments:       totalPayments,
+		TotalRevenue:        totalRevenue,
+		TotalVisitors:       totalVisitors,
+		TodayVisitors:       todayVisitors,
+		TodayUniqueVisitors: todayUniqueVisitors,
+		TodayBotVisits:      todayBotVisits,
+		VisitorSegments:     visitorSegments,
+		RecentEvents:        recentEvents,
+		RecentPayments:      recentPayments,
 	}
 
 	utils.Success(w, stats)
@@ -124,15 +131,8 @@ func (h *AdminHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *AdminHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		models.EventCreateRequest
-		CreatorID   string `json:"creator_id"`
-		ImageURL    string `json:"image_url"`
-		IsPaid      *bool  `json:"is_paid"`
-		IsPublished *bool  `json:"is_published"`
-	}
-	if err := utils.ParseJSON(r, &req); err != nil {
-		utils.BadRequest(w, "Invalid request body")
+	var req models.AdminEventCreateRequest
+	if !utils.DecodeJSON(w, r, &req) {
 		return
 	}
 
@@ -142,104 +142,59 @@ func (h *AdminHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	event, err := h.services.Event.Create(r.Context(), creatorID, &req.EventCreateRequest)
+	var imageURL *string
+	if req.ImageURL != "" {
+		ref := h.services.Upload.ImageRef(req.ImageURL)
+		imageURL = &ref
+	}
+	event, err := h.services.Event.CreateWithAdminFields(r.Context(), creatorID, &req.EventCreateRequest, imageURL, req.IsPaid, req.IsPublished)
 	if err != nil {
-		if err == services.ErrInvalidDate {
-			utils.BadRequest(w, "Invalid date format. Use YYYY-MM-DD")
-			return
-		}
-		if err.Error() == "price_thousands must be between 0 and 100000" {
-			utils.BadRequest(w, err.Error())
-			return
-		}
-		utils.InternalError(w, "Failed to create event")
+		writeError(w, err, "Failed to create event")
 		return
 	}
 
-	if req.ImageURL != "" || req.IsPaid != nil || req.IsPublished != nil {
-		var imageURL *string
-		if req.ImageURL != "" {
-			imageURL = &req.ImageURL
-		}
-		if err := h.repos.Event.UpdateAdminFields(r.Context(), event.ID, imageURL, req.IsPaid, req.IsPublished); err != nil {
-			utils.InternalError(w, "Failed to update event admin fields")
-			return
-		}
-		event, err = h.services.Event.GetByID(r.Context(), event.ID)
-		if err != nil {
-			utils.InternalError(w, "Failed to fetch created event")
-			return
-		}
-	}
-
 	utils.Created(w, event.ToResponse())
 }
 
-func (h *AdminHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	id, err := uuid.Parse(idStr)
+// ImportEvents bulk-creates events for one creator from CSV or NDJSON.
+// Query: creator_id (required), dry_run, strict, publish, format, report=csv.
+func (h *AdminHandler) ImportEvents(w http.ResponseWriter, r *http.Request) {
+	creatorID, err := uuid.Parse(r.URL.Query().Get("creator_id"))
 	if err != nil {
-		utils.BadRequest(w, "Invalid event ID")
+		utils.BadRequest(w, "Invalid creator ID")
 		return
 	}
 
-	var req struct {
-		models.EventUpdateRequest
-		CreatorID   string `json:"creator_id"`
-		ImageURL    string `json:"image_url"`
-		IsPaid      *bool  `json:"is_paid"`
-		IsPublished *bool  `json:"is_published"`
+	creator, err := h.repos.Creator.GetByID(r.Context(), creatorID)
+	if err != nil {
+		utils.InternalError(w, "Failed to fetch creator")
+		return
 	}
-	if err := utils.ParseJSON(r, &req); err != nil {
-		utils.BadRequest(w, "Invalid request body")
+	if creator == nil {
+		utils.NotFound(w, "Creator not found")
 		return
 	}
 
-	event, err := h.services.Event.Update(r.Context(), id, uuid.Nil, &req.EventUpdateRequest, true)
+	runEventImport(w, r, h.services, h.repos, creator.ID, parseImportOptions(r))
+}
+
+func (h *AdminHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
 	if err != nil {
-		if err == services.ErrEventNotFound {
-			utils.NotFound(w, "Event not found")
-			return
-		}
-		if err.Error() == "price_thousands must be between 0 and 100000" {
-			utils.BadRequest(w, err.Error())
-			return
-		}
-		utils.InternalError(w, "Failed to update event")
+		utils.BadRequest(w, "Invalid event ID")
 		return
 	}
 
-	if req.ImageURL != "" || req.IsPaid != nil || req.IsPublished != nil {
-		var imageURL *string
-		if req.ImageURL != "" {
-			imageURL = &req.ImageURL
-		}
-		if err := h.repos.Event.UpdateAdminFields(r.Context(), id, imageURL, req.IsPaid, req.IsPublished); err != nil {
-			utils.InternalError(w, "Failed to update event admin fields")
-			return
-		}
-		event, err = h.services.Event.GetByID(r.Context(), id)
-		if err != nil {
-			utils.InternalError(w, "Failed to fetch updated event")
-			return
-		}
+	var req models.AdminEventUpdateRequest
+	if !utils.DecodeJSON(w, r, &req) {
+		return
 	}
 
-	if req.CreatorID != "" {
-		creatorID, err := uuid.Parse(req.CreatorID)
-		if err != nil {
-			utils.BadRequest(w, "Invalid creator ID")
-			return
-		}
-		if err := h.repos.Event.UpdateCreator(r.Context(), id, creatorID); err != nil {
-			utils.InternalError(w, "Failed to update event creator")
-			return
-		}
-		event, err = h.services.Event.GetByID(r.Context(), id)
-		if err != nil {
-			utils.InternalError(w, "Failed to fetch updated event")
-			return
-		}
+	event, err := h.services.Event.AdminUpdate(r.Context(), id, &req)
+	if err != nil {
+		writeError(w, err, "Failed to update event")
+		return
 	}
 
 	utils.Success(w, event.ToResponse())
@@ -254,17 +209,43 @@ func (h *AdminHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.services.Event.Delete(r.Context(), id, uuid.Nil, true); err != nil {
-		if err == services.ErrEventNotFound {
-			utils.NotFound(w, "Event not found")
-			return
-		}
-		utils.InternalError(w, "Failed to delete event")
+		writeError(w, err, "Failed to delete event")
 		return
 	}
 
 	utils.Message(w, "Event deleted successfully")
 }
 
+// adminGallery lets admins edit any event's gallery
+var adminGallery = galleryActor{isAdmin: true}
+
+func (h *AdminHandler) AddEventImage(w http.ResponseWriter, r *http.Request) {
+	owner := models.UploadOwner{Source: models.UploadSourceAdmin}
+	uploadGalleryImage(w, r, h.services, adminGallery, owner)
+}
+
+func (h *AdminHandler) CreateEventImageUpload(w http.ResponseWriter, r *http.Request) {
+	owner := models.UploadOwner{Source: models.UploadSourceAdmin}
+	requestDirectUpload(w, r, h.services, adminGallery, owner)
+}
+
+func (h *AdminHandler) ConfirmEventImageUpload(w http.ResponseWriter, r *http.Request) {
+	owner := models.UploadOwner{Source: models.UploadSourceAdmin}
+	confirmDirectUpload(w, r, h.services, adminGallery, owner)
+}
+
+func (h *AdminHandler) UpdateEventImage(w http.ResponseWriter, r *http.Request) {
+	updateGalleryImage(w, r, h.services, adminGallery)
+}
+
+func (h *AdminHandler) ReorderEventImages(w http.ResponseWriter, r *http.Request) {
+	reorderGallery(w, r, h.services, adminGallery)
+}
+
+func (h *AdminHandler) DeleteEventImage(w http.ResponseWriter, r *http.Request) {
+	deleteGalleryImage(w, r, h.services, adminGallery)
+}
+
 func (h *AdminHandler) ListCreators(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	page, limit := 1, 20
@@ -302,26 +283,8 @@ func (h *AdminHandler) ListCreators(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *AdminHandler) CreateCreator(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Name             string `json:"name"`
-		OrganizationName string `json:"organization_name"`
-		Email            string `json:"email"`
-		Mobile           string `json:"mobile"`
-		Password         string `json:"password"`
-		IsVerified       bool   `json:"is_verified"`
-		IsActive         bool   `json:"is_active"`
-	}
-	if err := utils.ParseJSON(r, &req); err != nil {
-		utils.BadRequest(w, "Invalid request body")
-		return
-	}
-
-	if req.Name == "" || req.Email == "" || req.Password == "" {
-		utils.BadRequest(w, "Name, email, and password are required")
-		return
-	}
-	if len(req.Password) < 8 {
-		utils.BadRequest(w, "Password must be at least 8 characters")
+	var req models.AdminCreatorCreateRequest
+	if !utils.DecodeJSON(w, r, &req) {
 		return
 	}
 
@@ -331,7 +294,7 @@ func (h *AdminHandler) CreateCreator(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if existing != nil {
-		utils.BadRequest(w, "Email already registered")
+		writeError(w, services.ErrEmailExists, "")
 		return
 	}
 
@@ -376,17 +339,8 @@ func (h *AdminHandler) UpdateCreator(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		Name             string `json:"name"`
-		OrganizationName string `json:"organization_name"`
-		Email            string `json:"email"`
-		Mobile           string `json:"mobile"`
-		Password         string `json:"password"`
-		IsActive         *bool  `json:"is_active"`
-		IsVerified       *bool  `json:"is_verified"`
-	}
-	if err := utils.ParseJSON(r, &req); err != nil {
-		utils.BadRequest(w, "Invalid request body")
+	var req models.AdminCreatorUpdateRequest
+	if !utils.DecodeJSON(w, r, &req) {
 		return
 	}
 
@@ -409,7 +363,7 @@ func (h *AdminHandler) UpdateCreator(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if existing != nil && existing.ID != creator.ID {
-			utils.BadRequest(w, "Email already registered")
+			writeError(w, services.ErrEmailExists, "")
 			return
 		}
 		creator.Email = req.Email
@@ -550,13 +504,7 @@ func (h *AdminHandler) ListLocations(w http.ResponseWriter, r *http.Request) {
 
 func (h *AdminHandler) CreateLocation(w http.ResponseWriter, r *http.Request) {
 	var req models.LocationRequest
-	if err := utils.ParseJSON(r, &req); err != nil {
-		utils.BadRequest(w, "Invalid request body")
-		return
-	}
-
-	if req.Name == "" {
-		utils.BadRequest(w, "Name is required")
+	if !utils.DecodeJSON(w, r, &req) {
 		return
 	}
 
@@ -578,8 +526,7 @@ func (h *AdminHandler) UpdateLocation(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req models.LocationRequest
-	if err := utils.ParseJSON(r, &req); err != nil {
-		utils.BadRequest(w, "Invalid request body")
+	if !utils.DecodeJSON(w, r, &req) {
 		return
 	}
 
@@ -607,13 +554,7 @@ func (h *AdminHandler) ListEventTypes(w http.ResponseWriter, r *http.Request) {
 
 func (h *AdminHandler) CreateEventType(w http.ResponseWriter, r *http.Request) {
 	var req models.EventTypeRequest
-	if err := utils.ParseJSON(r, &req); err != nil {
-		utils.BadRequest(w, "Invalid request body")
-		return
-	}
-
-	if req.Name == "" {
-		utils.BadRequest(w, "Name is required")
+	if !utils.DecodeJSON(w, r, &req) {
 		return
 	}
 
@@ -635,8 +576,7 @@ func (h *AdminHandler) UpdateEventType(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req models.EventTypeRequest
-	if err := utils.ParseJSON(r, &req); err != nil {
-		utils.BadRequest(w, "Invalid request body")
+	if !utils.DecodeJSON(w, r, &req) {
 		return
 	}
 
@@ -652,3 +592,134 @@ func (h *AdminHandler) UpdateEventType(w http.ResponseWriter, r *http.Request) {
 
 	utils.Message(w, "Event type updated successfully")
 }
+
+func (h *AdminHandler) ListAgentKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.services.AgentKey.List(r.Context())
+	if err != nil {
+		utils.InternalError(w, "Failed to fetch agent keys")
+		return
+	}
+
+	responses := []*models.AgentAPIKeyResponse{}
+	for _, k := range keys {
+		responses = append(responses, k.ToResponse())
+	}
+
+	utils.Success(w, responses)
+}
+
+func (h *AdminHandler) CreateAgentKey(w http.ResponseWriter, r *http.Request) {
+	var req models.AgentAPIKeyCreateRequest
+	if !utils.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	key, plaintext, err := h.services.AgentKey.Issue(r.Context(), &req, GetUserIDFromContext(r.Context()))
+	if err != nil {
+		writeError(w, err, "Failed to create agent key")
+		return
+	}
+
+	utils.Created(w, &models.AgentAPIKeyIssuedResponse{
+		AgentAPIKeyResponse: key.ToResponse(),
+		Key:                 plaintext,
+	})
+}
+
+func (h *AdminHandler) RevokeAgentKey(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.BadRequest(w, "Invalid agent key ID")
+		return
+	}
+
+	key, err := h.services.AgentKey.Revoke(r.Context(), id)
+	if err != nil {
+		writeError(w, err, "Failed to revoke agent key")
+		return
+	}
+
+	utils.Success(w, key.ToResponse())
+}
+
+// ListOrphanedUploads reports the uploads the next sweep would delete
+func (h *AdminHandler) ListOrphanedUploads(w http.ResponseWriter, r *http.Request) {
+	report, err := h.services.UploadSweeper.Sweep(r.Context(), true)
+	if err != nil {
+		utils.InternalError(w, "Failed to list orphaned uploads")
+		return
+	}
+
+	utils.Success(w, report)
+}
+
+// SweepUploads deletes orphaned uploads now. ?dry_run=true only reports them.
+func (h *AdminHandler) SweepUploads(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	report, err := h.services.UploadSweeper.Sweep(r.Context(), dryRun)
+	if err != nil {
+		utils.InternalError(w, "Failed to sweep uploads")
+		return
+	}
+
+	utils.Success(w, report)
+}
+
+// TimeSeriesReport returns new events, new creators, paid postings, revenue
+// and visitors per ?interval= (day, week or month; default day) over ?from=
+// and ?to= (default the last 30 days), compared with the period before, and
+// the creator conversion funnel
+func (h *AdminHandler) TimeSeriesReport(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := parseDateRange(w, r)
+	if !ok {
+		return
+	}
+	interval := r.URL.Query().Get("interval")
+	if interval == "" {
+		interval = models.ReportIntervalDay
+	}
+
+	report, err := h.services.Report.TimeSeries(r.Context(), from, to, interval)
+	if err != nil {
+		writeError(w, err, "Failed to load time series")
+		return
+	}
+
+	utils.Success(w, report)
+}
+
+// SourceReport breaks visitors, creator signups and paid postings down by
+// where they came from, over ?from= and ?to= (default the last 30 days)
+func (h *AdminHandler) SourceReport(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := parseDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.services.Visitor.SourceReport(r.Context(), from, to)
+	if err != nil {
+		writeError(w, err, "Failed to load source report")
+		return
+	}
+
+	utils.Success(w, report)
+}
+
+// BotReport shows the crawler and script traffic that visitor stats leave
+// out, over ?from= and ?to= (default the last 30 days)
+func (h *AdminHandler) BotReport(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := parseDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.services.Visitor.BotReport(r.Context(), from, to)
+	if err != nil {
+		writeError(w, err, "Failed to load bot report")
+		return
+	}
+
+	utils.Success(w, report)
+}
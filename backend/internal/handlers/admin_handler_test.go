@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+// loginAdmin creates an admin and returns their token
+func (s *testServer) loginAdmin(t *testing.T) string {
+	t.Helper()
+	if _, err := s.services.Auth.CreateAdmin(context.Background(), "ops@example.com", "Ops", "admin-password"); err != nil {
+		t.Fatal(err)
+	}
+	var login struct {
+		Token string `json:"token"`
+	}
+	body := map[string]string{"email": "ops@example.com", "password": "admin-password"}
+	if status, resp := s.do(t, http.MethodPost, "/api/admin/login", "", body, &login); status != http.StatusOK {
+		t.Fatalf("admin login: %d %s", status, resp.Detail)
+	}
+	return login.Token
+}
+
+func TestAdminUpdateEvent(t *testing.T) {
+	s := newTestServer(t)
+	creator := s.loginCreator(t, "owner@example.com")
+	admin := s.loginAdmin(t)
+
+	var event models.EventResponse
+	s.do(t, http.MethodPost, "/api/creator/events", creator, eventBody(), &event)
+	path := "/api/admin/events/" + event.ID.String()
+
+	body := map[string]any{"title": "Renamed", "is_paid": true, "is_published": true, "creator_id": uuid.NewString()}
+	if status, resp := s.do(t, http.MethodPut, path, admin, body, nil); status != http.StatusBadRequest || resp.Code != "CREATOR_NOT_FOUND" {
+		t.Errorf("unknown creator: %d %s", status, resp.Code)
+	}
+	stored, _ := s.repos.Event.GetByID(context.Background(), event.ID)
+	if stored.Title != event.Title || stored.IsPublished {
+		t.Errorf("failed update left the event %q, published %t", stored.Title, stored.IsPublished)
+	}
+
+	delete(body, "creator_id")
+	status, resp := s.do(t, http.MethodPut, path, admin, body, &event)
+	if status != http.StatusOK || event.Title != "Renamed" || !event.IsPublished {
+		t.Errorf("update: %d %s, event %q published %t", status, resp.Detail, event.Title, event.IsPublished)
+	}
+}
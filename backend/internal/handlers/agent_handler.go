@@ -2,12 +2,15 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/net1io/zenbali/internal/config"
 	"github.com/net1io/zenbali/internal/models"
 	"github.com/net1io/zenbali/internal/repository"
@@ -22,24 +25,50 @@ type AgentHandler struct {
 }
 
 type AgentEventCreateRequest struct {
-	Title                string  `json:"title"`
-	EventDate            string  `json:"event_date"`
-	EventTime            string  `json:"event_time"`
-	Location             string  `json:"location"`
-	EventType            string  `json:"event_type"`
-	DurationDays         int     `json:"duration_days"`
-	DurationHours        int     `json:"duration_hours"`
-	DurationMinutes      int     `json:"duration_minutes"`
-	EntranceType         string  `json:"entrance_type"`
-	ParticipantGroupType string  `json:"participant_group_type"`
-	LeadBy               string  `json:"lead_by"`
-	Venue                string  `json:"venue"`
-	ContactEmail         string  `json:"contact_email"`
-	ContactMobile        string  `json:"contact_mobile"`
-	EventDescription     string  `json:"event_description"`
+	Title                string  `json:"title" validate:"required,min=3,max=255"`
+	EventDate            string  `json:"event_date" validate:"required"`
+	EventTime            string  `json:"event_time" validate:"required"`
+	Location             string  `json:"location" validate:"required"`
+	EventType            string  `json:"event_type" validate:"required"`
+	DurationDays         int     `json:"duration_days" validate:"min=0"`
+	DurationHours        int     `json:"duration_hours" validate:"min=0"`
+	DurationMinutes      int     `json:"duration_minutes" validate:"min=0"`
+	EntranceType         string  `json:"entrance_type" validate:"required"`
+	ParticipantGroupType string  `json:"participant_group_type" validate:"required,max=50"`
+	LeadBy               string  `json:"lead_by" validate:"required,max=255"`
+	Venue                string  `json:"venue" validate:"max=50"`
+	ContactEmail         string  `json:"contact_email" validate:"required,email"`
+	ContactMobile        string  `json:"contact_mobile" validate:"required,max=50"`
+	EventDescription     string  `json:"event_description" validate:"required,max=2000"`
 	ImageURL             string  `json:"image_url"`
-	EntranceFee          float64 `json:"entrance_fee"`
-	PriceThousands       *int    `json:"price_thousands,omitempty"`
+	EntranceFee          float64 `json:"entrance_fee" validate:"min=0"`
+	PriceThousands       *int    `json:"price_thousands,omitempty" validate:"omitempty,min=0,max=100000"`
+	ExternalID           string  `json:"external_id" validate:"max=255"`
+	AllowDuplicate       bool    `json:"allow_duplicate"`
+}
+
+// AgentEventUpdateRequest is a partial update; omitted fields are left unchanged.
+// Sending any duration field replaces the whole duration.
+type AgentEventUpdateRequest struct {
+	Title                *string  `json:"title,omitempty" validate:"omitnil,required,min=3,max=255"`
+	EventDate            *string  `json:"event_date,omitempty" validate:"omitnil,required"`
+	EventTime            *string  `json:"event_time,omitempty" validate:"omitnil,required"`
+	Location             *string  `json:"location,omitempty" validate:"omitnil,required"`
+	EventType            *string  `json:"event_type,omitempty" validate:"omitnil,required"`
+	DurationDays         *int     `json:"duration_days,omitempty" validate:"omitnil,min=0"`
+	DurationHours        *int     `json:"duration_hours,omitempty" validate:"omitnil,min=0"`
+	DurationMinutes      *int     `json:"duration_minutes,omitempty" validate:"omitnil,min=0"`
+	EntranceType         *string  `json:"entrance_type,omitempty" validate:"omitnil,required"`
+	ParticipantGroupType *string  `json:"participant_group_type,omitempty" validate:"omitnil,required,max=50"`
+	LeadBy               *string  `json:"lead_by,omitempty" validate:"omitnil,required,max=255"`
+	Venue                *string  `json:"venue,omitempty" validate:"omitnil,required,max=50"`
+	ContactEmail         *string  `json:"contact_email,omitempty" validate:"omitnil,required,email"`
+	ContactMobile        *string  `json:"contact_mobile,omitempty" validate:"omitnil,required,max=50"`
+	EventDescription     *string  `json:"event_description,omitempty" validate:"omitnil,required,max=2000"`
+	ImageURL             *string  `json:"image_url,omitempty" validate:"omitnil,required"`
+	EntranceFee          *float64 `json:"entrance_fee,omitempty" validate:"omitnil,min=0"`
+	PriceThousands       *int     `json:"price_thousands,omitempty" validate:"omitnil,min=0,max=100000"`
+	ExternalID           *string  `json:"external_id,omitempty" validate:"omitnil,max=255"`
 }
 
 func NewAgentHandler(svcs *services.Services, repos *repository.Repositories, cfg *config.Config) *AgentHandler {
@@ -51,105 +80,236 @@ func NewAgentHandler(svcs *services.Services, repos *repository.Repositories, cf
 }
 
 func (h *AgentHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
-	if strings.TrimSpace(h.config.Agent.CreatorEmail) == "" {
-		utils.InternalError(w, "Agent creator email is not configured")
+	creator := GetCreatorFromContext(r.Context())
+	if creator == nil {
+		utils.Unauthorized(w, "")
 		return
 	}
 
 	var req AgentEventCreateRequest
-	if err := utils.ParseJSON(r, &req); err != nil {
-		utils.BadRequest(w, "Invalid request body")
+	if !utils.DecodeJSON(w, r, &req) {
 		return
 	}
 
-	if err := validateAgentEventRequest(&req); err != nil {
-		utils.BadRequest(w, err.Error())
+	createReq, err := buildAgentCreateRequest(r.Context(), h.repos, &req)
+	if err != nil {
+		writeError(w, err, "Failed to resolve event fields")
+		return
+	}
+
+	if !req.AllowDuplicate {
+		duplicate, err := h.services.Event.FindDuplicate(r.Context(), creator.ID, createReq.EventDate, createReq.LocationID, createReq.Title)
+		if err != nil {
+			writeAgentEventError(w, err, "Failed to check for duplicate events")
+			return
+		}
+		if duplicate != nil {
+			writeAgentDuplicate(w, creator.ID, duplicate)
+			return
+		}
+	}
+
+	imageKey, fetched, ok := h.hostRemoteImage(w, r, req.ImageURL)
+	if !ok {
 		return
 	}
 
-	creator, err := h.repos.Creator.GetByEmail(r.Context(), h.config.Agent.CreatorEmail)
+	event, err := h.services.Event.CreatePublished(r.Context(), creator.ID, createReq, imageKey)
 	if err != nil {
-		utils.InternalError(w, "Failed to load agent creator")
+		if fetched {
+			if err := h.services.Upload.DeleteFile(imageKey); err != nil {
+				log.Printf("WARN failed to delete image %s of failed agent event: %v", imageKey, err)
+			}
+		}
+		writeAgentEventError(w, err, "Failed to create event")
 		return
 	}
-	if creator == nil || !creator.IsActive {
-		utils.InternalError(w, "Configured agent creator is unavailable")
+
+	utils.Created(w, event.ToResponse())
+}
+
+// ImportEvents creates and publishes a batch of events from CSV or NDJSON.
+// Query: dry_run, strict, format, report=csv.
+func (h *AgentHandler) ImportEvents(w http.ResponseWriter, r *http.Request) {
+	creator := GetCreatorFromContext(r.Context())
+	if creator == nil {
+		utils.Unauthorized(w, "")
 		return
 	}
 
-	locationID, err := resolveLocationID(r.Context(), h.repos, req.Location)
-	if err != nil {
-		utils.BadRequest(w, err.Error())
+	opts := parseImportOptions(r)
+	opts.Publish = true
+	runEventImport(w, r, h.services, h.repos, creator.ID, opts)
+}
+
+func (h *AgentHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	creator := GetCreatorFromContext(r.Context())
+	if creator == nil {
+		utils.Unauthorized(w, "")
 		return
 	}
 
-	eventTypeID, err := resolveEventTypeID(r.Context(), h.repos, req.EventType)
+	query := r.URL.Query()
+	page := 1
+	limit := 20
+	includePast := query.Get("include_past") == "true"
+
+	if p := query.Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+
+	if l := query.Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	result, err := h.services.Event.ListByCreator(r.Context(), creator.ID, page, limit, includePast)
 	if err != nil {
-		utils.BadRequest(w, err.Error())
+		log.Printf("ERROR listing agent events for creator %s: %v", creator.ID, err)
+		utils.InternalError(w, "Failed to fetch events")
 		return
 	}
 
-	entranceTypeID, err := resolveEntranceTypeID(r.Context(), h.repos, req.EntranceType)
-	if err != nil {
-		utils.BadRequest(w, err.Error())
+	events := []*models.EventResponse{}
+	for _, e := range result.Events {
+		events = append(events, e.ToResponse())
+	}
+
+	utils.Success(w, map[string]interface{}{
+		"events":      events,
+		"total":       result.Total,
+		"page":        result.Page,
+		"limit":       result.Limit,
+		"total_pages": result.TotalPages,
+	})
+}
+
+func (h *AgentHandler) GetEvent(w http.ResponseWriter, r *http.Request) {
+	event, ok := h.loadOwnedEvent(w, r)
+	if !ok {
 		return
 	}
+	utils.Success(w, event.ToResponse())
+}
 
-	duration, err := formatDuration(req.DurationDays, req.DurationHours, req.DurationMinutes)
+func (h *AgentHandler) GetEventByExternalID(w http.ResponseWriter, r *http.Request) {
+	creator := GetCreatorFromContext(r.Context())
+	if creator == nil {
+		utils.Unauthorized(w, "")
+		return
+	}
+
+	externalID := strings.TrimSpace(chi.URLParam(r, "externalID"))
+	if externalID == "" {
+		utils.BadRequest(w, "external_id is required")
+		return
+	}
+
+	event, err := h.services.Event.GetByExternalID(r.Context(), creator.ID, externalID)
 	if err != nil {
-		utils.BadRequest(w, err.Error())
+		writeAgentEventError(w, err, "Failed to fetch event")
 		return
 	}
 
-	eventTime, err := normalizeEventTime(req.EventTime)
+	utils.Success(w, event.ToResponse())
+}
+
+func (h *AgentHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
+	creator := GetCreatorFromContext(r.Context())
+	if creator == nil {
+		utils.Unauthorized(w, "")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		utils.BadRequest(w, err.Error())
+		utils.BadRequest(w, "Invalid event ID")
 		return
 	}
 
-	createReq := &models.EventCreateRequest{
-		Title:                strings.TrimSpace(req.Title),
-		EventDate:            strings.TrimSpace(req.EventDate),
-		EventTime:            eventTime,
-		LocationID:           locationID,
-		EventTypeID:          eventTypeID,
-		Duration:             duration,
-		EntranceTypeID:       entranceTypeID,
-		EntranceFee:          req.EntranceFee,
-		PriceThousands:       req.PriceThousands,
-		ParticipantGroupType: strings.TrimSpace(req.ParticipantGroupType),
-		LeadBy:               strings.TrimSpace(req.LeadBy),
-		Venue:                strings.TrimSpace(req.Venue),
-		ContactEmail:         strings.TrimSpace(req.ContactEmail),
-		ContactMobile:        strings.TrimSpace(req.ContactMobile),
-		Notes:                strings.TrimSpace(req.EventDescription),
+	var req AgentEventUpdateRequest
+	if !utils.DecodeJSON(w, r, &req) {
+		return
 	}
 
-	event, err := h.services.Event.Create(r.Context(), creator.ID, createReq)
+	updateReq, err := buildAgentUpdateRequest(r.Context(), h.repos, &req)
 	if err != nil {
-		if err == services.ErrInvalidDate {
-			utils.BadRequest(w, "Invalid date format. Use YYYY-MM-DD")
+		writeError(w, err, "Failed to resolve event fields")
+		return
+	}
+
+	var imageKey string
+	var fetched bool
+	if req.ImageURL != nil {
+		var ok bool
+		if imageKey, fetched, ok = h.hostRemoteImage(w, r, *req.ImageURL); !ok {
 			return
 		}
-		utils.InternalError(w, "Failed to create event")
+	}
+
+	event, err := h.services.Event.UpdateWithImage(r.Context(), id, creator.ID, updateReq, imageKey)
+	if err != nil {
+		if fetched {
+			_ = h.services.Upload.DeleteFile(imageKey)
+		}
+		writeAgentEventError(w, err, "Failed to update event")
 		return
 	}
 
-	imageURL := strings.TrimSpace(req.ImageURL)
-	isPaid := true
-	isPublished := true
-	if err := h.repos.Event.UpdateAdminFields(r.Context(), event.ID, optionalString(imageURL), &isPaid, &isPublished); err != nil {
-		utils.InternalError(w, "Failed to publish agent event")
+	utils.Success(w, event.ToResponse())
+}
+
+func (h *AgentHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
+	creator := GetCreatorFromContext(r.Context())
+	if creator == nil {
+		utils.Unauthorized(w, "")
 		return
 	}
 
-	event, err = h.services.Event.GetByID(r.Context(), event.ID)
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		utils.InternalError(w, "Failed to fetch created event")
+		utils.BadRequest(w, "Invalid event ID")
 		return
 	}
 
-	utils.Created(w, event.ToResponse())
+	if err := h.services.Event.Delete(r.Context(), id, creator.ID, false); err != nil {
+		writeAgentEventError(w, err, "Failed to delete event")
+		return
+	}
+
+	utils.Message(w, "Event deleted successfully")
+}
+
+// loadOwnedEvent fetches the {id} event and verifies it belongs to the agent's creator
+func (h *AgentHandler) loadOwnedEvent(w http.ResponseWriter, r *http.Request) (*models.Event, bool) {
+	creator := GetCreatorFromContext(r.Context())
+	if creator == nil {
+		utils.Unauthorized(w, "")
+		return nil, false
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid event ID")
+		return nil, false
+	}
+
+	event, err := h.services.Event.GetByID(r.Context(), id)
+	if err != nil {
+		writeAgentEventError(w, err, "Failed to fetch event")
+		return nil, false
+	}
+
+	// Don't reveal other creators' events to the agent
+	if event.CreatorID != creator.ID {
+		utils.NotFound(w, "Event not found")
+		return nil, false
+	}
+
+	return event, true
 }
 
 func (h *AgentHandler) UploadEventImage(w http.ResponseWriter, r *http.Request) {
@@ -166,86 +326,353 @@ func (h *AgentHandler) UploadEventImage(w http.ResponseWriter, r *http.Request)
 	}
 	defer file.Close()
 
-	imageURL, err := h.services.Upload.SaveEventImage(file, header)
+	imageKey, err := h.services.Upload.SaveEventImage(r.Context(), file, header, agentUploadOwner(r.Context(), models.UploadSourceAgent))
 	if err != nil {
-		log.Printf("ERROR uploading agent event image: %v", err)
-		switch err {
-		case services.ErrFileTooLarge:
-			utils.BadRequest(w, "File too large")
-		case services.ErrInvalidFileType:
-			utils.BadRequest(w, "Invalid file type. Allowed: jpg, jpeg, png, webp")
-		default:
-			utils.InternalError(w, "Failed to upload image")
-		}
+		writeError(w, err, "Failed to upload image")
 		return
 	}
 
-	utils.Created(w, map[string]string{
+	imageURL := h.services.Upload.URL(imageKey)
+	utils.Created(w, map[string]interface{}{
 		"image_url": imageURL,
+		"images":    models.NewEventImages(imageURL),
 	})
 }
 
-func validateAgentEventRequest(req *AgentEventCreateRequest) error {
+// AgentEventImageRequest adds a gallery image from a URL, which is fetched
+// into our storage like image_url on events
+type AgentEventImageRequest struct {
+	ImageURL string `json:"image_url" validate:"required"`
+	Caption  string `json:"caption" validate:"max=500"`
+	AltText  string `json:"alt_text" validate:"max=500"`
+}
+
+// galleryActor returns the agent's creator as a gallery editor
+func (h *AgentHandler) galleryActor(w http.ResponseWriter, r *http.Request) (galleryActor, bool) {
+	creator := GetCreatorFromContext(r.Context())
+	if creator == nil {
+		utils.Unauthorized(w, "")
+		return galleryActor{}, false
+	}
+	return galleryActor{creatorID: creator.ID, hideForeign: true}, true
+}
+
+// AddEventImage takes either a multipart "image" upload or a JSON
+// AgentEventImageRequest
+func (h *AgentHandler) AddEventImage(w http.ResponseWriter, r *http.Request) {
+	actor, ok := h.galleryActor(w, r)
+	if !ok {
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		uploadGalleryImage(w, r, h.services, actor, agentUploadOwner(r.Context(), models.UploadSourceAgent))
+		return
+	}
+
+	eventID, _, ok := galleryIDs(w, r, false)
+	if !ok {
+		return
+	}
+
+	var req AgentEventImageRequest
+	if !utils.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	imageKey, fetched, ok := h.hostRemoteImage(w, r, req.ImageURL)
+	if !ok {
+		return
+	}
+
+	image, err := h.services.Event.AddImage(r.Context(), eventID, actor.creatorID, imageKey, req.Caption, req.AltText, actor.isAdmin)
+	if err != nil {
+		if fetched {
+			_ = h.services.Upload.DeleteFile(imageKey)
+		}
+		actor.writeError(w, err, "Failed to add image")
+		return
+	}
+	utils.Created(w, image.ToResponse())
+}
+
+func (h *AgentHandler) CreateEventImageUpload(w http.ResponseWriter, r *http.Request) {
+	if actor, ok := h.galleryActor(w, r); ok {
+		requestDirectUpload(w, r, h.services, actor, agentUploadOwner(r.Context(), models.UploadSourceAgent))
+	}
+}
+
+func (h *AgentHandler) ConfirmEventImageUpload(w http.ResponseWriter, r *http.Request) {
+	if actor, ok := h.galleryActor(w, r); ok {
+		confirmDirectUpload(w, r, h.services, actor, agentUploadOwner(r.Context(), models.UploadSourceAgent))
+	}
+}
+
+func (h *AgentHandler) UpdateEventImage(w http.ResponseWriter, r *http.Request) {
+	if actor, ok := h.galleryActor(w, r); ok {
+		updateGalleryImage(w, r, h.services, actor)
+	}
+}
+
+func (h *AgentHandler) ReorderEventImages(w http.ResponseWriter, r *http.Request) {
+	if actor, ok := h.galleryActor(w, r); ok {
+		reorderGallery(w, r, h.services, actor)
+	}
+}
+
+func (h *AgentHandler) DeleteEventImage(w http.ResponseWriter, r *http.Request) {
+	if actor, ok := h.galleryActor(w, r); ok {
+		deleteGalleryImage(w, r, h.services, actor)
+	}
+}
+
+// hostRemoteImage downloads an agent-supplied image URL into our storage so
+// events never hotlink third-party images, and returns its storage key. URLs
+// that point at one of the agent's own uploads are resolved to their key
+// without a fetch; fetched reports whether a new file was stored. An empty URL
+// is passed through.
+func (h *AgentHandler) hostRemoteImage(w http.ResponseWriter, r *http.Request, rawURL string) (key string, fetched, ok bool) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", false, true
+	}
+	owner := agentUploadOwner(r.Context(), models.UploadSourceRemote)
+	key, hosted, err := h.services.Upload.HostedKey(r.Context(), rawURL, owner)
+	if err != nil {
+		writeError(w, err, "Failed to look up image_url")
+		return "", false, false
+	}
+	if hosted {
+		return key, false, true
+	}
+
+	key, err = h.services.Upload.SaveRemoteImage(r.Context(), rawURL, owner)
+	if err != nil {
+		log.Printf("ERROR fetching agent image %q: %v", rawURL, err)
+		writeError(w, remoteImageError(err), "")
+		return "", false, false
+	}
+	return key, true, true
+}
+
+// agentUploadOwner attributes an upload to the calling agent key and the
+// creator it acts for
+func agentUploadOwner(ctx context.Context, source string) models.UploadOwner {
+	owner := models.UploadOwner{Source: source}
+	if key := GetAgentKeyFromContext(ctx); key != nil {
+		creatorID := key.CreatorID
+		owner.CreatorID = &creatorID
+		if key.ID != uuid.Nil {
+			keyID := key.ID
+			owner.AgentKeyID = &keyID
+		}
+	}
+	return owner
+}
+
+// remoteImageError words an image_url failure in terms of the field. Errors
+// that are not the image's fault are reported as a failed download.
+func remoteImageError(err error) *services.Error {
 	switch {
-	case strings.TrimSpace(req.Title) == "":
-		return fmt.Errorf("title is required")
-	case strings.TrimSpace(req.EventDate) == "":
-		return fmt.Errorf("event_date is required")
-	case strings.TrimSpace(req.EventTime) == "":
-		return fmt.Errorf("event_time is required")
-	case strings.TrimSpace(req.Location) == "":
-		return fmt.Errorf("location is required")
-	case strings.TrimSpace(req.EventType) == "":
-		return fmt.Errorf("event_type is required")
-	case strings.TrimSpace(req.EntranceType) == "":
-		return fmt.Errorf("entrance_type is required")
-	case strings.TrimSpace(req.ParticipantGroupType) == "":
-		return fmt.Errorf("participant_group_type is required")
-	case strings.TrimSpace(req.LeadBy) == "":
-		return fmt.Errorf("lead_by is required")
-	case strings.TrimSpace(req.ContactEmail) == "":
-		return fmt.Errorf("contact_email is required")
-	case strings.TrimSpace(req.ContactMobile) == "":
-		return fmt.Errorf("contact_mobile is required")
-	case strings.TrimSpace(req.EventDescription) == "":
-		return fmt.Errorf("event_description is required")
-	}
-
-	if req.DurationDays < 0 || req.DurationHours < 0 || req.DurationMinutes < 0 {
-		return fmt.Errorf("duration values must be zero or positive")
-	}
-	if req.PriceThousands != nil && (*req.PriceThousands < 0 || *req.PriceThousands > 100000) {
-		return fmt.Errorf("price_thousands must be between 0 and 100000")
-	}
-
-	return nil
+	case errors.Is(err, services.ErrInvalidImageURL):
+		return services.ErrInvalidImageURL
+	case errors.Is(err, services.ErrRemoteImageBlocked):
+		return services.ErrRemoteImageBlocked
+	case errors.Is(err, services.ErrFileTooLarge):
+		return services.ErrFileTooLarge.WithMessage("image_url file too large")
+	case errors.Is(err, services.ErrInvalidFileType):
+		return services.ErrInvalidFileType.WithMessage("image_url is not a supported image. Allowed: jpg, jpeg, png, webp, heic, heif")
+	case errors.Is(err, services.ErrImageDimensions):
+		return services.ErrImageDimensions.WithMessage("image_url dimensions too large")
+	case errors.Is(err, services.ErrHEICUnsupported):
+		return services.ErrHEICUnsupported.WithMessage("image_url is a HEIC image, which this server cannot convert")
+	default:
+		return services.ErrRemoteImageFetch
+	}
+}
+
+// buildAgentCreateRequest resolves the reference names of a validated agent
+// payload into the IDs the event service expects.
+func buildAgentCreateRequest(ctx context.Context, repos *repository.Repositories, req *AgentEventCreateRequest) (*models.EventCreateRequest, error) {
+	locationID, err := resolveLocationID(ctx, repos, req.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	eventTypeID, err := resolveEventTypeID(ctx, repos, req.EventType)
+	if err != nil {
+		return nil, err
+	}
+
+	entranceTypeID, err := resolveEntranceTypeID(ctx, repos, req.EntranceType)
+	if err != nil {
+		return nil, err
+	}
+
+	duration, err := formatDuration(req.DurationDays, req.DurationHours, req.DurationMinutes)
+	if err != nil {
+		return nil, err
+	}
+
+	eventTime, err := normalizeEventTime(req.EventTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.EventCreateRequest{
+		Title:                strings.TrimSpace(req.Title),
+		EventDate:            strings.TrimSpace(req.EventDate),
+		EventTime:            eventTime,
+		LocationID:           locationID,
+		EventTypeID:          eventTypeID,
+		Duration:             duration,
+		EntranceTypeID:       entranceTypeID,
+		EntranceFee:          req.EntranceFee,
+		PriceThousands:       req.PriceThousands,
+		ParticipantGroupType: strings.TrimSpace(req.ParticipantGroupType),
+		LeadBy:               strings.TrimSpace(req.LeadBy),
+		Venue:                strings.TrimSpace(req.Venue),
+		ContactEmail:         strings.TrimSpace(req.ContactEmail),
+		ContactMobile:        strings.TrimSpace(req.ContactMobile),
+		Notes:                strings.TrimSpace(req.EventDescription),
+		ExternalID:           strings.TrimSpace(req.ExternalID),
+	}, nil
+}
+
+// buildAgentUpdateRequest converts a partial agent payload into an
+// EventUpdateRequest, applying the same name resolution and time rules as create.
+func buildAgentUpdateRequest(ctx context.Context, repos *repository.Repositories, req *AgentEventUpdateRequest) (*models.EventUpdateRequest, error) {
+	update := &models.EventUpdateRequest{
+		EntranceFee:    req.EntranceFee,
+		PriceThousands: req.PriceThousands,
+	}
+
+	fields := []struct {
+		value *string
+		dst   *string
+	}{
+		{req.Title, &update.Title},
+		{req.EventDate, &update.EventDate},
+		{req.ParticipantGroupType, &update.ParticipantGroupType},
+		{req.LeadBy, &update.LeadBy},
+		{req.ContactEmail, &update.ContactEmail},
+		{req.ContactMobile, &update.ContactMobile},
+		{req.EventDescription, &update.Notes},
+		{req.Venue, &update.Venue},
+	}
+	for _, field := range fields {
+		if field.value != nil {
+			*field.dst = strings.TrimSpace(*field.value)
+		}
+	}
+
+	if req.EventTime != nil {
+		eventTime, err := normalizeEventTime(*req.EventTime)
+		if err != nil {
+			return nil, err
+		}
+		update.EventTime = eventTime
+	}
+
+	if req.Location != nil {
+		id, err := resolveLocationID(ctx, repos, *req.Location)
+		if err != nil {
+			return nil, err
+		}
+		update.LocationID = id
+	}
+
+	if req.EventType != nil {
+		id, err := resolveEventTypeID(ctx, repos, *req.EventType)
+		if err != nil {
+			return nil, err
+		}
+		update.EventTypeID = id
+	}
+
+	if req.EntranceType != nil {
+		id, err := resolveEntranceTypeID(ctx, repos, *req.EntranceType)
+		if err != nil {
+			return nil, err
+		}
+		update.EntranceTypeID = id
+	}
+
+	if req.DurationDays != nil || req.DurationHours != nil || req.DurationMinutes != nil {
+		days, hours, minutes := intValue(req.DurationDays), intValue(req.DurationHours), intValue(req.DurationMinutes)
+		duration, err := formatDuration(days, hours, minutes)
+		if err != nil {
+			return nil, err
+		}
+		update.Duration = duration
+	}
+
+	if req.ExternalID != nil {
+		externalID := strings.TrimSpace(*req.ExternalID)
+		update.ExternalID = &externalID
+	}
+
+	return update, nil
+}
+
+// writeAgentDuplicate answers a create that matches an existing event. The
+// creator's own event is returned as-is; another creator's event is reported
+// as a 409 conflict carrying the match.
+func writeAgentDuplicate(w http.ResponseWriter, creatorID uuid.UUID, duplicate *models.Event) {
+	w.Header().Set("X-Duplicate-Of", duplicate.ID.String())
+	if duplicate.CreatorID == creatorID {
+		utils.JSON(w, http.StatusOK, utils.Response{
+			Success: true,
+			Data:    duplicate.ToResponse(),
+			Message: "Matching event already exists",
+		})
+		return
+	}
+	utils.WriteProblem(w, &utils.ProblemDetails{
+		Status: http.StatusConflict,
+		Code:   "DUPLICATE_EVENT",
+		Detail: "A matching event has already been published",
+		Data:   duplicate.ToResponse(),
+	})
+}
+
+// writeAgentEventError answers an event service error on the agent API,
+// where other creators' events are reported as missing
+func writeAgentEventError(w http.ResponseWriter, err error, fallback string) {
+	if errors.Is(err, services.ErrNotEventOwner) {
+		err = services.ErrEventNotFound
+	}
+	writeError(w, err, fallback)
 }
 
 func resolveLocationID(ctx context.Context, repos *repository.Repositories, input string) (int, error) {
 	return resolveReferenceID(ctx, input, repos.Location.List, func(loc *models.Location) (int, string, string) {
 		return loc.ID, loc.Name, loc.Slug
-	}, "location")
+	}, "location", services.ErrUnknownLocation)
 }
 
 func resolveEventTypeID(ctx context.Context, repos *repository.Repositories, input string) (int, error) {
 	return resolveReferenceID(ctx, input, repos.EventType.List, func(item *models.EventType) (int, string, string) {
 		return item.ID, item.Name, item.Slug
-	}, "event_type")
+	}, "event_type", services.ErrUnknownEventType)
 }
 
 func resolveEntranceTypeID(ctx context.Context, repos *repository.Repositories, input string) (int, error) {
 	return resolveReferenceID(ctx, input, repos.EntranceType.List, func(item *models.EntranceType) (int, string, string) {
 		return item.ID, item.Name, item.Slug
-	}, "entrance_type")
+	}, "entrance_type", services.ErrUnknownEntranceType)
 }
 
 type listFn[T any] func(ctx context.Context, onlyActive bool) ([]*T, error)
 type refPartsFn[T any] func(item *T) (int, string, string)
 
-func resolveReferenceID[T any](ctx context.Context, input string, list listFn[T], parts refPartsFn[T], field string) (int, error) {
+// resolveReferenceID finds the ID of the active item whose ID, name or slug
+// is input. A name that matches nothing is answered with unknown; a failure
+// to list the items is returned as is.
+func resolveReferenceID[T any](ctx context.Context, input string, list listFn[T], parts refPartsFn[T], field string, unknown *services.Error) (int, error) {
 	raw := strings.TrimSpace(input)
 	if raw == "" {
-		return 0, fmt.Errorf("%s is required", field)
+		return 0, unknown.WithMessage(fmt.Sprintf("%s is required", field))
 	}
 	if id, err := strconv.Atoi(raw); err == nil && id > 0 {
 		return id, nil
@@ -253,7 +680,7 @@ func resolveReferenceID[T any](ctx context.Context, input string, list listFn[T]
 
 	items, err := list(ctx, true)
 	if err != nil {
-		return 0, fmt.Errorf("failed to load %s options", field)
+		return 0, fmt.Errorf("failed to load %s options: %w", field, err)
 	}
 
 	normalized := normalizeLookup(raw)
@@ -264,7 +691,7 @@ func resolveReferenceID[T any](ctx context.Context, input string, list listFn[T]
 		}
 	}
 
-	return 0, fmt.Errorf("unknown %s: %s", field, raw)
+	return 0, unknown.WithMessage(fmt.Sprintf("unknown %s: %s", field, raw))
 }
 
 func normalizeLookup(value string) string {
@@ -282,10 +709,10 @@ func normalizeLookup(value string) string {
 
 func formatDuration(days, hours, minutes int) (string, error) {
 	if minutes%15 != 0 {
-		return "", fmt.Errorf("duration_minutes must be in 15-minute increments")
+		return "", services.ErrInvalidDuration.WithMessage("duration_minutes must be in 15-minute increments")
 	}
 	if days == 0 && hours == 0 && minutes == 0 {
-		return "", fmt.Errorf("at least one duration value is required")
+		return "", services.ErrInvalidDuration.WithMessage("at least one duration value is required")
 	}
 
 	parts := make([]string, 0, 3)
@@ -313,36 +740,43 @@ func normalizeEventTime(value string) (string, error) {
 	raw := strings.TrimSpace(value)
 	parts := strings.Split(raw, ":")
 	if len(parts) < 2 || len(parts) > 3 {
-		return "", fmt.Errorf("event_time must use HH:MM or HH:MM:SS format")
+		return "", services.ErrInvalidEventTime.WithMessage("event_time must use HH:MM or HH:MM:SS format")
 	}
 
 	hour, err := strconv.Atoi(parts[0])
 	if err != nil || hour < 0 || hour > 23 {
-		return "", fmt.Errorf("event_time hour must be between 00 and 23")
+		return "", services.ErrInvalidEventTime.WithMessage("event_time hour must be between 00 and 23")
 	}
 
 	minute, err := strconv.Atoi(parts[1])
 	if err != nil || minute < 0 || minute > 59 {
-		return "", fmt.Errorf("event_time minute must be between 00 and 59")
+		return "", services.ErrInvalidEventTime.WithMessage("event_time minute must be between 00 and 59")
 	}
 
 	if minute%15 != 0 {
-		return "", fmt.Errorf("event_time must be in 15-minute increments")
+		return "", services.ErrInvalidEventTime.WithMessage("event_time must be in 15-minute increments")
 	}
 
 	if len(parts) == 3 {
 		second, err := strconv.Atoi(parts[2])
 		if err != nil || second < 0 || second > 59 {
-			return "", fmt.Errorf("event_time second must be between 00 and 59")
+			return "", services.ErrInvalidEventTime.WithMessage("event_time second must be between 00 and 59")
 		}
 		if second != 0 {
-			return "", fmt.Errorf("event_time seconds must be 00")
+			return "", services.ErrInvalidEventTime.WithMessage("event_time seconds must be 00")
 		}
 	}
 
 	return fmt.Sprintf("%02d:%02d", hour, minute), nil
 }
 
+func intValue(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
 func optionalString(value string) *string {
 	if strings.TrimSpace(value) == "" {
 		return nil
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/repository"
+	"github.com/net1io/zenbali/internal/repository/memory"
+	"github.com/net1io/zenbali/internal/services"
+	"github.com/net1io/zenbali/internal/utils"
+)
+
+// failingLocations loses its database connection when listing
+type failingLocations struct{ repository.LocationStore }
+
+func (failingLocations) List(ctx context.Context, onlyActive bool) ([]*models.Location, error) {
+	return nil, errors.New("connection refused")
+}
+
+func agentCreateRequest() *AgentEventCreateRequest {
+	return &AgentEventCreateRequest{
+		Title: "Full Moon Kirtan", EventDate: "2030-01-15", EventTime: "18:30",
+		Location: "Ubud", EventType: "yoga", EntranceType: "free", DurationHours: 2,
+		ParticipantGroupType: "Everyone", LeadBy: "Made", ContactEmail: "made@example.com",
+		ContactMobile: "+628111", EventDescription: "Chanting under the full moon",
+	}
+}
+
+func TestBuildAgentRequestErrors(t *testing.T) {
+	ctx := context.Background()
+	repos := memory.New().Repositories()
+
+	if _, err := buildAgentCreateRequest(ctx, repos, agentCreateRequest()); err != nil {
+		t.Fatalf("valid request: %v", err)
+	}
+
+	str := func(s string) *string { return &s }
+	minutes := 10
+	tests := []struct {
+		name string
+		req  *AgentEventUpdateRequest
+		want *services.Error
+	}{
+		{"unknown location", &AgentEventUpdateRequest{Location: str("Atlantis")}, services.ErrUnknownLocation},
+		{"blank location", &AgentEventUpdateRequest{Location: str(" ")}, services.ErrUnknownLocation},
+		{"unknown event type", &AgentEventUpdateRequest{EventType: str("Skydiving")}, services.ErrUnknownEventType},
+		{"unknown entrance type", &AgentEventUpdateRequest{EntranceType: str("Barter")}, services.ErrUnknownEntranceType},
+		{"malformed time", &AgentEventUpdateRequest{EventTime: str("6pm")}, services.ErrInvalidEventTime},
+		{"time off the quarter hour", &AgentEventUpdateRequest{EventTime: str("18:10")}, services.ErrInvalidEventTime},
+		{"duration off the quarter hour", &AgentEventUpdateRequest{DurationMinutes: &minutes}, services.ErrInvalidDuration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := buildAgentUpdateRequest(ctx, repos, tt.req)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %s", err, tt.want.Code)
+			}
+			rec := httptest.NewRecorder()
+			writeError(rec, err, "Failed to resolve event fields")
+			var problem utils.ProblemDetails
+			json.NewDecoder(rec.Body).Decode(&problem)
+			if rec.Code != http.StatusBadRequest || problem.Code != tt.want.Code {
+				t.Errorf("answered %d with code %q, want 400 %s", rec.Code, problem.Code, tt.want.Code)
+			}
+		})
+	}
+
+	// A database failure is not the agent's fault and is not shown to it
+	repos.Location = failingLocations{repos.Location}
+	_, err := buildAgentCreateRequest(ctx, repos, agentCreateRequest())
+	var domainErr *services.Error
+	if err == nil || errors.As(err, &domainErr) {
+		t.Fatalf("err = %v, want a database error", err)
+	}
+	rec := httptest.NewRecorder()
+	writeError(rec, err, "Failed to resolve event fields")
+	var problem utils.ProblemDetails
+	json.NewDecoder(rec.Body).Decode(&problem)
+	if rec.Code != http.StatusInternalServerError || problem.Detail != "Failed to resolve event fields" {
+		t.Errorf("answered %d with %q, want 500 with the fallback message", rec.Code, problem.Detail)
+	}
+}
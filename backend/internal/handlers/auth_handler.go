@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"context"
-	"crypto/subtle"
 	"net/http"
 	"strings"
 
@@ -19,6 +18,8 @@ const (
 	ContextKeyCreator contextKey = "creator"
 	ContextKeyAdmin   contextKey = "admin"
 	ContextKeyUserID  contextKey = "user_id"
+
+	ContextKeyAgentKey contextKey = "agent_key"
 )
 
 type AuthHandler struct {
@@ -35,29 +36,13 @@ func NewAuthHandler(svcs *services.Services, cfg *config.Config) *AuthHandler {
 
 func (h *AuthHandler) CreatorRegister(w http.ResponseWriter, r *http.Request) {
 	var req models.CreatorRegisterRequest
-	if err := utils.ParseJSON(r, &req); err != nil {
-		utils.BadRequest(w, "Invalid request body")
-		return
-	}
-
-	// Basic validation
-	if req.Name == "" || req.Email == "" || req.Password == "" {
-		utils.BadRequest(w, "Name, email, and password are required")
-		return
-	}
-
-	if len(req.Password) < 8 {
-		utils.BadRequest(w, "Password must be at least 8 characters")
+	if !utils.DecodeJSON(w, r, &req) {
 		return
 	}
 
 	creator, err := h.services.Auth.RegisterCreator(r.Context(), &req)
 	if err != nil {
-		if err == services.ErrEmailExists {
-			utils.BadRequest(w, "Email already registered")
-			return
-		}
-		utils.InternalError(w, "Failed to register")
+		writeError(w, err, "Failed to register")
 		return
 	}
 
@@ -66,27 +51,13 @@ func (h *AuthHandler) CreatorRegister(w http.ResponseWriter, r *http.Request) {
 
 func (h *AuthHandler) CreatorLogin(w http.ResponseWriter, r *http.Request) {
 	var req models.CreatorLoginRequest
-	if err := utils.ParseJSON(r, &req); err != nil {
-		utils.BadRequest(w, "Invalid request body")
-		return
-	}
-
-	if req.Email == "" || req.Password == "" {
-		utils.BadRequest(w, "Email and password are required")
+	if !utils.DecodeJSON(w, r, &req) {
 		return
 	}
 
 	creator, token, err := h.services.Auth.LoginCreator(r.Context(), &req)
 	if err != nil {
-		if err == services.ErrInvalidCredentials {
-			utils.Unauthorized(w, "Invalid email or password")
-			return
-		}
-		if err == services.ErrAccountDisabled {
-			utils.Forbidden(w, "Account is disabled")
-			return
-		}
-		utils.InternalError(w, "Login failed")
+		writeError(w, err, "Login failed")
 		return
 	}
 
@@ -103,27 +74,13 @@ func (h *AuthHandler) CreatorLogout(w http.ResponseWriter, r *http.Request) {
 
 func (h *AuthHandler) AdminLogin(w http.ResponseWriter, r *http.Request) {
 	var req models.AdminLoginRequest
-	if err := utils.ParseJSON(r, &req); err != nil {
-		utils.BadRequest(w, "Invalid request body")
-		return
-	}
-
-	if req.Email == "" || req.Password == "" {
-		utils.BadRequest(w, "Email and password are required")
+	if !utils.DecodeJSON(w, r, &req) {
 		return
 	}
 
 	admin, token, err := h.services.Auth.LoginAdmin(r.Context(), &req)
 	if err != nil {
-		if err == services.ErrInvalidCredentials {
-			utils.Unauthorized(w, "Invalid email or password")
-			return
-		}
-		if err == services.ErrAccountDisabled {
-			utils.Forbidden(w, "Account is disabled")
-			return
-		}
-		utils.InternalError(w, "Login failed")
+		writeError(w, err, "Login failed")
 		return
 	}
 
@@ -137,13 +94,13 @@ func (h *AuthHandler) CreatorAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token := extractToken(r)
 		if token == "" {
-			utils.Unauthorized(w, "Missing authorization token")
+			utils.Problem(w, http.StatusUnauthorized, "MISSING_TOKEN", "Missing authorization token")
 			return
 		}
 
 		claims, err := h.services.Auth.ValidateToken(token)
 		if err != nil {
-			utils.Unauthorized(w, "Invalid or expired token")
+			utils.Problem(w, http.StatusUnauthorized, "INVALID_TOKEN", "Invalid or expired token")
 			return
 		}
 
@@ -154,12 +111,12 @@ func (h *AuthHandler) CreatorAuthMiddleware(next http.Handler) http.Handler {
 
 		creator, err := h.services.Auth.GetCreatorByID(r.Context(), claims.UserID)
 		if err != nil || creator == nil {
-			utils.Unauthorized(w, "Creator not found")
+			utils.Problem(w, http.StatusUnauthorized, "INVALID_TOKEN", "Creator not found")
 			return
 		}
 
 		if !creator.IsActive {
-			utils.Forbidden(w, "Account is disabled")
+			writeError(w, services.ErrAccountDisabled, "")
 			return
 		}
 
@@ -173,13 +130,13 @@ func (h *AuthHandler) AdminAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token := extractToken(r)
 		if token == "" {
-			utils.Unauthorized(w, "Missing authorization token")
+			utils.Problem(w, http.StatusUnauthorized, "MISSING_TOKEN", "Missing authorization token")
 			return
 		}
 
 		claims, err := h.services.Auth.ValidateToken(token)
 		if err != nil {
-			utils.Unauthorized(w, "Invalid or expired token")
+			utils.Problem(w, http.StatusUnauthorized, "INVALID_TOKEN", "Invalid or expired token")
 			return
 		}
 
@@ -190,12 +147,12 @@ func (h *AuthHandler) AdminAuthMiddleware(next http.Handler) http.Handler {
 
 		admin, err := h.services.Auth.GetAdminByID(r.Context(), claims.UserID)
 		if err != nil || admin == nil {
-			utils.Unauthorized(w, "Admin not found")
+			utils.Problem(w, http.StatusUnauthorized, "INVALID_TOKEN", "Admin not found")
 			return
 		}
 
 		if !admin.IsActive {
-			utils.Forbidden(w, "Account is disabled")
+			writeError(w, services.ErrAccountDisabled, "")
 			return
 		}
 
@@ -207,27 +164,43 @@ func (h *AuthHandler) AdminAuthMiddleware(next http.Handler) http.Handler {
 
 func (h *AuthHandler) AgentAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		expected := strings.TrimSpace(h.config.Agent.Token)
-		if expected == "" {
-			utils.InternalError(w, "Agent API token is not configured")
-			return
-		}
-
 		provided := extractAgentToken(r)
 		if provided == "" {
-			utils.Unauthorized(w, "Missing agent token")
+			utils.Problem(w, http.StatusUnauthorized, "MISSING_TOKEN", "Missing agent token")
 			return
 		}
 
-		if subtle.ConstantTimeCompare([]byte(provided), []byte(expected)) != 1 {
-			utils.Unauthorized(w, "Invalid agent token")
+		key, creator, err := h.services.AgentKey.Authenticate(r.Context(), provided)
+		if err != nil {
+			writeError(w, err, "Failed to authenticate agent")
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), ContextKeyAgentKey, key)
+		ctx = context.WithValue(ctx, ContextKeyCreator, creator)
+		ctx = context.WithValue(ctx, ContextKeyUserID, creator.ID)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireAgentScope rejects agent requests whose key does not carry the scope
+func (h *AuthHandler) RequireAgentScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := GetAgentKeyFromContext(r.Context())
+			if key == nil {
+				utils.Unauthorized(w, "")
+				return
+			}
+			if !key.HasScope(scope) {
+				utils.Problem(w, http.StatusForbidden, "MISSING_SCOPE", "Agent token lacks scope "+scope)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // OptionalCreatorAuthMiddleware attempts to authenticate a creator if a token is present,
 // but does not reject the request if no token is provided
 func (h *AuthHandler) OptionalCreatorAuthMiddleware(next http.Handler) http.Handler {
@@ -282,6 +255,14 @@ func GetAdminFromContext(ctx context.Context) *models.Admin {
 	return nil
 }
 
+// Helper to get the authenticated agent key from context
+func GetAgentKeyFromContext(ctx context.Context) *models.AgentAPIKey {
+	if key, ok := ctx.Value(ContextKeyAgentKey).(*models.AgentAPIKey); ok {
+		return key
+	}
+	return nil
+}
+
 func extractAgentToken(r *http.Request) string {
 	if token := strings.TrimSpace(r.Header.Get("X-Agent-Token")); token != "" {
 		return token
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAdminLogin(t *testing.T) {
+	s := newTestServer(t)
+	token := s.loginAdmin(t)
+
+	wrong := map[string]string{"email": "ops@example.com", "password": "guess"}
+	if status, _ := s.do(t, http.MethodPost, "/api/admin/login", "", wrong, nil); status != http.StatusUnauthorized {
+		t.Errorf("wrong password: %d, want 401", status)
+	}
+
+	if status, resp := s.do(t, http.MethodGet, "/api/admin/events", token, nil, nil); status != http.StatusOK {
+		t.Errorf("admin events with admin token: %d %s", status, resp.Detail)
+	}
+	if status, _ := s.do(t, http.MethodPost, "/api/creator/events", token, eventBody(), nil); status != http.StatusForbidden {
+		t.Errorf("creator route with admin token: %d, want 403", status)
+	}
+
+	creator := s.loginCreator(t, "creator@example.com")
+	if status, _ := s.do(t, http.MethodGet, "/api/admin/events", creator, nil, nil); status != http.StatusForbidden {
+		t.Errorf("admin route with creator token: %d, want 403", status)
+	}
+}
+
+func TestCreatorRegisterDuplicate(t *testing.T) {
+	s := newTestServer(t)
+	s.loginCreator(t, "creator@example.com")
+
+	body := map[string]string{"name": "Nyoman", "email": "creator@example.com", "password": "open-sesame"}
+	if status, resp := s.do(t, http.MethodPost, "/api/creator/register", "", body, nil); status != http.StatusConflict || resp.Code != "EMAIL_EXISTS" {
+		t.Errorf("duplicate email: %d %s, want 409 EMAIL_EXISTS", status, resp.Code)
+	}
+
+	body["password"] = "short"
+	body["email"] = "new@example.com"
+	status, resp := s.do(t, http.MethodPost, "/api/creator/register", "", body, nil)
+	if status != http.StatusUnprocessableEntity || len(resp.Errors) != 1 || resp.Errors[0].Field != "password" || resp.Errors[0].Rule != "min" {
+		t.Errorf("short password: %d %+v, want 422 on password", status, resp.Errors)
+	}
+}
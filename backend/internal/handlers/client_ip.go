@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIPMiddleware sets r.RemoteAddr to the address of the client, which
+// the request log, the rate limits and visitor hashing all read. Forwarding
+// headers say whatever their sender wants, so they are only believed when the
+// connection comes from one of the trusted proxies. X-Forwarded-For is then
+// read from the right, past the hops that trusted proxies added, and the
+// first other address is the client. X-Real-IP is used when a trusted proxy
+// sends no X-Forwarded-For.
+func ClientIPMiddleware(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if addr, ok := clientAddr(r, trusted); ok {
+				r.RemoteAddr = addr.String()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func clientAddr(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	peer, ok := parseAddr(r.RemoteAddr)
+	if !ok {
+		return netip.Addr{}, false
+	}
+	if !isTrustedProxy(peer, trusted) {
+		return peer, true
+	}
+
+	// Several X-Forwarded-For headers make up one list
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	if len(hops) == 0 {
+		if addr, ok := parseAddr(r.Header.Get("X-Real-IP")); ok {
+			return addr, true
+		}
+		return peer, true
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseAddr(hops[i])
+		if !ok {
+			// Nothing left of a malformed hop can be trusted; the last
+			// proxy that was stands in for the client
+			return peer, true
+		}
+		if !isTrustedProxy(addr, trusted) {
+			return addr, true
+		}
+		peer = addr
+	}
+	return peer, true
+}
+
+func isTrustedProxy(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// getClientIP returns the client's IP address, as set by ClientIPMiddleware,
+// or "" if the connection gives no valid one
+func getClientIP(r *http.Request) string {
+	ip, _ := parseClientIP(r.RemoteAddr)
+	return ip
+}
+
+// parseClientIP accepts an address with or without a port, IPv6 in brackets
+// included, and returns it in canonical form with IPv4-mapped IPv6 unmapped
+func parseClientIP(value string) (string, bool) {
+	addr, ok := parseAddr(value)
+	if !ok {
+		return "", false
+	}
+	return addr.String(), true
+}
+
+func parseAddr(value string) (netip.Addr, bool) {
+	value = strings.TrimSpace(value)
+	if addrPort, err := netip.ParseAddrPort(value); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	if addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")); err == nil {
+		return addr.Unmap(), true
+	}
+	return netip.Addr{}, false
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestClientIPMiddleware(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("10.0.0.0/8")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		realIP     string
+		want       string
+	}{
+		{"direct client", "203.0.113.7:5123", nil, "", "203.0.113.7"},
+		{"direct client spoofing X-Forwarded-For", "203.0.113.7:5123", []string{"198.51.100.1"}, "", "203.0.113.7"},
+		{"direct client spoofing X-Real-IP", "203.0.113.7:5123", nil, "198.51.100.1", "203.0.113.7"},
+		{"through trusted proxy", "127.0.0.1:40000", []string{"203.0.113.7"}, "", "203.0.113.7"},
+		{"client-supplied hop before the proxy's", "127.0.0.1:40000", []string{"198.51.100.1, 203.0.113.7"}, "", "203.0.113.7"},
+		{"chain of trusted proxies", "127.0.0.1:40000", []string{"198.51.100.1, 203.0.113.7, 10.1.2.3"}, "", "203.0.113.7"},
+		{"split across headers", "127.0.0.1:40000", []string{"198.51.100.1", "203.0.113.7, 10.1.2.3"}, "", "203.0.113.7"},
+		{"malformed hop", "127.0.0.1:40000", []string{"203.0.113.7, bogus, 10.1.2.3"}, "", "10.1.2.3"},
+		{"only trusted hops", "127.0.0.1:40000", []string{"10.1.2.3"}, "", "10.1.2.3"},
+		{"X-Real-IP from trusted proxy", "127.0.0.1:40000", nil, "203.0.113.7", "203.0.113.7"},
+		{"ipv6 client", "[2001:db8::1]:443", nil, "", "2001:db8::1"},
+		{"ipv4-mapped hop", "127.0.0.1:40000", []string{"::ffff:203.0.113.7"}, "", "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := ClientIPMiddleware(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = getClientIP(r)
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/api/visitors", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.xff {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("client IP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	limited := ClientIPMiddleware(nil)(RateLimitByIP(3, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	codes := map[int]int{}
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/api/visitors", nil)
+		req.RemoteAddr = "203.0.113.7:5123"
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i))
+		rec := httptest.NewRecorder()
+		limited.ServeHTTP(rec, req)
+		codes[rec.Code]++
+	}
+	if codes[http.StatusOK] != 3 || codes[http.StatusTooManyRequests] != 2 {
+		t.Errorf("responses = %v, want 3 OK and 2 rate limited", codes)
+	}
+}
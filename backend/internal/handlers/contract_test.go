@@ -0,0 +1,567 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/openapi"
+	"github.com/net1io/zenbali/internal/utils"
+	"github.com/net1io/zenbali/internal/validation"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const specURL = "openapi.json"
+
+// contract checks responses against the OpenAPI document. Schemas are
+// compiled from the document on first use.
+type contract struct {
+	doc      map[string]any
+	compiler *jsonschema.Compiler
+
+	mu      sync.Mutex
+	schemas map[string]*jsonschema.Schema
+}
+
+var (
+	specContract     *contract
+	specContractErr  error
+	specContractOnce sync.Once
+)
+
+// loadContract returns the contract of the embedded OpenAPI document
+func loadContract(t *testing.T) *contract {
+	t.Helper()
+	specContractOnce.Do(func() {
+		c := &contract{compiler: jsonschema.NewCompiler(), schemas: map[string]*jsonschema.Schema{}}
+		if specContractErr = json.Unmarshal(openapi.Spec, &c.doc); specContractErr != nil {
+			return
+		}
+		c.compiler.Draft = jsonschema.Draft2020
+		c.compiler.AssertFormat = true
+		specContractErr = c.compiler.AddResource(specURL, bytes.NewReader(openapi.Spec))
+		specContract = c
+	})
+	if specContractErr != nil {
+		t.Fatalf("load OpenAPI document: %v", specContractErr)
+	}
+	return specContract
+}
+
+// middleware records each response, fails t if the document does not allow
+// it for the route, and then sends it on
+func (c *contract) middleware(t *testing.T) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := httptest.NewRecorder()
+			next.ServeHTTP(rec, r)
+
+			pattern := chi.RouteContext(r.Context()).RoutePattern()
+			if err := c.check(r.Method, pattern, rec.Code, rec.Header().Get("Content-Type"), rec.Body.Bytes()); err != nil {
+				t.Errorf("%s %s: response breaks the OpenAPI document: %v", r.Method, r.URL.Path, err)
+			}
+
+			for key, values := range rec.Header() {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(rec.Code)
+			w.Write(rec.Body.Bytes())
+		})
+	}
+}
+
+// check validates one response of the operation at method and path
+func (c *contract) check(method, path string, status int, contentType string, body []byte) error {
+	pointer := "#/paths/" + escapePointer(path) + "/" + strings.ToLower(method)
+	op, ok := c.lookup(pointer).(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s %s is not documented", method, path)
+	}
+
+	responses, _ := op["responses"].(map[string]any)
+	key := strconv.Itoa(status)
+	if responses[key] == nil {
+		key = strconv.Itoa(status/100) + "XX"
+	}
+	if responses[key] == nil {
+		key = "default"
+	}
+	if responses[key] == nil {
+		return fmt.Errorf("status %d is not documented", status)
+	}
+	pointer += "/responses/" + key
+	response, _ := c.lookup(pointer).(map[string]any)
+	if ref, ok := response["$ref"].(string); ok {
+		pointer = ref
+		response, _ = c.lookup(ref).(map[string]any)
+	}
+
+	content, _ := response["content"].(map[string]any)
+	if len(content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("status %d has a body but none is documented", status)
+		}
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("status %d: bad Content-Type %q", status, contentType)
+	}
+	if content[mediaType] == nil {
+		return fmt.Errorf("status %d: Content-Type %s is not documented", status, mediaType)
+	}
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return nil
+	}
+
+	schema, err := c.schema(pointer + "/content/" + escapePointer(mediaType) + "/schema")
+	if err != nil {
+		return err
+	}
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Errorf("status %d: %v", status, err)
+	}
+	if err := schema.Validate(v); err != nil {
+		return fmt.Errorf("status %d: %v", status, err)
+	}
+	return nil
+}
+
+// schema compiles the schema at pointer, once
+func (c *contract) schema(pointer string) (*jsonschema.Schema, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if schema, ok := c.schemas[pointer]; ok {
+		return schema, nil
+	}
+	schema, err := c.compiler.Compile(specURL + pointer)
+	if err != nil {
+		return nil, fmt.Errorf("compile %s: %w", pointer, err)
+	}
+	c.schemas[pointer] = schema
+	return schema, nil
+}
+
+// lookup returns the value at a JSON pointer of the form #/a/b
+func (c *contract) lookup(pointer string) any {
+	var node any = c.doc
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "#/"), "/") {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		node = m[token]
+	}
+	return node
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+// TestSpecSchemasCompile compiles every schema of the document, including
+// those of operations the tests do not call
+func TestSpecSchemasCompile(t *testing.T) {
+	c := loadContract(t)
+	schemas, _ := c.lookup("#/components/schemas").(map[string]any)
+	for name := range schemas {
+		if _, err := c.schema("#/components/schemas/" + escapePointer(name)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	paths, _ := c.lookup("#/paths").(map[string]any)
+	for path, item := range paths {
+		for method, op := range item.(map[string]any) {
+			responses, _ := op.(map[string]any)["responses"].(map[string]any)
+			for status, response := range responses {
+				pointer := "#/paths/" + escapePointer(path) + "/" + method + "/responses/" + status
+				if _, ok := response.(map[string]any)["$ref"]; ok {
+					continue
+				}
+				content, _ := response.(map[string]any)["content"].(map[string]any)
+				for mediaType := range content {
+					if _, err := c.schema(pointer + "/content/" + escapePointer(mediaType) + "/schema"); err != nil {
+						t.Error(err)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestContractRejectsMismatch makes sure the contract is not vacuous
+func TestContractRejectsMismatch(t *testing.T) {
+	c := loadContract(t)
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"undocumented route", "GET", "/api/nowhere", 200, "application/json", `{"success":true}`},
+		{"undocumented status", "GET", "/api/health", 404, "application/problem+json", `{}`},
+		{"wrong content type", "GET", "/api/events/{id}", 404, "application/json", `{"type":"about:blank","title":"Not Found","status":404,"code":"EVENT_NOT_FOUND"}`},
+		{"missing field", "GET", "/api/health", 200, "application/json", `{"success":true,"data":{"status":"healthy"}}`},
+		{"unknown field", "GET", "/api/health", 200, "application/json", `{"success":true,"data":{"status":"healthy","service":"zenbali","uptime":1}}`},
+		{"wrong type", "GET", "/api/locations", 200, "application/json", `{"success":true,"data":[{"id":"1","name":"Ubud","slug":"ubud","is_active":true,"created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z"}]}`},
+		{"bad format", "GET", "/api/events/{id}", 404, "application/problem+json", `{"type":"about:blank","title":"Not Found","status":404,"code":"EVENT_NOT_FOUND","errors":[{"field":"id"}]}`},
+	}
+	for _, tt := range tests {
+		if err := c.check(tt.method, tt.path, tt.status, tt.contentType, []byte(tt.body)); err == nil {
+			t.Errorf("%s: accepted", tt.name)
+		}
+	}
+
+	ok := `{"success":true,"data":{"status":"healthy","service":"zenbali"}}`
+	if err := c.check("GET", "/api/health", 200, "application/json; charset=utf-8", []byte(ok)); err != nil {
+		t.Errorf("valid response rejected: %v", err)
+	}
+}
+
+// TestContractResponses sends typical requests through the test server, whose
+// middleware checks each response against the OpenAPI document
+func TestContractResponses(t *testing.T) {
+	s := newTestServer(t)
+	creator := s.loginCreator(t, "owner@example.com")
+	admin := s.loginAdmin(t)
+
+	var event models.EventResponse
+	s.do(t, http.MethodPost, "/api/creator/events", creator, eventBody(), &event)
+	if err := s.services.Event.PublishEvent(context.Background(), event.ID); err != nil {
+		t.Fatal(err)
+	}
+	eventPath := "/api/events/" + event.ID.String()
+	newCreator := map[string]any{"name": "Made", "email": "made@example.com", "password": "open-sesame", "is_active": true}
+
+	tests := []struct {
+		method string
+		path   string
+		token  string
+		body   any
+		status int
+	}{
+		{http.MethodGet, "/api/health", "", nil, http.StatusOK},
+		{http.MethodGet, "/api/locations", "", nil, http.StatusOK},
+		{http.MethodGet, "/api/event-types", "", nil, http.StatusOK},
+		{http.MethodGet, "/api/events", "", nil, http.StatusOK},
+		{http.MethodGet, "/api/events?search=nothing-matches", "", nil, http.StatusOK},
+		{http.MethodGet, eventPath, "", nil, http.StatusOK},
+		{http.MethodGet, "/api/events/" + uuid.NewString(), "", nil, http.StatusNotFound},
+		{http.MethodPost, "/api/creator/login", "", map[string]string{"email": "owner@example.com", "password": "wrong-password"}, http.StatusUnauthorized},
+		{http.MethodGet, "/api/creator/profile", creator, nil, http.StatusOK},
+		{http.MethodGet, "/api/creator/profile", "", nil, http.StatusUnauthorized},
+		{http.MethodGet, "/api/creator/events", creator, nil, http.StatusOK},
+		{http.MethodGet, "/api/creator/events", admin, nil, http.StatusForbidden},
+		{http.MethodPost, "/api/creator/events", creator, map[string]string{"title": "x"}, http.StatusUnprocessableEntity},
+		{http.MethodGet, "/api/admin/dashboard", admin, nil, http.StatusOK},
+		{http.MethodGet, "/api/admin/events", admin, nil, http.StatusOK},
+		{http.MethodGet, "/api/admin/creators", admin, nil, http.StatusOK},
+		{http.MethodPost, "/api/admin/creators", admin, newCreator, http.StatusCreated},
+		{http.MethodPost, "/api/admin/creators", admin, newCreator, http.StatusConflict},
+		{http.MethodGet, "/api/admin/creators", creator, nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if status, resp := s.do(t, tt.method, tt.path, tt.token, tt.body, nil); status != tt.status {
+			t.Errorf("%s %s: %d %s, want %d", tt.method, tt.path, status, resp.Detail, tt.status)
+		}
+	}
+}
+
+// componentTypes are the Go types behind the component schemas. Responses
+// must list every JSON field, and require those that are never omitted.
+var componentTypes = map[string]any{
+	"Problem":            utils.ProblemDetails{},
+	"FieldError":         validation.FieldError{},
+	"ImageVariant":       models.ImageVariant{},
+	"ImageSrcSet":        models.ImageSrcSet{},
+	"EventImages":        models.EventImages{},
+	"GalleryImage":       models.EventImageResponse{},
+	"Event":              models.EventResponse{},
+	"EventRecord":        models.Event{},
+	"GalleryImageRecord": models.EventImage{},
+	"Creator":            models.CreatorResponse{},
+	"Admin":              models.AdminResponse{},
+	"Location":           models.Location{},
+	"EventType":          models.EventType{},
+	"EntranceType":       models.EntranceType{},
+	"Payment":            models.PaymentResponse{},
+	"PaymentRecord":      models.Payment{},
+	"CheckoutSession":    models.CheckoutSessionResponse{},
+	"EventStatsCounts":   models.EventStatsCounts{},
+	"EventStatsDay":      models.EventStatsDay{},
+	"EventStatsSummary":  models.EventStatsSummary{},
+	"EventStats":         models.EventStatsResponse{},
+	"CreatorStats":       models.CreatorStatsResponse{},
+	"VisitorStats":       models.VisitorStats{},
+	"VisitorSegment":     models.VisitorSegment{},
+	"DashboardStats":     models.DashboardStats{},
+	"ReportTotals":       models.ReportTotals{},
+	"ReportPoint":        models.ReportPoint{},
+	"ReportChange":       models.ReportChange{},
+	"ConversionFunnel":   models.ConversionFunnel{},
+	"TimeSeriesReport":   models.TimeSeriesReport{},
+	"CreatorSourceStats": models.CreatorSourceStats{},
+	"SourceReport":       models.SourceReport{},
+	"BotReport":          models.BotReport{},
+	"AgentKey":           models.AgentAPIKeyResponse{},
+	"IssuedAgentKey":     models.AgentAPIKeyIssuedResponse{},
+	"Upload":             models.Upload{},
+	"UploadSweepReport":  models.UploadSweepReport{},
+	"DirectUpload":       models.DirectUpload{},
+	"ImportRow":          importRowResult{},
+	"ImportReport":       importReport{},
+}
+
+// requestTypes are the Go types behind the request schemas. They must list
+// every JSON field and require at least the fields validated as required.
+var requestTypes = map[string]any{
+	"CreatorRegisterRequest":     models.CreatorRegisterRequest{},
+	"CreatorLoginRequest":        models.CreatorLoginRequest{},
+	"AdminLoginRequest":          models.AdminLoginRequest{},
+	"CreatorUpdateRequest":       models.CreatorUpdateRequest{},
+	"AdminCreatorCreateRequest":  models.AdminCreatorCreateRequest{},
+	"AdminCreatorUpdateRequest":  models.AdminCreatorUpdateRequest{},
+	"EventCreateRequest":         models.EventCreateRequest{},
+	"EventUpdateRequest":         models.EventUpdateRequest{},
+	"AdminEventCreateRequest":    models.AdminEventCreateRequest{},
+	"AdminEventUpdateRequest":    models.AdminEventUpdateRequest{},
+	"EventImageUpdateRequest":    models.EventImageUpdateRequest{},
+	"EventImageOrderRequest":     models.EventImageOrderRequest{},
+	"DirectUploadRequest":        models.DirectUploadRequest{},
+	"DirectUploadConfirmRequest": models.DirectUploadConfirmRequest{},
+	"EventInteractionRequest":    models.EventInteractionRequest{},
+	"TrackVisitorRequest":        models.TrackVisitorRequest{},
+	"LocationRequest":            models.LocationRequest{},
+	"EventTypeRequest":           models.EventTypeRequest{},
+	"AgentKeyCreateRequest":      models.AgentAPIKeyCreateRequest{},
+	"AgentEventCreateRequest":    AgentEventCreateRequest{},
+	"AgentEventUpdateRequest":    AgentEventUpdateRequest{},
+	"AgentEventImageRequest":     AgentEventImageRequest{},
+}
+
+// inlineSchemas are component schemas of maps built in the handlers, or of
+// forms, with no Go type to compare with
+var inlineSchemas = []string{
+	"Message", "UploadedImage", "EventPage", "CreatorPage", "PaymentPage", "CreatorLogin",
+	"AdminLogin", "PaymentVerification", "Tracked", "Health", "ImageUploadForm",
+}
+
+// TestSchemasMatchModels checks the component schemas against the fields and
+// types of the models they describe
+func TestSchemasMatchModels(t *testing.T) {
+	c := loadContract(t)
+	schemas, _ := c.lookup("#/components/schemas").(map[string]any)
+
+	known := map[string]bool{}
+	for _, name := range inlineSchemas {
+		known[name] = true
+	}
+	check := func(name string, v any, request bool) {
+		known[name] = true
+		schema, ok := schemas[name].(map[string]any)
+		if !ok {
+			t.Errorf("%s: no such schema", name)
+			return
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		documentedRequired := map[string]bool{}
+		required, _ := schema["required"].([]any)
+		for _, field := range required {
+			documentedRequired[field.(string)] = true
+		}
+
+		fields := jsonFields(reflect.TypeOf(v))
+		for _, f := range fields {
+			property, ok := properties[f.name].(map[string]any)
+			if !ok {
+				t.Errorf("%s.%s is missing from the schema", name, f.name)
+				continue
+			}
+			if got, want := jsonType(f.typ), c.schemaTypes(property); got != "any" && want != nil && !want[got] {
+				t.Errorf("%s.%s is a %s, documented as %v", name, f.name, got, sortedSet(want))
+			}
+			if request {
+				if hasRule(f.validate, "required") && !hasRule(f.validate, "omitnil") && !documentedRequired[f.name] {
+					t.Errorf("%s.%s is validated as required but not documented so", name, f.name)
+				}
+			} else if f.omitempty && documentedRequired[f.name] {
+				t.Errorf("%s.%s may be omitted but is documented as required", name, f.name)
+			} else if !f.omitempty && !documentedRequired[f.name] {
+				t.Errorf("%s.%s is always sent but not documented as required", name, f.name)
+			}
+		}
+		for property := range properties {
+			if !hasField(fields, property) {
+				t.Errorf("%s.%s is documented but not a field of %T", name, property, v)
+			}
+		}
+	}
+
+	for name, v := range componentTypes {
+		check(name, v, false)
+	}
+	for name, v := range requestTypes {
+		check(name, v, true)
+	}
+	for name := range schemas {
+		if !known[name] {
+			t.Errorf("schema %s is not compared with a model", name)
+		}
+	}
+}
+
+// jsonField is a field as encoding/json sees it
+type jsonField struct {
+	name      string
+	typ       reflect.Type
+	omitempty bool
+	validate  string
+}
+
+// jsonFields lists the JSON fields of a struct type, with embedded structs
+// flattened as encoding/json does
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" {
+			embedded := sf.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				fields = append(fields, jsonFields(embedded)...)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, jsonField{
+			name:      name,
+			typ:       sf.Type,
+			omitempty: hasRule(options, "omitempty"),
+			validate:  sf.Tag.Get("validate"),
+		})
+	}
+	return fields
+}
+
+func hasRule(tag, rule string) bool {
+	for _, r := range strings.Split(tag, ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func hasField(fields []jsonField, name string) bool {
+	for _, f := range fields {
+		if f.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf(uuid.UUID{})
+)
+
+// jsonType is the JSON type a Go type is encoded as
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType || t == uuidType:
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+	return "any"
+}
+
+// schemaTypes returns the JSON types a property schema allows, following
+// refs and nullable unions, or nil for any type
+func (c *contract) schemaTypes(schema map[string]any) map[string]bool {
+	if ref, ok := schema["$ref"].(string); ok {
+		target, _ := c.lookup(ref).(map[string]any)
+		return c.schemaTypes(target)
+	}
+	types := map[string]bool{}
+	switch t := schema["type"].(type) {
+	case string:
+		types[t] = true
+	case []any:
+		for _, name := range t {
+			types[name.(string)] = true
+		}
+	default:
+		variants, ok := schema["oneOf"].([]any)
+		if !ok {
+			return nil
+		}
+		for _, variant := range variants {
+			sub := c.schemaTypes(variant.(map[string]any))
+			if sub == nil {
+				return nil
+			}
+			for name := range sub {
+				types[name] = true
+			}
+		}
+	}
+	return types
+}
+
+func sortedSet(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -45,8 +45,7 @@ func (h *CreatorHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req models.CreatorUpdateRequest
-	if err := utils.ParseJSON(r, &req); err != nil {
-		utils.BadRequest(w, "Invalid request body")
+	if !utils.DecodeJSON(w, r, &req) {
 		return
 	}
 
@@ -121,73 +120,13 @@ func (h *CreatorHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req models.EventCreateRequest
-	if err := utils.ParseJSON(r, &req); err != nil {
-		utils.BadRequest(w, "Invalid request body")
-		return
-	}
-
-	// Validation
-	if req.Title == "" {
-		utils.BadRequest(w, "Title is required")
-		return
-	}
-	if req.EventDate == "" {
-		utils.BadRequest(w, "Event date is required")
-		return
-	}
-	if req.EventTime == "" {
-		utils.BadRequest(w, "Event time is required")
-		return
-	}
-	if req.LocationID <= 0 {
-		utils.BadRequest(w, "Location is required")
-		return
-	}
-	if req.EventTypeID <= 0 {
-		utils.BadRequest(w, "Event type is required")
-		return
-	}
-	if req.Duration == "" {
-		utils.BadRequest(w, "Duration is required")
-		return
-	}
-	if req.EntranceTypeID <= 0 {
-		utils.BadRequest(w, "Entrance type is required")
-		return
-	}
-	if req.ParticipantGroupType == "" {
-		utils.BadRequest(w, "Participant group type is required")
-		return
-	}
-	if req.LeadBy == "" {
-		utils.BadRequest(w, "Lead by is required")
-		return
-	}
-	if req.ContactEmail == "" {
-		utils.BadRequest(w, "Contact email is required")
-		return
-	}
-	if req.ContactMobile == "" {
-		utils.BadRequest(w, "Contact mobile is required")
-		return
-	}
-	if req.Notes == "" {
-		utils.BadRequest(w, "Event description is required")
+	if !utils.DecodeJSON(w, r, &req) {
 		return
 	}
 
 	event, err := h.services.Event.Create(r.Context(), creator.ID, &req)
 	if err != nil {
-		if err == services.ErrInvalidDate {
-			utils.BadRequest(w, "Invalid date format. Use YYYY-MM-DD")
-			return
-		}
-		if err.Error() == "price_thousands must be between 0 and 100000" {
-			utils.BadRequest(w, err.Error())
-			return
-		}
-		log.Printf("ERROR creating event for creator %s: %v", creator.ID, err)
-		utils.InternalError(w, "Failed to create event")
+		writeError(w, err, "Failed to create event")
 		return
 	}
 
@@ -210,17 +149,13 @@ func (h *CreatorHandler) GetEvent(w http.ResponseWriter, r *http.Request) {
 
 	event, err := h.services.Event.GetByID(r.Context(), id)
 	if err != nil {
-		if err == services.ErrEventNotFound {
-			utils.NotFound(w, "Event not found")
-			return
-		}
-		utils.InternalError(w, "Failed to fetch event")
+		writeError(w, err, "Failed to fetch event")
 		return
 	}
 
 	// Check ownership
 	if event.CreatorID != creator.ID {
-		utils.Forbidden(w, "Not authorized to view this event")
+		writeError(w, services.ErrNotEventOwner, "")
 		return
 	}
 
@@ -242,29 +177,13 @@ func (h *CreatorHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req models.EventUpdateRequest
-	if err := utils.ParseJSON(r, &req); err != nil {
-		utils.BadRequest(w, "Invalid request body")
+	if !utils.DecodeJSON(w, r, &req) {
 		return
 	}
 
 	event, err := h.services.Event.Update(r.Context(), id, creator.ID, &req, false)
 	if err != nil {
-		switch err {
-		case services.ErrEventNotFound:
-			utils.NotFound(w, "Event not found")
-		case services.ErrNotEventOwner:
-			utils.Forbidden(w, "Not authorized to update this event")
-		case services.ErrEventInPast:
-			utils.BadRequest(w, "Cannot modify past events")
-		case services.ErrInvalidDate:
-			utils.BadRequest(w, "Invalid date format")
-		default:
-			if err.Error() == "price_thousands must be between 0 and 100000" {
-				utils.BadRequest(w, err.Error())
-				return
-			}
-			utils.InternalError(w, "Failed to update event")
-		}
+		writeError(w, err, "Failed to update event")
 		return
 	}
 
@@ -286,14 +205,7 @@ func (h *CreatorHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.services.Event.Delete(r.Context(), id, creator.ID, false); err != nil {
-		switch err {
-		case services.ErrEventNotFound:
-			utils.NotFound(w, "Event not found")
-		case services.ErrNotEventOwner:
-			utils.Forbidden(w, "Not authorized to delete this event")
-		default:
-			utils.InternalError(w, "Failed to delete event")
-		}
+		writeError(w, err, "Failed to delete event")
 		return
 	}
 
@@ -329,37 +241,89 @@ func (h *CreatorHandler) UploadEventImage(w http.ResponseWriter, r *http.Request
 	defer file.Close()
 
 	// Save file
-	imageURL, err := h.services.Upload.SaveEventImage(file, header)
+	imageKey, err := h.services.Upload.SaveEventImage(r.Context(), file, header, models.UploadOwner{
+		Source:    models.UploadSourceCreator,
+		CreatorID: &creator.ID,
+	})
 	if err != nil {
-		switch err {
-		case services.ErrFileTooLarge:
-			utils.BadRequest(w, "File too large")
-		case services.ErrInvalidFileType:
-			utils.BadRequest(w, "Invalid file type. Allowed: jpg, jpeg, png, webp")
-		default:
-			utils.InternalError(w, "Failed to upload image")
-		}
+		writeError(w, err, "Failed to upload image")
 		return
 	}
 
 	// Update event with image URL
-	if err := h.services.Event.UpdateImageURL(r.Context(), id, creator.ID, imageURL); err != nil {
-		switch err {
-		case services.ErrEventNotFound:
-			utils.NotFound(w, "Event not found")
-		case services.ErrNotEventOwner:
-			utils.Forbidden(w, "Not authorized")
-		default:
-			utils.InternalError(w, "Failed to update event")
-		}
+	if err := h.services.Event.UpdateImageURL(r.Context(), id, creator.ID, imageKey); err != nil {
+		writeError(w, err, "Failed to update event")
 		return
 	}
 
-	utils.Success(w, map[string]string{
+	imageURL := h.services.Upload.URL(imageKey)
+	utils.Success(w, map[string]interface{}{
 		"image_url": imageURL,
+		"images":    models.NewEventImages(imageURL),
+	})
+}
+
+// galleryActor returns the authenticated creator as a gallery editor
+func (h *CreatorHandler) galleryActor(w http.ResponseWriter, r *http.Request) (*models.Creator, galleryActor, bool) {
+	creator := GetCreatorFromContext(r.Context())
+	if creator == nil {
+		utils.Unauthorized(w, "")
+		return nil, galleryActor{}, false
+	}
+	return creator, galleryActor{creatorID: creator.ID}, true
+}
+
+func (h *CreatorHandler) AddEventImage(w http.ResponseWriter, r *http.Request) {
+	creator, actor, ok := h.galleryActor(w, r)
+	if !ok {
+		return
+	}
+	uploadGalleryImage(w, r, h.services, actor, models.UploadOwner{
+		Source:    models.UploadSourceCreator,
+		CreatorID: &creator.ID,
+	})
+}
+
+func (h *CreatorHandler) CreateEventImageUpload(w http.ResponseWriter, r *http.Request) {
+	creator, actor, ok := h.galleryActor(w, r)
+	if !ok {
+		return
+	}
+	requestDirectUpload(w, r, h.services, actor, models.UploadOwner{
+		Source:    models.UploadSourceCreator,
+		CreatorID: &creator.ID,
+	})
+}
+
+func (h *CreatorHandler) ConfirmEventImageUpload(w http.ResponseWriter, r *http.Request) {
+	creator, actor, ok := h.galleryActor(w, r)
+	if !ok {
+		return
+	}
+	confirmDirectUpload(w, r, h.services, actor, models.UploadOwner{
+		Source:    models.UploadSourceCreator,
+		CreatorID: &creator.ID,
 	})
 }
 
+func (h *CreatorHandler) UpdateEventImage(w http.ResponseWriter, r *http.Request) {
+	if _, actor, ok := h.galleryActor(w, r); ok {
+		updateGalleryImage(w, r, h.services, actor)
+	}
+}
+
+func (h *CreatorHandler) ReorderEventImages(w http.ResponseWriter, r *http.Request) {
+	if _, actor, ok := h.galleryActor(w, r); ok {
+		reorderGallery(w, r, h.services, actor)
+	}
+}
+
+func (h *CreatorHandler) DeleteEventImage(w http.ResponseWriter, r *http.Request) {
+	if _, actor, ok := h.galleryActor(w, r); ok {
+		deleteGalleryImage(w, r, h.services, actor)
+	}
+}
+
 func (h *CreatorHandler) CreatePaymentSession(w http.ResponseWriter, r *http.Request) {
 	creator := GetCreatorFromContext(r.Context())
 	if creator == nil {
@@ -376,21 +340,17 @@ func (h *CreatorHandler) CreatePaymentSession(w http.ResponseWriter, r *http.Req
 
 	event, err := h.services.Event.GetByID(r.Context(), id)
 	if err != nil {
-		if err == services.ErrEventNotFound {
-			utils.NotFound(w, "Event not found")
-			return
-		}
-		utils.InternalError(w, "Failed to fetch event")
+		writeError(w, err, "Failed to fetch event")
 		return
 	}
 
 	if event.CreatorID != creator.ID {
-		utils.Forbidden(w, "Not authorized")
+		writeError(w, services.ErrNotEventOwner, "")
 		return
 	}
 
 	if event.IsPaid {
-		utils.BadRequest(w, "Event is already paid")
+		writeError(w, services.ErrAlreadyPaid, "")
 		return
 	}
 
@@ -401,11 +361,7 @@ func (h *CreatorHandler) CreatePaymentSession(w http.ResponseWriter, r *http.Req
 
 	session, err := h.services.Payment.CreateCheckoutSession(r.Context(), event, successURL, cancelURL)
 	if err != nil {
-		if err == services.ErrAlreadyPaid {
-			utils.BadRequest(w, "Event is already paid")
-			return
-		}
-		utils.InternalError(w, "Failed to create payment session")
+		writeError(w, err, "Failed to create payment session")
 		return
 	}
 
@@ -428,16 +384,12 @@ func (h *CreatorHandler) VerifyPaymentSession(w http.ResponseWriter, r *http.Req
 
 	event, err := h.services.Event.GetByID(r.Context(), id)
 	if err != nil {
-		if err == services.ErrEventNotFound {
-			utils.NotFound(w, "Event not found")
-			return
-		}
-		utils.InternalError(w, "Failed to fetch event")
+		writeError(w, err, "Failed to fetch event")
 		return
 	}
 
 	if event.CreatorID != creator.ID {
-		utils.Forbidden(w, "Not authorized")
+		writeError(w, services.ErrNotEventOwner, "")
 		return
 	}
 
@@ -445,12 +397,7 @@ func (h *CreatorHandler) VerifyPaymentSession(w http.ResponseWriter, r *http.Req
 	verified, err := h.services.Payment.VerifyCheckoutSession(r.Context(), event, sessionID)
 	if err != nil {
 		log.Printf("ERROR verifying payment for event %s session %s: %v", id, sessionID, err)
-		switch err {
-		case services.ErrSessionMismatch:
-			utils.Forbidden(w, "Checkout session does not belong to this event")
-		default:
-			utils.InternalError(w, "Failed to verify payment")
-		}
+		writeError(w, err, "Failed to verify payment")
 		return
 	}
 
@@ -515,3 +462,54 @@ func (h *CreatorHandler) ListPayments(w http.ResponseWriter, r *http.Request) {
 		"total_pages": result.TotalPages,
 	})
 }
+
+// GetEventStats returns daily views, contact clicks and shares of one of the
+// creator's events
+func (h *CreatorHandler) GetEventStats(w http.ResponseWriter, r *http.Request) {
+	creator := GetCreatorFromContext(r.Context())
+	if creator == nil {
+		utils.Unauthorized(w, "")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid event ID")
+		return
+	}
+	from, to, ok := parseDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.services.EventStats.EventStats(r.Context(), id, creator.ID, from, to)
+	if err != nil {
+		writeError(w, err, "Failed to fetch event stats")
+		return
+	}
+
+	utils.Success(w, stats)
+}
+
+// GetStats sums the stats of all the creator's events, with a daily series
+// and per-event totals
+func (h *CreatorHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	creator := GetCreatorFromContext(r.Context())
+	if creator == nil {
+		utils.Unauthorized(w, "")
+		return
+	}
+
+	from, to, ok := parseDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.services.EventStats.CreatorStats(r.Context(), creator.ID, from, to)
+	if err != nil {
+		writeError(w, err, "Failed to fetch stats")
+		return
+	}
+
+	utils.Success(w, stats)
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/validation"
+)
+
+func eventBody() map[string]any {
+	return map[string]any{
+		"title":                  "Ecstatic Dance",
+		"event_date":             time.Now().AddDate(0, 0, 10).Format("2006-01-02"),
+		"event_time":             "18:00",
+		"location_id":            2,
+		"event_type_id":          4,
+		"duration":               "3 hours",
+		"entrance_type_id":       3,
+		"entrance_fee":           150000,
+		"participant_group_type": "Adults",
+		"lead_by":                "DJ Surya",
+		"contact_email":          "dance@example.com",
+		"contact_mobile":         "+628999",
+		"notes":                  "Barefoot, no phones",
+	}
+}
+
+func TestCreatorEventLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.loginCreator(t, "owner@example.com")
+	other := s.loginCreator(t, "other@example.com")
+
+	var event models.EventResponse
+	status, resp := s.do(t, http.MethodPost, "/api/creator/events", owner, eventBody(), &event)
+	if status != http.StatusCreated {
+		t.Fatalf("create: %d %s", status, resp.Detail)
+	}
+	if event.Location != "Canggu" || event.IsPublished {
+		t.Errorf("created event at %q, published %t", event.Location, event.IsPublished)
+	}
+	path := "/api/creator/events/" + event.ID.String()
+
+	// Unpublished events are only visible to their creator
+	if status, _ := s.do(t, http.MethodGet, "/api/events/"+event.ID.String(), "", nil, nil); status != http.StatusNotFound {
+		t.Errorf("public GET of unpublished event: %d, want 404", status)
+	}
+	if status, _ := s.do(t, http.MethodGet, "/api/events/"+event.ID.String(), owner, nil, nil); status != http.StatusOK {
+		t.Errorf("owner GET of unpublished event: %d, want 200", status)
+	}
+	if status, _ := s.do(t, http.MethodGet, path, other, nil, nil); status != http.StatusForbidden {
+		t.Errorf("other creator GET: %d, want 403", status)
+	}
+
+	update := map[string]any{"title": "Ecstatic Dance Sunday"}
+	if status, _ := s.do(t, http.MethodPut, path, other, update, nil); status != http.StatusForbidden {
+		t.Errorf("other creator PUT: %d, want 403", status)
+	}
+	status, resp = s.do(t, http.MethodPut, path, owner, update, &event)
+	if status != http.StatusOK || event.Title != "Ecstatic Dance Sunday" {
+		t.Errorf("owner PUT: %d %s, title %q", status, resp.Detail, event.Title)
+	}
+
+	if status, _ := s.do(t, http.MethodDelete, path, other, nil, nil); status != http.StatusForbidden {
+		t.Errorf("other creator DELETE: %d, want 403", status)
+	}
+	if status, _ := s.do(t, http.MethodDelete, path, owner, nil, nil); status != http.StatusOK {
+		t.Errorf("owner DELETE: %d, want 200", status)
+	}
+	if status, _ := s.do(t, http.MethodGet, path, owner, nil, nil); status != http.StatusNotFound {
+		t.Errorf("GET after delete: %d, want 404", status)
+	}
+}
+
+func TestCreatorEventValidation(t *testing.T) {
+	s := newTestServer(t)
+	token := s.loginCreator(t, "owner@example.com")
+
+	body := eventBody()
+	delete(body, "title")
+	body["contact_email"] = "not an email"
+	body["price_thousands"] = -1
+	status, resp := s.do(t, http.MethodPost, "/api/creator/events", token, body, nil)
+	if status != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid fields: %d %s, want 422", status, resp.Detail)
+	}
+	want := validation.Errors{
+		{Field: "title", Rule: "required", Message: "title is required"},
+		{Field: "price_thousands", Rule: "min", Message: "price_thousands must be at least 0"},
+		{Field: "contact_email", Rule: "email", Message: "contact_email must be a valid email address"},
+	}
+	if !reflect.DeepEqual(resp.Errors, want) {
+		t.Errorf("errors = %+v, want %+v", resp.Errors, want)
+	}
+
+	if status, resp := s.do(t, http.MethodPut, "/api/creator/events/"+uuid.NewString(), token, map[string]string{"title": "ab"}, nil); status != http.StatusUnprocessableEntity {
+		t.Errorf("short title on update: %d %s, want 422", status, resp.Detail)
+	}
+
+	body = eventBody()
+	body["event_date"] = "next friday"
+	if status, resp := s.do(t, http.MethodPost, "/api/creator/events", token, body, nil); status != http.StatusBadRequest {
+		t.Errorf("bad date: %d %s, want 400", status, resp.Detail)
+	}
+
+	if status, _ := s.do(t, http.MethodPost, "/api/creator/events", "", eventBody(), nil); status != http.StatusUnauthorized {
+		t.Errorf("no token: %d, want 401", status)
+	}
+	if status, _ := s.do(t, http.MethodPut, "/api/creator/events/"+uuid.NewString(), token, eventBody(), nil); status != http.StatusNotFound {
+		t.Errorf("unknown event: %d, want 404", status)
+	}
+}
+
+func TestErrorProblems(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.loginCreator(t, "owner@example.com")
+	other := s.loginCreator(t, "other@example.com")
+
+	var event models.EventResponse
+	s.do(t, http.MethodPost, "/api/creator/events", owner, eventBody(), &event)
+	badDate := eventBody()
+	badDate["event_date"] = "next friday"
+	noTitle := eventBody()
+	delete(noTitle, "title")
+
+	tests := []struct {
+		name, method, path, token string
+		body                      any
+		status                    int
+		code                      string
+	}{
+		{"domain error", http.MethodPost, "/api/creator/events", owner, badDate, http.StatusBadRequest, "INVALID_DATE"},
+		{"validation", http.MethodPost, "/api/creator/events", owner, noTitle, http.StatusUnprocessableEntity, "VALIDATION_FAILED"},
+		{"not found", http.MethodGet, "/api/creator/events/" + uuid.NewString(), owner, nil, http.StatusNotFound, "EVENT_NOT_FOUND"},
+		{"forbidden", http.MethodGet, "/api/creator/events/" + event.ID.String(), other, nil, http.StatusForbidden, "NOT_EVENT_OWNER"},
+		{"missing token", http.MethodPost, "/api/creator/events", "", eventBody(), http.StatusUnauthorized, "MISSING_TOKEN"},
+		{"bad path", http.MethodGet, "/api/creator/events/42", owner, nil, http.StatusBadRequest, "BAD_REQUEST"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := s.do(t, tt.method, tt.path, tt.token, tt.body, nil)
+			if status != tt.status || resp.Status != tt.status || resp.Code != tt.code {
+				t.Errorf("got %d (body %d) %s, want %d %s", status, resp.Status, resp.Code, tt.status, tt.code)
+			}
+			if resp.ContentType != "application/problem+json" || resp.Title != http.StatusText(tt.status) || resp.Detail == "" {
+				t.Errorf("problem %q %q %q is incomplete", resp.ContentType, resp.Title, resp.Detail)
+			}
+		})
+	}
+}
+
+func TestPublicListEvents(t *testing.T) {
+	s := newTestServer(t)
+	token := s.loginCreator(t, "owner@example.com")
+
+	var published models.EventResponse
+	s.do(t, http.MethodPost, "/api/creator/events", token, eventBody(), &published)
+	s.do(t, http.MethodPost, "/api/creator/events", token, eventBody(), nil)
+	if err := s.services.Event.PublishEvent(context.Background(), published.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var list struct {
+		Events []models.EventResponse `json:"events"`
+		Total  int                    `json:"total"`
+	}
+	if status, resp := s.do(t, http.MethodGet, "/api/events", "", nil, &list); status != http.StatusOK {
+		t.Fatalf("list: %d %s", status, resp.Detail)
+	}
+	if list.Total != 1 || len(list.Events) != 1 || list.Events[0].ID != published.ID {
+		t.Errorf("listed %d of %d events, want only the published one", len(list.Events), list.Total)
+	}
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/net1io/zenbali/internal/services"
+	"github.com/net1io/zenbali/internal/utils"
+)
+
+// kindStatus maps each kind of domain error to the status it is answered with
+var kindStatus = map[services.Kind]int{
+	services.KindInvalid:      http.StatusBadRequest,
+	services.KindUnauthorized: http.StatusUnauthorized,
+	services.KindForbidden:    http.StatusForbidden,
+	services.KindNotFound:     http.StatusNotFound,
+	services.KindConflict:     http.StatusConflict,
+}
+
+// writeError answers with the problem for a domain error from the services,
+// under its code and message. Any other error is logged and answered with a
+// 500 with fallback as the detail.
+func writeError(w http.ResponseWriter, err error, fallback string) {
+	var domainErr *services.Error
+	if errors.As(err, &domainErr) {
+		if status, ok := kindStatus[domainErr.Kind]; ok {
+			utils.Problem(w, status, domainErr.Code, domainErr.Message)
+			return
+		}
+	}
+	log.Printf("ERROR %s: %v", fallback, err)
+	utils.InternalError(w, fallback)
+}
@@ -0,0 +1,500 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/repository"
+	"github.com/net1io/zenbali/internal/services"
+	"github.com/net1io/zenbali/internal/utils"
+	"github.com/net1io/zenbali/internal/validation"
+)
+
+const (
+	maxImportRows  = 1000
+	maxImportBytes = 5 << 20
+
+	// Images are downloaded while the client waits, so a request may only
+	// name a few, fetched a few at a time, and all of them must arrive well
+	// within the server's write timeout
+	maxImportImages    = 50
+	importImageWorkers = 4
+	importImageTimeout = 10 * time.Second
+
+	importRowValid   = "valid"
+	importRowInvalid = "invalid"
+	importRowCreated = "created"
+)
+
+// importRow is one parsed line of a bulk import, in the agent payload shape
+type importRow struct {
+	Number   int
+	Request  *AgentEventCreateRequest
+	ParseErr error
+}
+
+type importRowResult struct {
+	Row        int      `json:"row"`
+	Status     string   `json:"status"`
+	Title      string   `json:"title,omitempty"`
+	ExternalID string   `json:"external_id,omitempty"`
+	EventID    string   `json:"event_id,omitempty"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+type importReport struct {
+	DryRun      bool               `json:"dry_run"`
+	TotalRows   int                `json:"total_rows"`
+	ValidRows   int                `json:"valid_rows"`
+	InvalidRows int                `json:"invalid_rows"`
+	Created     int                `json:"created"`
+	Rows        []*importRowResult `json:"rows"`
+}
+
+// importOptions are read from the query string of the import endpoints
+type importOptions struct {
+	DryRun  bool
+	Strict  bool
+	Publish bool
+	CSV     bool
+}
+
+func parseImportOptions(r *http.Request) importOptions {
+	query := r.URL.Query()
+	return importOptions{
+		DryRun:  query.Get("dry_run") == "true",
+		Strict:  query.Get("strict") == "true",
+		Publish: query.Get("publish") == "true",
+		CSV:     query.Get("report") == "csv",
+	}
+}
+
+// runEventImport validates every row, then (unless dry-run) commits the valid
+// rows in a single transaction and writes the per-row report.
+func runEventImport(w http.ResponseWriter, r *http.Request, svcs *services.Services, repos *repository.Repositories, creatorID uuid.UUID, opts importOptions) {
+	rows, err := readImportRows(w, r)
+	if err != nil {
+		utils.BadRequest(w, err.Error())
+		return
+	}
+	if len(rows) == 0 {
+		utils.BadRequest(w, "Import contains no rows")
+		return
+	}
+	images := 0
+	for _, row := range rows {
+		if row.Request != nil && strings.TrimSpace(row.Request.ImageURL) != "" {
+			images++
+		}
+	}
+	if images > maxImportImages {
+		utils.BadRequest(w, fmt.Sprintf("Import is limited to %d rows with an image_url; split it into smaller imports", maxImportImages))
+		return
+	}
+
+	report := &importReport{DryRun: opts.DryRun, TotalRows: len(rows)}
+	var pending []*models.Event
+	var pendingResults []*importRowResult
+	externalIDs := make(map[string]int)
+
+	for _, row := range rows {
+		result := &importRowResult{Row: row.Number, Status: importRowValid}
+		report.Rows = append(report.Rows, result)
+
+		if row.ParseErr != nil {
+			result.Status = importRowInvalid
+			result.Errors = append(result.Errors, row.ParseErr.Error())
+			continue
+		}
+		result.Title = strings.TrimSpace(row.Request.Title)
+		result.ExternalID = strings.TrimSpace(row.Request.ExternalID)
+
+		event, rowErrs := prepareImportRow(r, svcs, repos, creatorID, row, pending, pendingResults, externalIDs)
+		if len(rowErrs) > 0 {
+			result.Status = importRowInvalid
+			result.Errors = rowErrs
+			continue
+		}
+
+		event.IsPaid = opts.Publish
+		event.IsPublished = opts.Publish
+		if event.ExternalID != nil {
+			externalIDs[*event.ExternalID] = row.Number
+		}
+		pending = append(pending, event)
+		pendingResults = append(pendingResults, result)
+	}
+
+	commit := !opts.DryRun && len(pending) > 0 && !(opts.Strict && len(pending) < report.TotalRows)
+	var hosted []string
+	if commit {
+		// Images are only downloaded when rows are about to be committed
+		owner := agentUploadOwner(r.Context(), models.UploadSourceRemote)
+		owner.CreatorID = &creatorID
+		pending, pendingResults, hosted = hostImportImages(r, svcs, owner, pending, pendingResults)
+		commit = len(pending) > 0 && !(opts.Strict && len(pending) < report.TotalRows)
+	}
+
+	report.ValidRows = len(pending)
+	report.InvalidRows = report.TotalRows - report.ValidRows
+
+	if !commit {
+		// Nothing will reference the images we fetched
+		deleteImportImages(svcs, hosted)
+	} else {
+		created, err := svcs.Event.CreateBatch(r.Context(), pending)
+		if err != nil {
+			deleteImportImages(svcs, hosted)
+			utils.InternalError(w, "Failed to import events; no rows were committed")
+			return
+		}
+		for i, event := range created {
+			pendingResults[i].Status = importRowCreated
+			pendingResults[i].EventID = event.ID.String()
+		}
+		report.Created = len(created)
+	}
+
+	if opts.CSV {
+		writeImportReportCSV(w, report)
+		return
+	}
+	if commit {
+		utils.Created(w, report)
+		return
+	}
+	utils.Success(w, report)
+}
+
+func prepareImportRow(r *http.Request, svcs *services.Services, repos *repository.Repositories, creatorID uuid.UUID, row *importRow, pending []*models.Event, pendingResults []*importRowResult, externalIDs map[string]int) (*models.Event, []string) {
+	ctx := r.Context()
+
+	if errs := validation.Struct(row.Request); errs != nil {
+		return nil, errs.Messages()
+	}
+
+	createReq, err := buildAgentCreateRequest(ctx, repos, row.Request)
+	if err != nil {
+		var domainErr *services.Error
+		if !errors.As(err, &domainErr) {
+			log.Printf("ERROR resolving import row %d: %v", row.Number, err)
+			return nil, []string{"failed to resolve event fields"}
+		}
+		return nil, []string{err.Error()}
+	}
+
+	if createReq.ExternalID != "" {
+		if other, ok := externalIDs[createReq.ExternalID]; ok {
+			return nil, []string{fmt.Sprintf("external_id repeats row %d", other)}
+		}
+	}
+
+	event, err := svcs.Event.PrepareCreate(ctx, creatorID, createReq)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+	event.ImageURL = optionalString(strings.TrimSpace(row.Request.ImageURL))
+
+	if !row.Request.AllowDuplicate {
+		for i, other := range pending {
+			if other.EventDate.Equal(event.EventDate) && other.LocationID == event.LocationID && services.IsNearDuplicateTitle(other.Title, event.Title) {
+				return nil, []string{fmt.Sprintf("duplicate of row %d", pendingResults[i].Row)}
+			}
+		}
+		duplicate, err := svcs.Event.FindDuplicate(ctx, creatorID, createReq.EventDate, createReq.LocationID, createReq.Title)
+		if err != nil {
+			return nil, []string{"failed to check for duplicate events"}
+		}
+		if duplicate != nil {
+			return nil, []string{fmt.Sprintf("duplicate of existing event %s", duplicate.ID)}
+		}
+	}
+
+	return event, nil
+}
+
+// hostImportImages downloads each pending row's image_url into our storage,
+// importImageWorkers at a time and within importImageTimeout altogether.
+// Rows whose image cannot be fetched are marked invalid and dropped.
+func hostImportImages(r *http.Request, svcs *services.Services, owner models.UploadOwner, pending []*models.Event, results []*importRowResult) ([]*models.Event, []*importRowResult, []string) {
+	ctx, cancel := context.WithTimeout(r.Context(), importImageTimeout)
+	defer cancel()
+
+	keys := make([]string, len(pending))
+	fetched := make([]bool, len(pending))
+	errs := make([]string, len(pending))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for n := 0; n < importImageWorkers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				keys[i], fetched[i], errs[i] = hostImportImage(ctx, svcs, owner, results[i].Row, *pending[i].ImageURL)
+			}
+		}()
+	}
+	for i, event := range pending {
+		if event.ImageURL != nil {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	var keptEvents []*models.Event
+	var keptResults []*importRowResult
+	var hosted []string
+	for i, event := range pending {
+		if fetched[i] {
+			hosted = append(hosted, keys[i])
+		}
+		if errs[i] != "" {
+			results[i].Status = importRowInvalid
+			results[i].Errors = append(results[i].Errors, errs[i])
+			continue
+		}
+		if event.ImageURL != nil {
+			event.ImageURL = &keys[i]
+		}
+		keptEvents = append(keptEvents, event)
+		keptResults = append(keptResults, results[i])
+	}
+	return keptEvents, keptResults, hosted
+}
+
+// hostImportImage resolves one row's image_url to a storage key, downloading
+// it unless it is one of the owner's uploads. fetched reports whether a new
+// file was stored; a failure is described in errMsg.
+func hostImportImage(ctx context.Context, svcs *services.Services, owner models.UploadOwner, row int, rawURL string) (key string, fetched bool, errMsg string) {
+	key, hosted, err := svcs.Upload.HostedKey(ctx, rawURL, owner)
+	if err != nil {
+		log.Printf("ERROR looking up import row %d image: %v", row, err)
+		return "", false, "failed to look up image_url"
+	}
+	if hosted {
+		return key, false, ""
+	}
+
+	key, err = svcs.Upload.SaveRemoteImage(ctx, rawURL, owner)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", false, fmt.Sprintf("image_url was not downloaded within the import's %s limit", importImageTimeout)
+		}
+		return "", false, remoteImageError(err).Message
+	}
+	return key, true, ""
+}
+
+func deleteImportImages(svcs *services.Services, imageKeys []string) {
+	for _, key := range imageKeys {
+		_ = svcs.Upload.DeleteFile(key)
+	}
+}
+
+// readImportRows accepts CSV or NDJSON either as the raw body or as a
+// multipart "file" field. The format comes from ?format=, the file extension
+// or the Content-Type, in that order.
+func readImportRows(w http.ResponseWriter, r *http.Request) ([]*importRow, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	var body io.Reader = r.Body
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		if err := r.ParseMultipartForm(maxImportBytes); err != nil {
+			return nil, errors.New("import file too large")
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, errors.New("no import file provided")
+		}
+		defer file.Close()
+		body = file
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+		}
+	}
+
+	if format == "" {
+		switch mediaType {
+		case "text/csv", "application/csv":
+			format = "csv"
+		case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/json":
+			format = "ndjson"
+		}
+	}
+
+	switch format {
+	case "csv":
+		return readCSVImportRows(body)
+	case "ndjson", "jsonl", "json":
+		return readNDJSONImportRows(body)
+	default:
+		return nil, errors.New("unsupported import format. Use CSV or NDJSON")
+	}
+}
+
+func readNDJSONImportRows(body io.Reader) ([]*importRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	var rows []*importRow
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if len(rows) >= maxImportRows {
+			return nil, fmt.Errorf("import is limited to %d rows", maxImportRows)
+		}
+
+		row := &importRow{Number: len(rows) + 1, Request: &AgentEventCreateRequest{}}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(row.Request); err != nil {
+			row.ParseErr = fmt.Errorf("invalid JSON: %v", err)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read import: %v", err)
+	}
+	return rows, nil
+}
+
+func readCSVImportRows(body io.Reader) ([]*importRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("CSV import must start with a header row")
+	}
+
+	fields := agentRequestFields()
+	columns := make([]string, len(header))
+	for i, name := range header {
+		key := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))), " ", "_")
+		if _, ok := fields[key]; !ok {
+			return nil, fmt.Errorf("unknown CSV column: %s", name)
+		}
+		columns[i] = key
+	}
+
+	var rows []*importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if len(rows) >= maxImportRows {
+			return nil, fmt.Errorf("import is limited to %d rows", maxImportRows)
+		}
+
+		row := &importRow{Number: len(rows) + 1, Request: &AgentEventCreateRequest{}}
+		rows = append(rows, row)
+		if err != nil {
+			row.ParseErr = fmt.Errorf("invalid CSV: %v", err)
+			continue
+		}
+
+		value := reflect.ValueOf(row.Request).Elem()
+		for i, raw := range record {
+			if err := setImportField(value.Field(fields[columns[i]]), columns[i], strings.TrimSpace(raw)); err != nil {
+				row.ParseErr = err
+				break
+			}
+		}
+	}
+	return rows, nil
+}
+
+// agentRequestFields maps the JSON names of AgentEventCreateRequest to field indexes
+func agentRequestFields() map[string]int {
+	t := reflect.TypeOf(AgentEventCreateRequest{})
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = i
+		}
+	}
+	return fields
+}
+
+func setImportField(field reflect.Value, name, raw string) error {
+	if raw == "" {
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%s must be a whole number", name)
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number", name)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false", name)
+		}
+		field.SetBool(b)
+	case reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		if err := setImportField(elem.Elem(), name, raw); err != nil {
+			return err
+		}
+		field.Set(elem)
+	default:
+		return fmt.Errorf("unsupported column: %s", name)
+	}
+	return nil
+}
+
+func writeImportReportCSV(w http.ResponseWriter, report *importReport) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=event_import_%s.csv", time.Now().Format("20060102_150405")))
+
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	writer.Write([]string{"Row", "Status", "Title", "External ID", "Event ID", "Errors"})
+	for _, row := range report.Rows {
+		writer.Write([]string{
+			strconv.Itoa(row.Row),
+			row.Status,
+			row.Title,
+			row.ExternalID,
+			row.EventID,
+			strings.Join(row.Errors, "; "),
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/repository/memory"
+	"github.com/net1io/zenbali/internal/services"
+)
+
+func TestImportLimitsImageRows(t *testing.T) {
+	repos := memory.New().Repositories()
+	svcs := &services.Services{Event: services.NewEventService(repos, nil)}
+
+	ndjson := func(images int) string {
+		var b strings.Builder
+		for i := 0; i < images; i++ {
+			fmt.Fprintf(&b, `{"title":"Event %d","image_url":"https://example.com/%d.jpg"}`+"\n", i, i)
+		}
+		b.WriteString(`{"title":"No image"}` + "\n")
+		return b.String()
+	}
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/events/import?dry_run=true", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		rec := httptest.NewRecorder()
+		runEventImport(rec, req, svcs, repos, uuid.New(), parseImportOptions(req))
+		return rec
+	}
+
+	if rec := send(ndjson(maxImportImages + 1)); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "limited to 50 rows with an image_url") {
+		t.Errorf("too many images: %d %s", rec.Code, rec.Body)
+	}
+	if rec := send(ndjson(maxImportImages)); rec.Code != http.StatusOK {
+		t.Errorf("images at the limit: %d %s, want the dry-run report", rec.Code, rec.Body)
+	}
+}
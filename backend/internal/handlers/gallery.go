@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/services"
+	"github.com/net1io/zenbali/internal/utils"
+)
+
+// galleryActor is who is changing an event gallery. Creators, agents and
+// admins share the gallery endpoints and differ only in this.
+type galleryActor struct {
+	creatorID uuid.UUID
+	isAdmin   bool
+	// hideForeign reports other creators' events as missing rather than
+	// forbidden, as the agent API does
+	hideForeign bool
+}
+
+func (a galleryActor) writeError(w http.ResponseWriter, err error, fallback string) {
+	if a.hideForeign && errors.Is(err, services.ErrNotEventOwner) {
+		err = services.ErrEventNotFound
+	}
+	writeError(w, err, fallback)
+}
+
+// galleryIDs parses the {id} event and, if wanted, the {imageID} route params
+func galleryIDs(w http.ResponseWriter, r *http.Request, withImage bool) (eventID, imageID uuid.UUID, ok bool) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid event ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	if !withImage {
+		return eventID, uuid.Nil, true
+	}
+	imageID, err = uuid.Parse(chi.URLParam(r, "imageID"))
+	if err != nil {
+		utils.BadRequest(w, "Invalid image ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return eventID, imageID, true
+}
+
+// saveGalleryUpload stores the multipart "image" file and returns its key
+func saveGalleryUpload(w http.ResponseWriter, r *http.Request, upload *services.UploadService, owner models.UploadOwner) (string, bool) {
+	maxSize := int64(upload.GetMaxSizeMB() * 1024 * 1024)
+	if err := r.ParseMultipartForm(maxSize); err != nil {
+		utils.BadRequest(w, "File too large")
+		return "", false
+	}
+
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		utils.BadRequest(w, "No image file provided")
+		return "", false
+	}
+	defer file.Close()
+
+	key, err := upload.SaveEventImage(r.Context(), file, header, owner)
+	if err != nil {
+		writeUploadError(w, err)
+		return "", false
+	}
+	return key, true
+}
+
+// writeUploadError answers a failed image upload
+func writeUploadError(w http.ResponseWriter, err error) {
+	writeError(w, err, "Failed to upload image")
+}
+
+// requestDirectUpload issues a signed URL for uploading a gallery image
+// straight to storage
+func requestDirectUpload(w http.ResponseWriter, r *http.Request, svcs *services.Services, actor galleryActor, owner models.UploadOwner) {
+	eventID, _, ok := galleryIDs(w, r, false)
+	if !ok {
+		return
+	}
+
+	var req models.DirectUploadRequest
+	if !utils.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := svcs.Event.AuthorizeGallery(r.Context(), eventID, actor.creatorID, actor.isAdmin); err != nil {
+		actor.writeError(w, err, "Failed to create upload URL")
+		return
+	}
+
+	upload, err := svcs.Upload.CreateDirectUpload(r.Context(), &req, owner)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrFileTooLarge):
+			err = services.ErrFileTooLarge.WithMessage(fmt.Sprintf("size_bytes must be between 1 and %d", svcs.Upload.GetDirectMaxSizeMB()*1024*1024))
+		case errors.Is(err, services.ErrInvalidFileType):
+			err = services.ErrInvalidFileType.WithMessage("content_type must be image/jpeg, image/png, image/webp or image/heic")
+		}
+		writeError(w, err, "Failed to create upload URL")
+		return
+	}
+	utils.Created(w, upload)
+}
+
+// confirmDirectUpload validates an image uploaded through a signed URL and
+// adds it to the gallery
+func confirmDirectUpload(w http.ResponseWriter, r *http.Request, svcs *services.Services, actor galleryActor, owner models.UploadOwner) {
+	eventID, _, ok := galleryIDs(w, r, false)
+	if !ok {
+		return
+	}
+
+	var req models.DirectUploadConfirmRequest
+	if !utils.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	// Check before the staged file is consumed
+	if err := svcs.Event.AuthorizeGallery(r.Context(), eventID, actor.creatorID, actor.isAdmin); err != nil {
+		actor.writeError(w, err, "Failed to confirm upload")
+		return
+	}
+
+	key, err := svcs.Upload.ConfirmDirectUpload(r.Context(), req.Key, owner)
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+	addGalleryImage(w, r, svcs, actor, eventID, key, req.Caption, req.AltText)
+}
+
+// uploadGalleryImage handles a multipart gallery upload with optional
+// caption and alt_text form fields
+func uploadGalleryImage(w http.ResponseWriter, r *http.Request, svcs *services.Services, actor galleryActor, owner models.UploadOwner) {
+	eventID, _, ok := galleryIDs(w, r, false)
+	if !ok {
+		return
+	}
+
+	key, ok := saveGalleryUpload(w, r, svcs.Upload, owner)
+	if !ok {
+		return
+	}
+
+	// On failure the upload stays unattached and the sweeper collects it
+	addGalleryImage(w, r, svcs, actor, eventID, key, r.FormValue("caption"), r.FormValue("alt_text"))
+}
+
+func addGalleryImage(w http.ResponseWriter, r *http.Request, svcs *services.Services, actor galleryActor, eventID uuid.UUID, imageRef, caption, altText string) {
+	image, err := svcs.Event.AddImage(r.Context(), eventID, actor.creatorID, imageRef, caption, altText, actor.isAdmin)
+	if err != nil {
+		actor.writeError(w, err, "Failed to add image")
+		return
+	}
+	utils.Created(w, image.ToResponse())
+}
+
+func updateGalleryImage(w http.ResponseWriter, r *http.Request, svcs *services.Services, actor galleryActor) {
+	eventID, imageID, ok := galleryIDs(w, r, true)
+	if !ok {
+		return
+	}
+
+	var req models.EventImageUpdateRequest
+	if !utils.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	image, err := svcs.Event.UpdateImage(r.Context(), eventID, imageID, actor.creatorID, &req, actor.isAdmin)
+	if err != nil {
+		actor.writeError(w, err, "Failed to update image")
+		return
+	}
+	utils.Success(w, image.ToResponse())
+}
+
+func reorderGallery(w http.ResponseWriter, r *http.Request, svcs *services.Services, actor galleryActor) {
+	eventID, _, ok := galleryIDs(w, r, false)
+	if !ok {
+		return
+	}
+
+	var req models.EventImageOrderRequest
+	if !utils.DecodeJSON(w, r, &req) {
+		return
+	}
+
+	gallery, err := svcs.Event.ReorderImages(r.Context(), eventID, actor.creatorID, req.ImageIDs, actor.isAdmin)
+	if err != nil {
+		actor.writeError(w, err, "Failed to reorder images")
+		return
+	}
+	utils.Success(w, models.GalleryResponse(gallery))
+}
+
+func deleteGalleryImage(w http.ResponseWriter, r *http.Request, svcs *services.Services, actor galleryActor) {
+	eventID, imageID, ok := galleryIDs(w, r, true)
+	if !ok {
+		return
+	}
+
+	if err := svcs.Event.DeleteImage(r.Context(), eventID, imageID, actor.creatorID, actor.isAdmin); err != nil {
+		actor.writeError(w, err, "Failed to delete image")
+		return
+	}
+	utils.Message(w, "Image deleted successfully")
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/net1io/zenbali/internal/config"
+	"github.com/net1io/zenbali/internal/repository"
+	"github.com/net1io/zenbali/internal/repository/memory"
+	"github.com/net1io/zenbali/internal/services"
+	"github.com/net1io/zenbali/internal/validation"
+)
+
+// testServer serves the public, creator and admin routes over an in-memory
+// database. Every response is checked against the OpenAPI document.
+type testServer struct {
+	*httptest.Server
+	repos    *repository.Repositories
+	services *services.Services
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	cfg := &config.Config{
+		BaseURL: "https://zenbali.test",
+		JWT:     config.JWTConfig{Secret: "test-secret", ExpiryHours: 1},
+		Stripe:  config.StripeConfig{PriceCents: 1000},
+	}
+	repos := memory.New().Repositories()
+	svcs := &services.Services{
+		Auth:    services.NewAuthService(repos, cfg.JWT, cfg.BaseURL),
+		Event:   services.NewEventService(repos, nil),
+		Payment: services.NewPaymentService(repos, cfg.Stripe),
+	}
+	h := New(svcs, repos, cfg)
+
+	r := chi.NewRouter()
+	r.Use(loadContract(t).middleware(t))
+	r.Route("/api", func(r chi.Router) {
+		r.Get("/health", h.HealthCheck)
+		r.Get("/events", h.Public.ListEvents)
+		r.Get("/locations", h.Public.ListLocations)
+		r.Get("/event-types", h.Public.ListEventTypes)
+		r.With(h.Auth.OptionalCreatorAuthMiddleware).Get("/events/{id}", h.Public.GetEvent)
+
+		r.Post("/creator/register", h.Auth.CreatorRegister)
+		r.Post("/creator/login", h.Auth.CreatorLogin)
+		r.Group(func(r chi.Router) {
+			r.Use(h.Auth.CreatorAuthMiddleware)
+			r.Get("/creator/profile", h.Creator.GetProfile)
+			r.Get("/creator/events", h.Creator.ListEvents)
+			r.Post("/creator/events", h.Creator.CreateEvent)
+			r.Get("/creator/events/{id}", h.Creator.GetEvent)
+			r.Put("/creator/events/{id}", h.Creator.UpdateEvent)
+			r.Delete("/creator/events/{id}", h.Creator.DeleteEvent)
+		})
+
+		r.Post("/admin/login", h.Auth.AdminLogin)
+		r.Group(func(r chi.Router) {
+			r.Use(h.Auth.AdminAuthMiddleware)
+			r.Get("/admin/dashboard", h.Admin.Dashboard)
+			r.Get("/admin/events", h.Admin.ListEvents)
+			r.Put("/admin/events/{id}", h.Admin.UpdateEvent)
+			r.Get("/admin/creators", h.Admin.ListCreators)
+			r.Post("/admin/creators", h.Admin.CreateCreator)
+		})
+	})
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return &testServer{Server: srv, repos: repos, services: svcs}
+}
+
+// response is the envelope every handler answers with
+type response struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Message string          `json:"message"`
+
+	// Problem details of an error
+	ContentType string            `json:"-"`
+	Title       string            `json:"title"`
+	Status      int               `json:"status"`
+	Detail      string            `json:"detail"`
+	Code        string            `json:"code"`
+	Errors      validation.Errors `json:"errors"`
+}
+
+// do sends body as JSON with token as the bearer token, and decodes the
+// response into data when it succeeds
+func (s *testServer) do(t *testing.T, method, path, token string, body, data any) (int, *response) {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, s.URL+path, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	resp := &response{ContentType: res.Header.Get("Content-Type")}
+	if err := json.NewDecoder(res.Body).Decode(resp); err != nil {
+		t.Fatalf("%s %s: decode response: %v", method, path, err)
+	}
+	if data != nil && resp.Success {
+		if err := json.Unmarshal(resp.Data, data); err != nil {
+			t.Fatalf("%s %s: decode data: %v", method, path, err)
+		}
+	}
+	return res.StatusCode, resp
+}
+
+// loginCreator registers a creator and returns their token
+func (s *testServer) loginCreator(t *testing.T, email string) string {
+	t.Helper()
+	body := map[string]string{"name": "Nyoman", "email": email, "mobile": "+628777", "password": "open-sesame"}
+	if status, resp := s.do(t, http.MethodPost, "/api/creator/register", "", body, nil); status != http.StatusCreated {
+		t.Fatalf("register: %d %s", status, resp.Detail)
+	}
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	if status, resp := s.do(t, http.MethodPost, "/api/creator/login", "", body, &login); status != http.StatusOK {
+		t.Fatalf("login: %d %s", status, resp.Detail)
+	}
+	return login.Token
+}
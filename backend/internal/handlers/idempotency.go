@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/repository"
+	"github.com/net1io/zenbali/internal/utils"
+)
+
+const (
+	idempotencyHeader    = "Idempotency-Key"
+	idempotencyTTL       = 24 * time.Hour
+	idempotencyLease     = 5 * time.Minute
+	maxIdempotencyKeyLen = 255
+	maxIdempotentBody    = 10 << 20
+)
+
+// IdempotencyMiddleware honours the Idempotency-Key header for requests made by
+// an authenticated creator (including agents). The first request with a key is
+// processed and its response stored; retries with the same key and body replay
+// that response instead of repeating side effects. Requests without the header
+// pass through untouched. A reservation whose request never finished, because
+// the process died, is given up after idempotencyLease.
+func IdempotencyMiddleware(repos *repository.Repositories) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := strings.TrimSpace(r.Header.Get(idempotencyHeader))
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLen {
+				utils.BadRequest(w, "Idempotency-Key must be at most 255 characters")
+				return
+			}
+
+			creator := GetCreatorFromContext(r.Context())
+			if creator == nil {
+				utils.Unauthorized(w, "")
+				return
+			}
+
+			// One byte over the limit is read so that an oversized body is
+			// refused rather than stored and forwarded truncated.
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBody+1))
+			if err != nil {
+				utils.BadRequest(w, "Error reading request body")
+				return
+			}
+			if len(body) > maxIdempotentBody {
+				utils.Problem(w, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE", "Request body must be at most 10 MB")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			sum := sha256.Sum256(append([]byte(r.Method+" "+r.URL.Path+"\n"), body...))
+			record := &models.IdempotencyRecord{
+				CreatorID:      creator.ID,
+				IdempotencyKey: key,
+				RequestMethod:  r.Method,
+				RequestPath:    r.URL.Path,
+				RequestHash:    hex.EncodeToString(sum[:]),
+			}
+
+			reserved, existing, err := repos.Idempotency.Reserve(r.Context(), record, idempotencyTTL, idempotencyLease)
+			if err != nil {
+				log.Printf("ERROR reserving idempotency key: %v", err)
+				utils.InternalError(w, "Failed to process Idempotency-Key")
+				return
+			}
+
+			if !reserved {
+				switch {
+				case existing == nil:
+					utils.Problem(w, http.StatusConflict, "IDEMPOTENCY_KEY_IN_PROGRESS", "Idempotency-Key is being processed, retry shortly")
+				case existing.RequestHash != record.RequestHash:
+					utils.Problem(w, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED", "Idempotency-Key was already used with a different request")
+				case !existing.IsComplete():
+					utils.Problem(w, http.StatusConflict, "IDEMPOTENCY_KEY_IN_PROGRESS", "A request with this Idempotency-Key is still in progress")
+				default:
+					contentType := existing.ContentType
+					if contentType == "" {
+						contentType = "application/json"
+					}
+					w.Header().Set("Content-Type", contentType)
+					w.Header().Set("Idempotent-Replayed", "true")
+					w.WriteHeader(*existing.StatusCode)
+					w.Write(existing.ResponseBody)
+				}
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			// The client may have gone away, cancelling the request context,
+			// but the key must still be settled.
+			ctx := context.WithoutCancel(r.Context())
+
+			// Server errors are not stored so that the client can retry them.
+			if rec.status >= http.StatusInternalServerError {
+				if err := repos.Idempotency.Release(ctx, record.ID); err != nil {
+					log.Printf("ERROR releasing idempotency key %s: %v", record.ID, err)
+				}
+				return
+			}
+			if err := repos.Idempotency.Complete(ctx, record.ID, rec.status, rec.body.Bytes(), w.Header().Get("Content-Type")); err != nil {
+				log.Printf("ERROR storing idempotent response %s: %v", record.ID, err)
+			}
+		})
+	}
+}
+
+// responseRecorder passes a response through while keeping a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/repository"
+	"github.com/net1io/zenbali/internal/repository/memory"
+)
+
+// contextIdempotency fails writes made with a cancelled context, as a
+// database driver does
+type contextIdempotency struct{ repository.IdempotencyStore }
+
+func (s contextIdempotency) Complete(ctx context.Context, id uuid.UUID, statusCode int, body []byte, contentType string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.IdempotencyStore.Complete(ctx, id, statusCode, body, contentType)
+}
+
+func (s contextIdempotency) Release(ctx context.Context, id uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.IdempotencyStore.Release(ctx, id)
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	repos := memory.New().Repositories()
+	creator := &models.Creator{Name: "Agent", Email: "agent@example.com"}
+	if err := repos.Creator.Create(context.Background(), creator); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+	var seen []byte
+	handler := IdempotencyMiddleware(repos)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		seen, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"n":1}`))
+	}))
+	send := func(key string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/agent/events", bytes.NewReader(body))
+		req.Header.Set(idempotencyHeader, key)
+		req = req.WithContext(context.WithValue(req.Context(), ContextKeyCreator, creator))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	body := []byte(`{"title":"Sound Healing"}`)
+	if rec := send("k1", body); rec.Code != http.StatusCreated || !bytes.Equal(seen, body) {
+		t.Fatalf("first request: %d, handler saw %q", rec.Code, seen)
+	}
+	if rec := send("k1", body); rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "true" || calls != 1 {
+		t.Errorf("retry: %d replayed=%q after %d calls", rec.Code, rec.Header().Get("Idempotent-Replayed"), calls)
+	}
+	if rec := send("k1", []byte(`{"title":"Other"}`)); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key with another body: %d, want 422", rec.Code)
+	}
+
+	// A body over the limit is refused, not truncated and passed on
+	if rec := send("k2", bytes.Repeat([]byte("a"), maxIdempotentBody+1)); rec.Code != http.StatusRequestEntityTooLarge || calls != 1 {
+		t.Errorf("oversized body: %d after %d calls, want 413 without calling the handler", rec.Code, calls)
+	}
+	if rec := send("k3", bytes.Repeat([]byte("a"), maxIdempotentBody)); rec.Code != http.StatusCreated || len(seen) != maxIdempotentBody {
+		t.Errorf("body at the limit: %d, handler saw %d bytes", rec.Code, len(seen))
+	}
+}
+
+func TestIdempotencyClientGoneAway(t *testing.T) {
+	db := memory.New()
+	repos := db.Repositories()
+	repos.Idempotency = contextIdempotency{repos.Idempotency}
+	creator := &models.Creator{Name: "Agent", Email: "agent@example.com"}
+	if err := repos.Creator.Create(context.Background(), creator); err != nil {
+		t.Fatal(err)
+	}
+
+	// The request context is cancelled before the handler returns, as when
+	// the client times out
+	var calls int
+	var cancel context.CancelFunc
+	status := http.StatusCreated
+	handler := IdempotencyMiddleware(repos)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		cancel()
+		w.WriteHeader(status)
+	}))
+	send := func(key string) int {
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.WithValue(context.Background(), ContextKeyCreator, creator))
+		defer cancel()
+		req := httptest.NewRequest(http.MethodPost, "/api/agent/events", bytes.NewReader([]byte(`{}`))).WithContext(ctx)
+		req.Header.Set(idempotencyHeader, key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// The response is still stored and replayed
+	send("k1")
+	if code := send("k1"); code != http.StatusCreated || calls != 1 {
+		t.Errorf("retry after completing: %d after %d calls, want a replayed 201", code, calls)
+	}
+
+	// A server error still releases the key for a retry
+	status = http.StatusInternalServerError
+	send("k2")
+	status = http.StatusCreated
+	if code := send("k2"); code != http.StatusCreated || calls != 3 {
+		t.Errorf("retry after a server error: %d after %d calls, want the handler run again", code, calls)
+	}
+
+	// A reservation left behind by a crash is reclaimed once its lease runs out
+	sum := sha256.Sum256([]byte("POST /api/agent/events\n{}"))
+	record := &models.IdempotencyRecord{
+		CreatorID: creator.ID, IdempotencyKey: "k3", RequestMethod: http.MethodPost,
+		RequestPath: "/api/agent/events", RequestHash: hex.EncodeToString(sum[:]),
+	}
+	if reserved, _, err := repos.Idempotency.Reserve(context.Background(), record, idempotencyTTL, idempotencyLease); !reserved || err != nil {
+		t.Fatalf("reserving: %t, %v", reserved, err)
+	}
+	if code := send("k3"); code != http.StatusConflict {
+		t.Errorf("within the lease: %d, want 409", code)
+	}
+	later := time.Now().Add(idempotencyLease + time.Minute)
+	db.Now = func() time.Time { return later }
+	if code := send("k3"); code != http.StatusCreated || calls != 4 {
+		t.Errorf("after the lease: %d after %d calls, want the handler run", code, calls)
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/net1io/zenbali/internal/models"
 	"github.com/net1io/zenbali/internal/repository"
 	"github.com/net1io/zenbali/internal/services"
+	"github.com/net1io/zenbali/internal/storage"
 	"github.com/net1io/zenbali/internal/utils"
 )
 
@@ -117,11 +119,7 @@ func (h *PublicHandler) GetEvent(w http.ResponseWriter, r *http.Request) {
 
 	event, err := h.services.Event.GetByID(r.Context(), id)
 	if err != nil {
-		if err == services.ErrEventNotFound {
-			utils.NotFound(w, "Event not found")
-			return
-		}
-		utils.InternalError(w, "Failed to fetch event")
+		writeError(w, err, "Failed to fetch event")
 		return
 	}
 
@@ -172,3 +170,17 @@ func (h *Handlers) HealthCheck(w http.ResponseWriter, r *http.Request) {
 		"service": "zenbali",
 	})
 }
+
+// ReceiveDirectUpload accepts the PUT of a signed direct upload when images
+// are stored locally. The signature in the query stands in for auth.
+func (h *PublicHandler) ReceiveDirectUpload(w http.ResponseWriter, r *http.Request) {
+	err := h.services.Upload.ReceiveSignedPut(r.Context(), r.URL.Query(), r.Header.Get("Content-Type"), r.Body)
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusOK)
+	case errors.Is(err, storage.ErrInvalidSignature):
+		utils.Problem(w, http.StatusForbidden, "INVALID_UPLOAD_SIGNATURE", "Invalid or expired upload URL")
+	default:
+		writeError(w, err, "Failed to store upload")
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/net1io/zenbali/internal/utils"
+)
+
+// RateLimitByIP allows each client IP limit requests per window and answers
+// the rest with 429. A limit of 0 disables it.
+func RateLimitByIP(limit int, window time.Duration) func(http.Handler) http.Handler {
+	if limit <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	limiter := &ipRateLimiter{limit: limit, window: window, counts: map[string]int{}}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, retryAfter := limiter.allow(getClientIP(r), time.Now())
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+				utils.Problem(w, http.StatusTooManyRequests, "RATE_LIMITED", "Too many requests")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ipRateLimiter counts requests per IP in fixed windows. All counts are
+// dropped when a window ends, so it holds at most one window's clients.
+type ipRateLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	started time.Time
+	counts  map[string]int
+}
+
+// allow counts a request from ip; if it is over the limit, retryAfter is
+// how long until the window ends
+func (l *ipRateLimiter) allow(ip string, now time.Time) (ok bool, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.started) >= l.window {
+		l.started = now
+		l.counts = map[string]int{}
+	}
+	if l.counts[ip] >= l.limit {
+		return false, l.started.Add(l.window).Sub(now)
+	}
+	l.counts[ip]++
+	return true, 0
+}
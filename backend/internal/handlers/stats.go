@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/net1io/zenbali/internal/utils"
+)
+
+// defaultStatsRangeDays is the range used when a stats request gives no from
+const defaultStatsRangeDays = 30
+
+// parseDateRange reads the from and to (YYYY-MM-DD, inclusive) query
+// parameters. to defaults to today and from to the 30 days ending on to.
+func parseDateRange(w http.ResponseWriter, r *http.Request) (from, to time.Time, ok bool) {
+	query := r.URL.Query()
+
+	now := time.Now()
+	to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if value := query.Get("to"); value != "" {
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			utils.BadRequest(w, "to must be a date (YYYY-MM-DD)")
+			return time.Time{}, time.Time{}, false
+		}
+		to = t
+	}
+
+	from = to.AddDate(0, 0, 1-defaultStatsRangeDays)
+	if value := query.Get("from"); value != "" {
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			utils.BadRequest(w, "from must be a date (YYYY-MM-DD)")
+			return time.Time{}, time.Time{}, false
+		}
+		from = t
+	}
+	return from, to, true
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
-	"strings"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
 	"github.com/net1io/zenbali/internal/services"
 	"github.com/net1io/zenbali/internal/utils"
 )
@@ -21,48 +24,53 @@ func (h *VisitorHandler) TrackVisitor(w http.ResponseWriter, r *http.Request) {
 	ip := getClientIP(r)
 	userAgent := r.UserAgent()
 
+	// Attribution is optional; older pages post no body
+	var req models.TrackVisitorRequest
+	_ = utils.ParseJSON(r, &req)
+
 	// Track the visitor
-	if err := h.services.Visitor.TrackVisitor(r.Context(), ip, userAgent); err != nil {
+	if err := h.services.Visitor.TrackVisitor(r.Context(), ip, userAgent, req.Attribution); err != nil {
 		// Don't fail the request, just log
-		utils.Success(w, map[string]string{"status": "tracked"})
-		return
+		log.Printf("ERROR tracking visitor: %v", err)
 	}
 
 	utils.Success(w, map[string]string{"status": "tracked"})
 }
 
-func (h *VisitorHandler) GetStats(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.services.Visitor.GetStats(r.Context())
+// TrackEventInteraction records a view, contact click or share on an event
+// page
+func (h *VisitorHandler) TrackEventInteraction(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
-		utils.InternalError(w, "Failed to fetch visitor stats")
+		utils.BadRequest(w, "Invalid event ID")
 		return
 	}
 
-	utils.Success(w, stats)
-}
-
-// getClientIP extracts the client's real IP address
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header (for proxies/load balancers)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP in the list
-		parts := strings.Split(xff, ",")
-		if len(parts) > 0 {
-			return strings.TrimSpace(parts[0])
-		}
+	var req models.EventInteractionRequest
+	if !utils.DecodeJSON(w, r, &req) {
+		return
 	}
 
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
+	err = h.services.EventStats.Track(r.Context(), eventID, req.Action, getClientIP(r), r.UserAgent())
+	switch err {
+	case nil:
+	case services.ErrInvalidEventAction, services.ErrEventNotFound:
+		writeError(w, err, "")
+		return
+	default:
+		// Like visitor tracking, never fail the page over it
+		log.Printf("WARN failed to track %s on event %s: %v", req.Action, eventID, err)
 	}
 
-	// Fall back to RemoteAddr
-	ip := r.RemoteAddr
-	// Remove port if present
-	if colonIdx := strings.LastIndex(ip, ":"); colonIdx != -1 {
-		ip = ip[:colonIdx]
+	utils.Success(w, map[string]string{"status": "tracked"})
+}
+
+func (h *VisitorHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.services.Visitor.GetStats(r.Context())
+	if err != nil {
+		utils.InternalError(w, "Failed to fetch visitor stats")
+		return
 	}
 
-	return ip
+	utils.Success(w, stats)
 }
@@ -0,0 +1,171 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+)
+
+const exifOrientationTag = 0x0112
+
+// Orientation returns the EXIF orientation (1-8) stored in a JPEG, PNG or
+// WebP file, or 1 when there is none.
+func Orientation(data []byte) int {
+	tiff := exifTIFF(data)
+	if tiff == nil {
+		return 1
+	}
+	o := tiffOrientation(tiff)
+	if o < 1 || o > 8 {
+		return 1
+	}
+	return o
+}
+
+// exifTIFF locates the TIFF-structured EXIF payload inside the container
+func exifTIFF(data []byte) []byte {
+	switch {
+	case len(data) > 4 && data[0] == 0xff && data[1] == 0xd8:
+		return jpegEXIF(data)
+	case len(data) > 8 && bytes.Equal(data[:8], pngSignature):
+		return pngEXIF(data)
+	case len(data) > 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return webpEXIF(data)
+	}
+	return nil
+}
+
+func jpegEXIF(data []byte) []byte {
+	for p := 2; p+4 <= len(data); {
+		if data[p] != 0xff {
+			return nil
+		}
+		marker := data[p+1]
+		if marker == 0xd9 || marker == 0xda { // EOI, start of scan
+			return nil
+		}
+		size := int(binary.BigEndian.Uint16(data[p+2:]))
+		if size < 2 || p+2+size > len(data) {
+			return nil
+		}
+		segment := data[p+4 : p+2+size]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+		p += 2 + size
+	}
+	return nil
+}
+
+func pngEXIF(data []byte) []byte {
+	for p := 8; p+12 <= len(data); {
+		size := int(binary.BigEndian.Uint32(data[p:]))
+		if size < 0 || p+12+size > len(data) {
+			return nil
+		}
+		if string(data[p+4:p+8]) == "eXIf" {
+			return data[p+8 : p+8+size]
+		}
+		p += 12 + size
+	}
+	return nil
+}
+
+func webpEXIF(data []byte) []byte {
+	for p := 12; p+8 <= len(data); {
+		size := int(binary.LittleEndian.Uint32(data[p+4:]))
+		if size < 0 || p+8+size > len(data) {
+			return nil
+		}
+		if string(data[p:p+4]) == "EXIF" {
+			return bytes.TrimPrefix(data[p+8:p+8+size], []byte("Exif\x00\x00"))
+		}
+		p += 8 + size + size&1
+	}
+	return nil
+}
+
+// tiffOrientation reads the orientation tag from IFD0 of a TIFF header
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return 0
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
+
+// applyOrientation returns img rotated and flipped so that it displays
+// upright, given its EXIF orientation.
+func applyOrientation(img *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			s := img.PixOffset(sx, sy)
+			d := dst.PixOffset(x, y)
+			copy(dst.Pix[d:d+4], img.Pix[s:s+4])
+		}
+	}
+	return dst
+}
+
+// toNRGBA copies img into a zero-origin NRGBA image
+func toNRGBA(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
@@ -0,0 +1,211 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// exifTIFFWithOrientation builds a TIFF header whose IFD0 holds a single
+// orientation entry
+func exifTIFFWithOrientation(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 8, 26)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+
+	entry := make([]byte, 2+12)
+	order.PutUint16(entry, 1)
+	order.PutUint16(entry[2:], exifOrientationTag)
+	order.PutUint16(entry[4:], 3) // SHORT
+	order.PutUint32(entry[6:], 1)
+	order.PutUint16(entry[10:], orientation)
+	return append(tiff, entry...)
+}
+
+func jpegWithOrientation(t *testing.T, order binary.ByteOrder, orientation uint16) []byte {
+	t.Helper()
+	payload := append([]byte("Exif\x00\x00"), exifTIFFWithOrientation(order, orientation)...)
+	return jpegWithSegment(testJPEG(t), 0xe1, payload)
+}
+
+func pngWithOrientation(t *testing.T, orientation uint16) []byte {
+	t.Helper()
+	data := testPNG(t)
+	iend := len(data) - 12
+	out := append([]byte{}, data[:iend]...)
+	out = append(out, pngChunk("eXIf", exifTIFFWithOrientation(binary.BigEndian, orientation))...)
+	return append(out, data[iend:]...)
+}
+
+func webpWithOrientation(t *testing.T, orientation uint16) []byte {
+	t.Helper()
+	payload := exifTIFFWithOrientation(binary.LittleEndian, orientation)
+	chunk := make([]byte, 8, 8+len(payload)+1)
+	copy(chunk, "EXIF")
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(payload)))
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+
+	out := append(testWebP(t), chunk...)
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out
+}
+
+func TestOrientation(t *testing.T) {
+	for o := uint16(1); o <= 8; o++ {
+		files := map[string][]byte{
+			"jpeg little-endian": jpegWithOrientation(t, binary.LittleEndian, o),
+			"jpeg big-endian":    jpegWithOrientation(t, binary.BigEndian, o),
+			"png":                pngWithOrientation(t, o),
+			"webp":               webpWithOrientation(t, o),
+		}
+		for name, data := range files {
+			if got := Orientation(data); got != int(o) {
+				t.Errorf("%s with orientation %d: Orientation() = %d", name, o, got)
+			}
+		}
+	}
+
+	if got := Orientation(testJPEG(t)); got != 1 {
+		t.Errorf("jpeg without EXIF: Orientation() = %d, want 1", got)
+	}
+	for _, o := range []uint16{0, 9, 0xffff} {
+		if got := Orientation(jpegWithOrientation(t, binary.BigEndian, o)); got != 1 {
+			t.Errorf("out of range orientation %d: Orientation() = %d, want 1", o, got)
+		}
+	}
+}
+
+func TestOrientationMalformed(t *testing.T) {
+	valid := exifTIFFWithOrientation(binary.LittleEndian, 6)
+	hugeCount := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint16(hugeCount[8:], 0xffff)
+	binary.LittleEndian.PutUint16(hugeCount[10:], 0x010f) // Make, so the scan goes on
+	badOffset := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(badOffset[4:], 0xfffffff0)
+	tinyOffset := append([]byte{}, valid...)
+	binary.LittleEndian.PutUint32(tinyOffset[4:], 2)
+	badMagic := append([]byte{}, valid...)
+	badMagic[2] = 43
+
+	payloads := map[string][]byte{
+		"empty":                nil,
+		"prefix only":          []byte("Exif\x00\x00"),
+		"short header":         append([]byte("Exif\x00\x00"), valid[:6]...),
+		"truncated entry":      append([]byte("Exif\x00\x00"), valid[:len(valid)-4]...),
+		"entry count past end": append([]byte("Exif\x00\x00"), hugeCount...),
+		"IFD offset past end":  append([]byte("Exif\x00\x00"), badOffset...),
+		"IFD offset in header": append([]byte("Exif\x00\x00"), tinyOffset...),
+		"bad byte order":       append([]byte("Exif\x00\x00XX"), valid[2:]...),
+		"bad magic number":     append([]byte("Exif\x00\x00"), badMagic...),
+		"not exif":             []byte("http://ns.adobe.com/xap/1.0/"),
+		"garbage after prefix": append([]byte("Exif\x00\x00"), bytes.Repeat([]byte{0xff}, 40)...),
+	}
+	for name, payload := range payloads {
+		if got := Orientation(jpegWithSegment(testJPEG(t), 0xe1, payload)); got != 1 {
+			t.Errorf("%s: Orientation() = %d, want 1", name, got)
+		}
+	}
+
+	// Containers cut short at every length must not panic
+	for _, data := range [][]byte{
+		jpegWithOrientation(t, binary.BigEndian, 6),
+		pngWithOrientation(t, 6),
+		webpWithOrientation(t, 6),
+	} {
+		for n := 0; n < len(data); n++ {
+			Orientation(data[:n])
+		}
+	}
+
+	// Segment and chunk lengths that overrun the file
+	jpegOverrun := jpegWithOrientation(t, binary.BigEndian, 6)
+	binary.BigEndian.PutUint16(jpegOverrun[4:], 0xffff)
+	pngOverrun := pngWithOrientation(t, 6)
+	binary.BigEndian.PutUint32(pngOverrun[8:], 0xfffffff0)
+	webpOverrun := webpWithOrientation(t, 6)
+	binary.LittleEndian.PutUint32(webpOverrun[16:], 0xfffffff0)
+	for name, data := range map[string][]byte{"jpeg": jpegOverrun, "png": pngOverrun, "webp": webpOverrun} {
+		if got := Orientation(data); got != 1 {
+			t.Errorf("%s with overrunning length: Orientation() = %d, want 1", name, got)
+		}
+	}
+}
+
+func FuzzOrientation(f *testing.F) {
+	f.Add(jpegWithSegment([]byte{0xff, 0xd8, 0xff, 0xd9}, 0xe1, append([]byte("Exif\x00\x00"), exifTIFFWithOrientation(binary.BigEndian, 6)...)))
+	f.Add(append(append([]byte{}, pngSignature...), pngChunk("eXIf", exifTIFFWithOrientation(binary.BigEndian, 3))...))
+	f.Add([]byte("RIFF\x00\x00\x00\x00WEBPEXIF\x08\x00\x00\x00II*\x00\x08\x00\x00\x00"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if o := Orientation(data); o < 1 || o > 8 {
+			t.Fatalf("Orientation() = %d, want 1-8", o)
+		}
+	})
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// A 3x2 image with a distinct colour in every pixel
+	const w, h = 3, 2
+	src := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), A: 255})
+		}
+	}
+	a, b, c, d := image.Pt(0, 0), image.Pt(w-1, 0), image.Pt(0, h-1), image.Pt(w-1, h-1)
+
+	// The source corners that end up top-left, top-right and bottom-left
+	tests := []struct {
+		orientation int
+		tl, tr, bl  image.Point
+	}{
+		{1, a, b, c},
+		{2, b, a, d}, // mirrored horizontally
+		{3, d, c, b}, // rotated 180°
+		{4, c, d, a}, // mirrored vertically
+		{5, a, c, b}, // transposed
+		{6, c, a, d}, // rotated 90° clockwise to display
+		{7, d, b, c}, // transversed
+		{8, b, d, a}, // rotated 90° anticlockwise to display
+	}
+
+	for _, tt := range tests {
+		dst := applyOrientation(src, tt.orientation)
+		dw, dh := dst.Bounds().Dx(), dst.Bounds().Dy()
+		if tt.orientation >= 5 && (dw != h || dh != w) || tt.orientation < 5 && (dw != w || dh != h) {
+			t.Errorf("orientation %d: got %dx%d", tt.orientation, dw, dh)
+			continue
+		}
+		for _, corner := range []struct{ dst, src image.Point }{
+			{image.Pt(0, 0), tt.tl},
+			{image.Pt(dw-1, 0), tt.tr},
+			{image.Pt(0, dh-1), tt.bl},
+		} {
+			if got, want := dst.NRGBAAt(corner.dst.X, corner.dst.Y), src.NRGBAAt(corner.src.X, corner.src.Y); got != want {
+				t.Errorf("orientation %d: pixel %v = %v, want %v from %v", tt.orientation, corner.dst, got, want, corner.src)
+			}
+		}
+	}
+}
+
+func TestProcessAppliesOrientation(t *testing.T) {
+	// testImage is 48x32; orientation 6 displays it 32 wide and 48 tall
+	renditions, err := Process(jpegWithOrientation(t, binary.BigEndian, 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range renditions {
+		if r.Width != 32 || r.Height != 48 {
+			t.Errorf("%s %s rendition is %dx%d, want 32x48", r.Variant, r.Format, r.Width, r.Height)
+		}
+	}
+}
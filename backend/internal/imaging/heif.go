@@ -0,0 +1,202 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"io"
+	"sync"
+
+	"github.com/gen2brain/heic"
+)
+
+// ErrHEIFDecoderUnavailable is returned for HEIC/HEIF images when no decoder
+// has been registered. The container is parsed in pure Go, but the pixels are
+// HEVC-coded and need a codec to be registered.
+var ErrHEIFDecoderUnavailable = errors.New("no HEIF decoder available")
+
+// HEIFDecoder decodes a complete HEIC/HEIF file into its primary image, with
+// the container's rotation and mirroring already applied.
+type HEIFDecoder func(data []byte) (image.Image, error)
+
+var (
+	heifMu      sync.RWMutex
+	heifDecoder HEIFDecoder
+)
+
+// Brands that mark an ISO-BMFF file as an HEVC-coded still image
+var heifBrands = map[string]bool{
+	"heic": true, "heix": true, "heim": true, "heis": true,
+	"hevc": true, "hevx": true,
+}
+
+func init() {
+	for _, brand := range []string{"heic", "heix", "heim", "heis", "hevc", "hevx", "mif1", "msf1"} {
+		image.RegisterFormat(FormatHEIF, "????ftyp"+brand, decodeHEIF, decodeHEIFConfig)
+	}
+}
+
+// DecodeHEIFWASM decodes HEIC/HEIF with libheif and libde265 compiled to
+// WebAssembly and run in-process, so it needs neither cgo nor a system
+// library. Register it with RegisterHEIFDecoder.
+func DecodeHEIFWASM(data []byte) (image.Image, error) {
+	return heic.Decode(bytes.NewReader(data))
+}
+
+// RegisterHEIFDecoder plugs in the codec used to decode HEIC/HEIF uploads,
+// such as DecodeHEIFWASM or a cgo binding to libheif.
+func RegisterHEIFDecoder(decoder HEIFDecoder) {
+	heifMu.Lock()
+	defer heifMu.Unlock()
+	heifDecoder = decoder
+}
+
+// CanDecodeHEIF reports whether a HEIF decoder has been registered
+func CanDecodeHEIF() bool {
+	heifMu.RLock()
+	defer heifMu.RUnlock()
+	return heifDecoder != nil
+}
+
+func decodeHEIF(r io.Reader) (image.Image, error) {
+	heifMu.RLock()
+	decoder := heifDecoder
+	heifMu.RUnlock()
+	if decoder == nil {
+		return nil, ErrHEIFDecoderUnavailable
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decoder(data)
+}
+
+func decodeHEIFConfig(r io.Reader) (image.Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	width, height, ok := heifSize(data)
+	if !ok {
+		return image.Config{}, ErrUnsupportedImage
+	}
+	return image.Config{Width: width, Height: height}, nil
+}
+
+// isHEIF reports whether data starts with an ftyp box naming a HEIF brand.
+// The generic mif1/msf1 brands are also used by AVIF, so they only count when
+// no AVIF brand is present.
+func isHEIF(data []byte) bool {
+	if len(data) < 16 || string(data[4:8]) != "ftyp" {
+		return false
+	}
+	size := int(binary.BigEndian.Uint32(data))
+	if size < 16 || size > len(data) {
+		return false
+	}
+
+	generic, avif := false, false
+	for p := 8; p+4 <= size; p += 4 {
+		if p == 12 {
+			continue // minor version
+		}
+		switch brand := string(data[p : p+4]); {
+		case heifBrands[brand]:
+			return true
+		case brand == "mif1" || brand == "msf1":
+			generic = true
+		case brand == "avif" || brand == "avis":
+			avif = true
+		}
+	}
+	return generic && !avif
+}
+
+// heifSize returns the largest image spatial extent (ispe) declared in the
+// meta box. The primary image, or the grid that tiles it, is always the
+// largest item, so this avoids resolving item references.
+func heifSize(data []byte) (width, height int, ok bool) {
+	if !isHEIF(data) {
+		return 0, 0, false
+	}
+	meta := findBox(data, "meta")
+	if len(meta) < 4 {
+		return 0, 0, false
+	}
+	ipco := findBox(findBox(meta[4:], "iprp"), "ipco")
+
+	for p := 0; ; {
+		kind, payload, next := readBox(ipco, p)
+		if next <= p {
+			break
+		}
+		if kind == "ispe" && len(payload) >= 12 {
+			w := int(binary.BigEndian.Uint32(payload[4:]))
+			h := int(binary.BigEndian.Uint32(payload[8:]))
+			if w*h > width*height {
+				width, height = w, h
+			}
+		}
+		p = next
+	}
+	return width, height, width > 0 && height > 0
+}
+
+// heifEnd walks the top-level boxes and returns the offset where the last
+// well-formed one ends. Anything after it is not part of the image.
+func heifEnd(data []byte) int {
+	p := 0
+	for p < len(data) {
+		_, _, next := readBox(data, p)
+		if next <= p {
+			break
+		}
+		p = next
+	}
+	return p
+}
+
+// findBox returns the payload of the first child box of the given type
+func findBox(data []byte, kind string) []byte {
+	for p := 0; ; {
+		k, payload, next := readBox(data, p)
+		if next <= p {
+			return nil
+		}
+		if k == kind {
+			return payload
+		}
+		p = next
+	}
+}
+
+// readBox parses the ISO-BMFF box header at offset p. next is the offset of
+// the following box, or p when the box is truncated or malformed.
+func readBox(data []byte, p int) (kind string, payload []byte, next int) {
+	if p+8 > len(data) {
+		return "", nil, p
+	}
+	size := uint64(binary.BigEndian.Uint32(data[p:]))
+	kind = string(data[p+4 : p+8])
+	header := 8
+
+	switch size {
+	case 0:
+		size = uint64(len(data) - p)
+	case 1:
+		if p+16 > len(data) {
+			return "", nil, p
+		}
+		size = binary.BigEndian.Uint64(data[p+8:])
+		header = 16
+	}
+	if size < uint64(header) || size > uint64(len(data)-p) {
+		return "", nil, p
+	}
+
+	end := p + int(size)
+	return kind, data[p+header : end], end
+}
@@ -0,0 +1,119 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"os"
+	"testing"
+)
+
+func box(kind string, payload ...[]byte) []byte {
+	var body []byte
+	for _, p := range payload {
+		body = append(body, p...)
+	}
+	out := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(out, kind...), body...)
+}
+
+func ispe(width, height uint32) []byte {
+	payload := make([]byte, 12)
+	binary.BigEndian.PutUint32(payload[4:], width)
+	binary.BigEndian.PutUint32(payload[8:], height)
+	return box("ispe", payload)
+}
+
+// testHEIF builds the container of an iPhone-style HEIC: a thumbnail and the
+// full-size grid each declare their extent. The mdat holds no real HEVC data.
+func testHEIF(brands ...string) []byte {
+	ftyp := []byte(brands[0] + "\x00\x00\x00\x00")
+	for _, b := range brands {
+		ftyp = append(ftyp, b...)
+	}
+	meta := box("meta", []byte{0, 0, 0, 0}, box("iprp", box("ipco", ispe(320, 240), ispe(4032, 3024))))
+	return append(append(box("ftyp", ftyp), meta...), box("mdat", make([]byte, 64))...)
+}
+
+func TestValidateHEIF(t *testing.T) {
+	info, err := Validate(testHEIF("heic", "mif1"))
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if info.Format != FormatHEIF || info.Width != 4032 || info.Height != 3024 {
+		t.Fatalf("Validate() = %+v, want heif 4032x3024", info)
+	}
+	if info.ContentType() != "image/heic" {
+		t.Fatalf("ContentType() = %q", info.ContentType())
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"avif", testHEIF("avif", "mif1"), ErrUnsupportedImage},
+		{"data after last box", append(testHEIF("heic"), []byte("<html>")...), ErrPolyglotImage},
+		{"truncated box", testHEIF("heic")[:60], ErrUnsupportedImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Validate(tt.data); !errors.Is(err, tt.want) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessHEIF(t *testing.T) {
+	data := testHEIF("heic")
+	if _, err := Process(data); !errors.Is(err, ErrHEIFDecoderUnavailable) {
+		t.Fatalf("Process() error = %v, want %v", err, ErrHEIFDecoderUnavailable)
+	}
+
+	RegisterHEIFDecoder(func([]byte) (image.Image, error) {
+		return image.NewNRGBA(image.Rect(0, 0, 400, 300)), nil
+	})
+	defer RegisterHEIFDecoder(nil)
+
+	renditions, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if len(renditions) != len(Variants)*len(Formats) {
+		t.Fatalf("Process() returned %d renditions", len(renditions))
+	}
+}
+
+func TestProcessHEIFWithWASMDecoder(t *testing.T) {
+	// testdata/sample.heic is an 8-bit HEIC from the test suite of the
+	// decoder package
+	data, err := os.ReadFile("testdata/sample.heic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := Validate(data)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	RegisterHEIFDecoder(DecodeHEIFWASM)
+	defer RegisterHEIFDecoder(nil)
+
+	renditions, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	full := renditions[0]
+	if full.Variant != VariantFull || full.Width != min(info.Width, 1600) || full.Height == 0 {
+		t.Fatalf("full rendition is %s %dx%d, upload is %dx%d", full.Variant, full.Width, full.Height, info.Width, info.Height)
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(full.Data))
+	if err != nil {
+		t.Fatalf("decoding the JPEG rendition: %v", err)
+	}
+	if decoded.Bounds().Dx() != full.Width {
+		t.Errorf("JPEG rendition is %d wide, want %d", decoded.Bounds().Dx(), full.Width)
+	}
+}
@@ -0,0 +1,198 @@
+// Package imaging turns uploaded photos into the resized JPEG and WebP
+// renditions we serve.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"strings"
+
+	"github.com/gen2brain/webp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var ErrUnsupportedImage = errors.New("unsupported or corrupt image")
+
+// Image formats. Uploads may be any of them; renditions are JPEG and WebP.
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+	FormatHEIF = "heif"
+)
+
+// Formats lists the formats every variant is rendered in
+var Formats = []string{FormatJPEG, FormatWebP}
+
+const (
+	jpegQuality = 82
+	webpQuality = 80
+)
+
+// Variant is a named rendition size, bounded by width
+type Variant struct {
+	Name     string
+	MaxWidth int
+}
+
+// Variant names
+const (
+	VariantThumb = "thumb"
+	VariantCard  = "card"
+	VariantFull  = "full"
+)
+
+// Variants are rendered largest first so each can be scaled from the last
+var Variants = []Variant{
+	{Name: VariantFull, MaxWidth: 1600},
+	{Name: VariantCard, MaxWidth: 800},
+	{Name: VariantThumb, MaxWidth: 320},
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// Rendition is one encoded variant of an image
+type Rendition struct {
+	Variant string
+	Format  string
+	Width   int
+	Height  int
+	Data    []byte
+}
+
+func (r *Rendition) ContentType() string {
+	return ContentType(r.Format)
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatPNG:
+		return "image/png"
+	case FormatHEIF:
+		return "image/heic"
+	case FormatWebP:
+		return "image/webp"
+	}
+	return "image/jpeg"
+}
+
+func Extension(format string) string {
+	switch format {
+	case FormatPNG:
+		return ".png"
+	case FormatHEIF:
+		return ".heic"
+	case FormatWebP:
+		return ".webp"
+	}
+	return ".jpg"
+}
+
+// Process validates and decodes an uploaded image, applies its EXIF
+// orientation and renders every variant as JPEG and WebP. Only pixels are
+// carried over, so EXIF, GPS and other metadata in the upload are dropped.
+func Process(data []byte) ([]*Rendition, error) {
+	info, err := Validate(data)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := decode(data, info.Format)
+	if err != nil {
+		if errors.Is(err, ErrHEIFDecoderUnavailable) {
+			return nil, err
+		}
+		return nil, ErrUnsupportedImage
+	}
+	img := applyOrientation(toNRGBA(decoded), Orientation(data))
+
+	var renditions []*Rendition
+	src := img
+	for _, variant := range Variants {
+		src = resizeToWidth(src, variant.MaxWidth)
+		b := src.Bounds()
+
+		var jpegBuf bytes.Buffer
+		if err := jpeg.Encode(&jpegBuf, flatten(src), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		var webpBuf bytes.Buffer
+		if err := webp.Encode(&webpBuf, flatten(src), webp.Options{Quality: webpQuality}); err != nil {
+			return nil, err
+		}
+
+		renditions = append(renditions,
+			&Rendition{Variant: variant.Name, Format: FormatJPEG, Width: b.Dx(), Height: b.Dy(), Data: jpegBuf.Bytes()},
+			&Rendition{Variant: variant.Name, Format: FormatWebP, Width: b.Dx(), Height: b.Dy(), Data: webpBuf.Bytes()},
+		)
+	}
+	return renditions, nil
+}
+
+// resizeToWidth scales img down to at most width pixels wide, keeping its
+// aspect ratio. Images that are already small enough are returned as-is.
+func resizeToWidth(img *image.NRGBA, width int) *image.NRGBA {
+	b := img.Bounds()
+	if b.Dx() <= width {
+		return img
+	}
+	height := (b.Dy()*width + b.Dx()/2) / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// flatten composites img onto white, as JPEG has no alpha channel
+func flatten(img *image.NRGBA) image.Image {
+	opaque := true
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 0xff {
+			opaque = false
+			break
+		}
+	}
+	if opaque {
+		return img
+	}
+
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
+
+// FileName is the stored name of one rendition of the image with the given ID
+func FileName(id, variant, format string) string {
+	return id + "-" + variant + Extension(format)
+}
+
+// RenditionURL derives the URL of another rendition from the URL of the full
+// JPEG, which is what we store as an event's image_url. ok is false for images
+// that were stored before variants existed.
+func RenditionURL(fullURL, variant, format string) (string, bool) {
+	suffix := "-" + VariantFull + Extension(FormatJPEG)
+	if !strings.HasSuffix(fullURL, suffix) {
+		return "", false
+	}
+	return strings.TrimSuffix(fullURL, suffix) + "-" + variant + Extension(format), true
+}
+
+// RenditionURLs lists every rendition URL belonging to fullURL
+func RenditionURLs(fullURL string) []string {
+	var urls []string
+	for _, variant := range Variants {
+		for _, format := range Formats {
+			if u, ok := RenditionURL(fullURL, variant.Name, format); ok {
+				urls = append(urls, u)
+			}
+		}
+	}
+	return urls
+}
@@ -0,0 +1,275 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+)
+
+var (
+	ErrImageDimensions = errors.New("image dimensions too large")
+	ErrPolyglotImage   = errors.New("image contains trailing or embedded content")
+)
+
+// Limits checked against the header before any pixels are decoded, so a small
+// file that claims a huge canvas cannot exhaust memory.
+const (
+	MaxDimension = 12000
+	MaxPixels    = 50_000_000
+)
+
+// Markers of content a browser or interpreter would act on. They have no
+// business in a photo's metadata and are how image polyglots smuggle
+// payloads. Only metadata is searched: compressed pixel data is random enough
+// to contain them by chance.
+var activeContentMarkers = [][]byte{
+	[]byte("<script"),
+	[]byte("<html"),
+	[]byte("<svg"),
+	[]byte("<?php"),
+	[]byte("<!doctype"),
+	[]byte("javascript:"),
+}
+
+// Info describes an image as detected from its bytes
+type Info struct {
+	Format string
+	Width  int
+	Height int
+}
+
+func (i *Info) ContentType() string {
+	return ContentType(i.Format)
+}
+
+// Validate identifies an image by its signature and header rather than its
+// name or declared type. It rejects anything that is not a well-formed JPEG,
+// PNG, WebP or HEIF, claims dimensions above the limits, carries data after the end
+// of the image or embeds markup or script in its metadata.
+func Validate(data []byte) (*Info, error) {
+	format := sniff(data)
+	if format == "" {
+		return nil, ErrUnsupportedImage
+	}
+
+	cfg, err := decodeConfig(data, format)
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width > MaxDimension || cfg.Height > MaxDimension || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrImageDimensions
+	}
+
+	var end int
+	switch format {
+	case FormatJPEG:
+		end = jpegEnd(data)
+	case FormatPNG:
+		end = pngEnd(data)
+	case FormatWebP:
+		end = webpEnd(data)
+	case FormatHEIF:
+		end = heifEnd(data)
+	}
+	if end <= 0 {
+		return nil, ErrUnsupportedImage
+	}
+	if !isPadding(data[end:]) || hasActiveContent(metadata(data[:end], format)) {
+		return nil, ErrPolyglotImage
+	}
+
+	return &Info{Format: format, Width: cfg.Width, Height: cfg.Height}, nil
+}
+
+// decodeConfig reads the size of data, which sniff identified as format.
+// HEIF is read by this package directly: a codec package may register its
+// own decoder for some HEIC brands with image.RegisterFormat.
+func decodeConfig(data []byte, format string) (image.Config, error) {
+	if format == FormatHEIF {
+		return decodeHEIFConfig(bytes.NewReader(data))
+	}
+	cfg, decoded, err := image.DecodeConfig(bytes.NewReader(data))
+	if err == nil && decoded != format {
+		err = ErrUnsupportedImage
+	}
+	return cfg, err
+}
+
+// decode decodes data, which sniff identified as format. HEIF goes to the
+// registered HEIF decoder for the same reason as in decodeConfig.
+func decode(data []byte, format string) (image.Image, error) {
+	if format == FormatHEIF {
+		return decodeHEIF(bytes.NewReader(data))
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// sniff returns the format named by the file signature, or "" if unknown
+func sniff(data []byte) string {
+	switch {
+	case len(data) >= 3 && data[0] == 0xff && data[1] == 0xd8 && data[2] == 0xff:
+		return FormatJPEG
+	case len(data) >= 8 && bytes.Equal(data[:8], pngSignature):
+		return FormatPNG
+	case len(data) >= 16 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP" &&
+		(string(data[12:16]) == "VP8 " || string(data[12:16]) == "VP8L" || string(data[12:16]) == "VP8X"):
+		return FormatWebP
+	case isHEIF(data):
+		return FormatHEIF
+	}
+	return ""
+}
+
+// jpegEnd walks the marker segments and entropy-coded data of a JPEG and
+// returns the offset just past its EOI marker, or 0 if there is none.
+// Thumbnails embedded in APP segments are skipped over with their segment, so
+// their EOI is not mistaken for the end of the file.
+func jpegEnd(data []byte) int {
+	p := 2
+	for p+2 <= len(data) {
+		if data[p] != 0xff {
+			return 0
+		}
+		marker := data[p+1]
+		switch {
+		case marker == 0xff:
+			p++ // fill byte
+			continue
+		case marker == 0xd9:
+			return p + 2
+		case marker >= 0xd0 && marker <= 0xd7, marker == 0x01:
+			p += 2
+			continue
+		}
+
+		if p+4 > len(data) {
+			return 0
+		}
+		size := int(binary.BigEndian.Uint16(data[p+2:]))
+		if size < 2 || p+2+size > len(data) {
+			return 0
+		}
+		p += 2 + size
+
+		if marker == 0xda {
+			// Entropy-coded data runs until the next marker that is neither a
+			// stuffed zero nor a restart marker
+			for p+1 < len(data) {
+				if data[p] == 0xff && data[p+1] != 0x00 && (data[p+1] < 0xd0 || data[p+1] > 0xd7) && data[p+1] != 0xff {
+					break
+				}
+				p++
+			}
+		}
+	}
+	return 0
+}
+
+// pngEnd returns the offset just past the IEND chunk, or 0 if there is none
+func pngEnd(data []byte) int {
+	for p := 8; p+12 <= len(data); {
+		size := int(binary.BigEndian.Uint32(data[p:]))
+		if size < 0 || size > len(data) || p+12+size > len(data) {
+			return 0
+		}
+		next := p + 12 + size
+		if string(data[p+4:p+8]) == "IEND" {
+			return next
+		}
+		p = next
+	}
+	return 0
+}
+
+// webpEnd returns the end of the RIFF container as declared in its header,
+// or 0 if the header claims more data than the file holds.
+func webpEnd(data []byte) int {
+	size := int(binary.LittleEndian.Uint32(data[4:]))
+	end := 8 + size
+	if size < 4 || end > len(data) {
+		return 0
+	}
+	return end
+}
+
+// isPadding reports whether trailing bytes are only the zero padding some
+// encoders leave behind
+func isPadding(trailing []byte) bool {
+	for _, b := range trailing {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// metadata returns the comment and metadata segments of an image: JPEG APPn
+// and COM segments, PNG text and eXIf chunks, and WebP EXIF and XMP chunks.
+// HEIF keeps its metadata in items that are not parsed here; like every
+// upload, HEIF files are re-encoded before they are stored.
+func metadata(data []byte, format string) [][]byte {
+	var segments [][]byte
+	switch format {
+	case FormatJPEG:
+		for p := 2; p+4 <= len(data) && data[p] == 0xff; {
+			marker := data[p+1]
+			if marker == 0xda || marker == 0xd9 { // start of scan, EOI
+				break
+			}
+			if marker == 0xff || marker >= 0xd0 && marker <= 0xd7 || marker == 0x01 {
+				p++
+				continue
+			}
+			size := int(binary.BigEndian.Uint16(data[p+2:]))
+			if size < 2 || p+2+size > len(data) {
+				break
+			}
+			if marker >= 0xe0 && marker <= 0xef || marker == 0xfe {
+				segments = append(segments, data[p+4:p+2+size])
+			}
+			p += 2 + size
+		}
+	case FormatPNG:
+		for p := 8; p+12 <= len(data); {
+			size := int(binary.BigEndian.Uint32(data[p:]))
+			if size < 0 || size > len(data) || p+12+size > len(data) {
+				break
+			}
+			switch string(data[p+4 : p+8]) {
+			case "tEXt", "zTXt", "iTXt", "eXIf":
+				segments = append(segments, data[p+8:p+8+size])
+			}
+			p += 12 + size
+		}
+	case FormatWebP:
+		for p := 12; p+8 <= len(data); {
+			size := int(binary.LittleEndian.Uint32(data[p+4:]))
+			if size < 0 || size > len(data) || p+8+size > len(data) {
+				break
+			}
+			switch string(data[p : p+4]) {
+			case "EXIF", "XMP ":
+				segments = append(segments, data[p+8:p+8+size])
+			}
+			p += 8 + size + size&1
+		}
+	}
+	return segments
+}
+
+func hasActiveContent(segments [][]byte) bool {
+	for _, segment := range segments {
+		lower := bytes.ToLower(segment)
+		for _, marker := range activeContentMarkers {
+			if bytes.Contains(lower, marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/gen2brain/webp"
+)
+
+func testImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 48, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 48; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x * 5), G: uint8(y * 7), B: 90, A: 255})
+		}
+	}
+	return img
+}
+
+func testJPEG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testWebP(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := webp.Encode(&buf, testImage(), webp.Options{Quality: 80}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func pngChunk(kind string, payload []byte) []byte {
+	chunk := make([]byte, 8, 12+len(payload))
+	binary.BigEndian.PutUint32(chunk, uint32(len(payload)))
+	copy(chunk[4:], kind)
+	chunk = append(chunk, payload...)
+	crc := crc32.NewIEEE()
+	crc.Write(chunk[4:])
+	return binary.BigEndian.AppendUint32(chunk, crc.Sum32())
+}
+
+// pngWithSize returns a PNG whose header claims the given size. Only the
+// header is valid, which is all a dimension check should need to read.
+func pngWithSize(width, height uint32) []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr, width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 6 // RGBA
+
+	data := append([]byte{}, pngSignature...)
+	data = append(data, pngChunk("IHDR", ihdr)...)
+	data = append(data, pngChunk("IDAT", []byte{0x78, 0x9c, 0x03, 0x00, 0x00, 0x00, 0x00, 0x01})...)
+	return append(data, pngChunk("IEND", nil)...)
+}
+
+// jpegWithSegment inserts a marker segment straight after SOI
+func jpegWithSegment(data []byte, marker byte, payload []byte) []byte {
+	segment := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+// jpegWithSize rewrites the frame header to claim the given size
+func jpegWithSize(t *testing.T, data []byte, width, height uint16) []byte {
+	t.Helper()
+	out := append([]byte{}, data...)
+	for p := 2; p+9 < len(out); {
+		marker := out[p+1]
+		if marker == 0xc0 || marker == 0xc2 {
+			binary.BigEndian.PutUint16(out[p+5:], height)
+			binary.BigEndian.PutUint16(out[p+7:], width)
+			return out
+		}
+		p += 2 + int(binary.BigEndian.Uint16(out[p+2:]))
+	}
+	t.Fatal("no frame header found")
+	return nil
+}
+
+func TestValidateAcceptsImages(t *testing.T) {
+	// An EXIF thumbnail carries its own SOI/EOI inside APP1 and must not be
+	// taken for the end of the outer image
+	thumb := testJPEG(t)
+	withThumb := jpegWithSegment(testJPEG(t), 0xe1, append([]byte("Exif\x00\x00"), thumb...))
+
+	// Image data that happens to spell out a marker is not metadata. Here
+	// it is a quantization table, whose 64 entries may be any byte.
+	table := append([]byte{0}, []byte("<svg")...)
+	table = append(table, bytes.Repeat([]byte{1}, 60)...)
+	markupInTables := jpegWithSegment(testJPEG(t), 0xdb, table)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		format string
+	}{
+		{"jpeg", testJPEG(t), FormatJPEG},
+		{"jpeg with embedded thumbnail", withThumb, FormatJPEG},
+		{"jpeg with zero padding", append(testJPEG(t), 0, 0, 0, 0), FormatJPEG},
+		{"jpeg with markup-like bytes in image data", markupInTables, FormatJPEG},
+		{"png", testPNG(t), FormatPNG},
+		{"webp", testWebP(t), FormatWebP},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Validate(tt.data)
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if info.Format != tt.format || info.Width != 48 || info.Height != 32 {
+				t.Fatalf("Validate() = %+v, want %s 48x32", info, tt.format)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsMaliciousFiles(t *testing.T) {
+	jpg := testJPEG(t)
+	pngData := testPNG(t)
+	webp := testWebP(t)
+
+	oversizedRIFF := append([]byte{}, webp...)
+	binary.LittleEndian.PutUint32(oversizedRIFF[4:], uint32(len(webp)*2))
+
+	iend := bytes.LastIndex(pngData, []byte("IEND")) - 4
+	pngWithText := append([]byte{}, pngData[:iend]...)
+	pngWithText = append(pngWithText, pngChunk("tEXt", []byte("Comment\x00<?php system($_GET['c']); ?>"))...)
+	pngWithText = append(pngWithText, pngData[iend:]...)
+
+	webpWithXMP := append(append([]byte{}, webp...), []byte("XMP \x0e\x00\x00\x00<svg onload=x>")...)
+	binary.LittleEndian.PutUint32(webpWithXMP[4:], uint32(len(webpWithXMP)-8))
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"empty", nil, ErrUnsupportedImage},
+		{"html renamed to jpg", []byte("<!DOCTYPE html><html><script>alert(1)</script></html>"), ErrUnsupportedImage},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"/>`), ErrUnsupportedImage},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"), ErrUnsupportedImage},
+		{"jpeg signature only", []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x10}, ErrUnsupportedImage},
+		{"truncated jpeg", jpg[:len(jpg)/2], ErrUnsupportedImage},
+		{"riff that is not webp", append([]byte("RIFF\x04\x00\x00\x00WAVEfmt "), make([]byte, 16)...), ErrUnsupportedImage},
+		{"webp with riff size past end of file", oversizedRIFF, ErrUnsupportedImage},
+		{"jpeg with zip appended", append(append([]byte{}, jpg...), []byte("PK\x03\x04\x14\x00\x00\x00payload")...), ErrPolyglotImage},
+		{"jpeg with html appended", append(append([]byte{}, jpg...), []byte("<html><body>hi</body></html>")...), ErrPolyglotImage},
+		{"jpeg with script in comment", jpegWithSegment(jpg, 0xfe, []byte("*/=1;<script>alert(1)</script>")), ErrPolyglotImage},
+		{"png with data after IEND", append(append([]byte{}, pngData...), []byte("%PDF-1.4")...), ErrPolyglotImage},
+		{"png with php in text chunk", pngWithText, ErrPolyglotImage},
+		{"webp with svg in xmp chunk", webpWithXMP, ErrPolyglotImage},
+		{"webp with data after riff", append(append([]byte{}, webp...), []byte("javascript:alert(1)")...), ErrPolyglotImage},
+		{"png wider than limit", pngWithSize(MaxDimension+1, 10), ErrImageDimensions},
+		{"png decompression bomb", pngWithSize(10000, 10000), ErrImageDimensions},
+		{"jpeg claiming huge frame", jpegWithSize(t, jpg, 65000, 65000), ErrImageDimensions},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Validate(tt.data)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Validate() = %+v, %v; want error %v", info, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessRejectsPolyglot(t *testing.T) {
+	data := append(testJPEG(t), []byte("<script>alert(1)</script>")...)
+	if _, err := Process(data); !errors.Is(err, ErrPolyglotImage) {
+		t.Fatalf("Process() error = %v, want %v", err, ErrPolyglotImage)
+	}
+}
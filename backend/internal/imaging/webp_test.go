@@ -0,0 +1,147 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// testPhoto draws a w x h photo-like image: smooth colour waves with a little
+// noise and a dark square with hard edges. The pattern depends on the pixel
+// position, not the image size, so small crops are as smooth as large ones.
+func testPhoto(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	rng := rand.New(rand.NewSource(int64(w*1000 + h)))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			fx, fy := float64(x), float64(y)
+			c := color.NRGBA{
+				R: clampByte(128 + 100*math.Sin(fx/9)),
+				G: clampByte(128 + 100*math.Cos(fy/11)),
+				B: clampByte(128 + 60*math.Sin((fx+fy)/7) + float64(rng.Intn(9)-4)),
+				A: 255,
+			}
+			if x >= 40 && x < 72 && y >= 24 && y < 56 {
+				c = color.NRGBA{R: 20, G: 24, B: 30, A: 255}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// decodeWebP decodes data and converts it to RGB. The x/image decoder hands
+// back raw YCbCr, which Go would convert as full-range JPEG colour; VP8 uses
+// the limited-range BT.601 that browsers decode, so that is applied here.
+func decodeWebP(t *testing.T, data []byte) *image.NRGBA {
+	t.Helper()
+	decoded, err := webp.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decoding: %v", err)
+	}
+	ycc, ok := decoded.(*image.YCbCr)
+	if !ok {
+		t.Fatalf("decoded a %T, want *image.YCbCr", decoded)
+	}
+	b := ycc.Bounds()
+	rgb := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			c := ycc.YCbCrAt(b.Min.X+x, b.Min.Y+y)
+			yy := 1.164 * (float64(c.Y) - 16)
+			cb, cr := float64(c.Cb)-128, float64(c.Cr)-128
+			rgb.SetNRGBA(x, y, color.NRGBA{
+				R: clampByte(yy + 1.596*cr),
+				G: clampByte(yy - 0.813*cr - 0.391*cb),
+				B: clampByte(yy + 2.018*cb),
+				A: 255,
+			})
+		}
+	}
+	return rgb
+}
+
+func clampByte(v float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(v))))
+}
+
+// psnr compares the RGB channels of two images of the same size
+func psnr(t *testing.T, want, got *image.NRGBA) float64 {
+	t.Helper()
+	wb, gb := want.Bounds(), got.Bounds()
+	if wb.Dx() != gb.Dx() || wb.Dy() != gb.Dy() {
+		t.Fatalf("decoded size %dx%d, want %dx%d", gb.Dx(), gb.Dy(), wb.Dx(), wb.Dy())
+	}
+	var sum float64
+	for i := 0; i < len(want.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			d := float64(want.Pix[i+c]) - float64(got.Pix[i+c])
+			sum += d * d
+		}
+	}
+	mse := sum / float64(3*wb.Dx()*wb.Dy())
+	if mse == 0 {
+		return math.Inf(1)
+	}
+	return 10 * math.Log10(255*255/mse)
+}
+
+func TestProcessWebPRenditions(t *testing.T) {
+	src := testPhoto(200, 150)
+	// A transparent corner, which the WebP rendition flattens onto white as
+	// the JPEG one does
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: 10, G: 20, B: 30, A: 0})
+		}
+	}
+	var upload bytes.Buffer
+	if err := png.Encode(&upload, src); err != nil {
+		t.Fatal(err)
+	}
+
+	renditions, err := Process(upload.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found int
+	for _, r := range renditions {
+		if r.Format != FormatWebP {
+			continue
+		}
+		found++
+		if info, err := Validate(r.Data); err != nil || info.Format != FormatWebP {
+			t.Fatalf("%s: Validate = %+v, %v", r.Variant, info, err)
+		}
+		decoded := decodeWebP(t, r.Data)
+		if b := decoded.Bounds(); b.Dx() != r.Width || b.Dy() != r.Height {
+			t.Errorf("%s: decoded %dx%d, want %dx%d", r.Variant, b.Dx(), b.Dy(), r.Width, r.Height)
+		}
+		if r.Width != 200 {
+			// Only the unscaled renditions can be compared pixel for pixel
+			continue
+		}
+		want := image.NewNRGBA(src.Bounds())
+		copy(want.Pix, src.Pix)
+		for i := 0; i < len(want.Pix); i += 4 {
+			if want.Pix[i+3] == 0 {
+				want.Pix[i], want.Pix[i+1], want.Pix[i+2], want.Pix[i+3] = 255, 255, 255, 255
+			}
+		}
+		if got := psnr(t, want, decoded); got < 30 {
+			t.Errorf("%s: PSNR %.1f dB, want at least 30", r.Variant, got)
+		}
+		if got := decoded.NRGBAAt(4, 4); got.R < 250 || got.G < 250 || got.B < 250 {
+			t.Errorf("%s: transparent corner decoded as %v, want white", r.Variant, got)
+		}
+	}
+	if found != len(Variants) {
+		t.Errorf("got %d WebP renditions, want %d", found, len(Variants))
+	}
+}
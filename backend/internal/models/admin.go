@@ -49,6 +49,12 @@ type DashboardStats struct {
 	TotalRevenue     float64 `json:"total_revenue"`
 	TotalVisitors    int     `json:"total_visitors"`
 	TodayVisitors    int     `json:"today_visitors"`
+	TodayUniqueVisitors int  `json:"today_unique_visitors"`
+	// Visits by crawlers and scripts, which the counts above leave out
+	TodayBotVisits   int     `json:"today_bot_visits"`
+	// Top countries, browsers, systems, devices, sources and bots over the
+	// last 30 days
+	VisitorSegments  []*VisitorSegment `json:"visitor_segments"`
 	RecentEvents     []*Event `json:"recent_events"`
 	RecentPayments   []*Payment `json:"recent_payments"`
 }
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Agent API key scopes
+const (
+	AgentScopeEventsRead   = "events:read"
+	AgentScopeEventsWrite  = "events:write"
+	AgentScopeImagesUpload = "images:upload"
+)
+
+// AgentScopes lists every scope an agent key may carry
+var AgentScopes = []string{
+	AgentScopeEventsRead,
+	AgentScopeEventsWrite,
+	AgentScopeImagesUpload,
+}
+
+type AgentAPIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	KeyPrefix  string     `json:"key_prefix"`
+	KeyHash    string     `json:"-"`
+	CreatorID  uuid.UUID  `json:"creator_id"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// Joined fields
+	CreatorName  string `json:"creator_name,omitempty"`
+	CreatorEmail string `json:"creator_email,omitempty"`
+}
+
+type AgentAPIKeyCreateRequest struct {
+	Name          string   `json:"name" validate:"required,min=2,max=100"`
+	CreatorID     string   `json:"creator_id" validate:"required,uuid"`
+	Scopes        []string `json:"scopes" validate:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"min=0"`
+}
+
+type AgentAPIKeyResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	Name         string     `json:"name"`
+	KeyPrefix    string     `json:"key_prefix"`
+	CreatorID    uuid.UUID  `json:"creator_id"`
+	CreatorName  string     `json:"creator_name"`
+	CreatorEmail string     `json:"creator_email"`
+	Scopes       []string   `json:"scopes"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	IsActive     bool       `json:"is_active"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// AgentAPIKeyIssuedResponse is returned once on creation and is the only
+// time the plaintext key is ever exposed.
+type AgentAPIKeyIssuedResponse struct {
+	*AgentAPIKeyResponse
+	Key string `json:"key"`
+}
+
+// HasScope reports whether the key grants the given scope
+func (k *AgentAPIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActive reports whether the key is neither revoked nor expired
+func (k *AgentAPIKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	if k.ExpiresAt != nil && !now.Before(*k.ExpiresAt) {
+		return false
+	}
+	return true
+}
+
+func (k *AgentAPIKey) ToResponse() *AgentAPIKeyResponse {
+	return &AgentAPIKeyResponse{
+		ID:           k.ID,
+		Name:         k.Name,
+		KeyPrefix:    k.KeyPrefix,
+		CreatorID:    k.CreatorID,
+		CreatorName:  k.CreatorName,
+		CreatorEmail: k.CreatorEmail,
+		Scopes:       k.Scopes,
+		ExpiresAt:    k.ExpiresAt,
+		LastUsedAt:   k.LastUsedAt,
+		RevokedAt:    k.RevokedAt,
+		IsActive:     k.IsActive(time.Now()),
+		CreatedAt:    k.CreatedAt,
+	}
+}
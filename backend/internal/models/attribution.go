@@ -0,0 +1,40 @@
+package models
+
+// Attribution is where a visit or creator signup came from: the page that
+// linked to the site, where the visitor landed, and any UTM campaign tags.
+// Source is derived on the server from utm_source or the referrer.
+type Attribution struct {
+	Referrer    string `json:"referrer"`
+	LandingPath string `json:"landing_path"`
+	UTMSource   string `json:"utm_source"`
+	UTMMedium   string `json:"utm_medium"`
+	UTMCampaign string `json:"utm_campaign"`
+	UTMTerm     string `json:"utm_term"`
+	UTMContent  string `json:"utm_content"`
+	Source      string `json:"source"`
+}
+
+// Attribution sources that are not a referring site's name
+const (
+	AttributionDirect   = "direct"
+	AttributionInternal = "internal"
+	AttributionUnknown  = "unknown"
+)
+
+// CreatorSourceStats is the creators who came from one source, and what they
+// paid for, over a report period
+type CreatorSourceStats struct {
+	Source         string  `json:"source"`
+	Signups        int     `json:"signups"`
+	PayingCreators int     `json:"paying_creators"`
+	PaidPostings   int     `json:"paid_postings"`
+	Revenue        float64 `json:"revenue"`
+}
+
+// SourceReport breaks visitors and paid postings down by source
+type SourceReport struct {
+	From     string                `json:"from"`
+	To       string                `json:"to"`
+	Visitors []*VisitorSegment     `json:"visitors"`
+	Creators []*CreatorSourceStats `json:"creators"`
+}
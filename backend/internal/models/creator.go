@@ -7,16 +7,17 @@ import (
 )
 
 type Creator struct {
-	ID               uuid.UUID  `json:"id"`
-	Name             string     `json:"name"`
-	OrganizationName string     `json:"organization_name"`
-	Email            string     `json:"email"`
-	Mobile           string     `json:"mobile"`
-	PasswordHash     string     `json:"-"`
-	IsVerified       bool       `json:"is_verified"`
-	IsActive         bool       `json:"is_active"`
-	CreatedAt        time.Time  `json:"created_at"`
-	UpdatedAt        time.Time  `json:"updated_at"`
+	ID               uuid.UUID   `json:"id"`
+	Name             string      `json:"name"`
+	OrganizationName string      `json:"organization_name"`
+	Email            string      `json:"email"`
+	Mobile           string      `json:"mobile"`
+	PasswordHash     string      `json:"-"`
+	IsVerified       bool        `json:"is_verified"`
+	IsActive         bool        `json:"is_active"`
+	Attribution      Attribution `json:"attribution"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
 }
 
 type CreatorRegisterRequest struct {
@@ -25,6 +26,8 @@ type CreatorRegisterRequest struct {
 	Email            string `json:"email" validate:"required,email"`
 	Mobile           string `json:"mobile" validate:"max=50"`
 	Password         string `json:"password" validate:"required,min=8,max=100"`
+	// First-touch attribution the site stored when the visitor arrived
+	Attribution
 }
 
 type CreatorLoginRequest struct {
@@ -33,11 +36,34 @@ type CreatorLoginRequest struct {
 }
 
 type CreatorUpdateRequest struct {
-	Name             string `json:"name" validate:"min=2,max=255"`
+	Name             string `json:"name" validate:"omitempty,min=2,max=255"`
 	OrganizationName string `json:"organization_name" validate:"max=255"`
 	Mobile           string `json:"mobile" validate:"max=50"`
 }
 
+// AdminCreatorCreateRequest is a creator account created by an admin
+type AdminCreatorCreateRequest struct {
+	Name             string `json:"name" validate:"required,min=2,max=255"`
+	OrganizationName string `json:"organization_name" validate:"max=255"`
+	Email            string `json:"email" validate:"required,email"`
+	Mobile           string `json:"mobile" validate:"max=50"`
+	Password         string `json:"password" validate:"required,min=8,max=100"`
+	IsVerified       bool   `json:"is_verified"`
+	IsActive         bool   `json:"is_active"`
+}
+
+// AdminCreatorUpdateRequest is a partial update of a creator account by an
+// admin; empty fields are left unchanged
+type AdminCreatorUpdateRequest struct {
+	Name             string `json:"name" validate:"omitempty,min=2,max=255"`
+	OrganizationName string `json:"organization_name" validate:"max=255"`
+	Email            string `json:"email" validate:"omitempty,email"`
+	Mobile           string `json:"mobile" validate:"max=50"`
+	Password         string `json:"password" validate:"omitempty,min=8,max=100"`
+	IsActive         *bool  `json:"is_active"`
+	IsVerified       *bool  `json:"is_verified"`
+}
+
 type CreatorResponse struct {
 	ID               uuid.UUID `json:"id"`
 	Name             string    `json:"name"`
@@ -46,6 +72,7 @@ type CreatorResponse struct {
 	Mobile           string    `json:"mobile"`
 	IsVerified       bool      `json:"is_verified"`
 	IsActive         bool      `json:"is_active"`
+	Source           string    `json:"source,omitempty"`
 	CreatedAt        time.Time `json:"created_at"`
 }
 
@@ -58,6 +85,7 @@ func (c *Creator) ToResponse() *CreatorResponse {
 		Mobile:           c.Mobile,
 		IsVerified:       c.IsVerified,
 		IsActive:         c.IsActive,
+		Source:           c.Attribution.Source,
 		CreatedAt:        c.CreatedAt,
 	}
 }
@@ -23,7 +23,8 @@ type Event struct {
 	ContactEmail         string    `json:"contact_email"`
 	ContactMobile        *string   `json:"contact_mobile,omitempty"`
 	Notes                *string   `json:"notes,omitempty"`
-	ImageURL             *string   `json:"image_url,omitempty"`
+	ImageURL             *string   `json:"image_url,omitempty"` // storage key, or an external URL
+	ExternalID           *string   `json:"external_id,omitempty"`
 	IsPaid               bool      `json:"is_paid"`
 	IsPublished          bool      `json:"is_published"`
 	CreatedAt            time.Time `json:"created_at"`
@@ -35,6 +36,9 @@ type Event struct {
 	LocationName     string `json:"location_name,omitempty"`
 	EventTypeName    string `json:"event_type_name,omitempty"`
 	EntranceTypeName string `json:"entrance_type_name,omitempty"`
+
+	// Gallery images in display order, cover included
+	Gallery []*EventImage `json:"gallery,omitempty"`
 }
 
 type EventCreateRequest struct {
@@ -53,24 +57,46 @@ type EventCreateRequest struct {
 	ContactEmail         string  `json:"contact_email" validate:"required,email"`
 	ContactMobile        string  `json:"contact_mobile" validate:"required,max=50"`
 	Notes                string  `json:"notes" validate:"required,max=2000"`
+	ExternalID           string  `json:"external_id" validate:"max=255"`
 }
 
 type EventUpdateRequest struct {
-	Title                string   `json:"title" validate:"min=3,max=255"`
+	Title                string   `json:"title" validate:"omitempty,min=3,max=255"`
 	EventDate            string   `json:"event_date"`
 	EventTime            string   `json:"event_time"`
-	LocationID           int      `json:"location_id" validate:"min=1"`
-	EventTypeID          int      `json:"event_type_id" validate:"min=1"`
+	LocationID           int      `json:"location_id" validate:"omitempty,min=1"`
+	EventTypeID          int      `json:"event_type_id" validate:"omitempty,min=1"`
 	Duration             string   `json:"duration" validate:"max=100"`
-	EntranceTypeID       int      `json:"entrance_type_id" validate:"min=1"`
+	EntranceTypeID       int      `json:"entrance_type_id" validate:"omitempty,min=1"`
 	EntranceFee          *float64 `json:"entrance_fee,omitempty" validate:"omitempty,min=0"`
 	PriceThousands       *int     `json:"price_thousands,omitempty" validate:"omitempty,min=0,max=100000"`
 	ParticipantGroupType string   `json:"participant_group_type" validate:"max=50"`
 	LeadBy               string   `json:"lead_by" validate:"max=255"`
 	Venue                string   `json:"venue" validate:"max=50"`
-	ContactEmail         string   `json:"contact_email" validate:"email"`
+	ContactEmail         string   `json:"contact_email" validate:"omitempty,email"`
 	ContactMobile        string   `json:"contact_mobile" validate:"max=50"`
 	Notes                string   `json:"notes" validate:"max=2000"`
+	ExternalID           *string  `json:"external_id,omitempty" validate:"omitempty,max=255"`
+}
+
+// AdminEventCreateRequest is an event created by an admin on behalf of a
+// creator, optionally with its image and the payment and publish flags set
+type AdminEventCreateRequest struct {
+	EventCreateRequest
+	CreatorID   string `json:"creator_id" validate:"required,uuid"`
+	ImageURL    string `json:"image_url"`
+	IsPaid      *bool  `json:"is_paid"`
+	IsPublished *bool  `json:"is_published"`
+}
+
+// AdminEventUpdateRequest is an event update by an admin, who can also set the
+// image, the payment and publish flags, and the owner
+type AdminEventUpdateRequest struct {
+	EventUpdateRequest
+	CreatorID   string `json:"creator_id" validate:"omitempty,uuid"`
+	ImageURL    string `json:"image_url"`
+	IsPaid      *bool  `json:"is_paid"`
+	IsPublished *bool  `json:"is_published"`
 }
 
 type EventListFilter struct {
@@ -97,32 +123,35 @@ type EventListResponse struct {
 }
 
 type EventResponse struct {
-	ID                   uuid.UUID `json:"id"`
-	CreatorID            uuid.UUID `json:"creator_id"`
-	Title                string    `json:"title"`
-	EventDate            string    `json:"event_date"`
-	EventTime            string    `json:"event_time,omitempty"`
-	Location             string    `json:"location"`
-	LocationID           int       `json:"location_id"`
-	EventType            string    `json:"event_type"`
-	EventTypeID          int       `json:"event_type_id"`
-	Duration             string    `json:"duration"`
-	EntranceType         string    `json:"entrance_type"`
-	EntranceTypeID       int       `json:"entrance_type_id"`
-	EntranceFee          float64   `json:"entrance_fee"`
-	PriceThousands       int       `json:"price_thousands"`
-	ParticipantGroupType string    `json:"participant_group_type,omitempty"`
-	LeadBy               string    `json:"lead_by,omitempty"`
-	Venue                string    `json:"venue,omitempty"`
-	ContactEmail         string    `json:"contact_email"`
-	ContactMobile        string    `json:"contact_mobile"`
-	Notes                string    `json:"notes"`
-	ImageURL             string    `json:"image_url"`
-	Organizer            string    `json:"organizer"`
-	OrganizationName     string    `json:"organization_name"`
-	IsPaid               bool      `json:"is_paid"`
-	IsPublished          bool      `json:"is_published"`
-	CreatedAt            time.Time `json:"created_at"`
+	ID                   uuid.UUID             `json:"id"`
+	CreatorID            uuid.UUID             `json:"creator_id"`
+	Title                string                `json:"title"`
+	EventDate            string                `json:"event_date"`
+	EventTime            string                `json:"event_time,omitempty"`
+	Location             string                `json:"location"`
+	LocationID           int                   `json:"location_id"`
+	EventType            string                `json:"event_type"`
+	EventTypeID          int                   `json:"event_type_id"`
+	Duration             string                `json:"duration"`
+	EntranceType         string                `json:"entrance_type"`
+	EntranceTypeID       int                   `json:"entrance_type_id"`
+	EntranceFee          float64               `json:"entrance_fee"`
+	PriceThousands       int                   `json:"price_thousands"`
+	ParticipantGroupType string                `json:"participant_group_type,omitempty"`
+	LeadBy               string                `json:"lead_by,omitempty"`
+	Venue                string                `json:"venue,omitempty"`
+	ContactEmail         string                `json:"contact_email"`
+	ContactMobile        string                `json:"contact_mobile"`
+	Notes                string                `json:"notes"`
+	ImageURL             string                `json:"image_url"`
+	Images               *EventImages          `json:"images,omitempty"`
+	Gallery              []*EventImageResponse `json:"gallery"`
+	ExternalID           string                `json:"external_id,omitempty"`
+	Organizer            string                `json:"organizer"`
+	OrganizationName     string                `json:"organization_name"`
+	IsPaid               bool                  `json:"is_paid"`
+	IsPublished          bool                  `json:"is_published"`
+	CreatedAt            time.Time             `json:"created_at"`
 }
 
 func (e *Event) ToResponse() *EventResponse {
@@ -163,7 +192,12 @@ func (e *Event) ToResponse() *EventResponse {
 
 	imageURL := ""
 	if e.ImageURL != nil {
-		imageURL = *e.ImageURL
+		imageURL = ResolveImageURL(*e.ImageURL)
+	}
+
+	externalID := ""
+	if e.ExternalID != nil {
+		externalID = *e.ExternalID
 	}
 
 	return &EventResponse{
@@ -188,6 +222,9 @@ func (e *Event) ToResponse() *EventResponse {
 		ContactMobile:        contactMobile,
 		Notes:                notes,
 		ImageURL:             imageURL,
+		Images:               NewEventImages(imageURL),
+		Gallery:              GalleryResponse(e.Gallery),
+		ExternalID:           externalID,
 		Organizer:            e.CreatorName,
 		OrganizationName:     e.OrganizationName,
 		IsPaid:               e.IsPaid,
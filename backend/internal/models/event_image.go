@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxEventImages caps the size of an event's gallery
+const MaxEventImages = 12
+
+// EventImage is one photo in an event's gallery. The cover image is also
+// stored in events.image_url, so clients that only know about a single image
+// keep working.
+type EventImage struct {
+	ID        uuid.UUID `json:"id"`
+	EventID   uuid.UUID `json:"event_id"`
+	ImageURL  string    `json:"image_url"` // storage key, or an external URL
+	Caption   *string   `json:"caption,omitempty"`
+	AltText   *string   `json:"alt_text,omitempty"`
+	Position  int       `json:"position"`
+	IsCover   bool      `json:"is_cover"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type EventImageResponse struct {
+	ID       uuid.UUID    `json:"id"`
+	ImageURL string       `json:"image_url"`
+	Images   *EventImages `json:"images,omitempty"`
+	Caption  string       `json:"caption"`
+	AltText  string       `json:"alt_text"`
+	Position int          `json:"position"`
+	IsCover  bool         `json:"is_cover"`
+}
+
+// EventImageUpdateRequest edits a gallery image; omitted fields are left
+// unchanged. An image can be made the cover but not un-made: choose another
+// cover instead.
+type EventImageUpdateRequest struct {
+	Caption *string `json:"caption,omitempty" validate:"omitempty,max=500"`
+	AltText *string `json:"alt_text,omitempty" validate:"omitempty,max=500"`
+	IsCover *bool   `json:"is_cover,omitempty"`
+}
+
+// EventImageOrderRequest lists every image of a gallery in its new order
+type EventImageOrderRequest struct {
+	ImageIDs []uuid.UUID `json:"image_ids" validate:"required"`
+}
+
+func (i *EventImage) ToResponse() *EventImageResponse {
+	caption := ""
+	if i.Caption != nil {
+		caption = *i.Caption
+	}
+
+	altText := ""
+	if i.AltText != nil {
+		altText = *i.AltText
+	}
+
+	imageURL := ResolveImageURL(i.ImageURL)
+	return &EventImageResponse{
+		ID:       i.ID,
+		ImageURL: imageURL,
+		Images:   NewEventImages(imageURL),
+		Caption:  caption,
+		AltText:  altText,
+		Position: i.Position,
+		IsCover:  i.IsCover,
+	}
+}
+
+// GalleryResponse converts a gallery for the API, always as an array
+func GalleryResponse(images []*EventImage) []*EventImageResponse {
+	gallery := make([]*EventImageResponse, 0, len(images))
+	for _, image := range images {
+		gallery = append(gallery, image.ToResponse())
+	}
+	return gallery
+}
@@ -0,0 +1,76 @@
+package models
+
+import "github.com/google/uuid"
+
+// Event actions visitors can take on an event page
+const (
+	EventActionView     = "view"
+	EventActionEmail    = "email"
+	EventActionWhatsApp = "whatsapp"
+	EventActionShare    = "share"
+)
+
+// IsEventAction reports whether action is one of the event actions
+func IsEventAction(action string) bool {
+	switch action {
+	case EventActionView, EventActionEmail, EventActionWhatsApp, EventActionShare:
+		return true
+	}
+	return false
+}
+
+// EventInteractionRequest records an action on an event page
+type EventInteractionRequest struct {
+	Action string `json:"action" validate:"required,oneof=view email whatsapp share"`
+}
+
+// EventStatsCounts counts distinct visitors per action, each at most once a
+// day
+type EventStatsCounts struct {
+	Views          int `json:"views"`
+	EmailClicks    int `json:"email_clicks"`
+	WhatsAppClicks int `json:"whatsapp_clicks"`
+	ShareClicks    int `json:"share_clicks"`
+}
+
+// Add adds other's counts to c
+func (c *EventStatsCounts) Add(other EventStatsCounts) {
+	c.Views += other.Views
+	c.EmailClicks += other.EmailClicks
+	c.WhatsAppClicks += other.WhatsAppClicks
+	c.ShareClicks += other.ShareClicks
+}
+
+// EventStatsDay is one day of a time series, with every day of the range
+// present
+type EventStatsDay struct {
+	Date string `json:"date"`
+	EventStatsCounts
+}
+
+// EventStatsResponse is an event's stats over a date range
+type EventStatsResponse struct {
+	EventID uuid.UUID        `json:"event_id"`
+	Title   string           `json:"title"`
+	From    string           `json:"from"`
+	To      string           `json:"to"`
+	Totals  EventStatsCounts `json:"totals"`
+	Daily   []*EventStatsDay `json:"daily"`
+}
+
+// EventStatsSummary is one event's totals within a creator summary
+type EventStatsSummary struct {
+	EventID   uuid.UUID `json:"event_id"`
+	Title     string    `json:"title"`
+	EventDate string    `json:"event_date"`
+	EventStatsCounts
+}
+
+// CreatorStatsResponse sums the stats of all of a creator's events
+type CreatorStatsResponse struct {
+	From   string               `json:"from"`
+	To     string               `json:"to"`
+	Totals EventStatsCounts     `json:"totals"`
+	Daily  []*EventStatsDay     `json:"daily"`
+	Events []*EventStatsSummary `json:"events"`
+}
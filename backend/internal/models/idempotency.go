@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyRecord stores the outcome of a request made with an
+// Idempotency-Key header so that retries can be answered without side effects.
+type IdempotencyRecord struct {
+	ID             uuid.UUID
+	CreatorID      uuid.UUID
+	IdempotencyKey string
+	RequestMethod  string
+	RequestPath    string
+	RequestHash    string
+	StatusCode     *int
+	ResponseBody   []byte
+	ContentType    string
+	CreatedAt      time.Time
+	CompletedAt    *time.Time
+}
+
+// IsComplete reports whether a response has been stored for the key
+func (r *IdempotencyRecord) IsComplete() bool {
+	return r.StatusCode != nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/net1io/zenbali/internal/imaging"
+	"github.com/net1io/zenbali/internal/storage"
+)
+
+// ImageVariant is one size of an event image in every format we serve
+type ImageVariant struct {
+	Width int    `json:"width"`
+	JPEG  string `json:"jpeg"`
+	WebP  string `json:"webp"`
+}
+
+// ImageSrcSet holds srcset attribute values, one per format
+type ImageSrcSet struct {
+	JPEG string `json:"jpeg"`
+	WebP string `json:"webp"`
+}
+
+type EventImages struct {
+	Thumb  *ImageVariant `json:"thumb"`
+	Card   *ImageVariant `json:"card"`
+	Full   *ImageVariant `json:"full"`
+	SrcSet ImageSrcSet   `json:"srcset"`
+}
+
+// imageURLResolver turns a stored image key into its public URL. It is set
+// from the configured blob store at startup.
+var imageURLResolver = func(key string) string { return key }
+
+// SetImageURLResolver installs the function used to resolve image keys
+func SetImageURLResolver(resolve func(key string) string) {
+	imageURLResolver = resolve
+}
+
+// ResolveImageURL returns the public URL for a stored image reference. Keys
+// are resolved; full URLs and paths, such as external images set by an
+// admin, are returned unchanged.
+func ResolveImageURL(ref string) string {
+	if ref == "" || storage.IsURL(ref) {
+		return ref
+	}
+	return imageURLResolver(ref)
+}
+
+// NewEventImages derives the resized renditions of an event image from its
+// stored URL. It returns nil for images uploaded before renditions existed.
+func NewEventImages(imageURL string) *EventImages {
+	if _, ok := imaging.RenditionURL(imageURL, imaging.VariantFull, imaging.FormatJPEG); !ok {
+		return nil
+	}
+
+	images := &EventImages{}
+	var jpegSet, webpSet []string
+	// Variants are listed largest first; srcset reads better smallest first
+	for i := len(imaging.Variants) - 1; i >= 0; i-- {
+		variant := imaging.Variants[i]
+		jpegURL, _ := imaging.RenditionURL(imageURL, variant.Name, imaging.FormatJPEG)
+		webpURL, _ := imaging.RenditionURL(imageURL, variant.Name, imaging.FormatWebP)
+		v := &ImageVariant{Width: variant.MaxWidth, JPEG: jpegURL, WebP: webpURL}
+
+		switch variant.Name {
+		case imaging.VariantThumb:
+			images.Thumb = v
+		case imaging.VariantCard:
+			images.Card = v
+		case imaging.VariantFull:
+			images.Full = v
+		}
+		jpegSet = append(jpegSet, fmt.Sprintf("%s %dw", jpegURL, variant.MaxWidth))
+		webpSet = append(webpSet, fmt.Sprintf("%s %dw", webpURL, variant.MaxWidth))
+	}
+
+	images.SrcSet = ImageSrcSet{
+		JPEG: strings.Join(jpegSet, ", "),
+		WebP: strings.Join(webpSet, ", "),
+	}
+	return images
+}
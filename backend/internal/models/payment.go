@@ -18,6 +18,9 @@ type Payment struct {
 	CreatedAt             time.Time `json:"created_at"`
 	UpdatedAt             time.Time `json:"updated_at"`
 
+	// CompletedAt is when the payment first became completed
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+
 	// Joined fields
 	EventTitle  string `json:"event_title,omitempty"`
 	CreatorName string `json:"creator_name,omitempty"`
@@ -0,0 +1,87 @@
+package models
+
+// Report intervals: the size of each time-series bucket. Weeks start on
+// Monday.
+const (
+	ReportIntervalDay   = "day"
+	ReportIntervalWeek  = "week"
+	ReportIntervalMonth = "month"
+)
+
+// IsReportInterval reports whether interval is a known report interval
+func IsReportInterval(interval string) bool {
+	switch interval {
+	case ReportIntervalDay, ReportIntervalWeek, ReportIntervalMonth:
+		return true
+	}
+	return false
+}
+
+// ReportTotals are the site's activity over a period. Visitors exclude bots;
+// unique visitors are summed per day.
+type ReportTotals struct {
+	NewEvents      int     `json:"new_events"`
+	NewCreators    int     `json:"new_creators"`
+	PaidPostings   int     `json:"paid_postings"`
+	Revenue        float64 `json:"revenue"`
+	Visitors       int     `json:"visitors"`
+	UniqueVisitors int     `json:"unique_visitors"`
+}
+
+// Add adds other's totals to t
+func (t *ReportTotals) Add(other ReportTotals) {
+	t.NewEvents += other.NewEvents
+	t.NewCreators += other.NewCreators
+	t.PaidPostings += other.PaidPostings
+	t.Revenue += other.Revenue
+	t.Visitors += other.Visitors
+	t.UniqueVisitors += other.UniqueVisitors
+}
+
+// ReportPoint is one bucket of a time series. Period is the first day of the
+// bucket; the first and last buckets only count days inside the range.
+type ReportPoint struct {
+	Period string `json:"period"`
+	ReportTotals
+}
+
+// ReportChange is the percentage change of each total from the previous
+// period. A total that was 0 before has no change.
+type ReportChange struct {
+	NewEvents      *float64 `json:"new_events"`
+	NewCreators    *float64 `json:"new_creators"`
+	PaidPostings   *float64 `json:"paid_postings"`
+	Revenue        *float64 `json:"revenue"`
+	Visitors       *float64 `json:"visitors"`
+	UniqueVisitors *float64 `json:"unique_visitors"`
+}
+
+// ConversionFunnel follows the creators who registered in a period: how many
+// have posted an event and how many have paid for one since. Rates are
+// percentages.
+type ConversionFunnel struct {
+	Registered int `json:"registered"`
+	Posted     int `json:"posted"`
+	Paid       int `json:"paid"`
+	// Posted / Registered, Paid / Posted and Paid / Registered
+	PostedRate  float64 `json:"posted_rate"`
+	PaidRate    float64 `json:"paid_rate"`
+	OverallRate float64 `json:"overall_rate"`
+}
+
+// TimeSeriesReport is the admin dashboard's time series over a range,
+// compared with the range of the same length just before it
+type TimeSeriesReport struct {
+	From         string         `json:"from"`
+	To           string         `json:"to"`
+	Interval     string         `json:"interval"`
+	PreviousFrom string         `json:"previous_from"`
+	PreviousTo   string         `json:"previous_to"`
+	Series       []*ReportPoint `json:"series"`
+	Totals       ReportTotals   `json:"totals"`
+	Previous     ReportTotals   `json:"previous"`
+	Change       ReportChange   `json:"change"`
+	// Funnel and PreviousFunnel follow the creators of each period
+	Funnel         ConversionFunnel `json:"funnel"`
+	PreviousFunnel ConversionFunnel `json:"previous_funnel"`
+}
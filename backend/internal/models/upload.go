@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Upload sources
+const (
+	UploadSourceCreator = "creator" // creator dashboard upload
+	UploadSourceAgent   = "agent"   // agent multipart upload
+	UploadSourceAdmin   = "admin"   // admin dashboard upload
+	UploadSourceRemote  = "remote"  // image_url fetched on an agent's behalf
+	UploadSourceLegacy  = "legacy"  // stored before the registry existed
+)
+
+// UploadOwner identifies who stored an upload
+type UploadOwner struct {
+	Source     string
+	CreatorID  *uuid.UUID
+	AgentKeyID *uuid.UUID
+}
+
+// Upload is a registry entry for one stored image and its renditions. An
+// upload with no event is unattached and is swept once its grace period ends.
+type Upload struct {
+	ID         uuid.UUID  `json:"id"`
+	StorageKey string     `json:"storage_key"`
+	Source     string     `json:"source"`
+	CreatorID  *uuid.UUID `json:"creator_id,omitempty"`
+	AgentKeyID *uuid.UUID `json:"agent_key_id,omitempty"`
+	EventID    *uuid.UUID `json:"event_id,omitempty"`
+	SizeBytes  int64      `json:"size_bytes"`
+	AttachedAt *time.Time `json:"attached_at,omitempty"`
+	DetachedAt *time.Time `json:"detached_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// UploadSweepReport describes one run of the orphaned upload sweeper
+type UploadSweepReport struct {
+	DryRun     bool      `json:"dry_run"`
+	Cutoff     time.Time `json:"cutoff"`
+	Reattached int64     `json:"reattached"`
+	Orphans    int       `json:"orphans"`
+	Deleted    int       `json:"deleted"`
+	Failed     int       `json:"failed"`
+	Bytes      int64     `json:"bytes"`
+	Uploads    []*Upload `json:"uploads"`
+	Errors     []string  `json:"errors,omitempty"`
+}
+
+// DirectUploadRequest asks for a signed URL to upload one image to
+type DirectUploadRequest struct {
+	ContentType string `json:"content_type" validate:"required"`
+	SizeBytes   int64  `json:"size_bytes" validate:"min=1"`
+}
+
+// DirectUploadConfirmRequest adds a directly uploaded image to a gallery
+type DirectUploadConfirmRequest struct {
+	Key     string `json:"key" validate:"required"`
+	Caption string `json:"caption" validate:"max=500"`
+	AltText string `json:"alt_text" validate:"max=500"`
+}
+
+// DirectUpload is a signed request the client sends the image with, and the
+// key it then confirms
+type DirectUpload struct {
+	Key       string            `json:"key"`
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
@@ -6,13 +6,59 @@ import (
 	"github.com/google/uuid"
 )
 
+// Visitor is one recorded visit. The IP address and user agent are never
+// stored: VisitorHash identifies the visitor for the day only, and the user
+// agent is reduced to Browser, OS and Device. For a bot, Browser is the
+// crawler's name.
 type Visitor struct {
-	ID        uuid.UUID `json:"id"`
-	IPAddress string    `json:"ip_address"`
-	UserAgent string    `json:"user_agent"`
-	Country   string    `json:"country"`
-	City      string    `json:"city"`
-	VisitedAt time.Time `json:"visited_at"`
+	ID          uuid.UUID `json:"id"`
+	VisitorHash string    `json:"-"`
+	Browser     string    `json:"browser"`
+	OS          string    `json:"os"`
+	Device      string    `json:"device"`
+	Country     string    `json:"country"`
+	City        string    `json:"city"`
+	IsBot       bool      `json:"is_bot"`
+	VisitedAt   time.Time `json:"visited_at"`
+	Attribution
+}
+
+// Visitor segment dimensions
+const (
+	VisitorDimensionCountry = "country"
+	VisitorDimensionBrowser = "browser"
+	VisitorDimensionOS      = "os"
+	VisitorDimensionDevice  = "device"
+	VisitorDimensionSource  = "source"
+	// Bot visits are only counted under this dimension, by crawler name
+	VisitorDimensionBot = "bot"
+)
+
+// VisitorSegment is the traffic from one country, browser, OS, device class
+// or source over a period
+type VisitorSegment struct {
+	Dimension      string `json:"dimension"`
+	Value          string `json:"value"`
+	Visits         int    `json:"visits"`
+	UniqueVisitors int    `json:"unique_visitors"`
+}
+
+// VisitorRetentionReport is the result of one run of the visitor retention job
+type VisitorRetentionReport struct {
+	DaysRolledUp  int   `json:"days_rolled_up"`
+	VisitsDeleted int64 `json:"visits_deleted"`
+	SaltsDeleted  int64 `json:"salts_deleted"`
+	// Event interactions are only kept for the day they happen
+	InteractionsDeleted int64 `json:"interactions_deleted"`
+}
+
+// BotReport is the traffic from crawlers and scripts over a period
+type BotReport struct {
+	From           string            `json:"from"`
+	To             string            `json:"to"`
+	Visits         int               `json:"visits"`
+	UniqueVisitors int               `json:"unique_visitors"`
+	Bots           []*VisitorSegment `json:"bots"`
 }
 
 type VisitorStats struct {
@@ -24,4 +70,5 @@ type VisitorStats struct {
 
 type TrackVisitorRequest struct {
 	UserAgent string `json:"user_agent"`
+	Attribution
 }
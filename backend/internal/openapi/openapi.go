@@ -0,0 +1,31 @@
+// Package openapi serves the OpenAPI 3.1 document describing the API, and a
+// page that renders it. The document is maintained by hand next to the
+// routes; the contract tests check it against the router and the handlers.
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// Spec is the OpenAPI document as JSON
+//
+//go:embed openapi.json
+var Spec []byte
+
+//go:embed docs.html
+var docsPage []byte
+
+// ServeSpec writes the OpenAPI document
+func ServeSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(Spec)
+}
+
+// ServeDocs writes the documentation page, which loads the document from
+// /api/openapi.json. It needs no assets from outside the site.
+func ServeDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docsPage)
+}
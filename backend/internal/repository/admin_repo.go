@@ -6,16 +6,15 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/net1io/zenbali/internal/models"
 )
 
 type AdminRepository struct {
-	pool *pgxpool.Pool
+	db DBTX
 }
 
-func NewAdminRepository(pool *pgxpool.Pool) *AdminRepository {
-	return &AdminRepository{pool: pool}
+func NewAdminRepository(db DBTX) *AdminRepository {
+	return &AdminRepository{db: db}
 }
 
 func (r *AdminRepository) Create(ctx context.Context, admin *models.Admin) error {
@@ -24,7 +23,7 @@ func (r *AdminRepository) Create(ctx context.Context, admin *models.Admin) error
 		VALUES ($1, $2, $3)
 		RETURNING id, created_at, updated_at
 	`
-	return r.pool.QueryRow(ctx, query,
+	return r.db.QueryRow(ctx, query,
 		admin.Email,
 		admin.PasswordHash,
 		admin.Name,
@@ -38,7 +37,7 @@ func (r *AdminRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Ad
 		FROM admins
 		WHERE id = $1
 	`
-	err := r.pool.QueryRow(ctx, query, id).Scan(
+	err := r.db.QueryRow(ctx, query, id).Scan(
 		&admin.ID,
 		&admin.Email,
 		&admin.PasswordHash,
@@ -63,7 +62,7 @@ func (r *AdminRepository) GetByEmail(ctx context.Context, email string) (*models
 		FROM admins
 		WHERE email = $1
 	`
-	err := r.pool.QueryRow(ctx, query, email).Scan(
+	err := r.db.QueryRow(ctx, query, email).Scan(
 		&admin.ID,
 		&admin.Email,
 		&admin.PasswordHash,
@@ -92,6 +91,43 @@ func (r *AdminRepository) EnsureDefaultAdmin(ctx context.Context, email, passwor
 			is_active = true,
 			updated_at = NOW()
 	`
-	_, err := r.pool.Exec(ctx, query, email, passwordHash, "Admin")
+	_, err := r.db.Exec(ctx, query, email, passwordHash, "Admin")
 	return err
 }
+
+func (r *AdminRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	query := `UPDATE admins SET password_hash = $1, updated_at = NOW() WHERE id = $2`
+	_, err := r.db.Exec(ctx, query, passwordHash, id)
+	return err
+}
+
+func (r *AdminRepository) List(ctx context.Context) ([]*models.Admin, error) {
+	query := `
+		SELECT id, email, password_hash, name, is_active, created_at, updated_at
+		FROM admins
+		ORDER BY created_at
+	`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var admins []*models.Admin
+	for rows.Next() {
+		admin := &models.Admin{}
+		if err := rows.Scan(
+			&admin.ID,
+			&admin.Email,
+			&admin.PasswordHash,
+			&admin.Name,
+			&admin.IsActive,
+			&admin.CreatedAt,
+			&admin.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		admins = append(admins, admin)
+	}
+	return admins, rows.Err()
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+type AgentKeyRepository struct {
+	db DBTX
+}
+
+func NewAgentKeyRepository(db DBTX) *AgentKeyRepository {
+	return &AgentKeyRepository{db: db}
+}
+
+const agentKeySelect = `
+	SELECT k.id, k.name, k.key_prefix, k.key_hash, k.creator_id, k.scopes,
+	       k.expires_at, k.last_used_at, k.revoked_at, k.created_by,
+	       k.created_at, k.updated_at,
+	       c.name as creator_name, c.email as creator_email
+	FROM agent_api_keys k
+	JOIN creators c ON k.creator_id = c.id
+`
+
+func scanAgentKey(row pgx.Row) (*models.AgentAPIKey, error) {
+	key := &models.AgentAPIKey{}
+	err := row.Scan(
+		&key.ID, &key.Name, &key.KeyPrefix, &key.KeyHash, &key.CreatorID, &key.Scopes,
+		&key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedBy,
+		&key.CreatedAt, &key.UpdatedAt,
+		&key.CreatorName, &key.CreatorEmail,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (r *AgentKeyRepository) Create(ctx context.Context, key *models.AgentAPIKey) error {
+	query := `
+		INSERT INTO agent_api_keys (name, key_prefix, key_hash, creator_id, scopes, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at
+	`
+	return r.db.QueryRow(ctx, query,
+		key.Name,
+		key.KeyPrefix,
+		key.KeyHash,
+		key.CreatorID,
+		key.Scopes,
+		key.ExpiresAt,
+		key.CreatedBy,
+	).Scan(&key.ID, &key.CreatedAt, &key.UpdatedAt)
+}
+
+func (r *AgentKeyRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.AgentAPIKey, error) {
+	key, err := scanAgentKey(r.db.QueryRow(ctx, agentKeySelect+" WHERE k.id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return key, err
+}
+
+func (r *AgentKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.AgentAPIKey, error) {
+	key, err := scanAgentKey(r.db.QueryRow(ctx, agentKeySelect+" WHERE k.key_hash = $1", keyHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return key, err
+}
+
+func (r *AgentKeyRepository) List(ctx context.Context) ([]*models.AgentAPIKey, error) {
+	rows, err := r.db.Query(ctx, agentKeySelect+" ORDER BY k.created_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*models.AgentAPIKey
+	for rows.Next() {
+		key, err := scanAgentKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *AgentKeyRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE agent_api_keys SET revoked_at = COALESCE(revoked_at, NOW()), updated_at = NOW() WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, id)
+	return err
+}
+
+func (r *AgentKeyRepository) TouchLastUsed(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE agent_api_keys SET last_used_at = NOW() WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, id)
+	return err
+}
@@ -3,33 +3,51 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/net1io/zenbali/internal/models"
 )
 
 type CreatorRepository struct {
-	pool *pgxpool.Pool
+	db DBTX
 }
 
-func NewCreatorRepository(pool *pgxpool.Pool) *CreatorRepository {
-	return &CreatorRepository{pool: pool}
+// creatorAttributionColumns selects a creator's signup attribution
+const creatorAttributionColumns = `
+		COALESCE(source, ''), COALESCE(referrer, ''), COALESCE(landing_path, ''),
+		COALESCE(utm_source, ''), COALESCE(utm_medium, ''), COALESCE(utm_campaign, ''),
+		COALESCE(utm_term, ''), COALESCE(utm_content, '')`
+
+func NewCreatorRepository(db DBTX) *CreatorRepository {
+	return &CreatorRepository{db: db}
 }
 
 func (r *CreatorRepository) Create(ctx context.Context, creator *models.Creator) error {
 	query := `
-		INSERT INTO creators (name, organization_name, email, mobile, password_hash)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO creators (
+			name, organization_name, email, mobile, password_hash,
+			source, referrer, landing_path, utm_source, utm_medium, utm_campaign, utm_term, utm_content
+		)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''),
+			NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''), NULLIF($12, ''), NULLIF($13, ''))
 		RETURNING id, created_at, updated_at
 	`
-	return r.pool.QueryRow(ctx, query,
+	return r.db.QueryRow(ctx, query,
 		creator.Name,
 		creator.OrganizationName,
 		creator.Email,
 		creator.Mobile,
 		creator.PasswordHash,
+		creator.Attribution.Source,
+		creator.Attribution.Referrer,
+		creator.Attribution.LandingPath,
+		creator.Attribution.UTMSource,
+		creator.Attribution.UTMMedium,
+		creator.Attribution.UTMCampaign,
+		creator.Attribution.UTMTerm,
+		creator.Attribution.UTMContent,
 	).Scan(&creator.ID, &creator.CreatedAt, &creator.UpdatedAt)
 }
 
@@ -37,11 +55,11 @@ func (r *CreatorRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 	creator := &models.Creator{}
 	query := `
 		SELECT id, name, organization_name, email, mobile, password_hash, 
-		       is_verified, is_active, created_at, updated_at
+		       is_verified, is_active, created_at, updated_at, ` + creatorAttributionColumns + `
 		FROM creators
 		WHERE id = $1
 	`
-	err := r.pool.QueryRow(ctx, query, id).Scan(
+	err := r.db.QueryRow(ctx, query, id).Scan(
 		&creator.ID,
 		&creator.Name,
 		&creator.OrganizationName,
@@ -52,6 +70,14 @@ func (r *CreatorRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 		&creator.IsActive,
 		&creator.CreatedAt,
 		&creator.UpdatedAt,
+		&creator.Attribution.Source,
+		&creator.Attribution.Referrer,
+		&creator.Attribution.LandingPath,
+		&creator.Attribution.UTMSource,
+		&creator.Attribution.UTMMedium,
+		&creator.Attribution.UTMCampaign,
+		&creator.Attribution.UTMTerm,
+		&creator.Attribution.UTMContent,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
@@ -66,11 +92,11 @@ func (r *CreatorRepository) GetByEmail(ctx context.Context, email string) (*mode
 	creator := &models.Creator{}
 	query := `
 		SELECT id, name, organization_name, email, mobile, password_hash, 
-		       is_verified, is_active, created_at, updated_at
+		       is_verified, is_active, created_at, updated_at, ` + creatorAttributionColumns + `
 		FROM creators
 		WHERE email = $1
 	`
-	err := r.pool.QueryRow(ctx, query, email).Scan(
+	err := r.db.QueryRow(ctx, query, email).Scan(
 		&creator.ID,
 		&creator.Name,
 		&creator.OrganizationName,
@@ -81,6 +107,14 @@ func (r *CreatorRepository) GetByEmail(ctx context.Context, email string) (*mode
 		&creator.IsActive,
 		&creator.CreatedAt,
 		&creator.UpdatedAt,
+		&creator.Attribution.Source,
+		&creator.Attribution.Referrer,
+		&creator.Attribution.LandingPath,
+		&creator.Attribution.UTMSource,
+		&creator.Attribution.UTMMedium,
+		&creator.Attribution.UTMCampaign,
+		&creator.Attribution.UTMTerm,
+		&creator.Attribution.UTMContent,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
@@ -97,7 +131,7 @@ func (r *CreatorRepository) Update(ctx context.Context, creator *models.Creator)
 		SET name = $1, organization_name = $2, mobile = $3, updated_at = NOW()
 		WHERE id = $4
 	`
-	_, err := r.pool.Exec(ctx, query,
+	_, err := r.db.Exec(ctx, query,
 		creator.Name,
 		creator.OrganizationName,
 		creator.Mobile,
@@ -118,7 +152,7 @@ func (r *CreatorRepository) UpdateAdmin(ctx context.Context, creator *models.Cre
 		    updated_at = NOW()
 		WHERE id = $7
 	`
-	_, err := r.pool.Exec(ctx, query,
+	_, err := r.db.Exec(ctx, query,
 		creator.Name,
 		creator.OrganizationName,
 		creator.Email,
@@ -132,7 +166,7 @@ func (r *CreatorRepository) UpdateAdmin(ctx context.Context, creator *models.Cre
 
 func (r *CreatorRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
 	query := `UPDATE creators SET password_hash = $1, updated_at = NOW() WHERE id = $2`
-	_, err := r.pool.Exec(ctx, query, passwordHash, id)
+	_, err := r.db.Exec(ctx, query, passwordHash, id)
 	return err
 }
 
@@ -150,7 +184,7 @@ func (r *CreatorRepository) EnsureDefaultCreator(ctx context.Context, creator *m
 			is_active = EXCLUDED.is_active,
 			updated_at = NOW()
 	`
-	_, err := r.pool.Exec(ctx, query,
+	_, err := r.db.Exec(ctx, query,
 		creator.ID,
 		creator.Name,
 		creator.OrganizationName,
@@ -165,13 +199,13 @@ func (r *CreatorRepository) EnsureDefaultCreator(ctx context.Context, creator *m
 
 func (r *CreatorRepository) UpdateStatus(ctx context.Context, id uuid.UUID, isActive bool) error {
 	query := `UPDATE creators SET is_active = $1, updated_at = NOW() WHERE id = $2`
-	_, err := r.pool.Exec(ctx, query, isActive, id)
+	_, err := r.db.Exec(ctx, query, isActive, id)
 	return err
 }
 
 func (r *CreatorRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM creators WHERE id = $1`
-	_, err := r.pool.Exec(ctx, query, id)
+	_, err := r.db.Exec(ctx, query, id)
 	return err
 }
 
@@ -181,19 +215,19 @@ func (r *CreatorRepository) List(ctx context.Context, page, limit int) ([]*model
 	// Get total count
 	var total int
 	countQuery := `SELECT COUNT(*) FROM creators`
-	if err := r.pool.QueryRow(ctx, countQuery).Scan(&total); err != nil {
+	if err := r.db.QueryRow(ctx, countQuery).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
 	// Get creators
 	query := `
 		SELECT id, name, organization_name, email, mobile, password_hash,
-		       is_verified, is_active, created_at, updated_at
+		       is_verified, is_active, created_at, updated_at, ` + creatorAttributionColumns + `
 		FROM creators
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
 	`
-	rows, err := r.pool.Query(ctx, query, limit, offset)
+	rows, err := r.db.Query(ctx, query, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -213,6 +247,14 @@ func (r *CreatorRepository) List(ctx context.Context, page, limit int) ([]*model
 			&creator.IsActive,
 			&creator.CreatedAt,
 			&creator.UpdatedAt,
+			&creator.Attribution.Source,
+			&creator.Attribution.Referrer,
+			&creator.Attribution.LandingPath,
+			&creator.Attribution.UTMSource,
+			&creator.Attribution.UTMMedium,
+			&creator.Attribution.UTMCampaign,
+			&creator.Attribution.UTMTerm,
+			&creator.Attribution.UTMContent,
 		); err != nil {
 			return nil, 0, err
 		}
@@ -230,6 +272,54 @@ func (r *CreatorRepository) Count(ctx context.Context) (int, int, error) {
 			COUNT(*) FILTER (WHERE is_active = true) as active
 		FROM creators
 	`
-	err := r.pool.QueryRow(ctx, query).Scan(&total, &active)
+	err := r.db.QueryRow(ctx, query).Scan(&total, &active)
 	return total, active, err
 }
+
+// CreatorsBySource counts the creators who signed up from from to to, and the
+// payments completed in that period, by the source of the creator's
+// signup
+func (r *CreatorRepository) CreatorsBySource(ctx context.Context, from, to time.Time) ([]*models.CreatorSourceStats, error) {
+	query := `
+		WITH signups AS (
+			SELECT COALESCE(source, 'unknown') AS source, COUNT(*) AS signups
+			FROM creators
+			WHERE created_at::date BETWEEN $1::date AND $2::date
+			GROUP BY 1
+		), paid AS (
+			SELECT COALESCE(c.source, 'unknown') AS source,
+				COUNT(DISTINCT p.creator_id) AS paying_creators,
+				COUNT(DISTINCT p.event_id) AS paid_postings,
+				SUM(p.amount_cents) AS revenue_cents
+			FROM payments p
+			JOIN creators c ON c.id = p.creator_id
+			WHERE p.status = 'completed' AND p.completed_at::date BETWEEN $1::date AND $2::date
+			GROUP BY 1
+		)
+		SELECT COALESCE(s.source, p.source),
+			COALESCE(s.signups, 0),
+			COALESCE(p.paying_creators, 0),
+			COALESCE(p.paid_postings, 0),
+			COALESCE(p.revenue_cents, 0)
+		FROM signups s
+		FULL OUTER JOIN paid p ON p.source = s.source
+		ORDER BY 5 DESC, 2 DESC, 1
+	`
+	rows, err := r.db.Query(ctx, query, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sources := []*models.CreatorSourceStats{}
+	for rows.Next() {
+		var revenueCents int64
+		stats := &models.CreatorSourceStats{}
+		if err := rows.Scan(&stats.Source, &stats.Signups, &stats.PayingCreators, &stats.PaidPostings, &revenueCents); err != nil {
+			return nil, err
+		}
+		stats.Revenue = float64(revenueCents) / 100
+		sources = append(sources, stats)
+	}
+	return sources, rows.Err()
+}
@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+// EventImageRepository manages event galleries. Every change that moves the
+// cover also rewrites events.image_url, in the same transaction.
+type EventImageRepository struct {
+	db DBTX
+}
+
+func NewEventImageRepository(db DBTX) *EventImageRepository {
+	return &EventImageRepository{db: db}
+}
+
+const eventImageColumns = `id, event_id, image_url, caption, alt_text, position, is_cover, created_at, updated_at`
+
+// querier is satisfied by both the pool and a transaction
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func scanEventImage(row pgx.Row, image *models.EventImage) error {
+	return row.Scan(
+		&image.ID, &image.EventID, &image.ImageURL, &image.Caption, &image.AltText,
+		&image.Position, &image.IsCover, &image.CreatedAt, &image.UpdatedAt,
+	)
+}
+
+// listEventImages returns the galleries of the given events in display order
+func listEventImages(ctx context.Context, q querier, eventIDs []uuid.UUID) (map[uuid.UUID][]*models.EventImage, error) {
+	galleries := make(map[uuid.UUID][]*models.EventImage)
+	if len(eventIDs) == 0 {
+		return galleries, nil
+	}
+
+	query := `
+		SELECT ` + eventImageColumns + `
+		FROM event_images
+		WHERE event_id = ANY($1)
+		ORDER BY event_id, position, created_at
+	`
+	rows, err := q.Query(ctx, query, eventIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		image := &models.EventImage{}
+		if err := scanEventImage(rows, image); err != nil {
+			return nil, err
+		}
+		galleries[image.EventID] = append(galleries[image.EventID], image)
+	}
+	return galleries, rows.Err()
+}
+
+func (r *EventImageRepository) ListByEvent(ctx context.Context, eventID uuid.UUID) ([]*models.EventImage, error) {
+	galleries, err := listEventImages(ctx, r.db, []uuid.UUID{eventID})
+	if err != nil {
+		return nil, err
+	}
+	return galleries[eventID], nil
+}
+
+func (r *EventImageRepository) GetByID(ctx context.Context, eventID, id uuid.UUID) (*models.EventImage, error) {
+	image := &models.EventImage{}
+	query := `SELECT ` + eventImageColumns + ` FROM event_images WHERE id = $1 AND event_id = $2`
+	err := scanEventImage(r.db.QueryRow(ctx, query, id, eventID), image)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return image, nil
+}
+
+// Contains reports whether imageRef is in the event's gallery
+func (r *EventImageRepository) Contains(ctx context.Context, eventID uuid.UUID, imageRef string) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM event_images WHERE event_id = $1 AND image_url = $2)`
+	err := r.db.QueryRow(ctx, query, eventID, imageRef).Scan(&exists)
+	return exists, err
+}
+
+// Add appends an image to the end of the gallery unless it already holds
+// limit images; added reports whether it did. The first image of a gallery
+// becomes its cover.
+func (r *EventImageRepository) Add(ctx context.Context, image *models.EventImage, limit int) (added bool, err error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the event so concurrent uploads cannot overfill the gallery
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM events WHERE id = $1 FOR UPDATE`, image.EventID); err != nil {
+		return false, err
+	}
+
+	var count, next int
+	query := `SELECT COUNT(*), COALESCE(MAX(position) + 1, 0) FROM event_images WHERE event_id = $1`
+	if err := tx.QueryRow(ctx, query, image.EventID).Scan(&count, &next); err != nil {
+		return false, err
+	}
+	if count >= limit {
+		return false, nil
+	}
+
+	image.Position = next
+	image.IsCover = count == 0
+	query = `
+		INSERT INTO event_images (event_id, image_url, caption, alt_text, position, is_cover)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+	if err := tx.QueryRow(ctx, query,
+		image.EventID, image.ImageURL, image.Caption, image.AltText, image.Position, image.IsCover,
+	).Scan(&image.ID, &image.CreatedAt, &image.UpdatedAt); err != nil {
+		return false, err
+	}
+
+	if image.IsCover {
+		if err := setEventImageURL(ctx, tx, image.EventID, &image.ImageURL); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// UpdateMeta saves an image's caption and alt text
+func (r *EventImageRepository) UpdateMeta(ctx context.Context, image *models.EventImage) error {
+	query := `UPDATE event_images SET caption = $1, alt_text = $2 WHERE id = $3 AND event_id = $4`
+	_, err := r.db.Exec(ctx, query, image.Caption, image.AltText, image.ID, image.EventID)
+	return err
+}
+
+// SetCover makes an image the event's cover
+func (r *EventImageRepository) SetCover(ctx context.Context, eventID, id uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := setCover(ctx, tx, eventID, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SyncCover brings the gallery in line after events.image_url was set
+// directly, as the single-image endpoints do. An image already in the gallery
+// becomes the cover; otherwise it replaces the current cover, or becomes the
+// first image of a gallery without one. It returns the reference that was
+// replaced, if any.
+func (r *EventImageRepository) SyncCover(ctx context.Context, eventID uuid.UUID, imageRef string) (replaced *string, err error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var existing uuid.UUID
+	err = tx.QueryRow(ctx, `SELECT id FROM event_images WHERE event_id = $1 AND image_url = $2 LIMIT 1`, eventID, imageRef).Scan(&existing)
+	switch {
+	case err == nil:
+		if err := setCover(ctx, tx, eventID, existing); err != nil {
+			return nil, err
+		}
+		return nil, tx.Commit(ctx)
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, err
+	}
+
+	var coverID uuid.UUID
+	var coverRef string
+	err = tx.QueryRow(ctx, `SELECT id, image_url FROM event_images WHERE event_id = $1 AND is_cover`, eventID).Scan(&coverID, &coverRef)
+	switch {
+	case err == nil:
+		// A new image replaces the cover's file but keeps its place, caption
+		// and alt text
+		query := `UPDATE event_images SET image_url = $1 WHERE id = $2`
+		if _, err := tx.Exec(ctx, query, imageRef, coverID); err != nil {
+			return nil, err
+		}
+		replaced = &coverRef
+	case errors.Is(err, pgx.ErrNoRows):
+		if _, err := tx.Exec(ctx, `UPDATE event_images SET position = position + 1 WHERE event_id = $1`, eventID); err != nil {
+			return nil, err
+		}
+		query := `INSERT INTO event_images (event_id, image_url, position, is_cover) VALUES ($1, $2, 0, TRUE)`
+		if _, err := tx.Exec(ctx, query, eventID, imageRef); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if err := setEventImageURL(ctx, tx, eventID, &imageRef); err != nil {
+		return nil, err
+	}
+	return replaced, tx.Commit(ctx)
+}
+
+// Reorder renumbers the gallery in the order of ids, which must list every
+// image of the event exactly once; reordered reports whether it did.
+func (r *EventImageRepository) Reorder(ctx context.Context, eventID uuid.UUID, ids []uuid.UUID) (reordered bool, err error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM events WHERE id = $1 FOR UPDATE`, eventID); err != nil {
+		return false, err
+	}
+
+	var total, listed int
+	query := `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE id = ANY($2))
+		FROM event_images
+		WHERE event_id = $1
+	`
+	if err := tx.QueryRow(ctx, query, eventID, ids).Scan(&total, &listed); err != nil {
+		return false, err
+	}
+	if total != len(ids) || listed != len(ids) {
+		return false, nil
+	}
+
+	for position, id := range ids {
+		query := `UPDATE event_images SET position = $1 WHERE id = $2 AND event_id = $3`
+		if _, err := tx.Exec(ctx, query, position, id, eventID); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// Delete removes an image from the gallery and returns it, or nil if it was
+// not there. Deleting the cover promotes the next image, or clears the
+// event's image when the gallery is left empty.
+func (r *EventImageRepository) Delete(ctx context.Context, eventID, id uuid.UUID) (*models.EventImage, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	image := &models.EventImage{}
+	query := `DELETE FROM event_images WHERE id = $1 AND event_id = $2 RETURNING ` + eventImageColumns
+	err = scanEventImage(tx.QueryRow(ctx, query, id, eventID), image)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if image.IsCover {
+		var nextID uuid.UUID
+		query := `SELECT id FROM event_images WHERE event_id = $1 ORDER BY position, created_at LIMIT 1`
+		err := tx.QueryRow(ctx, query, eventID).Scan(&nextID)
+		switch {
+		case err == nil:
+			if err := setCover(ctx, tx, eventID, nextID); err != nil {
+				return nil, err
+			}
+		case errors.Is(err, pgx.ErrNoRows):
+			if err := setEventImageURL(ctx, tx, eventID, nil); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, err
+		}
+	}
+
+	return image, tx.Commit(ctx)
+}
+
+// setCover moves the cover flag to id and mirrors its image into the event
+func setCover(ctx context.Context, tx pgx.Tx, eventID, id uuid.UUID) error {
+	// Clear first: the one-cover index is checked per statement
+	if _, err := tx.Exec(ctx, `UPDATE event_images SET is_cover = FALSE WHERE event_id = $1 AND is_cover AND id <> $2`, eventID, id); err != nil {
+		return err
+	}
+
+	var imageRef string
+	query := `UPDATE event_images SET is_cover = TRUE WHERE id = $1 AND event_id = $2 RETURNING image_url`
+	if err := tx.QueryRow(ctx, query, id, eventID).Scan(&imageRef); err != nil {
+		return err
+	}
+	return setEventImageURL(ctx, tx, eventID, &imageRef)
+}
+
+func setEventImageURL(ctx context.Context, tx pgx.Tx, eventID uuid.UUID, imageRef *string) error {
+	_, err := tx.Exec(ctx, `UPDATE events SET image_url = $1, updated_at = NOW() WHERE id = $2`, imageRef, eventID)
+	return err
+}
@@ -9,16 +9,15 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/net1io/zenbali/internal/models"
 )
 
 type EventRepository struct {
-	pool *pgxpool.Pool
+	db DBTX
 }
 
-func NewEventRepository(pool *pgxpool.Pool) *EventRepository {
-	return &EventRepository{pool: pool}
+func NewEventRepository(db DBTX) *EventRepository {
+	return &EventRepository{db: db}
 }
 
 func (r *EventRepository) Create(ctx context.Context, event *models.Event) error {
@@ -26,12 +25,12 @@ func (r *EventRepository) Create(ctx context.Context, event *models.Event) error
 		INSERT INTO events (
 			creator_id, title, event_date, event_time, location_id, event_type_id,
 			duration, entrance_type_id, entrance_fee, participant_group_type, lead_by,
-			venue, contact_email, contact_mobile, notes
+			venue, contact_email, contact_mobile, notes, external_id
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 		RETURNING id, created_at, updated_at
 	`
-	return r.pool.QueryRow(ctx, query,
+	return r.db.QueryRow(ctx, query,
 		event.CreatorID,
 		event.Title,
 		event.EventDate,
@@ -47,9 +46,58 @@ func (r *EventRepository) Create(ctx context.Context, event *models.Event) error
 		event.ContactEmail,
 		event.ContactMobile,
 		event.Notes,
+		event.ExternalID,
 	).Scan(&event.ID, &event.CreatedAt, &event.UpdatedAt)
 }
 
+// CreateBatch inserts all events, including their image and publish flags,
+// in one transaction.
+func (r *EventRepository) CreateBatch(ctx context.Context, events []*models.Event) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO events (
+			creator_id, title, event_date, event_time, location_id, event_type_id,
+			duration, entrance_type_id, entrance_fee, participant_group_type, lead_by,
+			venue, contact_email, contact_mobile, notes, external_id,
+			image_url, is_paid, is_published
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		RETURNING id, created_at, updated_at
+	`
+	for _, event := range events {
+		if err := tx.QueryRow(ctx, query,
+			event.CreatorID,
+			event.Title,
+			event.EventDate,
+			event.EventTime,
+			event.LocationID,
+			event.EventTypeID,
+			event.Duration,
+			event.EntranceTypeID,
+			event.EntranceFee,
+			event.ParticipantGroupType,
+			event.LeadBy,
+			event.Venue,
+			event.ContactEmail,
+			event.ContactMobile,
+			event.Notes,
+			event.ExternalID,
+			event.ImageURL,
+			event.IsPaid,
+			event.IsPublished,
+		).Scan(&event.ID, &event.CreatedAt, &event.UpdatedAt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
 func (r *EventRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Event, error) {
 	event := &models.Event{}
 	query := `
@@ -57,7 +105,7 @@ func (r *EventRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Ev
 			e.id, e.creator_id, e.title, e.event_date, e.event_time::text, e.location_id,
 			e.event_type_id, e.duration, e.entrance_type_id, e.entrance_fee,
 			e.participant_group_type, e.lead_by, e.venue,
-			e.contact_email, e.contact_mobile, e.notes, e.image_url,
+			e.contact_email, e.contact_mobile, e.notes, e.external_id, e.image_url,
 			e.is_paid, e.is_published, e.created_at, e.updated_at,
 			c.name as creator_name, c.organization_name,
 			l.name as location_name, et.name as event_type_name, ent.name as entrance_type_name
@@ -68,11 +116,11 @@ func (r *EventRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Ev
 		JOIN entrance_types ent ON e.entrance_type_id = ent.id
 		WHERE e.id = $1
 	`
-	err := r.pool.QueryRow(ctx, query, id).Scan(
+	err := r.db.QueryRow(ctx, query, id).Scan(
 		&event.ID, &event.CreatorID, &event.Title, &event.EventDate, &event.EventTime,
 		&event.LocationID, &event.EventTypeID, &event.Duration, &event.EntranceTypeID,
 		&event.EntranceFee, &event.ParticipantGroupType, &event.LeadBy, &event.Venue,
-		&event.ContactEmail, &event.ContactMobile, &event.Notes,
+		&event.ContactEmail, &event.ContactMobile, &event.Notes, &event.ExternalID,
 		&event.ImageURL, &event.IsPaid, &event.IsPublished, &event.CreatedAt, &event.UpdatedAt,
 		&event.CreatorName, &event.OrganizationName, &event.LocationName,
 		&event.EventTypeName, &event.EntranceTypeName,
@@ -83,36 +131,128 @@ func (r *EventRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Ev
 	if err != nil {
 		return nil, err
 	}
+	if err := r.loadGalleries(ctx, []*models.Event{event}); err != nil {
+		return nil, err
+	}
 	return event, nil
 }
 
+// GetByIDForUpdate is GetByID after locking the event's row until the
+// transaction ends, so that payments for one event are recorded one at a
+// time. It must run inside Tx.InTx.
+func (r *EventRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*models.Event, error) {
+	if _, err := r.db.Exec(ctx, `SELECT 1 FROM events WHERE id = $1 FOR UPDATE`, id); err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+// loadGalleries fills in the gallery of each event with one query
+func (r *EventRepository) loadGalleries(ctx context.Context, events []*models.Event) error {
+	ids := make([]uuid.UUID, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+
+	galleries, err := listEventImages(ctx, r.db, ids)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		event.Gallery = galleries[event.ID]
+	}
+	return nil
+}
+
+func (r *EventRepository) GetByExternalID(ctx context.Context, creatorID uuid.UUID, externalID string) (*models.Event, error) {
+	var id uuid.UUID
+	query := `SELECT id FROM events WHERE creator_id = $1 AND external_id = $2`
+	err := r.db.QueryRow(ctx, query, creatorID, externalID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
 func (r *EventRepository) Update(ctx context.Context, event *models.Event) error {
 	query := `
 		UPDATE events
 		SET title = $1, event_date = $2, event_time = $3, location_id = $4,
 		    event_type_id = $5, duration = $6, entrance_type_id = $7, entrance_fee = $8,
 		    participant_group_type = $9, lead_by = $10, venue = $11,
-		    contact_email = $12, contact_mobile = $13, notes = $14, updated_at = NOW()
-		WHERE id = $15
+		    contact_email = $12, contact_mobile = $13, notes = $14, external_id = $15,
+		    updated_at = NOW()
+		WHERE id = $16
 	`
-	_, err := r.pool.Exec(ctx, query,
+	_, err := r.db.Exec(ctx, query,
 		event.Title, event.EventDate, event.EventTime, event.LocationID,
 		event.EventTypeID, event.Duration, event.EntranceTypeID, event.EntranceFee,
 		event.ParticipantGroupType, event.LeadBy,
-		event.Venue, event.ContactEmail, event.ContactMobile, event.Notes, event.ID,
+		event.Venue, event.ContactEmail, event.ContactMobile, event.Notes, event.ExternalID, event.ID,
 	)
 	return err
 }
 
 func (r *EventRepository) UpdateImageURL(ctx context.Context, id uuid.UUID, imageURL string) error {
 	query := `UPDATE events SET image_url = $1, updated_at = NOW() WHERE id = $2`
-	_, err := r.pool.Exec(ctx, query, imageURL, id)
+	_, err := r.db.Exec(ctx, query, imageURL, id)
 	return err
 }
 
+// ListImageRefs returns the image_url of every event that has one, keyed by
+// event ID
+func (r *EventRepository) ListImageRefs(ctx context.Context) (map[uuid.UUID]string, error) {
+	query := `SELECT id, image_url FROM events WHERE image_url IS NOT NULL AND image_url <> ''`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refs := make(map[uuid.UUID]string)
+	for rows.Next() {
+		var id uuid.UUID
+		var ref string
+		if err := rows.Scan(&id, &ref); err != nil {
+			return nil, err
+		}
+		refs[id] = ref
+	}
+	return refs, rows.Err()
+}
+
+// ReplaceImageRef rewrites an event's image_url only if it still holds from,
+// along with the matching gallery image. It leaves updated_at alone, as the
+// event itself has not changed.
+func (r *EventRepository) ReplaceImageRef(ctx context.Context, id uuid.UUID, from, to string) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE events SET image_url = $1 WHERE id = $2 AND image_url = $3`
+	tag, err := tx.Exec(ctx, query, to, id, from)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() != 1 {
+		return false, nil
+	}
+
+	query = `UPDATE event_images SET image_url = $1 WHERE event_id = $2 AND image_url = $3`
+	if _, err := tx.Exec(ctx, query, to, id, from); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
 func (r *EventRepository) UpdatePaymentStatus(ctx context.Context, id uuid.UUID, isPaid, isPublished bool) error {
 	query := `UPDATE events SET is_paid = $1, is_published = $2, updated_at = NOW() WHERE id = $3`
-	_, err := r.pool.Exec(ctx, query, isPaid, isPublished, id)
+	_, err := r.db.Exec(ctx, query, isPaid, isPublished, id)
 	return err
 }
 
@@ -125,19 +265,19 @@ func (r *EventRepository) UpdateAdminFields(ctx context.Context, id uuid.UUID, i
 		    updated_at = NOW()
 		WHERE id = $4
 	`
-	_, err := r.pool.Exec(ctx, query, imageURL, isPaid, isPublished, id)
+	_, err := r.db.Exec(ctx, query, imageURL, isPaid, isPublished, id)
 	return err
 }
 
 func (r *EventRepository) UpdateCreator(ctx context.Context, id, creatorID uuid.UUID) error {
 	query := `UPDATE events SET creator_id = $1, updated_at = NOW() WHERE id = $2`
-	_, err := r.pool.Exec(ctx, query, creatorID, id)
+	_, err := r.db.Exec(ctx, query, creatorID, id)
 	return err
 }
 
 func (r *EventRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM events WHERE id = $1`
-	_, err := r.pool.Exec(ctx, query, id)
+	_, err := r.db.Exec(ctx, query, id)
 	return err
 }
 
@@ -224,7 +364,7 @@ func (r *EventRepository) List(ctx context.Context, filter models.EventListFilte
 	// Count query
 	countQuery := "SELECT COUNT(*) " + whereClause
 	var total int
-	if err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+	if err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
@@ -243,7 +383,7 @@ func (r *EventRepository) List(ctx context.Context, filter models.EventListFilte
 			e.id, e.creator_id, e.title, e.event_date, e.event_time::text, e.location_id,
 			e.event_type_id, e.duration, e.entrance_type_id, e.entrance_fee,
 			e.participant_group_type, e.lead_by, e.venue,
-			e.contact_email, e.contact_mobile, e.notes, e.image_url,
+			e.contact_email, e.contact_mobile, e.notes, e.external_id, e.image_url,
 			e.is_paid, e.is_published, e.created_at, e.updated_at,
 			c.name as creator_name, c.organization_name,
 			l.name as location_name, et.name as event_type_name, ent.name as entrance_type_name
@@ -251,7 +391,7 @@ func (r *EventRepository) List(ctx context.Context, filter models.EventListFilte
 
 	args = append(args, filter.Limit, offset)
 
-	rows, err := r.pool.Query(ctx, selectQuery, args...)
+	rows, err := r.db.Query(ctx, selectQuery, args...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -264,7 +404,7 @@ func (r *EventRepository) List(ctx context.Context, filter models.EventListFilte
 			&event.ID, &event.CreatorID, &event.Title, &event.EventDate, &event.EventTime,
 			&event.LocationID, &event.EventTypeID, &event.Duration, &event.EntranceTypeID,
 			&event.EntranceFee, &event.ParticipantGroupType, &event.LeadBy, &event.Venue,
-			&event.ContactEmail, &event.ContactMobile, &event.Notes,
+			&event.ContactEmail, &event.ContactMobile, &event.Notes, &event.ExternalID,
 			&event.ImageURL, &event.IsPaid, &event.IsPublished, &event.CreatedAt, &event.UpdatedAt,
 			&event.CreatorName, &event.OrganizationName, &event.LocationName,
 			&event.EventTypeName, &event.EntranceTypeName,
@@ -273,6 +413,14 @@ func (r *EventRepository) List(ctx context.Context, filter models.EventListFilte
 		}
 		events = append(events, event)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	rows.Close()
+
+	if err := r.loadGalleries(ctx, events); err != nil {
+		return nil, 0, err
+	}
 
 	return events, total, nil
 }
@@ -285,7 +433,7 @@ func (r *EventRepository) Count(ctx context.Context) (total, published, upcoming
 			COUNT(*) FILTER (WHERE event_date >= CURRENT_DATE AND is_published = true) as upcoming
 		FROM events
 	`
-	err = r.pool.QueryRow(ctx, query).Scan(&total, &published, &upcoming)
+	err = r.db.QueryRow(ctx, query).Scan(&total, &published, &upcoming)
 	return
 }
 
@@ -295,7 +443,7 @@ func (r *EventRepository) GetRecent(ctx context.Context, limit int) ([]*models.E
 			e.id, e.creator_id, e.title, e.event_date, e.event_time::text, e.location_id,
 			e.event_type_id, e.duration, e.entrance_type_id, e.entrance_fee,
 			e.participant_group_type, e.lead_by, e.venue,
-			e.contact_email, e.contact_mobile, e.notes, e.image_url,
+			e.contact_email, e.contact_mobile, e.notes, e.external_id, e.image_url,
 			e.is_paid, e.is_published, e.created_at, e.updated_at,
 			c.name as creator_name, c.organization_name,
 			l.name as location_name, et.name as event_type_name, ent.name as entrance_type_name
@@ -307,7 +455,7 @@ func (r *EventRepository) GetRecent(ctx context.Context, limit int) ([]*models.E
 		ORDER BY e.created_at DESC
 		LIMIT $1
 	`
-	rows, err := r.pool.Query(ctx, query, limit)
+	rows, err := r.db.Query(ctx, query, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -320,7 +468,7 @@ func (r *EventRepository) GetRecent(ctx context.Context, limit int) ([]*models.E
 			&event.ID, &event.CreatorID, &event.Title, &event.EventDate, &event.EventTime,
 			&event.LocationID, &event.EventTypeID, &event.Duration, &event.EntranceTypeID,
 			&event.EntranceFee, &event.ParticipantGroupType, &event.LeadBy, &event.Venue,
-			&event.ContactEmail, &event.ContactMobile, &event.Notes,
+			&event.ContactEmail, &event.ContactMobile, &event.Notes, &event.ExternalID,
 			&event.ImageURL, &event.IsPaid, &event.IsPublished, &event.CreatedAt, &event.UpdatedAt,
 			&event.CreatorName, &event.OrganizationName, &event.LocationName,
 			&event.EventTypeName, &event.EntranceTypeName,
@@ -329,5 +477,13 @@ func (r *EventRepository) GetRecent(ctx context.Context, limit int) ([]*models.E
 		}
 		events = append(events, event)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := r.loadGalleries(ctx, events); err != nil {
+		return nil, err
+	}
 	return events, nil
 }
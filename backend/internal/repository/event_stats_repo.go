@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+type EventStatsRepository struct {
+	db DBTX
+}
+
+func NewEventStatsRepository(db DBTX) *EventStatsRepository {
+	return &EventStatsRepository{db: db}
+}
+
+// eventStatsColumns maps each action to its event_daily_stats column
+var eventStatsColumns = map[string]string{
+	models.EventActionView:     "views",
+	models.EventActionEmail:    "email_clicks",
+	models.EventActionWhatsApp: "whatsapp_clicks",
+	models.EventActionShare:    "share_clicks",
+}
+
+// Record counts an action by a visitor unless they already took it on the
+// event today; recorded reports whether it was counted
+func (r *EventStatsRepository) Record(ctx context.Context, eventID uuid.UUID, action, visitorHash string) (recorded bool, err error) {
+	column, ok := eventStatsColumns[action]
+	if !ok {
+		return false, fmt.Errorf("unknown event action %q", action)
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO event_interactions (event_id, action, visitor_hash)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`
+	tag, err := tx.Exec(ctx, query, eventID, action, visitorHash)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	query = `
+		INSERT INTO event_daily_stats (event_id, day, ` + column + `)
+		VALUES ($1, CURRENT_DATE, 1)
+		ON CONFLICT (event_id, day) DO UPDATE SET ` + column + ` = event_daily_stats.` + column + ` + 1
+	`
+	if _, err := tx.Exec(ctx, query, eventID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// DeleteOldInteractions forgets who did what on past days. Their counts stay.
+func (r *EventStatsRepository) DeleteOldInteractions(ctx context.Context) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM event_interactions WHERE day < CURRENT_DATE`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// EventDaily returns an event's counts for every day from from to to
+func (r *EventStatsRepository) EventDaily(ctx context.Context, eventID uuid.UUID, from, to time.Time) ([]*models.EventStatsDay, error) {
+	return r.daily(ctx, `s.event_id = $3`, from, to, eventID)
+}
+
+// CreatorDaily returns the summed counts of a creator's events for every day
+// from from to to
+func (r *EventStatsRepository) CreatorDaily(ctx context.Context, creatorID uuid.UUID, from, to time.Time) ([]*models.EventStatsDay, error) {
+	return r.daily(ctx, `s.event_id IN (SELECT id FROM events WHERE creator_id = $3)`, from, to, creatorID)
+}
+
+func (r *EventStatsRepository) daily(ctx context.Context, where string, from, to time.Time, id uuid.UUID) ([]*models.EventStatsDay, error) {
+	query := `
+		SELECT d.day::date,
+			COALESCE(SUM(s.views), 0),
+			COALESCE(SUM(s.email_clicks), 0),
+			COALESCE(SUM(s.whatsapp_clicks), 0),
+			COALESCE(SUM(s.share_clicks), 0)
+		FROM generate_series($1::date, $2::date, INTERVAL '1 day') AS d(day)
+		LEFT JOIN event_daily_stats s ON s.day = d.day::date AND ` + where + `
+		GROUP BY d.day
+		ORDER BY d.day
+	`
+	rows, err := r.db.Query(ctx, query, from, to, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []*models.EventStatsDay{}
+	for rows.Next() {
+		var date time.Time
+		day := &models.EventStatsDay{}
+		if err := rows.Scan(&date, &day.Views, &day.EmailClicks, &day.WhatsAppClicks, &day.ShareClicks); err != nil {
+			return nil, err
+		}
+		day.Date = date.Format("2006-01-02")
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
+
+// CreatorEventTotals returns the totals of each of a creator's events from
+// from to to, most viewed first
+func (r *EventStatsRepository) CreatorEventTotals(ctx context.Context, creatorID uuid.UUID, from, to time.Time) ([]*models.EventStatsSummary, error) {
+	query := `
+		SELECT e.id, e.title, e.event_date,
+			COALESCE(SUM(s.views), 0) AS views,
+			COALESCE(SUM(s.email_clicks), 0),
+			COALESCE(SUM(s.whatsapp_clicks), 0),
+			COALESCE(SUM(s.share_clicks), 0)
+		FROM events e
+		LEFT JOIN event_daily_stats s ON s.event_id = e.id AND s.day BETWEEN $2::date AND $3::date
+		WHERE e.creator_id = $1
+		GROUP BY e.id, e.title, e.event_date
+		ORDER BY views DESC, e.event_date DESC
+	`
+	rows, err := r.db.Query(ctx, query, creatorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []*models.EventStatsSummary{}
+	for rows.Next() {
+		var eventDate time.Time
+		summary := &models.EventStatsSummary{}
+		if err := rows.Scan(
+			&summary.EventID, &summary.Title, &eventDate,
+			&summary.Views, &summary.EmailClicks, &summary.WhatsAppClicks, &summary.ShareClicks,
+		); err != nil {
+			return nil, err
+		}
+		summary.EventDate = eventDate.Format("2006-01-02")
+		summaries = append(summaries, summary)
+	}
+	return summaries, rows.Err()
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+type IdempotencyRepository struct {
+	db DBTX
+}
+
+func NewIdempotencyRepository(db DBTX) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve claims a key for the creator. It returns reserved=true when the
+// caller owns the key and should process the request; otherwise the existing
+// record is returned. Records older than ttl are reclaimed, as are
+// reservations still in progress after lease, whose request has died.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record *models.IdempotencyRecord, ttl, lease time.Duration) (bool, *models.IdempotencyRecord, error) {
+	query := `
+		INSERT INTO idempotency_keys (creator_id, idempotency_key, request_method, request_path, request_hash)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (creator_id, idempotency_key) DO UPDATE SET
+			request_method = EXCLUDED.request_method,
+			request_path = EXCLUDED.request_path,
+			request_hash = EXCLUDED.request_hash,
+			status_code = NULL,
+			response_body = NULL,
+			response_content_type = NULL,
+			created_at = NOW(),
+			completed_at = NULL
+		WHERE idempotency_keys.created_at < $6
+		   OR (idempotency_keys.completed_at IS NULL AND idempotency_keys.created_at < $7)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRow(ctx, query,
+		record.CreatorID,
+		record.IdempotencyKey,
+		record.RequestMethod,
+		record.RequestPath,
+		record.RequestHash,
+		time.Now().Add(-ttl),
+		time.Now().Add(-lease),
+	).Scan(&record.ID, &record.CreatedAt)
+	if err == nil {
+		return true, record, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return false, nil, err
+	}
+
+	existing, err := r.Get(ctx, record.CreatorID, record.IdempotencyKey)
+	if err != nil {
+		return false, nil, err
+	}
+	return false, existing, nil
+}
+
+func (r *IdempotencyRepository) Get(ctx context.Context, creatorID uuid.UUID, key string) (*models.IdempotencyRecord, error) {
+	record := &models.IdempotencyRecord{}
+	query := `
+		SELECT id, creator_id, idempotency_key, request_method, request_path, request_hash,
+		       status_code, response_body, COALESCE(response_content_type, ''), created_at, completed_at
+		FROM idempotency_keys
+		WHERE creator_id = $1 AND idempotency_key = $2
+	`
+	err := r.db.QueryRow(ctx, query, creatorID, key).Scan(
+		&record.ID, &record.CreatorID, &record.IdempotencyKey, &record.RequestMethod,
+		&record.RequestPath, &record.RequestHash, &record.StatusCode, &record.ResponseBody,
+		&record.ContentType, &record.CreatedAt, &record.CompletedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, id uuid.UUID, statusCode int, body []byte, contentType string) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = $1, response_body = $2, response_content_type = $3, completed_at = NOW()
+		WHERE id = $4
+	`
+	_, err := r.db.Exec(ctx, query, statusCode, body, contentType, id)
+	return err
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM idempotency_keys WHERE id = $1`
+	_, err := r.db.Exec(ctx, query, id)
+	return err
+}
@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+type AdminStore struct{ db *DB }
+
+func (s *AdminStore) Create(ctx context.Context, admin *models.Admin) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if s.db.adminByEmail(admin.Email) != nil {
+		return ErrDuplicate
+	}
+	now := s.db.Now()
+	stored := &models.Admin{
+		ID:           uuid.New(),
+		Email:        admin.Email,
+		PasswordHash: admin.PasswordHash,
+		Name:         admin.Name,
+		IsActive:     true,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	s.db.admins = append(s.db.admins, stored)
+	admin.ID, admin.CreatedAt, admin.UpdatedAt = stored.ID, stored.CreatedAt, stored.UpdatedAt
+	return nil
+}
+
+func (s *AdminStore) GetByID(ctx context.Context, id uuid.UUID) (*models.Admin, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, admin := range s.db.admins {
+		if admin.ID == id {
+			copied := *admin
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *AdminStore) GetByEmail(ctx context.Context, email string) (*models.Admin, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if admin := s.db.adminByEmail(email); admin != nil {
+		copied := *admin
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (s *AdminStore) EnsureDefaultAdmin(ctx context.Context, email, passwordHash string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	now := s.db.Now()
+	if admin := s.db.adminByEmail(email); admin != nil {
+		admin.PasswordHash, admin.Name, admin.IsActive, admin.UpdatedAt = passwordHash, "Admin", true, now
+		return nil
+	}
+	s.db.admins = append(s.db.admins, &models.Admin{
+		ID:           uuid.New(),
+		Email:        email,
+		PasswordHash: passwordHash,
+		Name:         "Admin",
+		IsActive:     true,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	})
+	return nil
+}
+
+func (s *AdminStore) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, admin := range s.db.admins {
+		if admin.ID == id {
+			admin.PasswordHash, admin.UpdatedAt = passwordHash, s.db.Now()
+		}
+	}
+	return nil
+}
+
+func (s *AdminStore) List(ctx context.Context) ([]*models.Admin, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	admins := make([]*models.Admin, 0, len(s.db.admins))
+	for _, admin := range s.db.admins {
+		copied := *admin
+		admins = append(admins, &copied)
+	}
+	sortStable(admins, func(a, b *models.Admin) bool { return a.CreatedAt.Before(b.CreatedAt) })
+	return admins, nil
+}
+
+func (db *DB) adminByEmail(email string) *models.Admin {
+	for _, admin := range db.admins {
+		if admin.Email == email {
+			return admin
+		}
+	}
+	return nil
+}
+
+func (db *DB) admin(id uuid.UUID) *models.Admin {
+	for _, admin := range db.admins {
+		if admin.ID == id {
+			return admin
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+type AgentKeyStore struct{ db *DB }
+
+func (s *AgentKeyStore) Create(ctx context.Context, key *models.AgentAPIKey) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if s.db.creator(key.CreatorID) == nil {
+		return ErrMissingReference
+	}
+	if key.CreatedBy != nil && s.db.admin(*key.CreatedBy) == nil {
+		return ErrMissingReference
+	}
+	for _, existing := range s.db.agentKeys {
+		if existing.KeyHash == key.KeyHash {
+			return ErrDuplicate
+		}
+	}
+
+	now := s.db.Now()
+	stored := &models.AgentAPIKey{
+		ID:        uuid.New(),
+		Name:      key.Name,
+		KeyPrefix: key.KeyPrefix,
+		KeyHash:   key.KeyHash,
+		CreatorID: key.CreatorID,
+		Scopes:    append([]string(nil), key.Scopes...),
+		ExpiresAt: timePtr(key.ExpiresAt),
+		CreatedBy: key.CreatedBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.db.agentKeys = append(s.db.agentKeys, stored)
+	key.ID, key.CreatedAt, key.UpdatedAt = stored.ID, stored.CreatedAt, stored.UpdatedAt
+	return nil
+}
+
+func (s *AgentKeyStore) GetByID(ctx context.Context, id uuid.UUID) (*models.AgentAPIKey, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, key := range s.db.agentKeys {
+		if key.ID == id {
+			return s.db.joinAgentKey(key), nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *AgentKeyStore) GetByHash(ctx context.Context, keyHash string) (*models.AgentAPIKey, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, key := range s.db.agentKeys {
+		if key.KeyHash == keyHash {
+			return s.db.joinAgentKey(key), nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *AgentKeyStore) List(ctx context.Context) ([]*models.AgentAPIKey, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var keys []*models.AgentAPIKey
+	for _, key := range s.db.agentKeys {
+		keys = append(keys, s.db.joinAgentKey(key))
+	}
+	sortStable(keys, func(a, b *models.AgentAPIKey) bool { return a.CreatedAt.After(b.CreatedAt) })
+	return keys, nil
+}
+
+func (s *AgentKeyStore) Revoke(ctx context.Context, id uuid.UUID) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, key := range s.db.agentKeys {
+		if key.ID == id {
+			now := s.db.Now()
+			if key.RevokedAt == nil {
+				key.RevokedAt = &now
+			}
+			key.UpdatedAt = now
+		}
+	}
+	return nil
+}
+
+func (s *AgentKeyStore) TouchLastUsed(ctx context.Context, id uuid.UUID) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, key := range s.db.agentKeys {
+		if key.ID == id {
+			now := s.db.Now()
+			key.LastUsedAt, key.UpdatedAt = &now, now
+		}
+	}
+	return nil
+}
+
+// joinAgentKey copies a key with its creator's name and email
+func (db *DB) joinAgentKey(stored *models.AgentAPIKey) *models.AgentAPIKey {
+	key := *stored
+	key.Scopes = append([]string(nil), stored.Scopes...)
+	key.ExpiresAt, key.LastUsedAt, key.RevokedAt = timePtr(stored.ExpiresAt), timePtr(stored.LastUsedAt), timePtr(stored.RevokedAt)
+	if stored.CreatedBy != nil {
+		createdBy := *stored.CreatedBy
+		key.CreatedBy = &createdBy
+	}
+	if creator := db.creator(stored.CreatorID); creator != nil {
+		key.CreatorName, key.CreatorEmail = creator.Name, creator.Email
+	}
+	return &key
+}
+
+// deleteAgentKey removes a key and clears it from its uploads
+func (db *DB) deleteAgentKey(id uuid.UUID) {
+	db.agentKeys = remove(db.agentKeys, func(k *models.AgentAPIKey) bool { return k.ID == id })
+	for _, upload := range db.uploads {
+		if upload.AgentKeyID != nil && *upload.AgentKeyID == id {
+			upload.AgentKeyID = nil
+		}
+	}
+}
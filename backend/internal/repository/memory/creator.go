@@ -0,0 +1,281 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+type CreatorStore struct{ db *DB }
+
+func (s *CreatorStore) Create(ctx context.Context, creator *models.Creator) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if s.db.creatorByEmail(creator.Email) != nil {
+		return ErrDuplicate
+	}
+	now := s.db.Now()
+	stored := &models.Creator{
+		ID:               uuid.New(),
+		Name:             creator.Name,
+		OrganizationName: creator.OrganizationName,
+		Email:            creator.Email,
+		Mobile:           creator.Mobile,
+		PasswordHash:     creator.PasswordHash,
+		IsActive:         true,
+		Attribution:      creator.Attribution,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	s.db.creators = append(s.db.creators, stored)
+	creator.ID, creator.CreatedAt, creator.UpdatedAt = stored.ID, stored.CreatedAt, stored.UpdatedAt
+	return nil
+}
+
+func (s *CreatorStore) GetByID(ctx context.Context, id uuid.UUID) (*models.Creator, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if creator := s.db.creator(id); creator != nil {
+		copied := *creator
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (s *CreatorStore) GetByEmail(ctx context.Context, email string) (*models.Creator, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if creator := s.db.creatorByEmail(email); creator != nil {
+		copied := *creator
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (s *CreatorStore) Update(ctx context.Context, creator *models.Creator) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if stored := s.db.creator(creator.ID); stored != nil {
+		stored.Name, stored.OrganizationName, stored.Mobile = creator.Name, creator.OrganizationName, creator.Mobile
+		stored.UpdatedAt = s.db.Now()
+	}
+	return nil
+}
+
+func (s *CreatorStore) UpdateAdmin(ctx context.Context, creator *models.Creator) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored := s.db.creator(creator.ID)
+	if stored == nil {
+		return nil
+	}
+	if other := s.db.creatorByEmail(creator.Email); other != nil && other.ID != creator.ID {
+		return ErrDuplicate
+	}
+	stored.Name, stored.OrganizationName, stored.Email, stored.Mobile = creator.Name, creator.OrganizationName, creator.Email, creator.Mobile
+	stored.IsVerified, stored.IsActive, stored.UpdatedAt = creator.IsVerified, creator.IsActive, s.db.Now()
+	return nil
+}
+
+func (s *CreatorStore) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if stored := s.db.creator(id); stored != nil {
+		stored.PasswordHash, stored.UpdatedAt = passwordHash, s.db.Now()
+	}
+	return nil
+}
+
+func (s *CreatorStore) EnsureDefaultCreator(ctx context.Context, creator *models.Creator) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if other := s.db.creatorByEmail(creator.Email); other != nil && other.ID != creator.ID {
+		return ErrDuplicate
+	}
+	now := s.db.Now()
+	stored := s.db.creator(creator.ID)
+	if stored == nil {
+		stored = &models.Creator{ID: creator.ID, CreatedAt: now}
+		s.db.creators = append(s.db.creators, stored)
+	}
+	stored.Name, stored.OrganizationName, stored.Email, stored.Mobile = creator.Name, creator.OrganizationName, creator.Email, creator.Mobile
+	stored.PasswordHash, stored.IsVerified, stored.IsActive = creator.PasswordHash, creator.IsVerified, creator.IsActive
+	stored.UpdatedAt = now
+	return nil
+}
+
+func (s *CreatorStore) UpdateStatus(ctx context.Context, id uuid.UUID, isActive bool) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if stored := s.db.creator(id); stored != nil {
+		stored.IsActive, stored.UpdatedAt = isActive, s.db.Now()
+	}
+	return nil
+}
+
+// Delete removes the creator with their events, payments, agent keys and
+// idempotency keys, and clears them from their uploads
+func (s *CreatorStore) Delete(ctx context.Context, id uuid.UUID) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.creators = remove(s.db.creators, func(c *models.Creator) bool { return c.ID == id })
+	var eventIDs, keyIDs []uuid.UUID
+	for _, event := range s.db.events {
+		if event.CreatorID == id {
+			eventIDs = append(eventIDs, event.ID)
+		}
+	}
+	for _, key := range s.db.agentKeys {
+		if key.CreatorID == id {
+			keyIDs = append(keyIDs, key.ID)
+		}
+	}
+	for _, eventID := range eventIDs {
+		s.db.deleteEvent(eventID)
+	}
+	for _, keyID := range keyIDs {
+		s.db.deleteAgentKey(keyID)
+	}
+	s.db.payments = remove(s.db.payments, func(p *models.Payment) bool { return p.CreatorID == id })
+	s.db.idempotency = remove(s.db.idempotency, func(r *models.IdempotencyRecord) bool { return r.CreatorID == id })
+	for _, upload := range s.db.uploads {
+		if upload.CreatorID != nil && *upload.CreatorID == id {
+			upload.CreatorID = nil
+		}
+	}
+	return nil
+}
+
+func (s *CreatorStore) List(ctx context.Context, page, limit int) ([]*models.Creator, int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	creators := make([]*models.Creator, 0, len(s.db.creators))
+	for _, creator := range s.db.creators {
+		copied := *creator
+		creators = append(creators, &copied)
+	}
+	sortStable(creators, func(a, b *models.Creator) bool { return a.CreatedAt.After(b.CreatedAt) })
+
+	total := len(creators)
+	creators, err := paginate(creators, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return creators, total, nil
+}
+
+func (s *CreatorStore) Count(ctx context.Context) (int, int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var active int
+	for _, creator := range s.db.creators {
+		if creator.IsActive {
+			active++
+		}
+	}
+	return len(s.db.creators), active, nil
+}
+
+func (s *CreatorStore) CreatorsBySource(ctx context.Context, from, to time.Time) ([]*models.CreatorSourceStats, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	fromDay, toDay := dateOf(from), dateOf(to)
+	bySource := make(map[string]*models.CreatorSourceStats)
+	stats := func(source string) *models.CreatorSourceStats {
+		if source == "" {
+			source = models.AttributionUnknown
+		}
+		if bySource[source] == nil {
+			bySource[source] = &models.CreatorSourceStats{Source: source}
+		}
+		return bySource[source]
+	}
+
+	for _, creator := range s.db.creators {
+		if day := dateOf(creator.CreatedAt); day >= fromDay && day <= toDay {
+			stats(creator.Attribution.Source).Signups++
+		}
+	}
+
+	payingCreators := make(map[string]map[uuid.UUID]bool)
+	paidEvents := make(map[string]map[uuid.UUID]bool)
+	revenueCents := make(map[string]int)
+	for _, payment := range s.db.payments {
+		if payment.Status != models.PaymentStatusCompleted || payment.CompletedAt == nil {
+			continue
+		}
+		day := dateOf(*payment.CompletedAt)
+		creator := s.db.creator(payment.CreatorID)
+		if day < fromDay || day > toDay || creator == nil {
+			continue
+		}
+		source := stats(creator.Attribution.Source).Source
+		if payingCreators[source] == nil {
+			payingCreators[source], paidEvents[source] = make(map[uuid.UUID]bool), make(map[uuid.UUID]bool)
+		}
+		payingCreators[source][payment.CreatorID] = true
+		paidEvents[source][payment.EventID] = true
+		revenueCents[source] += payment.AmountCents
+	}
+
+	sources := []*models.CreatorSourceStats{}
+	for source, stats := range bySource {
+		stats.PayingCreators = len(payingCreators[source])
+		stats.PaidPostings = len(paidEvents[source])
+		stats.Revenue = float64(revenueCents[source]) / 100
+		sources = append(sources, stats)
+	}
+	sortStable(sources, func(a, b *models.CreatorSourceStats) bool {
+		if a.Revenue != b.Revenue {
+			return a.Revenue > b.Revenue
+		}
+		if a.Signups != b.Signups {
+			return a.Signups > b.Signups
+		}
+		return a.Source < b.Source
+	})
+	return sources, nil
+}
+
+func (db *DB) creator(id uuid.UUID) *models.Creator {
+	for _, creator := range db.creators {
+		if creator.ID == id {
+			return creator
+		}
+	}
+	return nil
+}
+
+func (db *DB) creatorByEmail(email string) *models.Creator {
+	for _, creator := range db.creators {
+		if creator.Email == email {
+			return creator
+		}
+	}
+	return nil
+}
+
+// remove deletes the rows matching match, keeping the order of the rest
+func remove[T any](rows []T, match func(T) bool) []T {
+	kept := rows[:0]
+	for _, row := range rows {
+		if !match(row) {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
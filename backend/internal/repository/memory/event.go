@@ -0,0 +1,443 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+type EventStore struct{ db *DB }
+
+func (s *EventStore) Create(ctx context.Context, event *models.Event) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return s.db.insertEvent(event, false)
+}
+
+// CreateBatch inserts all events, including their image and publish flags,
+// or none of them
+func (s *EventStore) CreateBatch(ctx context.Context, events []*models.Event) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	inserted := len(s.db.events)
+	for _, event := range events {
+		if err := s.db.insertEvent(event, true); err != nil {
+			s.db.events = s.db.events[:inserted]
+			return err
+		}
+	}
+	return nil
+}
+
+// insertEvent stores a copy of event. Only batches insert the image and
+// publish flags; single inserts leave them at their defaults.
+func (db *DB) insertEvent(event *models.Event, withFlags bool) error {
+	stored, err := db.eventRow(event)
+	if err != nil {
+		return err
+	}
+	if err := db.checkExternalID(stored); err != nil {
+		return err
+	}
+
+	now := db.Now()
+	stored.ID, stored.CreatedAt, stored.UpdatedAt = uuid.New(), now, now
+	if withFlags {
+		stored.ImageURL, stored.IsPaid, stored.IsPublished = stringPtr(event.ImageURL), event.IsPaid, event.IsPublished
+	}
+	db.events = append(db.events, stored)
+	event.ID, event.CreatedAt, event.UpdatedAt = stored.ID, stored.CreatedAt, stored.UpdatedAt
+	return nil
+}
+
+// eventRow converts event's columns as Postgres would store them, checking
+// its references
+func (db *DB) eventRow(event *models.Event) (*models.Event, error) {
+	if db.creator(event.CreatorID) == nil {
+		return nil, ErrMissingReference
+	}
+	if _, ok := db.locationName(event.LocationID); !ok {
+		return nil, ErrMissingReference
+	}
+	if _, ok := db.eventTypeName(event.EventTypeID); !ok {
+		return nil, ErrMissingReference
+	}
+	if _, ok := db.entranceTypeName(event.EntranceTypeID); !ok {
+		return nil, ErrMissingReference
+	}
+
+	eventTime, err := timeColumn(event.EventTime)
+	if err != nil {
+		return nil, err
+	}
+	y, m, d := event.EventDate.Date()
+	return &models.Event{
+		CreatorID:            event.CreatorID,
+		Title:                event.Title,
+		EventDate:            time.Date(y, m, d, 0, 0, 0, 0, time.UTC),
+		EventTime:            eventTime,
+		LocationID:           event.LocationID,
+		EventTypeID:          event.EventTypeID,
+		Duration:             stringPtr(event.Duration),
+		EntranceTypeID:       event.EntranceTypeID,
+		EntranceFee:          cents(event.EntranceFee),
+		ParticipantGroupType: stringPtr(event.ParticipantGroupType),
+		LeadBy:               stringPtr(event.LeadBy),
+		Venue:                stringPtr(event.Venue),
+		ContactEmail:         event.ContactEmail,
+		ContactMobile:        stringPtr(event.ContactMobile),
+		Notes:                stringPtr(event.Notes),
+		ExternalID:           stringPtr(event.ExternalID),
+	}, nil
+}
+
+// timeColumn stores a TIME value, which reads back as HH:MM:SS
+func timeColumn(value *string) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.Parse(layout, *value); err == nil {
+			formatted := t.Format("15:04:05")
+			return &formatted, nil
+		}
+	}
+	return nil, fmt.Errorf("memory: invalid input syntax for type time: %q", *value)
+}
+
+// checkExternalID enforces the unique index on (creator_id, external_id)
+func (db *DB) checkExternalID(event *models.Event) error {
+	if event.ExternalID == nil {
+		return nil
+	}
+	for _, other := range db.events {
+		if other.ID != event.ID && other.CreatorID == event.CreatorID &&
+			other.ExternalID != nil && *other.ExternalID == *event.ExternalID {
+			return ErrDuplicate
+		}
+	}
+	return nil
+}
+
+func (s *EventStore) GetByID(ctx context.Context, id uuid.UUID) (*models.Event, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if event := s.db.event(id); event != nil {
+		return s.db.joinEvent(event), nil
+	}
+	return nil, nil
+}
+
+// GetByIDForUpdate is GetByID: memory transactions are not isolated, so
+// there is no row to lock
+func (s *EventStore) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*models.Event, error) {
+	return s.GetByID(ctx, id)
+}
+
+func (s *EventStore) GetByExternalID(ctx context.Context, creatorID uuid.UUID, externalID string) (*models.Event, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, event := range s.db.events {
+		if event.CreatorID == creatorID && event.ExternalID != nil && *event.ExternalID == externalID {
+			return s.db.joinEvent(event), nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *EventStore) Update(ctx context.Context, event *models.Event) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored := s.db.event(event.ID)
+	if stored == nil {
+		return nil
+	}
+	// The creator is not updated, but the row must still reference it
+	event = withCreator(event, stored.CreatorID)
+	row, err := s.db.eventRow(event)
+	if err != nil {
+		return err
+	}
+	row.ID = stored.ID
+	if err := s.db.checkExternalID(row); err != nil {
+		return err
+	}
+
+	stored.Title, stored.EventDate, stored.EventTime = row.Title, row.EventDate, row.EventTime
+	stored.LocationID, stored.EventTypeID, stored.Duration = row.LocationID, row.EventTypeID, row.Duration
+	stored.EntranceTypeID, stored.EntranceFee = row.EntranceTypeID, row.EntranceFee
+	stored.ParticipantGroupType, stored.LeadBy, stored.Venue = row.ParticipantGroupType, row.LeadBy, row.Venue
+	stored.ContactEmail, stored.ContactMobile, stored.Notes = row.ContactEmail, row.ContactMobile, row.Notes
+	stored.ExternalID, stored.UpdatedAt = row.ExternalID, s.db.Now()
+	return nil
+}
+
+func withCreator(event *models.Event, creatorID uuid.UUID) *models.Event {
+	copied := *event
+	copied.CreatorID = creatorID
+	return &copied
+}
+
+func (s *EventStore) UpdateImageURL(ctx context.Context, id uuid.UUID, imageURL string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.setEventImageURL(id, &imageURL)
+	return nil
+}
+
+func (s *EventStore) ListImageRefs(ctx context.Context) (map[uuid.UUID]string, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	refs := make(map[uuid.UUID]string)
+	for _, event := range s.db.events {
+		if event.ImageURL != nil && *event.ImageURL != "" {
+			refs[event.ID] = *event.ImageURL
+		}
+	}
+	return refs, nil
+}
+
+// ReplaceImageRef rewrites an event's image_url only if it still holds from,
+// along with the matching gallery image
+func (s *EventStore) ReplaceImageRef(ctx context.Context, id uuid.UUID, from, to string) (bool, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	event := s.db.event(id)
+	if event == nil || event.ImageURL == nil || *event.ImageURL != from {
+		return false, nil
+	}
+	now := s.db.Now()
+	event.ImageURL, event.UpdatedAt = &to, now
+	for _, image := range s.db.images {
+		if image.EventID == id && image.ImageURL == from {
+			image.ImageURL, image.UpdatedAt = to, now
+		}
+	}
+	return true, nil
+}
+
+func (s *EventStore) UpdatePaymentStatus(ctx context.Context, id uuid.UUID, isPaid, isPublished bool) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if event := s.db.event(id); event != nil {
+		event.IsPaid, event.IsPublished, event.UpdatedAt = isPaid, isPublished, s.db.Now()
+	}
+	return nil
+}
+
+func (s *EventStore) UpdateAdminFields(ctx context.Context, id uuid.UUID, imageURL *string, isPaid, isPublished *bool) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	event := s.db.event(id)
+	if event == nil {
+		return nil
+	}
+	if imageURL != nil {
+		event.ImageURL = stringPtr(imageURL)
+	}
+	if isPaid != nil {
+		event.IsPaid = *isPaid
+	}
+	if isPublished != nil {
+		event.IsPublished = *isPublished
+	}
+	event.UpdatedAt = s.db.Now()
+	return nil
+}
+
+func (s *EventStore) UpdateCreator(ctx context.Context, id, creatorID uuid.UUID) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	event := s.db.event(id)
+	if event == nil {
+		return nil
+	}
+	if s.db.creator(creatorID) == nil {
+		return ErrMissingReference
+	}
+	if err := s.db.checkExternalID(withCreator(event, creatorID)); err != nil {
+		return err
+	}
+	event.CreatorID, event.UpdatedAt = creatorID, s.db.Now()
+	return nil
+}
+
+func (s *EventStore) Delete(ctx context.Context, id uuid.UUID) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.deleteEvent(id)
+	return nil
+}
+
+// deleteEvent removes an event with its payments, gallery and stats, and
+// clears it from its uploads
+func (db *DB) deleteEvent(id uuid.UUID) {
+	db.events = remove(db.events, func(e *models.Event) bool { return e.ID == id })
+	db.payments = remove(db.payments, func(p *models.Payment) bool { return p.EventID == id })
+	db.images = remove(db.images, func(i *models.EventImage) bool { return i.EventID == id })
+	for key := range db.interactions {
+		if key.eventID == id {
+			delete(db.interactions, key)
+		}
+	}
+	for key := range db.eventDays {
+		if key.eventID == id {
+			delete(db.eventDays, key)
+		}
+	}
+	for _, upload := range db.uploads {
+		if upload.EventID != nil && *upload.EventID == id {
+			upload.EventID = nil
+		}
+	}
+}
+
+func (s *EventStore) List(ctx context.Context, filter models.EventListFilter) ([]*models.Event, int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	search := strings.ToLower(filter.Search)
+	var events []*models.Event
+	for _, stored := range s.db.events {
+		event := s.db.joinEvent(stored)
+		day := dateOf(event.EventDate)
+		switch {
+		case filter.OnlyPublished && !event.IsPublished,
+			!filter.IncludePast && day < s.db.today(),
+			!filter.MinEventDate.IsZero() && day < dateOf(filter.MinEventDate),
+			filter.LocationID > 0 && event.LocationID != filter.LocationID,
+			filter.EventTypeID > 0 && event.EventTypeID != filter.EventTypeID,
+			filter.EntranceTypeID > 0 && event.EntranceTypeID != filter.EntranceTypeID,
+			!filter.DateFrom.IsZero() && day < dateOf(filter.DateFrom),
+			!filter.DateTo.IsZero() && day > dateOf(filter.DateTo),
+			filter.CreatorID != uuid.Nil && event.CreatorID != filter.CreatorID:
+			continue
+		}
+		if search != "" && !containsFold(search, event.Title, event.CreatorName, event.OrganizationName, event.Notes) {
+			continue
+		}
+		events = append(events, event)
+	}
+	total := len(events)
+
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	sortStable(events, func(a, b *models.Event) bool {
+		if !a.EventDate.Equal(b.EventDate) {
+			return a.EventDate.Before(b.EventDate)
+		}
+		return a.CreatedAt.After(b.CreatedAt)
+	})
+	events, err := paginate(events, filter.Limit, (filter.Page-1)*filter.Limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// containsFold is ILIKE '%search%' over values; nil values never match
+func containsFold(search string, values ...any) bool {
+	for _, value := range values {
+		var s string
+		switch v := value.(type) {
+		case string:
+			s = v
+		case *string:
+			if v == nil {
+				continue
+			}
+			s = *v
+		}
+		if strings.Contains(strings.ToLower(s), search) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *EventStore) Count(ctx context.Context) (total, published, upcoming int, err error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, event := range s.db.events {
+		total++
+		if event.IsPublished {
+			published++
+			if dateOf(event.EventDate) >= s.db.today() {
+				upcoming++
+			}
+		}
+	}
+	return total, published, upcoming, nil
+}
+
+func (s *EventStore) GetRecent(ctx context.Context, limit int) ([]*models.Event, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var events []*models.Event
+	for _, event := range s.db.events {
+		events = append(events, s.db.joinEvent(event))
+	}
+	sortStable(events, func(a, b *models.Event) bool { return a.CreatedAt.After(b.CreatedAt) })
+	return paginate(events, limit, 0)
+}
+
+func (db *DB) event(id uuid.UUID) *models.Event {
+	for _, event := range db.events {
+		if event.ID == id {
+			return event
+		}
+	}
+	return nil
+}
+
+// joinEvent copies an event with the names its queries join in and its
+// gallery
+func (db *DB) joinEvent(stored *models.Event) *models.Event {
+	event := *stored
+	event.EventTime = stringPtr(stored.EventTime)
+	event.Duration = stringPtr(stored.Duration)
+	event.ParticipantGroupType = stringPtr(stored.ParticipantGroupType)
+	event.LeadBy = stringPtr(stored.LeadBy)
+	event.Venue = stringPtr(stored.Venue)
+	event.ContactMobile = stringPtr(stored.ContactMobile)
+	event.Notes = stringPtr(stored.Notes)
+	event.ImageURL = stringPtr(stored.ImageURL)
+	event.ExternalID = stringPtr(stored.ExternalID)
+
+	if creator := db.creator(stored.CreatorID); creator != nil {
+		event.CreatorName, event.OrganizationName = creator.Name, creator.OrganizationName
+	}
+	event.LocationName, _ = db.locationName(stored.LocationID)
+	event.EventTypeName, _ = db.eventTypeName(stored.EventTypeID)
+	event.EntranceTypeName, _ = db.entranceTypeName(stored.EntranceTypeID)
+	event.Gallery = db.gallery(stored.ID)
+	return &event
+}
+
+func (db *DB) setEventImageURL(id uuid.UUID, imageRef *string) {
+	if event := db.event(id); event != nil {
+		event.ImageURL, event.UpdatedAt = stringPtr(imageRef), db.Now()
+	}
+}
@@ -0,0 +1,238 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+// EventImageStore keeps events.image_url in line with the gallery's cover,
+// like the repository
+type EventImageStore struct{ db *DB }
+
+func (s *EventImageStore) ListByEvent(ctx context.Context, eventID uuid.UUID) ([]*models.EventImage, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return s.db.gallery(eventID), nil
+}
+
+func (s *EventImageStore) GetByID(ctx context.Context, eventID, id uuid.UUID) (*models.EventImage, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if image := s.db.image(eventID, id); image != nil {
+		return copyImage(image), nil
+	}
+	return nil, nil
+}
+
+func (s *EventImageStore) Contains(ctx context.Context, eventID uuid.UUID, imageRef string) (bool, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return s.db.imageByRef(eventID, imageRef) != nil, nil
+}
+
+func (s *EventImageStore) Add(ctx context.Context, image *models.EventImage, limit int) (added bool, err error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if s.db.event(image.EventID) == nil {
+		return false, ErrMissingReference
+	}
+	count, next := 0, 0
+	for _, existing := range s.db.images {
+		if existing.EventID == image.EventID {
+			count++
+			if existing.Position >= next {
+				next = existing.Position + 1
+			}
+		}
+	}
+	if count >= limit {
+		return false, nil
+	}
+
+	now := s.db.Now()
+	image.ID, image.Position, image.IsCover = uuid.New(), next, count == 0
+	image.CreatedAt, image.UpdatedAt = now, now
+	s.db.images = append(s.db.images, copyImage(image))
+	if image.IsCover {
+		s.db.setEventImageURL(image.EventID, &image.ImageURL)
+	}
+	return true, nil
+}
+
+func (s *EventImageStore) UpdateMeta(ctx context.Context, image *models.EventImage) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if stored := s.db.image(image.EventID, image.ID); stored != nil {
+		stored.Caption, stored.AltText, stored.UpdatedAt = stringPtr(image.Caption), stringPtr(image.AltText), s.db.Now()
+	}
+	return nil
+}
+
+func (s *EventImageStore) SetCover(ctx context.Context, eventID, id uuid.UUID) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return s.db.setCover(eventID, id)
+}
+
+// SyncCover brings the gallery in line after events.image_url was set
+// directly, returning the reference that was replaced, if any
+func (s *EventImageStore) SyncCover(ctx context.Context, eventID uuid.UUID, imageRef string) (replaced *string, err error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if existing := s.db.imageByRef(eventID, imageRef); existing != nil {
+		return nil, s.db.setCover(eventID, existing.ID)
+	}
+
+	now := s.db.Now()
+	if cover := s.db.cover(eventID); cover != nil {
+		previous := cover.ImageURL
+		cover.ImageURL, cover.UpdatedAt = imageRef, now
+		replaced = &previous
+	} else {
+		if s.db.event(eventID) == nil {
+			return nil, ErrMissingReference
+		}
+		for _, image := range s.db.images {
+			if image.EventID == eventID {
+				image.Position, image.UpdatedAt = image.Position+1, now
+			}
+		}
+		s.db.images = append(s.db.images, &models.EventImage{
+			ID: uuid.New(), EventID: eventID, ImageURL: imageRef, Position: 0, IsCover: true, CreatedAt: now, UpdatedAt: now,
+		})
+	}
+
+	s.db.setEventImageURL(eventID, &imageRef)
+	return replaced, nil
+}
+
+func (s *EventImageStore) Reorder(ctx context.Context, eventID uuid.UUID, ids []uuid.UUID) (reordered bool, err error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	listed := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		listed[id] = true
+	}
+	total, matched := 0, 0
+	for _, image := range s.db.images {
+		if image.EventID == eventID {
+			total++
+			if listed[image.ID] {
+				matched++
+			}
+		}
+	}
+	if total != len(ids) || matched != len(ids) {
+		return false, nil
+	}
+
+	now := s.db.Now()
+	for position, id := range ids {
+		if image := s.db.image(eventID, id); image != nil {
+			image.Position, image.UpdatedAt = position, now
+		}
+	}
+	return true, nil
+}
+
+// Delete removes an image and returns it, or nil if it was not there.
+// Deleting the cover promotes the next image, or clears the event's image.
+func (s *EventImageStore) Delete(ctx context.Context, eventID, id uuid.UUID) (*models.EventImage, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	image := s.db.image(eventID, id)
+	if image == nil {
+		return nil, nil
+	}
+	s.db.images = remove(s.db.images, func(i *models.EventImage) bool { return i.ID == id })
+
+	if image.IsCover {
+		if gallery := s.db.gallery(eventID); len(gallery) > 0 {
+			if err := s.db.setCover(eventID, gallery[0].ID); err != nil {
+				return nil, err
+			}
+		} else {
+			s.db.setEventImageURL(eventID, nil)
+		}
+	}
+	return image, nil
+}
+
+// setCover moves the cover flag to id and mirrors its image into the event
+func (db *DB) setCover(eventID, id uuid.UUID) error {
+	cover := db.image(eventID, id)
+	if cover == nil {
+		return pgx.ErrNoRows
+	}
+	now := db.Now()
+	for _, image := range db.images {
+		if image.EventID == eventID && image.IsCover != (image.ID == id) {
+			image.IsCover, image.UpdatedAt = image.ID == id, now
+		}
+	}
+	db.setEventImageURL(eventID, &cover.ImageURL)
+	return nil
+}
+
+// gallery returns copies of an event's images in display order, or nil
+func (db *DB) gallery(eventID uuid.UUID) []*models.EventImage {
+	var images []*models.EventImage
+	for _, image := range db.images {
+		if image.EventID == eventID {
+			images = append(images, copyImage(image))
+		}
+	}
+	sortStable(images, func(a, b *models.EventImage) bool {
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	return images
+}
+
+func (db *DB) image(eventID, id uuid.UUID) *models.EventImage {
+	for _, image := range db.images {
+		if image.ID == id && image.EventID == eventID {
+			return image
+		}
+	}
+	return nil
+}
+
+func (db *DB) imageByRef(eventID uuid.UUID, imageRef string) *models.EventImage {
+	for _, image := range db.images {
+		if image.EventID == eventID && image.ImageURL == imageRef {
+			return image
+		}
+	}
+	return nil
+}
+
+func (db *DB) cover(eventID uuid.UUID) *models.EventImage {
+	for _, image := range db.images {
+		if image.EventID == eventID && image.IsCover {
+			return image
+		}
+	}
+	return nil
+}
+
+func copyImage(image *models.EventImage) *models.EventImage {
+	copied := *image
+	copied.Caption = stringPtr(image.Caption)
+	copied.AltText = stringPtr(image.AltText)
+	return &copied
+}
@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+// interactionKey is the primary key of event_interactions
+type interactionKey struct {
+	eventID     uuid.UUID
+	day         string
+	action      string
+	visitorHash string
+}
+
+// eventDayKey is the primary key of event_daily_stats
+type eventDayKey struct {
+	eventID uuid.UUID
+	day     string
+}
+
+type EventStatsStore struct{ db *DB }
+
+func (s *EventStatsStore) Record(ctx context.Context, eventID uuid.UUID, action, visitorHash string) (bool, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	switch action {
+	case models.EventActionView, models.EventActionEmail, models.EventActionWhatsApp, models.EventActionShare:
+	default:
+		return false, fmt.Errorf("unknown event action %q", action)
+	}
+	if s.db.event(eventID) == nil {
+		return false, ErrMissingReference
+	}
+
+	today := s.db.today()
+	key := interactionKey{eventID: eventID, day: today, action: action, visitorHash: visitorHash}
+	if s.db.interactions[key] {
+		return false, nil
+	}
+	s.db.interactions[key] = true
+
+	day := s.db.eventDays[eventDayKey{eventID, today}]
+	if day == nil {
+		day = &models.EventStatsDay{Date: today}
+		s.db.eventDays[eventDayKey{eventID, today}] = day
+	}
+	switch action {
+	case models.EventActionView:
+		day.Views++
+	case models.EventActionEmail:
+		day.EmailClicks++
+	case models.EventActionWhatsApp:
+		day.WhatsAppClicks++
+	case models.EventActionShare:
+		day.ShareClicks++
+	}
+	return true, nil
+}
+
+func (s *EventStatsStore) DeleteOldInteractions(ctx context.Context) (int64, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var deleted int64
+	today := s.db.today()
+	for key := range s.db.interactions {
+		if key.day < today {
+			delete(s.db.interactions, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *EventStatsStore) EventDaily(ctx context.Context, eventID uuid.UUID, from, to time.Time) ([]*models.EventStatsDay, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return s.db.daily(from, to, func(id uuid.UUID) bool { return id == eventID }), nil
+}
+
+func (s *EventStatsStore) CreatorDaily(ctx context.Context, creatorID uuid.UUID, from, to time.Time) ([]*models.EventStatsDay, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return s.db.daily(from, to, func(id uuid.UUID) bool {
+		event := s.db.event(id)
+		return event != nil && event.CreatorID == creatorID
+	}), nil
+}
+
+// daily sums the counts of the events matching match for every day from from
+// to to
+func (db *DB) daily(from, to time.Time, match func(eventID uuid.UUID) bool) []*models.EventStatsDay {
+	days := []*models.EventStatsDay{}
+	for date := from; dateOf(date) <= dateOf(to); date = date.AddDate(0, 0, 1) {
+		day := &models.EventStatsDay{Date: dateOf(date)}
+		for key, counts := range db.eventDays {
+			if key.day == day.Date && match(key.eventID) {
+				day.Add(counts.EventStatsCounts)
+			}
+		}
+		days = append(days, day)
+	}
+	return days
+}
+
+func (s *EventStatsStore) CreatorEventTotals(ctx context.Context, creatorID uuid.UUID, from, to time.Time) ([]*models.EventStatsSummary, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	summaries := []*models.EventStatsSummary{}
+	for _, event := range s.db.events {
+		if event.CreatorID != creatorID {
+			continue
+		}
+		summary := &models.EventStatsSummary{EventID: event.ID, Title: event.Title, EventDate: dateOf(event.EventDate)}
+		for key, counts := range s.db.eventDays {
+			if key.eventID == event.ID && key.day >= dateOf(from) && key.day <= dateOf(to) {
+				summary.Add(counts.EventStatsCounts)
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	sortStable(summaries, func(a, b *models.EventStatsSummary) bool {
+		if a.Views != b.Views {
+			return a.Views > b.Views
+		}
+		return a.EventDate > b.EventDate
+	})
+	return summaries, nil
+}
@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+type IdempotencyStore struct{ db *DB }
+
+func (s *IdempotencyStore) Reserve(ctx context.Context, record *models.IdempotencyRecord, ttl, lease time.Duration) (bool, *models.IdempotencyRecord, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if s.db.creator(record.CreatorID) == nil {
+		return false, nil, ErrMissingReference
+	}
+	now := s.db.Now()
+	existing := s.db.idempotencyRecord(record.CreatorID, record.IdempotencyKey)
+	if existing != nil && !existing.CreatedAt.Before(now.Add(-ttl)) &&
+		(existing.IsComplete() || !existing.CreatedAt.Before(now.Add(-lease))) {
+		return false, copyRecord(existing), nil
+	}
+
+	if existing == nil {
+		existing = &models.IdempotencyRecord{ID: uuid.New(), CreatorID: record.CreatorID, IdempotencyKey: record.IdempotencyKey}
+		s.db.idempotency = append(s.db.idempotency, existing)
+	}
+	existing.RequestMethod, existing.RequestPath, existing.RequestHash = record.RequestMethod, record.RequestPath, record.RequestHash
+	existing.StatusCode, existing.ResponseBody, existing.ContentType = nil, nil, ""
+	existing.CreatedAt, existing.CompletedAt = now, nil
+	record.ID, record.CreatedAt = existing.ID, existing.CreatedAt
+	return true, record, nil
+}
+
+func (s *IdempotencyStore) Get(ctx context.Context, creatorID uuid.UUID, key string) (*models.IdempotencyRecord, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if record := s.db.idempotencyRecord(creatorID, key); record != nil {
+		return copyRecord(record), nil
+	}
+	return nil, nil
+}
+
+func (s *IdempotencyStore) Complete(ctx context.Context, id uuid.UUID, statusCode int, body []byte, contentType string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, record := range s.db.idempotency {
+		if record.ID == id {
+			now := s.db.Now()
+			record.StatusCode, record.ResponseBody, record.ContentType = &statusCode, append([]byte(nil), body...), contentType
+			record.CompletedAt = &now
+		}
+	}
+	return nil
+}
+
+func (s *IdempotencyStore) Release(ctx context.Context, id uuid.UUID) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	s.db.idempotency = remove(s.db.idempotency, func(r *models.IdempotencyRecord) bool { return r.ID == id })
+	return nil
+}
+
+func (db *DB) idempotencyRecord(creatorID uuid.UUID, key string) *models.IdempotencyRecord {
+	for _, record := range db.idempotency {
+		if record.CreatorID == creatorID && record.IdempotencyKey == key {
+			return record
+		}
+	}
+	return nil
+}
+
+func copyRecord(stored *models.IdempotencyRecord) *models.IdempotencyRecord {
+	record := *stored
+	if stored.StatusCode != nil {
+		statusCode := *stored.StatusCode
+		record.StatusCode = &statusCode
+	}
+	record.ResponseBody = append([]byte(nil), stored.ResponseBody...)
+	record.CompletedAt = timePtr(stored.CompletedAt)
+	return &record
+}
@@ -0,0 +1,172 @@
+// Package memory implements the repository stores in memory, so services and
+// handlers can be tested without Postgres. The stores keep the behaviour of
+// the SQL they stand in for: defaults and timestamps, unique keys, foreign
+// keys and their cascades, joined names, and the cover and rollup
+// bookkeeping. They hand out copies, so a caller changing a returned model
+// does not change what is stored.
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/repository"
+)
+
+var (
+	// ErrDuplicate is returned where Postgres would report a unique violation
+	ErrDuplicate = errors.New("memory: duplicate key")
+	// ErrMissingReference is returned where Postgres would report a foreign
+	// key violation
+	ErrMissingReference = errors.New("memory: referenced row does not exist")
+)
+
+// DB holds the tables the stores share
+type DB struct {
+	mu sync.Mutex
+
+	// Now is the clock behind NOW() and CURRENT_DATE
+	Now func() time.Time
+
+	tables
+}
+
+type tables struct {
+	admins        []*models.Admin
+	creators      []*models.Creator
+	events        []*models.Event
+	images        []*models.EventImage
+	payments      []*models.Payment
+	locations     []*models.Location
+	eventTypes    []*models.EventType
+	entranceTypes []*models.EntranceType
+	agentKeys     []*models.AgentAPIKey
+	idempotency   []*models.IdempotencyRecord
+	uploads       []*models.Upload
+
+	visitors        []*models.Visitor
+	salts           map[string][]byte
+	visitorDays     map[string]*visitorDay
+	visitorSegments map[segmentKey]*models.VisitorSegment
+
+	interactions map[interactionKey]bool
+	eventDays    map[eventDayKey]*models.EventStatsDay
+}
+
+// New returns an empty database with the first few locations, event types
+// and entrance types of the seed data
+func New() *DB {
+	db := &DB{
+		Now: time.Now,
+		tables: tables{
+			salts:           make(map[string][]byte),
+			visitorDays:     make(map[string]*visitorDay),
+			visitorSegments: make(map[segmentKey]*models.VisitorSegment),
+			interactions:    make(map[interactionKey]bool),
+			eventDays:       make(map[eventDayKey]*models.EventStatsDay),
+		},
+	}
+	db.seed()
+	return db
+}
+
+// Repositories returns the stores of db
+func (db *DB) Repositories() *repository.Repositories {
+	return &repository.Repositories{
+		Creator:      &CreatorStore{db},
+		Event:        &EventStore{db},
+		EventImage:   &EventImageStore{db},
+		EventStats:   &EventStatsStore{db},
+		Payment:      &PaymentStore{db},
+		Report:       &ReportStore{db},
+		Admin:        &AdminStore{db},
+		Location:     &LocationStore{db},
+		EventType:    &EventTypeStore{db},
+		EntranceType: &EntranceTypeStore{db},
+		Visitor:      &VisitorStore{db},
+		AgentKey:     &AgentKeyStore{db},
+		Idempotency:  &IdempotencyStore{db},
+		Upload:       &UploadStore{db},
+		Tx:           &transactor{db},
+	}
+}
+
+func (db *DB) seed() {
+	now := db.Now()
+	for i, name := range []string{"Ubud", "Canggu", "Seminyak", "Uluwatu"} {
+		db.locations = append(db.locations, &models.Location{
+			ID: i + 1, Name: name, Slug: slug(name), IsActive: true, CreatedAt: now, UpdatedAt: now,
+		})
+	}
+	for i, name := range []string{"Yoga", "Healing", "Therapy", "Music Concert"} {
+		db.eventTypes = append(db.eventTypes, &models.EventType{
+			ID: i + 1, Name: name, Slug: slug(name), IsActive: true, CreatedAt: now, UpdatedAt: now,
+		})
+	}
+	for i, name := range []string{"Free", "Prepaid Online", "Pay at Site", "Donation-based"} {
+		db.entranceTypes = append(db.entranceTypes, &models.EntranceType{
+			ID: i + 1, Name: name, Slug: slug(name), IsActive: true, CreatedAt: now, UpdatedAt: now,
+		})
+	}
+}
+
+// today is CURRENT_DATE
+func (db *DB) today() string {
+	return dateOf(db.Now())
+}
+
+// dateOf is t::date
+func dateOf(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// daysAgo is CURRENT_DATE - days
+func (db *DB) daysAgo(days int) string {
+	return dateOf(db.Now().AddDate(0, 0, -days))
+}
+
+// paginate applies LIMIT limit OFFSET offset
+func paginate[T any](rows []T, limit, offset int) ([]T, error) {
+	if limit < 0 || offset < 0 {
+		return nil, fmt.Errorf("memory: negative LIMIT %d or OFFSET %d", limit, offset)
+	}
+	if offset >= len(rows) {
+		return nil, nil
+	}
+	rows = rows[offset:]
+	if limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows, nil
+}
+
+// cents rounds to the two decimals of a DECIMAL(10, 2) column
+func cents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func stringPtr(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := *s
+	return &v
+}
+
+func timePtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := *t
+	return &v
+}
+
+// sortStable sorts rows by less, keeping insertion order between equal rows
+func sortStable[T any](rows []T, less func(a, b T) bool) {
+	sort.SliceStable(rows, func(i, j int) bool { return less(rows[i], rows[j]) })
+}
@@ -0,0 +1,228 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/repository"
+)
+
+func newCreatorWithEvent(t *testing.T, db *DB) (*models.Creator, *models.Event) {
+	t.Helper()
+	ctx := context.Background()
+	repos := db.Repositories()
+	creator := &models.Creator{Name: "Made", Email: "made@example.com", PasswordHash: "x"}
+	if err := repos.Creator.Create(ctx, creator); err != nil {
+		t.Fatal(err)
+	}
+	event := &models.Event{CreatorID: creator.ID, Title: "Kirtan", EventDate: db.Now(), LocationID: 1, EventTypeID: 1, EntranceTypeID: 1}
+	if err := repos.Event.Create(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	return creator, event
+}
+
+func TestKeysAndCascades(t *testing.T) {
+	ctx := context.Background()
+	db := New()
+	repos := db.Repositories()
+	creator, event := newCreatorWithEvent(t, db)
+
+	if err := repos.Creator.Create(ctx, &models.Creator{Email: creator.Email}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("duplicate email: err = %v, want ErrDuplicate", err)
+	}
+	if err := repos.Event.Create(ctx, &models.Event{CreatorID: creator.ID, LocationID: 99, EventTypeID: 1, EntranceTypeID: 1}); !errors.Is(err, ErrMissingReference) {
+		t.Errorf("unknown location: err = %v, want ErrMissingReference", err)
+	}
+
+	payment := &models.Payment{EventID: event.ID, CreatorID: creator.ID, Status: models.PaymentStatusPending}
+	if err := repos.Payment.Create(ctx, payment); err != nil {
+		t.Fatal(err)
+	}
+	upload := &models.Upload{StorageKey: "events/a.jpg", Source: models.UploadSourceCreator, CreatorID: &creator.ID}
+	if err := repos.Upload.Create(ctx, upload); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Upload.Attach(ctx, upload.StorageKey, event.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repos.Creator.Delete(ctx, creator.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repos.Event.GetByID(ctx, event.ID); got != nil {
+		t.Error("event of deleted creator was kept")
+	}
+	if got, _ := repos.Payment.GetByID(ctx, payment.ID); got != nil {
+		t.Error("payment of deleted creator was kept")
+	}
+	got, _ := repos.Upload.GetByKey(ctx, upload.StorageKey)
+	if got == nil || got.CreatorID != nil || got.EventID != nil {
+		t.Errorf("upload = %+v, want it kept with its creator and event cleared", got)
+	}
+}
+
+func TestIdempotencyReserve(t *testing.T) {
+	ctx := context.Background()
+	db := New()
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	db.Now = func() time.Time { return now }
+	creator, _ := newCreatorWithEvent(t, db)
+	store := db.Repositories().Idempotency
+
+	record := func() *models.IdempotencyRecord {
+		return &models.IdempotencyRecord{CreatorID: creator.ID, IdempotencyKey: "k1", RequestHash: "h"}
+	}
+	reserved, first, err := store.Reserve(ctx, record(), time.Hour, time.Minute)
+	if err != nil || !reserved {
+		t.Fatalf("first Reserve() = %t, %v", reserved, err)
+	}
+	if err := store.Complete(ctx, first.ID, 201, []byte(`{}`), "application/json"); err != nil {
+		t.Fatal(err)
+	}
+
+	reserved, existing, err := store.Reserve(ctx, record(), time.Hour, time.Minute)
+	if err != nil || reserved || !existing.IsComplete() || *existing.StatusCode != 201 {
+		t.Fatalf("retry Reserve() = %t, %+v, %v; want the completed record", reserved, existing, err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	reserved, reclaimed, err := store.Reserve(ctx, record(), time.Hour, time.Minute)
+	if err != nil || !reserved || reclaimed.ID != first.ID {
+		t.Fatalf("Reserve() after ttl = %t, %v; want the key reclaimed", reserved, err)
+	}
+	if got, _ := store.Get(ctx, creator.ID, "k1"); got.IsComplete() {
+		t.Error("reclaimed key kept its old response")
+	}
+
+	// An unfinished reservation is only held for the lease
+	now = now.Add(30 * time.Second)
+	if reserved, _, _ := store.Reserve(ctx, record(), time.Hour, time.Minute); reserved {
+		t.Error("Reserve() within the lease took over a key in progress")
+	}
+	now = now.Add(time.Minute)
+	if reserved, _, _ := store.Reserve(ctx, record(), time.Hour, time.Minute); !reserved {
+		t.Error("Reserve() after the lease did not reclaim the abandoned key")
+	}
+}
+
+func TestVisitorRollUp(t *testing.T) {
+	ctx := context.Background()
+	db := New()
+	store := db.Repositories().Visitor
+
+	visits := []*models.Visitor{
+		{VisitorHash: "a", Country: "Indonesia", Browser: "Firefox"},
+		{VisitorHash: "a", Country: "Australia", Browser: "Firefox"},
+		{VisitorHash: "b", Browser: "Safari"},
+		{VisitorHash: "c", Browser: "Googlebot", IsBot: true},
+	}
+	for _, visit := range visits {
+		if err := store.Create(ctx, visit); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(when string) {
+		t.Helper()
+		visits, unique, bots, _ := store.GetToday(ctx)
+		if visits != 3 || unique != 2 || bots != 1 {
+			t.Errorf("%s: today = %d visits, %d unique, %d bots; want 3, 2, 1", when, visits, unique, bots)
+		}
+		countries, _ := store.SegmentTotals(ctx, models.VisitorDimensionCountry, db.Now(), db.Now())
+		if len(countries) != 3 {
+			t.Errorf("%s: countries = %d, want Indonesia, Australia and Unknown", when, len(countries))
+		}
+	}
+	check("after Create")
+
+	days, err := store.RollUp(ctx, 1)
+	if err != nil || days != 1 {
+		t.Fatalf("RollUp() = %d, %v", days, err)
+	}
+	check("after RollUp")
+
+	// The visitor who moved counts as unique in both countries
+	countries, _ := store.SegmentTotals(ctx, models.VisitorDimensionCountry, db.Now(), db.Now())
+	for _, country := range countries {
+		if country.Visits != 1 || country.UniqueVisitors != 1 {
+			t.Errorf("%s: %d visits by %d visitors, want 1 by 1", country.Value, country.Visits, country.UniqueVisitors)
+		}
+	}
+}
+
+func TestInTx(t *testing.T) {
+	ctx := context.Background()
+	db := New()
+	repos := db.Repositories()
+	creator, event := newCreatorWithEvent(t, db)
+
+	failed := errors.New("step failed")
+	err := repos.Tx.InTx(ctx, func(tx *repository.Repositories) error {
+		if err := tx.Event.UpdatePaymentStatus(ctx, event.ID, true, true); err != nil {
+			return err
+		}
+		if err := tx.Creator.Delete(ctx, creator.ID); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("InTx() = %v, want the error of fn", err)
+	}
+	got, _ := repos.Event.GetByID(ctx, event.ID)
+	if got == nil || got.IsPaid {
+		t.Fatalf("event after rollback = %+v, want it back unpaid", got)
+	}
+
+	err = repos.Tx.InTx(ctx, func(tx *repository.Repositories) error {
+		return tx.Event.UpdatePaymentStatus(ctx, event.ID, true, true)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repos.Event.GetByID(ctx, event.ID); !got.IsPaid {
+		t.Error("committed change was lost")
+	}
+}
+
+func TestSeriesCountsPaymentsWhenCompleted(t *testing.T) {
+	ctx := context.Background()
+	db := New()
+	repos := db.Repositories()
+	opened := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	completed := opened.AddDate(0, 0, 2)
+	db.Now = func() time.Time { return opened }
+	creator, event := newCreatorWithEvent(t, db)
+
+	payment := &models.Payment{EventID: event.ID, CreatorID: creator.ID, AmountCents: 1000, Status: models.PaymentStatusPending}
+	if err := repos.Payment.Create(ctx, payment); err != nil {
+		t.Fatal(err)
+	}
+	db.Now = func() time.Time { return completed }
+	if err := repos.Payment.UpdateStatus(ctx, payment.ID, models.PaymentStatusCompleted, "pi_1"); err != nil {
+		t.Fatal(err)
+	}
+	// A later status change keeps the first completion time
+	db.Now = func() time.Time { return completed.AddDate(0, 0, 1) }
+	if err := repos.Payment.UpdateStatus(ctx, payment.ID, models.PaymentStatusCompleted, "pi_1"); err != nil {
+		t.Fatal(err)
+	}
+
+	points, err := repos.Report.Series(ctx, opened, completed.AddDate(0, 0, 1), models.ReportIntervalDay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range points {
+		want := 0.0
+		if p.Period == completed.Format("2006-01-02") {
+			want = 10
+		}
+		if p.Revenue != want {
+			t.Errorf("%s: revenue %.2f, want %.2f", p.Period, p.Revenue, want)
+		}
+	}
+}
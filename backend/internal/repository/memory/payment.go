@@ -0,0 +1,156 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+type PaymentStore struct{ db *DB }
+
+func (s *PaymentStore) Create(ctx context.Context, payment *models.Payment) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if s.db.event(payment.EventID) == nil || s.db.creator(payment.CreatorID) == nil {
+		return ErrMissingReference
+	}
+	now := s.db.Now()
+	stored := &models.Payment{
+		ID:              uuid.New(),
+		EventID:         payment.EventID,
+		CreatorID:       payment.CreatorID,
+		StripeSessionID: payment.StripeSessionID,
+		AmountCents:     payment.AmountCents,
+		Currency:        payment.Currency,
+		Status:          payment.Status,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	if stored.Status == models.PaymentStatusCompleted {
+		stored.CompletedAt = &now
+	}
+	s.db.payments = append(s.db.payments, stored)
+	payment.ID, payment.CreatedAt, payment.UpdatedAt, payment.CompletedAt = stored.ID, stored.CreatedAt, stored.UpdatedAt, stored.CompletedAt
+	return nil
+}
+
+func (s *PaymentStore) GetByID(ctx context.Context, id uuid.UUID) (*models.Payment, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, payment := range s.db.payments {
+		if payment.ID == id {
+			copied := *payment
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *PaymentStore) GetByStripeSessionID(ctx context.Context, sessionID string) (*models.Payment, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, payment := range s.db.payments {
+		if payment.StripeSessionID == sessionID {
+			copied := *payment
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *PaymentStore) UpdateStatus(ctx context.Context, id uuid.UUID, status, paymentIntentID string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, payment := range s.db.payments {
+		if payment.ID == id {
+			now := s.db.Now()
+			payment.Status, payment.StripePaymentIntentID, payment.UpdatedAt = status, paymentIntentID, now
+			if status == models.PaymentStatusCompleted && payment.CompletedAt == nil {
+				payment.CompletedAt = &now
+			}
+		}
+	}
+	return nil
+}
+
+func (s *PaymentStore) ListByCreator(ctx context.Context, creatorID uuid.UUID, page, limit int) ([]*models.Payment, int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var total int
+	for _, payment := range s.db.payments {
+		if payment.CreatorID == creatorID {
+			total++
+		}
+	}
+	payments := s.db.joinPayments(func(p *models.Payment) bool { return p.CreatorID == creatorID })
+	for _, payment := range payments {
+		payment.CreatorName = ""
+	}
+	payments, err := paginate(payments, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return payments, total, nil
+}
+
+func (s *PaymentStore) ListAll(ctx context.Context, page, limit int, status string) ([]*models.Payment, int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var total int
+	for _, payment := range s.db.payments {
+		if status == "" || payment.Status == status {
+			total++
+		}
+	}
+	payments := s.db.joinPayments(func(p *models.Payment) bool { return status == "" || p.Status == status })
+	payments, err := paginate(payments, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return payments, total, nil
+}
+
+func (s *PaymentStore) GetStats(ctx context.Context) (int, float64, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var count, totalCents int
+	for _, payment := range s.db.payments {
+		if payment.Status == models.PaymentStatusCompleted {
+			count++
+			totalCents += payment.AmountCents
+		}
+	}
+	return count, float64(totalCents) / 100, nil
+}
+
+func (s *PaymentStore) GetRecent(ctx context.Context, limit int) ([]*models.Payment, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return paginate(s.db.joinPayments(func(*models.Payment) bool { return true }), limit, 0)
+}
+
+// joinPayments returns copies of the payments matching match, newest first,
+// with their event title and creator name
+func (db *DB) joinPayments(match func(*models.Payment) bool) []*models.Payment {
+	var payments []*models.Payment
+	for _, stored := range db.payments {
+		event, creator := db.event(stored.EventID), db.creator(stored.CreatorID)
+		if !match(stored) || event == nil || creator == nil {
+			continue
+		}
+		payment := *stored
+		payment.EventTitle, payment.CreatorName = event.Title, creator.Name
+		payments = append(payments, &payment)
+	}
+	sortStable(payments, func(a, b *models.Payment) bool { return a.CreatedAt.After(b.CreatedAt) })
+	return payments
+}
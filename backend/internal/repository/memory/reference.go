@@ -0,0 +1,182 @@
+package memory
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/net1io/zenbali/internal/models"
+)
+
+type LocationStore struct{ db *DB }
+
+func (s *LocationStore) List(ctx context.Context, onlyActive bool) ([]*models.Location, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var locations []*models.Location
+	for _, loc := range s.db.locations {
+		if onlyActive && !loc.IsActive {
+			continue
+		}
+		copied := *loc
+		locations = append(locations, &copied)
+	}
+	sortStable(locations, func(a, b *models.Location) bool { return a.Name < b.Name })
+	return locations, nil
+}
+
+func (s *LocationStore) Create(ctx context.Context, loc *models.Location) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	loc.Slug = slug(loc.Name)
+	for _, existing := range s.db.locations {
+		if existing.Name == loc.Name || existing.Slug == loc.Slug {
+			return ErrDuplicate
+		}
+	}
+	now := s.db.Now()
+	stored := &models.Location{ID: nextReferenceID(len(s.db.locations), func(i int) int { return s.db.locations[i].ID }),
+		Name: loc.Name, Slug: loc.Slug, IsActive: true, CreatedAt: now, UpdatedAt: now}
+	s.db.locations = append(s.db.locations, stored)
+	loc.ID, loc.CreatedAt, loc.UpdatedAt = stored.ID, stored.CreatedAt, stored.UpdatedAt
+	return nil
+}
+
+func (s *LocationStore) Update(ctx context.Context, id int, name string, isActive bool) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	newSlug := slug(name)
+	for _, existing := range s.db.locations {
+		if existing.ID != id && (existing.Name == name || existing.Slug == newSlug) {
+			return ErrDuplicate
+		}
+	}
+	for _, loc := range s.db.locations {
+		if loc.ID == id {
+			loc.Name, loc.Slug, loc.IsActive, loc.UpdatedAt = name, newSlug, isActive, s.db.Now()
+		}
+	}
+	return nil
+}
+
+type EventTypeStore struct{ db *DB }
+
+func (s *EventTypeStore) List(ctx context.Context, onlyActive bool) ([]*models.EventType, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var types []*models.EventType
+	for _, t := range s.db.eventTypes {
+		if onlyActive && !t.IsActive {
+			continue
+		}
+		copied := *t
+		types = append(types, &copied)
+	}
+	sortStable(types, func(a, b *models.EventType) bool { return a.Name < b.Name })
+	return types, nil
+}
+
+func (s *EventTypeStore) Create(ctx context.Context, et *models.EventType) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	et.Slug = slug(et.Name)
+	for _, existing := range s.db.eventTypes {
+		if existing.Name == et.Name || existing.Slug == et.Slug {
+			return ErrDuplicate
+		}
+	}
+	now := s.db.Now()
+	stored := &models.EventType{ID: nextReferenceID(len(s.db.eventTypes), func(i int) int { return s.db.eventTypes[i].ID }),
+		Name: et.Name, Slug: et.Slug, IsActive: true, CreatedAt: now, UpdatedAt: now}
+	s.db.eventTypes = append(s.db.eventTypes, stored)
+	et.ID, et.CreatedAt, et.UpdatedAt = stored.ID, stored.CreatedAt, stored.UpdatedAt
+	return nil
+}
+
+func (s *EventTypeStore) Update(ctx context.Context, id int, name string, isActive bool) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	newSlug := slug(name)
+	for _, existing := range s.db.eventTypes {
+		if existing.ID != id && (existing.Name == name || existing.Slug == newSlug) {
+			return ErrDuplicate
+		}
+	}
+	for _, t := range s.db.eventTypes {
+		if t.ID == id {
+			t.Name, t.Slug, t.IsActive, t.UpdatedAt = name, newSlug, isActive, s.db.Now()
+		}
+	}
+	return nil
+}
+
+type EntranceTypeStore struct{ db *DB }
+
+func (s *EntranceTypeStore) List(ctx context.Context, onlyActive bool) ([]*models.EntranceType, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var types []*models.EntranceType
+	for _, t := range s.db.entranceTypes {
+		if onlyActive && !t.IsActive {
+			continue
+		}
+		copied := *t
+		types = append(types, &copied)
+	}
+	sortStable(types, func(a, b *models.EntranceType) bool { return a.ID < b.ID })
+	return types, nil
+}
+
+// nextReferenceID is the next value of a SERIAL column
+func nextReferenceID(n int, id func(int) int) int {
+	next := 1
+	for i := 0; i < n; i++ {
+		if id(i) >= next {
+			next = id(i) + 1
+		}
+	}
+	return next
+}
+
+var slugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug matches the repository's slugs
+func slug(name string) string {
+	return strings.Trim(slugSeparators.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// locationName, eventTypeName and entranceTypeName are the joined names of
+// an event's reference data; ok is false if the row does not exist
+func (db *DB) locationName(id int) (string, bool) {
+	for _, loc := range db.locations {
+		if loc.ID == id {
+			return loc.Name, true
+		}
+	}
+	return "", false
+}
+
+func (db *DB) eventTypeName(id int) (string, bool) {
+	for _, t := range db.eventTypes {
+		if t.ID == id {
+			return t.Name, true
+		}
+	}
+	return "", false
+}
+
+func (db *DB) entranceTypeName(id int) (string, bool) {
+	for _, t := range db.entranceTypes {
+		if t.ID == id {
+			return t.Name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+type ReportStore struct{ db *DB }
+
+func (s *ReportStore) Series(ctx context.Context, from, to time.Time, interval string) ([]*models.ReportPoint, error) {
+	if !models.IsReportInterval(interval) {
+		return nil, fmt.Errorf("memory: unknown interval %q", interval)
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	points := []*models.ReportPoint{}
+	byPeriod := make(map[string]*models.ReportPoint)
+	for period := truncate(from, interval); dateOf(period) <= dateOf(to); period = next(period, interval) {
+		point := &models.ReportPoint{Period: dateOf(period)}
+		points = append(points, point)
+		byPeriod[point.Period] = point
+	}
+	// point returns the bucket of a day in the range, or nil
+	point := func(t time.Time) *models.ReportPoint {
+		if dateOf(t) < dateOf(from) || dateOf(t) > dateOf(to) {
+			return nil
+		}
+		return byPeriod[dateOf(truncate(t, interval))]
+	}
+
+	for _, event := range s.db.events {
+		if p := point(event.CreatedAt); p != nil {
+			p.NewEvents++
+		}
+	}
+	for _, creator := range s.db.creators {
+		if p := point(creator.CreatedAt); p != nil {
+			p.NewCreators++
+		}
+	}
+	paid := make(map[string]map[uuid.UUID]bool)
+	for _, payment := range s.db.payments {
+		if payment.Status != models.PaymentStatusCompleted || payment.CompletedAt == nil {
+			continue
+		}
+		p := point(*payment.CompletedAt)
+		if p == nil {
+			continue
+		}
+		if paid[p.Period] == nil {
+			paid[p.Period] = make(map[uuid.UUID]bool)
+		}
+		paid[p.Period][payment.EventID] = true
+		p.PaidPostings = len(paid[p.Period])
+		p.Revenue = cents(p.Revenue + float64(payment.AmountCents)/100)
+	}
+	for day, counts := range s.db.visitorDays {
+		date, err := time.Parse("2006-01-02", day)
+		if err != nil {
+			return nil, err
+		}
+		if p := point(date); p != nil {
+			p.Visitors += counts.visits
+			p.UniqueVisitors += counts.uniqueVisitors
+		}
+	}
+	return points, nil
+}
+
+// truncate is date_trunc(interval, t::date)
+func truncate(t time.Time, interval string) time.Time {
+	t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case models.ReportIntervalWeek:
+		return t.AddDate(0, 0, -(int(t.Weekday())+6)%7)
+	case models.ReportIntervalMonth:
+		return t.AddDate(0, 0, 1-t.Day())
+	}
+	return t
+}
+
+func next(t time.Time, interval string) time.Time {
+	switch interval {
+	case models.ReportIntervalWeek:
+		return t.AddDate(0, 0, 7)
+	case models.ReportIntervalMonth:
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+func (s *ReportStore) Funnel(ctx context.Context, from, to time.Time) (*models.ConversionFunnel, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	funnel := &models.ConversionFunnel{}
+	for _, creator := range s.db.creators {
+		day := dateOf(creator.CreatedAt)
+		if day < dateOf(from) || day > dateOf(to) {
+			continue
+		}
+		funnel.Registered++
+		for _, event := range s.db.events {
+			if event.CreatorID == creator.ID {
+				funnel.Posted++
+				break
+			}
+		}
+		for _, payment := range s.db.payments {
+			if payment.CreatorID == creator.ID && payment.Status == models.PaymentStatusCompleted {
+				funnel.Paid++
+				break
+			}
+		}
+	}
+	return funnel, nil
+}
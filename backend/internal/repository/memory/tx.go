@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/repository"
+)
+
+// transactor runs a function against the same tables and puts back a copy
+// of them taken beforehand when it fails. Transactions are not isolated:
+// other callers see their writes at once, and a rollback also undoes what
+// other callers wrote in the meantime.
+type transactor struct{ db *DB }
+
+func (t *transactor) InTx(ctx context.Context, fn func(tx *repository.Repositories) error) (err error) {
+	t.db.mu.Lock()
+	saved := t.db.tables.clone()
+	t.db.mu.Unlock()
+
+	defer func() {
+		if p := recover(); p != nil {
+			t.db.restore(saved)
+			panic(p)
+		}
+		if err != nil {
+			t.db.restore(saved)
+		}
+	}()
+	return fn(t.db.Repositories())
+}
+
+func (db *DB) restore(saved tables) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.tables = saved
+}
+
+// clone copies the tables deeply enough that the stores, which update rows
+// in place, leave the copy unchanged
+func (t *tables) clone() tables {
+	c := tables{
+		admins:        cloneRows(t.admins),
+		creators:      cloneRows(t.creators),
+		events:        cloneRows(t.events),
+		images:        cloneRows(t.images),
+		payments:      cloneRows(t.payments),
+		locations:     cloneRows(t.locations),
+		eventTypes:    cloneRows(t.eventTypes),
+		entranceTypes: cloneRows(t.entranceTypes),
+		agentKeys:     cloneRows(t.agentKeys),
+		idempotency:   cloneRows(t.idempotency),
+		uploads:       cloneRows(t.uploads),
+		visitors:      cloneRows(t.visitors),
+
+		salts:           make(map[string][]byte, len(t.salts)),
+		visitorDays:     make(map[string]*visitorDay, len(t.visitorDays)),
+		visitorSegments: make(map[segmentKey]*models.VisitorSegment, len(t.visitorSegments)),
+		interactions:    make(map[interactionKey]bool, len(t.interactions)),
+		eventDays:       make(map[eventDayKey]*models.EventStatsDay, len(t.eventDays)),
+	}
+	for day, salt := range t.salts {
+		c.salts[day] = salt
+	}
+	for day, counts := range t.visitorDays {
+		copied := *counts
+		c.visitorDays[day] = &copied
+	}
+	for key, segment := range t.visitorSegments {
+		copied := *segment
+		c.visitorSegments[key] = &copied
+	}
+	for key, seen := range t.interactions {
+		c.interactions[key] = seen
+	}
+	for key, day := range t.eventDays {
+		copied := *day
+		c.eventDays[key] = &copied
+	}
+	return c
+}
+
+func cloneRows[T any](rows []*T) []*T {
+	c := make([]*T, len(rows))
+	for i, row := range rows {
+		copied := *row
+		c[i] = &copied
+	}
+	return c
+}
@@ -0,0 +1,167 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+type UploadStore struct{ db *DB }
+
+func (s *UploadStore) Create(ctx context.Context, upload *models.Upload) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if s.db.upload(upload.StorageKey) != nil {
+		return ErrDuplicate
+	}
+	if upload.CreatorID != nil && s.db.creator(*upload.CreatorID) == nil {
+		return ErrMissingReference
+	}
+	stored := &models.Upload{
+		ID:         uuid.New(),
+		StorageKey: upload.StorageKey,
+		Source:     upload.Source,
+		CreatorID:  uuidPtr(upload.CreatorID),
+		AgentKeyID: uuidPtr(upload.AgentKeyID),
+		SizeBytes:  upload.SizeBytes,
+		CreatedAt:  s.db.Now(),
+	}
+	s.db.uploads = append(s.db.uploads, stored)
+	upload.ID, upload.CreatedAt = stored.ID, stored.CreatedAt
+	return nil
+}
+
+func (s *UploadStore) GetByKey(ctx context.Context, storageKey string) (*models.Upload, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if upload := s.db.upload(storageKey); upload != nil {
+		return copyUpload(upload), nil
+	}
+	return nil, nil
+}
+
+func (s *UploadStore) Attach(ctx context.Context, storageKey string, eventID uuid.UUID) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	upload := s.db.upload(storageKey)
+	if upload == nil {
+		return nil
+	}
+	if s.db.event(eventID) == nil {
+		return ErrMissingReference
+	}
+	now := s.db.Now()
+	upload.EventID, upload.AttachedAt, upload.DetachedAt = &eventID, &now, nil
+	return nil
+}
+
+func (s *UploadStore) Detach(ctx context.Context, storageKey string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	now := s.db.Now()
+	upload := s.db.upload(storageKey)
+	if upload == nil {
+		upload = &models.Upload{ID: uuid.New(), StorageKey: storageKey, Source: models.UploadSourceLegacy, CreatedAt: now}
+		s.db.uploads = append(s.db.uploads, upload)
+	}
+	upload.EventID, upload.DetachedAt = nil, &now
+	return nil
+}
+
+func (s *UploadStore) Reattach(ctx context.Context) (int64, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var reattached int64
+	for _, upload := range s.db.uploads {
+		eventIDs := s.db.referencingEvents(upload.StorageKey)
+		if len(eventIDs) == 0 || upload.EventID != nil && eventIDs[*upload.EventID] {
+			continue
+		}
+		for eventID := range eventIDs {
+			now := s.db.Now()
+			upload.EventID, upload.AttachedAt, upload.DetachedAt = &eventID, &now, nil
+			break
+		}
+		reattached++
+	}
+	return reattached, nil
+}
+
+func (s *UploadStore) ListOrphans(ctx context.Context, cutoff time.Time, limit int) ([]*models.Upload, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var uploads []*models.Upload
+	for _, upload := range s.db.uploads {
+		since := upload.CreatedAt
+		if upload.DetachedAt != nil {
+			since = *upload.DetachedAt
+		}
+		if s.db.orphaned(upload) && since.Before(cutoff) {
+			uploads = append(uploads, copyUpload(upload))
+		}
+	}
+	sortStable(uploads, func(a, b *models.Upload) bool { return a.CreatedAt.Before(b.CreatedAt) })
+	return paginate(uploads, limit, 0)
+}
+
+func (s *UploadStore) Delete(ctx context.Context, id uuid.UUID) (bool, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	before := len(s.db.uploads)
+	s.db.uploads = remove(s.db.uploads, func(u *models.Upload) bool { return u.ID == id && s.db.orphaned(u) })
+	return len(s.db.uploads) < before, nil
+}
+
+func (db *DB) upload(storageKey string) *models.Upload {
+	for _, upload := range db.uploads {
+		if upload.StorageKey == storageKey {
+			return upload
+		}
+	}
+	return nil
+}
+
+// referencingEvents returns the events whose image or gallery uses imageRef
+func (db *DB) referencingEvents(imageRef string) map[uuid.UUID]bool {
+	eventIDs := make(map[uuid.UUID]bool)
+	for _, event := range db.events {
+		if event.ImageURL != nil && *event.ImageURL == imageRef {
+			eventIDs[event.ID] = true
+		}
+	}
+	for _, image := range db.images {
+		if image.ImageURL == imageRef {
+			eventIDs[image.EventID] = true
+		}
+	}
+	return eventIDs
+}
+
+// orphaned reports whether an upload is unattached and unreferenced
+func (db *DB) orphaned(upload *models.Upload) bool {
+	return upload.EventID == nil && len(db.referencingEvents(upload.StorageKey)) == 0
+}
+
+func copyUpload(stored *models.Upload) *models.Upload {
+	upload := *stored
+	upload.CreatorID, upload.AgentKeyID, upload.EventID = uuidPtr(stored.CreatorID), uuidPtr(stored.AgentKeyID), uuidPtr(stored.EventID)
+	upload.AttachedAt, upload.DetachedAt = timePtr(stored.AttachedAt), timePtr(stored.DetachedAt)
+	return &upload
+}
+
+func uuidPtr(id *uuid.UUID) *uuid.UUID {
+	if id == nil {
+		return nil
+	}
+	v := *id
+	return &v
+}
@@ -0,0 +1,293 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+// visitorDay is a row of visitor_daily_stats
+type visitorDay struct {
+	visits, uniqueVisitors       int
+	botVisits, botUniqueVisitors int
+}
+
+// segmentKey is the primary key of visitor_daily_segments
+type segmentKey struct {
+	day, dimension, value string
+}
+
+type VisitorStore struct{ db *DB }
+
+func (s *VisitorStore) DailySalt(ctx context.Context, candidate []byte) ([]byte, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	today := s.db.today()
+	if _, ok := s.db.salts[today]; !ok {
+		s.db.salts[today] = append([]byte(nil), candidate...)
+	}
+	return append([]byte(nil), s.db.salts[today]...), nil
+}
+
+func (s *VisitorStore) Create(ctx context.Context, visitor *models.Visitor) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	today := s.db.today()
+	unique := 1
+	for _, existing := range s.db.visitors {
+		if existing.VisitorHash == visitor.VisitorHash && dateOf(existing.VisitedAt) >= today {
+			unique = 0
+		}
+	}
+
+	stored := *visitor
+	stored.ID, stored.VisitedAt = uuid.New(), s.db.Now()
+	s.db.visitors = append(s.db.visitors, &stored)
+	visitor.ID, visitor.VisitedAt = stored.ID, stored.VisitedAt
+
+	day := s.db.visitorDays[today]
+	if day == nil {
+		day = &visitorDay{}
+		s.db.visitorDays[today] = day
+	}
+	if visitor.IsBot {
+		day.botVisits++
+		day.botUniqueVisitors += unique
+	} else {
+		day.visits++
+		day.uniqueVisitors += unique
+	}
+	for _, segment := range visitorSegments(visitor) {
+		s.db.countSegment(segmentKey{today, segment.Dimension, segment.Value}, 1, unique)
+	}
+	return nil
+}
+
+// visitorSegments returns the segments a visit counts in
+func visitorSegments(visitor *models.Visitor) []models.VisitorSegment {
+	if visitor.IsBot {
+		return []models.VisitorSegment{{Dimension: models.VisitorDimensionBot, Value: segmentValue(visitor.Browser)}}
+	}
+	return []models.VisitorSegment{
+		{Dimension: models.VisitorDimensionCountry, Value: segmentValue(visitor.Country)},
+		{Dimension: models.VisitorDimensionBrowser, Value: segmentValue(visitor.Browser)},
+		{Dimension: models.VisitorDimensionOS, Value: segmentValue(visitor.OS)},
+		{Dimension: models.VisitorDimensionDevice, Value: segmentValue(visitor.Device)},
+		{Dimension: models.VisitorDimensionSource, Value: sourceValue(visitor.Source)},
+	}
+}
+
+func (db *DB) countSegment(key segmentKey, visits, uniqueVisitors int) {
+	segment := db.visitorSegments[key]
+	if segment == nil {
+		segment = &models.VisitorSegment{Dimension: key.dimension, Value: key.value}
+		db.visitorSegments[key] = segment
+	}
+	segment.Visits += visits
+	segment.UniqueVisitors += uniqueVisitors
+}
+
+func (s *VisitorStore) RollUp(ctx context.Context, days int) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	since := s.db.daysAgo(days)
+	rolled := make(map[string]*visitorDay)
+	hashes := make(map[string]map[string]bool)
+	segmentHashes := make(map[segmentKey]map[string]bool)
+	segments := make(map[segmentKey]int)
+	for _, visitor := range s.db.visitors {
+		day := dateOf(visitor.VisitedAt)
+		if day < since {
+			continue
+		}
+		if rolled[day] == nil {
+			rolled[day] = &visitorDay{}
+			hashes[day+"/bot"], hashes[day] = make(map[string]bool), make(map[string]bool)
+		}
+		if visitor.IsBot {
+			rolled[day].botVisits++
+			hashes[day+"/bot"][visitor.VisitorHash] = true
+		} else {
+			rolled[day].visits++
+			hashes[day][visitor.VisitorHash] = true
+		}
+		for _, segment := range visitorSegments(visitor) {
+			key := segmentKey{day, segment.Dimension, segment.Value}
+			if segmentHashes[key] == nil {
+				segmentHashes[key] = make(map[string]bool)
+			}
+			segments[key]++
+			segmentHashes[key][visitor.VisitorHash] = true
+		}
+	}
+
+	for day, counts := range rolled {
+		counts.uniqueVisitors, counts.botUniqueVisitors = len(hashes[day]), len(hashes[day+"/bot"])
+		s.db.visitorDays[day] = counts
+	}
+	for key := range s.db.visitorSegments {
+		if rolled[key.day] != nil {
+			delete(s.db.visitorSegments, key)
+		}
+	}
+	for key, visits := range segments {
+		s.db.countSegment(key, visits, len(segmentHashes[key]))
+	}
+	return len(rolled), nil
+}
+
+func (s *VisitorStore) DeleteBefore(ctx context.Context, days int) (int64, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	before := len(s.db.visitors)
+	cutoff := s.db.daysAgo(days)
+	s.db.visitors = remove(s.db.visitors, func(v *models.Visitor) bool { return dateOf(v.VisitedAt) < cutoff })
+	return int64(before - len(s.db.visitors)), nil
+}
+
+func (s *VisitorStore) DeleteOldSalts(ctx context.Context) (int64, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var deleted int64
+	today := s.db.today()
+	for day := range s.db.salts {
+		if day < today {
+			delete(s.db.salts, day)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *VisitorStore) GetStats(ctx context.Context) (*models.VisitorStats, error) {
+	total, _ := s.GetTotalCount(ctx)
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stats := &models.VisitorStats{TotalVisitors: total, LastVisitorDate: s.db.Now()}
+	var last *models.Visitor
+	for _, visitor := range s.db.visitors {
+		if !visitor.IsBot && (last == nil || !visitor.VisitedAt.Before(last.VisitedAt)) {
+			last = visitor
+		}
+	}
+	if last != nil {
+		stats.LastVisitorDate, stats.LastVisitorCity, stats.LastVisitorCountry = last.VisitedAt, last.City, last.Country
+	}
+	return stats, nil
+}
+
+func (s *VisitorStore) GetToday(ctx context.Context) (int, int, int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	day := s.db.visitorDays[s.db.today()]
+	if day == nil {
+		return 0, 0, 0, nil
+	}
+	return day.visits, day.uniqueVisitors, day.botVisits, nil
+}
+
+func (s *VisitorStore) BotTotals(ctx context.Context, from, to time.Time) (int, int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var visits, uniqueVisitors int
+	for day, counts := range s.db.visitorDays {
+		if day >= dateOf(from) && day <= dateOf(to) {
+			visits += counts.botVisits
+			uniqueVisitors += counts.botUniqueVisitors
+		}
+	}
+	return visits, uniqueVisitors, nil
+}
+
+func (s *VisitorStore) GetTotalCount(ctx context.Context) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	var total int
+	for _, counts := range s.db.visitorDays {
+		total += counts.visits
+	}
+	return total, nil
+}
+
+func (s *VisitorStore) ListSegments(ctx context.Context, days, limit int) ([]*models.VisitorSegment, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	since := s.db.daysAgo(days)
+	segments := s.db.sumSegments(func(key segmentKey) bool { return key.day > since })
+	var ranked []*models.VisitorSegment
+	rank := make(map[string]int)
+	for _, segment := range segments {
+		if rank[segment.Dimension] < limit {
+			rank[segment.Dimension]++
+			ranked = append(ranked, segment)
+		}
+	}
+	sortStable(ranked, func(a, b *models.VisitorSegment) bool { return a.Dimension < b.Dimension })
+	if ranked == nil {
+		ranked = []*models.VisitorSegment{}
+	}
+	return ranked, nil
+}
+
+func (s *VisitorStore) SegmentTotals(ctx context.Context, dimension string, from, to time.Time) ([]*models.VisitorSegment, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	return s.db.sumSegments(func(key segmentKey) bool {
+		return key.dimension == dimension && key.day >= dateOf(from) && key.day <= dateOf(to)
+	}), nil
+}
+
+// sumSegments sums the segment rows matching match per dimension and value,
+// most visits first
+func (db *DB) sumSegments(match func(segmentKey) bool) []*models.VisitorSegment {
+	sums := make(map[[2]string]*models.VisitorSegment)
+	segments := []*models.VisitorSegment{}
+	for key, counts := range db.visitorSegments {
+		if !match(key) {
+			continue
+		}
+		sum := sums[[2]string{key.dimension, key.value}]
+		if sum == nil {
+			sum = &models.VisitorSegment{Dimension: key.dimension, Value: key.value}
+			sums[[2]string{key.dimension, key.value}] = sum
+			segments = append(segments, sum)
+		}
+		sum.Visits += counts.Visits
+		sum.UniqueVisitors += counts.UniqueVisitors
+	}
+	sortStable(segments, func(a, b *models.VisitorSegment) bool {
+		if a.Visits != b.Visits {
+			return a.Visits > b.Visits
+		}
+		return a.Value < b.Value
+	})
+	return segments
+}
+
+func segmentValue(value string) string {
+	if value == "" {
+		return "Unknown"
+	}
+	return value
+}
+
+func sourceValue(value string) string {
+	if value == "" {
+		return models.AttributionUnknown
+	}
+	return value
+}
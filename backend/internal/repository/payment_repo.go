@@ -9,12 +9,11 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/net1io/zenbali/internal/models"
 )
 
 type PaymentRepository struct {
-	pool *pgxpool.Pool
+	db DBTX
 }
 
 func scanNullableString(src sql.NullString) string {
@@ -24,24 +23,24 @@ func scanNullableString(src sql.NullString) string {
 	return ""
 }
 
-func NewPaymentRepository(pool *pgxpool.Pool) *PaymentRepository {
-	return &PaymentRepository{pool: pool}
+func NewPaymentRepository(db DBTX) *PaymentRepository {
+	return &PaymentRepository{db: db}
 }
 
 func (r *PaymentRepository) Create(ctx context.Context, payment *models.Payment) error {
 	query := `
-		INSERT INTO payments (event_id, creator_id, stripe_session_id, amount_cents, currency, status)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, created_at, updated_at
+		INSERT INTO payments (event_id, creator_id, stripe_session_id, amount_cents, currency, status, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, CASE WHEN $6 = 'completed' THEN NOW() END)
+		RETURNING id, created_at, updated_at, completed_at
 	`
-	return r.pool.QueryRow(ctx, query,
+	return r.db.QueryRow(ctx, query,
 		payment.EventID,
 		payment.CreatorID,
 		payment.StripeSessionID,
 		payment.AmountCents,
 		payment.Currency,
 		payment.Status,
-	).Scan(&payment.ID, &payment.CreatedAt, &payment.UpdatedAt)
+	).Scan(&payment.ID, &payment.CreatedAt, &payment.UpdatedAt, &payment.CompletedAt)
 }
 
 func (r *PaymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Payment, error) {
@@ -49,17 +48,17 @@ func (r *PaymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 	var paymentIntent sql.NullString
 	query := `
 		SELECT p.id, p.event_id, p.creator_id, p.stripe_session_id, p.stripe_payment_intent_id,
-		       p.amount_cents, p.currency, p.status, p.created_at, p.updated_at,
+		       p.amount_cents, p.currency, p.status, p.created_at, p.updated_at, p.completed_at,
 		       e.title as event_title, c.name as creator_name
 		FROM payments p
 		JOIN events e ON p.event_id = e.id
 		JOIN creators c ON p.creator_id = c.id
 		WHERE p.id = $1
 	`
-	err := r.pool.QueryRow(ctx, query, id).Scan(
+	err := r.db.QueryRow(ctx, query, id).Scan(
 		&payment.ID, &payment.EventID, &payment.CreatorID, &payment.StripeSessionID,
 		&paymentIntent, &payment.AmountCents, &payment.Currency,
-		&payment.Status, &payment.CreatedAt, &payment.UpdatedAt,
+		&payment.Status, &payment.CreatedAt, &payment.UpdatedAt, &payment.CompletedAt,
 		&payment.EventTitle, &payment.CreatorName,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -77,14 +76,14 @@ func (r *PaymentRepository) GetByStripeSessionID(ctx context.Context, sessionID
 	var paymentIntent sql.NullString
 	query := `
 		SELECT p.id, p.event_id, p.creator_id, p.stripe_session_id, p.stripe_payment_intent_id,
-		       p.amount_cents, p.currency, p.status, p.created_at, p.updated_at
+		       p.amount_cents, p.currency, p.status, p.created_at, p.updated_at, p.completed_at
 		FROM payments p
 		WHERE p.stripe_session_id = $1
 	`
-	err := r.pool.QueryRow(ctx, query, sessionID).Scan(
+	err := r.db.QueryRow(ctx, query, sessionID).Scan(
 		&payment.ID, &payment.EventID, &payment.CreatorID, &payment.StripeSessionID,
 		&paymentIntent, &payment.AmountCents, &payment.Currency,
-		&payment.Status, &payment.CreatedAt, &payment.UpdatedAt,
+		&payment.Status, &payment.CreatedAt, &payment.UpdatedAt, &payment.CompletedAt,
 	)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
@@ -96,13 +95,16 @@ func (r *PaymentRepository) GetByStripeSessionID(ctx context.Context, sessionID
 	return payment, nil
 }
 
+// UpdateStatus sets the status of a payment, and stamps completed_at the first
+// time it becomes completed
 func (r *PaymentRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status, paymentIntentID string) error {
 	query := `
 		UPDATE payments 
-		SET status = $1, stripe_payment_intent_id = $2, updated_at = NOW()
+		SET status = $1, stripe_payment_intent_id = $2, updated_at = NOW(),
+		    completed_at = CASE WHEN $1 = 'completed' THEN COALESCE(completed_at, NOW()) ELSE completed_at END
 		WHERE id = $3
 	`
-	_, err := r.pool.Exec(ctx, query, status, paymentIntentID, id)
+	_, err := r.db.Exec(ctx, query, status, paymentIntentID, id)
 	return err
 }
 
@@ -112,14 +114,14 @@ func (r *PaymentRepository) ListByCreator(ctx context.Context, creatorID uuid.UU
 	// Count
 	var total int
 	countQuery := `SELECT COUNT(*) FROM payments WHERE creator_id = $1`
-	if err := r.pool.QueryRow(ctx, countQuery, creatorID).Scan(&total); err != nil {
+	if err := r.db.QueryRow(ctx, countQuery, creatorID).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
 	// Data
 	query := `
 		SELECT p.id, p.event_id, p.creator_id, p.stripe_session_id, p.stripe_payment_intent_id,
-		       p.amount_cents, p.currency, p.status, p.created_at, p.updated_at,
+		       p.amount_cents, p.currency, p.status, p.created_at, p.updated_at, p.completed_at,
 		       e.title as event_title
 		FROM payments p
 		JOIN events e ON p.event_id = e.id
@@ -127,7 +129,7 @@ func (r *PaymentRepository) ListByCreator(ctx context.Context, creatorID uuid.UU
 		ORDER BY p.created_at DESC
 		LIMIT $2 OFFSET $3
 	`
-	rows, err := r.pool.Query(ctx, query, creatorID, limit, offset)
+	rows, err := r.db.Query(ctx, query, creatorID, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -140,7 +142,7 @@ func (r *PaymentRepository) ListByCreator(ctx context.Context, creatorID uuid.UU
 		if err := rows.Scan(
 			&payment.ID, &payment.EventID, &payment.CreatorID, &payment.StripeSessionID,
 			&paymentIntent, &payment.AmountCents, &payment.Currency,
-			&payment.Status, &payment.CreatedAt, &payment.UpdatedAt, &payment.EventTitle,
+			&payment.Status, &payment.CreatedAt, &payment.UpdatedAt, &payment.CompletedAt, &payment.EventTitle,
 		); err != nil {
 			return nil, 0, err
 		}
@@ -170,14 +172,14 @@ func (r *PaymentRepository) ListAll(ctx context.Context, page, limit int, status
 	// Count
 	var total int
 	countQuery := "SELECT COUNT(*) FROM payments p " + whereClause
-	if err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+	if err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
 	// Data
 	query := fmt.Sprintf(`
 		SELECT p.id, p.event_id, p.creator_id, p.stripe_session_id, p.stripe_payment_intent_id,
-		       p.amount_cents, p.currency, p.status, p.created_at, p.updated_at,
+		       p.amount_cents, p.currency, p.status, p.created_at, p.updated_at, p.completed_at,
 		       e.title as event_title, c.name as creator_name
 		FROM payments p
 		JOIN events e ON p.event_id = e.id
@@ -189,7 +191,7 @@ func (r *PaymentRepository) ListAll(ctx context.Context, page, limit int, status
 
 	args = append(args, limit, offset)
 
-	rows, err := r.pool.Query(ctx, query, args...)
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -202,7 +204,7 @@ func (r *PaymentRepository) ListAll(ctx context.Context, page, limit int, status
 		if err := rows.Scan(
 			&payment.ID, &payment.EventID, &payment.CreatorID, &payment.StripeSessionID,
 			&paymentIntent, &payment.AmountCents, &payment.Currency,
-			&payment.Status, &payment.CreatedAt, &payment.UpdatedAt,
+			&payment.Status, &payment.CreatedAt, &payment.UpdatedAt, &payment.CompletedAt,
 			&payment.EventTitle, &payment.CreatorName,
 		); err != nil {
 			return nil, 0, err
@@ -221,7 +223,7 @@ func (r *PaymentRepository) GetStats(ctx context.Context) (int, float64, error)
 		FROM payments
 		WHERE status = 'completed'
 	`
-	if err := r.pool.QueryRow(ctx, query).Scan(&count, &totalCents); err != nil {
+	if err := r.db.QueryRow(ctx, query).Scan(&count, &totalCents); err != nil {
 		return 0, 0, err
 	}
 	return count, float64(totalCents) / 100, nil
@@ -230,7 +232,7 @@ func (r *PaymentRepository) GetStats(ctx context.Context) (int, float64, error)
 func (r *PaymentRepository) GetRecent(ctx context.Context, limit int) ([]*models.Payment, error) {
 	query := `
 		SELECT p.id, p.event_id, p.creator_id, p.stripe_session_id, p.stripe_payment_intent_id,
-		       p.amount_cents, p.currency, p.status, p.created_at, p.updated_at,
+		       p.amount_cents, p.currency, p.status, p.created_at, p.updated_at, p.completed_at,
 		       e.title as event_title, c.name as creator_name
 		FROM payments p
 		JOIN events e ON p.event_id = e.id
@@ -238,7 +240,7 @@ func (r *PaymentRepository) GetRecent(ctx context.Context, limit int) ([]*models
 		ORDER BY p.created_at DESC
 		LIMIT $1
 	`
-	rows, err := r.pool.Query(ctx, query, limit)
+	rows, err := r.db.Query(ctx, query, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -251,7 +253,7 @@ func (r *PaymentRepository) GetRecent(ctx context.Context, limit int) ([]*models
 		if err := rows.Scan(
 			&payment.ID, &payment.EventID, &payment.CreatorID, &payment.StripeSessionID,
 			&paymentIntent, &payment.AmountCents, &payment.Currency,
-			&payment.Status, &payment.CreatedAt, &payment.UpdatedAt,
+			&payment.Status, &payment.CreatedAt, &payment.UpdatedAt, &payment.CompletedAt,
 			&payment.EventTitle, &payment.CreatorName,
 		); err != nil {
 			return nil, err
@@ -5,17 +5,16 @@ import (
 	"regexp"
 	"strings"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/net1io/zenbali/internal/models"
 )
 
 // LocationRepository handles location data operations
 type LocationRepository struct {
-	pool *pgxpool.Pool
+	db DBTX
 }
 
-func NewLocationRepository(pool *pgxpool.Pool) *LocationRepository {
-	return &LocationRepository{pool: pool}
+func NewLocationRepository(db DBTX) *LocationRepository {
+	return &LocationRepository{db: db}
 }
 
 func (r *LocationRepository) List(ctx context.Context, onlyActive bool) ([]*models.Location, error) {
@@ -25,7 +24,7 @@ func (r *LocationRepository) List(ctx context.Context, onlyActive bool) ([]*mode
 	}
 	query += ` ORDER BY name ASC`
 
-	rows, err := r.pool.Query(ctx, query)
+	rows, err := r.db.Query(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -45,23 +44,23 @@ func (r *LocationRepository) List(ctx context.Context, onlyActive bool) ([]*mode
 func (r *LocationRepository) Create(ctx context.Context, loc *models.Location) error {
 	loc.Slug = generateSlug(loc.Name)
 	query := `INSERT INTO locations (name, slug) VALUES ($1, $2) RETURNING id, created_at, updated_at`
-	return r.pool.QueryRow(ctx, query, loc.Name, loc.Slug).Scan(&loc.ID, &loc.CreatedAt, &loc.UpdatedAt)
+	return r.db.QueryRow(ctx, query, loc.Name, loc.Slug).Scan(&loc.ID, &loc.CreatedAt, &loc.UpdatedAt)
 }
 
 func (r *LocationRepository) Update(ctx context.Context, id int, name string, isActive bool) error {
 	slug := generateSlug(name)
 	query := `UPDATE locations SET name = $1, slug = $2, is_active = $3 WHERE id = $4`
-	_, err := r.pool.Exec(ctx, query, name, slug, isActive, id)
+	_, err := r.db.Exec(ctx, query, name, slug, isActive, id)
 	return err
 }
 
 // EventTypeRepository handles event type data operations
 type EventTypeRepository struct {
-	pool *pgxpool.Pool
+	db DBTX
 }
 
-func NewEventTypeRepository(pool *pgxpool.Pool) *EventTypeRepository {
-	return &EventTypeRepository{pool: pool}
+func NewEventTypeRepository(db DBTX) *EventTypeRepository {
+	return &EventTypeRepository{db: db}
 }
 
 func (r *EventTypeRepository) List(ctx context.Context, onlyActive bool) ([]*models.EventType, error) {
@@ -71,7 +70,7 @@ func (r *EventTypeRepository) List(ctx context.Context, onlyActive bool) ([]*mod
 	}
 	query += ` ORDER BY name ASC`
 
-	rows, err := r.pool.Query(ctx, query)
+	rows, err := r.db.Query(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -91,23 +90,23 @@ func (r *EventTypeRepository) List(ctx context.Context, onlyActive bool) ([]*mod
 func (r *EventTypeRepository) Create(ctx context.Context, et *models.EventType) error {
 	et.Slug = generateSlug(et.Name)
 	query := `INSERT INTO event_types (name, slug) VALUES ($1, $2) RETURNING id, created_at, updated_at`
-	return r.pool.QueryRow(ctx, query, et.Name, et.Slug).Scan(&et.ID, &et.CreatedAt, &et.UpdatedAt)
+	return r.db.QueryRow(ctx, query, et.Name, et.Slug).Scan(&et.ID, &et.CreatedAt, &et.UpdatedAt)
 }
 
 func (r *EventTypeRepository) Update(ctx context.Context, id int, name string, isActive bool) error {
 	slug := generateSlug(name)
 	query := `UPDATE event_types SET name = $1, slug = $2, is_active = $3 WHERE id = $4`
-	_, err := r.pool.Exec(ctx, query, name, slug, isActive, id)
+	_, err := r.db.Exec(ctx, query, name, slug, isActive, id)
 	return err
 }
 
 // EntranceTypeRepository handles entrance type data operations
 type EntranceTypeRepository struct {
-	pool *pgxpool.Pool
+	db DBTX
 }
 
-func NewEntranceTypeRepository(pool *pgxpool.Pool) *EntranceTypeRepository {
-	return &EntranceTypeRepository{pool: pool}
+func NewEntranceTypeRepository(db DBTX) *EntranceTypeRepository {
+	return &EntranceTypeRepository{db: db}
 }
 
 func (r *EntranceTypeRepository) List(ctx context.Context, onlyActive bool) ([]*models.EntranceType, error) {
@@ -117,7 +116,7 @@ func (r *EntranceTypeRepository) List(ctx context.Context, onlyActive bool) ([]*
 	}
 	query += ` ORDER BY id ASC`
 
-	rows, err := r.pool.Query(ctx, query)
+	rows, err := r.db.Query(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/net1io/zenbali/internal/models"
+)
+
+// ReportRepository aggregates activity across tables for admin reports
+type ReportRepository struct {
+	db DBTX
+}
+
+func NewReportRepository(db DBTX) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+// Series returns the totals of every interval (day, week or month) from from
+// to to. Intervals are cut at the range, so the first and last may count
+// fewer days. Payments count in the interval they completed in.
+func (r *ReportRepository) Series(ctx context.Context, from, to time.Time, interval string) ([]*models.ReportPoint, error) {
+	query := `
+		WITH buckets AS (
+			SELECT b::date AS period
+			FROM generate_series(date_trunc($3::text, $1::date), $2::date, ('1 ' || $3::text)::interval) AS b
+		), new_events AS (
+			SELECT date_trunc($3::text, created_at::date)::date AS period, COUNT(*) AS n
+			FROM events
+			WHERE created_at::date BETWEEN $1::date AND $2::date
+			GROUP BY 1
+		), new_creators AS (
+			SELECT date_trunc($3::text, created_at::date)::date AS period, COUNT(*) AS n
+			FROM creators
+			WHERE created_at::date BETWEEN $1::date AND $2::date
+			GROUP BY 1
+		), paid AS (
+			SELECT date_trunc($3::text, completed_at::date)::date AS period,
+				COUNT(DISTINCT event_id) AS postings, SUM(amount_cents) AS cents
+			FROM payments
+			WHERE status = 'completed' AND completed_at::date BETWEEN $1::date AND $2::date
+			GROUP BY 1
+		), visits AS (
+			SELECT date_trunc($3::text, day)::date AS period,
+				SUM(visits) AS visits, SUM(unique_visitors) AS unique_visitors
+			FROM visitor_daily_stats
+			WHERE day BETWEEN $1::date AND $2::date
+			GROUP BY 1
+		)
+		SELECT b.period,
+			COALESCE(e.n, 0),
+			COALESCE(c.n, 0),
+			COALESCE(p.postings, 0),
+			COALESCE(p.cents, 0),
+			COALESCE(v.visits, 0),
+			COALESCE(v.unique_visitors, 0)
+		FROM buckets b
+		LEFT JOIN new_events e ON e.period = b.period
+		LEFT JOIN new_creators c ON c.period = b.period
+		LEFT JOIN paid p ON p.period = b.period
+		LEFT JOIN visits v ON v.period = b.period
+		ORDER BY b.period
+	`
+	rows, err := r.db.Query(ctx, query, from, to, interval)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []*models.ReportPoint{}
+	for rows.Next() {
+		var period time.Time
+		var revenueCents int64
+		point := &models.ReportPoint{}
+		if err := rows.Scan(
+			&period, &point.NewEvents, &point.NewCreators, &point.PaidPostings,
+			&revenueCents, &point.Visitors, &point.UniqueVisitors,
+		); err != nil {
+			return nil, err
+		}
+		point.Period = period.Format("2006-01-02")
+		point.Revenue = float64(revenueCents) / 100
+		points = append(points, point)
+	}
+	return points, rows.Err()
+}
+
+// Funnel counts the creators who registered from from to to, and how many of
+// them have since posted an event and paid for one
+func (r *ReportRepository) Funnel(ctx context.Context, from, to time.Time) (*models.ConversionFunnel, error) {
+	funnel := &models.ConversionFunnel{}
+	query := `
+		SELECT COUNT(*),
+			COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM events e WHERE e.creator_id = c.id)),
+			COUNT(*) FILTER (WHERE EXISTS (
+				SELECT 1 FROM payments p WHERE p.creator_id = c.id AND p.status = 'completed'
+			))
+		FROM creators c
+		WHERE c.created_at::date BETWEEN $1::date AND $2::date
+	`
+	err := r.db.QueryRow(ctx, query, from, to).Scan(&funnel.Registered, &funnel.Posted, &funnel.Paid)
+	if err != nil {
+		return nil, err
+	}
+	return funnel, nil
+}
@@ -6,14 +6,44 @@ import (
 
 // Repositories holds all repository instances
 type Repositories struct {
-	Creator      *CreatorRepository
-	Event        *EventRepository
-	Payment      *PaymentRepository
-	Admin        *AdminRepository
-	Location     *LocationRepository
-	EventType    *EventTypeRepository
-	EntranceType *EntranceTypeRepository
-	Visitor      *VisitorRepository
+	Creator      CreatorStore
+	Event        EventStore
+	EventImage   EventImageStore
+	EventStats   EventStatsStore
+	Payment      PaymentStore
+	Report       ReportStore
+	Admin        AdminStore
+	Location     LocationStore
+	EventType    EventTypeStore
+	EntranceType EntranceTypeStore
+	Visitor      VisitorStore
+	AgentKey     AgentKeyStore
+	Idempotency  IdempotencyStore
+	Upload       UploadStore
+
+	// Tx runs calls to the stores above in a transaction
+	Tx Transactor
+}
+
+// New returns the repositories backed by db
+func New(db DBTX) *Repositories {
+	return &Repositories{
+		Creator:      NewCreatorRepository(db),
+		Event:        NewEventRepository(db),
+		EventImage:   NewEventImageRepository(db),
+		EventStats:   NewEventStatsRepository(db),
+		Payment:      NewPaymentRepository(db),
+		Report:       NewReportRepository(db),
+		Admin:        NewAdminRepository(db),
+		Location:     NewLocationRepository(db),
+		EventType:    NewEventTypeRepository(db),
+		EntranceType: NewEntranceTypeRepository(db),
+		Visitor:      NewVisitorRepository(db),
+		AgentKey:     NewAgentKeyRepository(db),
+		Idempotency:  NewIdempotencyRepository(db),
+		Upload:       NewUploadRepository(db),
+		Tx:           &pgTransactor{db: db},
+	}
 }
 
 // BaseRepository provides common database functionality
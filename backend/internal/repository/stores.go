@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+// The stores below are what services and handlers depend on. The pgx-backed
+// repositories in this package implement them against Postgres; the memory
+// package implements them in memory for tests.
+
+type CreatorStore interface {
+	Create(ctx context.Context, creator *models.Creator) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Creator, error)
+	GetByEmail(ctx context.Context, email string) (*models.Creator, error)
+	Update(ctx context.Context, creator *models.Creator) error
+	UpdateAdmin(ctx context.Context, creator *models.Creator) error
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+	EnsureDefaultCreator(ctx context.Context, creator *models.Creator) error
+	UpdateStatus(ctx context.Context, id uuid.UUID, isActive bool) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context, page, limit int) ([]*models.Creator, int, error)
+	Count(ctx context.Context) (total, active int, err error)
+	CreatorsBySource(ctx context.Context, from, to time.Time) ([]*models.CreatorSourceStats, error)
+}
+
+type EventStore interface {
+	Create(ctx context.Context, event *models.Event) error
+	CreateBatch(ctx context.Context, events []*models.Event) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Event, error)
+	// GetByIDForUpdate locks the event until the transaction it runs in ends
+	GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*models.Event, error)
+	GetByExternalID(ctx context.Context, creatorID uuid.UUID, externalID string) (*models.Event, error)
+	Update(ctx context.Context, event *models.Event) error
+	UpdateImageURL(ctx context.Context, id uuid.UUID, imageURL string) error
+	ListImageRefs(ctx context.Context) (map[uuid.UUID]string, error)
+	ReplaceImageRef(ctx context.Context, id uuid.UUID, from, to string) (bool, error)
+	UpdatePaymentStatus(ctx context.Context, id uuid.UUID, isPaid, isPublished bool) error
+	UpdateAdminFields(ctx context.Context, id uuid.UUID, imageURL *string, isPaid, isPublished *bool) error
+	UpdateCreator(ctx context.Context, id, creatorID uuid.UUID) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context, filter models.EventListFilter) ([]*models.Event, int, error)
+	Count(ctx context.Context) (total, published, upcoming int, err error)
+	GetRecent(ctx context.Context, limit int) ([]*models.Event, error)
+}
+
+type EventImageStore interface {
+	ListByEvent(ctx context.Context, eventID uuid.UUID) ([]*models.EventImage, error)
+	GetByID(ctx context.Context, eventID, id uuid.UUID) (*models.EventImage, error)
+	Contains(ctx context.Context, eventID uuid.UUID, imageRef string) (bool, error)
+	Add(ctx context.Context, image *models.EventImage, limit int) (added bool, err error)
+	UpdateMeta(ctx context.Context, image *models.EventImage) error
+	SetCover(ctx context.Context, eventID, id uuid.UUID) error
+	SyncCover(ctx context.Context, eventID uuid.UUID, imageRef string) (replaced *string, err error)
+	Reorder(ctx context.Context, eventID uuid.UUID, ids []uuid.UUID) (reordered bool, err error)
+	Delete(ctx context.Context, eventID, id uuid.UUID) (*models.EventImage, error)
+}
+
+type EventStatsStore interface {
+	Record(ctx context.Context, eventID uuid.UUID, action, visitorHash string) (recorded bool, err error)
+	DeleteOldInteractions(ctx context.Context) (int64, error)
+	EventDaily(ctx context.Context, eventID uuid.UUID, from, to time.Time) ([]*models.EventStatsDay, error)
+	CreatorDaily(ctx context.Context, creatorID uuid.UUID, from, to time.Time) ([]*models.EventStatsDay, error)
+	CreatorEventTotals(ctx context.Context, creatorID uuid.UUID, from, to time.Time) ([]*models.EventStatsSummary, error)
+}
+
+type PaymentStore interface {
+	Create(ctx context.Context, payment *models.Payment) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Payment, error)
+	GetByStripeSessionID(ctx context.Context, sessionID string) (*models.Payment, error)
+	UpdateStatus(ctx context.Context, id uuid.UUID, status, paymentIntentID string) error
+	ListByCreator(ctx context.Context, creatorID uuid.UUID, page, limit int) ([]*models.Payment, int, error)
+	ListAll(ctx context.Context, page, limit int, status string) ([]*models.Payment, int, error)
+	GetStats(ctx context.Context) (count int, revenue float64, err error)
+	GetRecent(ctx context.Context, limit int) ([]*models.Payment, error)
+}
+
+type ReportStore interface {
+	Series(ctx context.Context, from, to time.Time, interval string) ([]*models.ReportPoint, error)
+	Funnel(ctx context.Context, from, to time.Time) (*models.ConversionFunnel, error)
+}
+
+type AdminStore interface {
+	Create(ctx context.Context, admin *models.Admin) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Admin, error)
+	GetByEmail(ctx context.Context, email string) (*models.Admin, error)
+	EnsureDefaultAdmin(ctx context.Context, email, passwordHash string) error
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+	List(ctx context.Context) ([]*models.Admin, error)
+}
+
+type LocationStore interface {
+	List(ctx context.Context, onlyActive bool) ([]*models.Location, error)
+	Create(ctx context.Context, loc *models.Location) error
+	Update(ctx context.Context, id int, name string, isActive bool) error
+}
+
+type EventTypeStore interface {
+	List(ctx context.Context, onlyActive bool) ([]*models.EventType, error)
+	Create(ctx context.Context, et *models.EventType) error
+	Update(ctx context.Context, id int, name string, isActive bool) error
+}
+
+type EntranceTypeStore interface {
+	List(ctx context.Context, onlyActive bool) ([]*models.EntranceType, error)
+}
+
+type VisitorStore interface {
+	DailySalt(ctx context.Context, candidate []byte) ([]byte, error)
+	Create(ctx context.Context, visitor *models.Visitor) error
+	RollUp(ctx context.Context, days int) (int, error)
+	DeleteBefore(ctx context.Context, days int) (int64, error)
+	DeleteOldSalts(ctx context.Context) (int64, error)
+	GetStats(ctx context.Context) (*models.VisitorStats, error)
+	GetToday(ctx context.Context) (visits, uniqueVisitors, botVisits int, err error)
+	BotTotals(ctx context.Context, from, to time.Time) (visits, uniqueVisitors int, err error)
+	GetTotalCount(ctx context.Context) (int, error)
+	ListSegments(ctx context.Context, days, limit int) ([]*models.VisitorSegment, error)
+	SegmentTotals(ctx context.Context, dimension string, from, to time.Time) ([]*models.VisitorSegment, error)
+}
+
+type AgentKeyStore interface {
+	Create(ctx context.Context, key *models.AgentAPIKey) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.AgentAPIKey, error)
+	GetByHash(ctx context.Context, keyHash string) (*models.AgentAPIKey, error)
+	List(ctx context.Context) ([]*models.AgentAPIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	TouchLastUsed(ctx context.Context, id uuid.UUID) error
+}
+
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, record *models.IdempotencyRecord, ttl, lease time.Duration) (bool, *models.IdempotencyRecord, error)
+	Get(ctx context.Context, creatorID uuid.UUID, key string) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, id uuid.UUID, statusCode int, body []byte, contentType string) error
+	Release(ctx context.Context, id uuid.UUID) error
+}
+
+type UploadStore interface {
+	Create(ctx context.Context, upload *models.Upload) error
+	GetByKey(ctx context.Context, storageKey string) (*models.Upload, error)
+	Attach(ctx context.Context, storageKey string, eventID uuid.UUID) error
+	Detach(ctx context.Context, storageKey string) error
+	Reattach(ctx context.Context) (int64, error)
+	ListOrphans(ctx context.Context, cutoff time.Time, limit int) ([]*models.Upload, error)
+	Delete(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+var (
+	_ CreatorStore      = (*CreatorRepository)(nil)
+	_ EventStore        = (*EventRepository)(nil)
+	_ EventImageStore   = (*EventImageRepository)(nil)
+	_ EventStatsStore   = (*EventStatsRepository)(nil)
+	_ PaymentStore      = (*PaymentRepository)(nil)
+	_ ReportStore       = (*ReportRepository)(nil)
+	_ AdminStore        = (*AdminRepository)(nil)
+	_ LocationStore     = (*LocationRepository)(nil)
+	_ EventTypeStore    = (*EventTypeRepository)(nil)
+	_ EntranceTypeStore = (*EntranceTypeRepository)(nil)
+	_ VisitorStore      = (*VisitorRepository)(nil)
+	_ AgentKeyStore     = (*AgentKeyRepository)(nil)
+	_ IdempotencyStore  = (*IdempotencyRepository)(nil)
+	_ UploadStore       = (*UploadRepository)(nil)
+)
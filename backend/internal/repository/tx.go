@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DBTX is what repositories run their queries on: the pool, or a
+// transaction. Begin on a transaction starts a savepoint, so a repository
+// method with a transaction of its own nests inside one it is called in.
+type DBTX interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Transactor runs several repository calls as one unit of work
+type Transactor interface {
+	// InTx calls fn with repositories whose calls all run in one
+	// transaction. The transaction commits when fn returns nil and rolls
+	// back when it returns an error or panics. Calls made through any other
+	// Repositories are not part of it.
+	InTx(ctx context.Context, fn func(tx *Repositories) error) error
+}
+
+type pgTransactor struct {
+	db DBTX
+}
+
+func (t *pgTransactor) InTx(ctx context.Context, fn func(tx *Repositories) error) error {
+	tx, err := t.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(New(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
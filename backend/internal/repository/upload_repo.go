@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+type UploadRepository struct {
+	db DBTX
+}
+
+func NewUploadRepository(db DBTX) *UploadRepository {
+	return &UploadRepository{db: db}
+}
+
+const uploadColumns = `id, storage_key, source, creator_id, agent_key_id, event_id, size_bytes, attached_at, detached_at, created_at`
+
+func (r *UploadRepository) Create(ctx context.Context, upload *models.Upload) error {
+	query := `
+		INSERT INTO uploads (storage_key, source, creator_id, agent_key_id, size_bytes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(ctx, query,
+		upload.StorageKey,
+		upload.Source,
+		upload.CreatorID,
+		upload.AgentKeyID,
+		upload.SizeBytes,
+	).Scan(&upload.ID, &upload.CreatedAt)
+}
+
+func (r *UploadRepository) GetByKey(ctx context.Context, storageKey string) (*models.Upload, error) {
+	upload := &models.Upload{}
+	query := `SELECT ` + uploadColumns + ` FROM uploads WHERE storage_key = $1`
+	err := r.db.QueryRow(ctx, query, storageKey).Scan(
+		&upload.ID, &upload.StorageKey, &upload.Source, &upload.CreatorID, &upload.AgentKeyID,
+		&upload.EventID, &upload.SizeBytes, &upload.AttachedAt, &upload.DetachedAt, &upload.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+// Attach records that an event uses the upload
+func (r *UploadRepository) Attach(ctx context.Context, storageKey string, eventID uuid.UUID) error {
+	query := `
+		UPDATE uploads
+		SET event_id = $2, attached_at = NOW(), detached_at = NULL
+		WHERE storage_key = $1
+	`
+	_, err := r.db.Exec(ctx, query, storageKey, eventID)
+	return err
+}
+
+// Detach marks an upload as no longer used, starting its grace period. Keys
+// stored before the registry existed are registered on the way.
+func (r *UploadRepository) Detach(ctx context.Context, storageKey string) error {
+	query := `
+		INSERT INTO uploads (storage_key, source, detached_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (storage_key) DO UPDATE SET event_id = NULL, detached_at = NOW()
+	`
+	_, err := r.db.Exec(ctx, query, storageKey, models.UploadSourceLegacy)
+	return err
+}
+
+// Reattach repairs the registry for uploads an event or gallery references
+// but which are not recorded as attached to it, so they are never swept
+func (r *UploadRepository) Reattach(ctx context.Context) (int64, error) {
+	query := `
+		UPDATE uploads u
+		SET event_id = refs.event_id, attached_at = NOW(), detached_at = NULL
+		FROM (
+			SELECT id AS event_id, image_url FROM events
+			UNION
+			SELECT event_id, image_url FROM event_images
+		) refs
+		WHERE refs.image_url = u.storage_key AND u.event_id IS DISTINCT FROM refs.event_id
+	`
+	tag, err := r.db.Exec(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ListOrphans returns unattached uploads whose grace period ended before
+// cutoff. Keys still referenced by an event or a gallery are never returned,
+// whatever the registry says.
+func (r *UploadRepository) ListOrphans(ctx context.Context, cutoff time.Time, limit int) ([]*models.Upload, error) {
+	query := `
+		SELECT ` + uploadColumns + `
+		FROM uploads u
+		WHERE u.event_id IS NULL
+		  AND COALESCE(u.detached_at, u.created_at) < $1
+		  AND NOT EXISTS (SELECT 1 FROM events e WHERE e.image_url = u.storage_key)
+		  AND NOT EXISTS (SELECT 1 FROM event_images i WHERE i.image_url = u.storage_key)
+		ORDER BY u.created_at
+		LIMIT $2
+	`
+	rows, err := r.db.Query(ctx, query, cutoff, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uploads []*models.Upload
+	for rows.Next() {
+		upload := &models.Upload{}
+		if err := rows.Scan(
+			&upload.ID, &upload.StorageKey, &upload.Source, &upload.CreatorID, &upload.AgentKeyID,
+			&upload.EventID, &upload.SizeBytes, &upload.AttachedAt, &upload.DetachedAt, &upload.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads, rows.Err()
+}
+
+// Delete removes an upload from the registry if it is still unattached and
+// unreferenced. The sweeper deletes the row before the objects, so an upload
+// attached in the meantime is kept; deleted reports whether the row went.
+func (r *UploadRepository) Delete(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `
+		DELETE FROM uploads u
+		WHERE u.id = $1
+		  AND u.event_id IS NULL
+		  AND NOT EXISTS (SELECT 1 FROM events e WHERE e.image_url = u.storage_key)
+		  AND NOT EXISTS (SELECT 1 FROM event_images i WHERE i.image_url = u.storage_key)
+	`
+	tag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
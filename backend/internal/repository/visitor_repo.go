@@ -4,38 +4,243 @@ import (
 	"context"
 	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/net1io/zenbali/internal/models"
 )
 
 type VisitorRepository struct {
-	pool *pgxpool.Pool
+	db DBTX
 }
 
-func NewVisitorRepository(pool *pgxpool.Pool) *VisitorRepository {
-	return &VisitorRepository{pool: pool}
+func NewVisitorRepository(db DBTX) *VisitorRepository {
+	return &VisitorRepository{db: db}
 }
 
+// visitorSegmentsQuery lists each visit by a person once per dimension, and
+// each visit by a bot once, under the bot's name
+const visitorSegmentsQuery = `
+	SELECT v.visited_at::date, s.dimension, s.value, COUNT(*), COUNT(DISTINCT v.visitor_hash)
+	FROM visitors v
+	CROSS JOIN LATERAL (
+		SELECT * FROM (VALUES
+			('country', COALESCE(NULLIF(v.country, ''), 'Unknown')),
+			('browser', COALESCE(v.browser, 'Unknown')),
+			('os', COALESCE(v.os, 'Unknown')),
+			('device', COALESCE(v.device, 'Unknown')),
+			('source', COALESCE(v.source, 'unknown'))
+		) AS person(dimension, value)
+		WHERE NOT v.is_bot
+		UNION ALL
+		SELECT 'bot', COALESCE(v.browser, 'Unknown')
+		WHERE v.is_bot
+	) AS s(dimension, value)
+`
+
+// DailySalt returns today's salt, storing candidate as it if there is none yet
+func (r *VisitorRepository) DailySalt(ctx context.Context, candidate []byte) ([]byte, error) {
+	var salt []byte
+	query := `
+		INSERT INTO visitor_salts (day, salt)
+		VALUES (CURRENT_DATE, $1)
+		ON CONFLICT (day) DO UPDATE SET day = EXCLUDED.day
+		RETURNING salt
+	`
+	err := r.db.QueryRow(ctx, query, candidate).Scan(&salt)
+	return salt, err
+}
+
+// Create records a visit and counts it in today's rollups. A visitor's first
+// visit of the day counts as unique in every segment; RollUp corrects that
+// for visitors whose country or device changed during the day. Bot visits
+// are only counted in the bot columns and segments.
 func (r *VisitorRepository) Create(ctx context.Context, visitor *models.Visitor) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
-		INSERT INTO visitors (ip_address, user_agent, country, city)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO visitors (
+			visitor_hash, browser, os, device, country, city, is_bot,
+			source, referrer, landing_path, utm_source, utm_medium, utm_campaign, utm_term, utm_content
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''),
+			NULLIF($11, ''), NULLIF($12, ''), NULLIF($13, ''), NULLIF($14, ''), NULLIF($15, ''))
 		RETURNING id, visited_at
 	`
-	return r.pool.QueryRow(ctx, query,
-		visitor.IPAddress,
-		visitor.UserAgent,
+	if err := tx.QueryRow(ctx, query,
+		visitor.VisitorHash,
+		visitor.Browser,
+		visitor.OS,
+		visitor.Device,
 		visitor.Country,
 		visitor.City,
-	).Scan(&visitor.ID, &visitor.VisitedAt)
+		visitor.IsBot,
+		visitor.Source,
+		visitor.Referrer,
+		visitor.LandingPath,
+		visitor.UTMSource,
+		visitor.UTMMedium,
+		visitor.UTMCampaign,
+		visitor.UTMTerm,
+		visitor.UTMContent,
+	).Scan(&visitor.ID, &visitor.VisitedAt); err != nil {
+		return err
+	}
+
+	var unique int
+	query = `
+		SELECT CASE WHEN EXISTS (
+			SELECT 1 FROM visitors
+			WHERE visitor_hash = $1 AND visited_at >= CURRENT_DATE AND id <> $2
+		) THEN 0 ELSE 1 END
+	`
+	if err := tx.QueryRow(ctx, query, visitor.VisitorHash, visitor.ID).Scan(&unique); err != nil {
+		return err
+	}
+
+	if visitor.IsBot {
+		query = `
+			INSERT INTO visitor_daily_stats (day, bot_visits, bot_unique_visitors)
+			VALUES (CURRENT_DATE, 1, $1)
+			ON CONFLICT (day) DO UPDATE SET
+				bot_visits = visitor_daily_stats.bot_visits + 1,
+				bot_unique_visitors = visitor_daily_stats.bot_unique_visitors + EXCLUDED.bot_unique_visitors,
+				updated_at = NOW()
+		`
+		if _, err := tx.Exec(ctx, query, unique); err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO visitor_daily_segments (day, dimension, value, visits, unique_visitors)
+			VALUES (CURRENT_DATE, 'bot', $1, 1, $2)
+			ON CONFLICT (day, dimension, value) DO UPDATE SET
+				visits = visitor_daily_segments.visits + 1,
+				unique_visitors = visitor_daily_segments.unique_visitors + EXCLUDED.unique_visitors
+		`
+		if _, err := tx.Exec(ctx, query, segmentValue(visitor.Browser), unique); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	}
+
+	query = `
+		INSERT INTO visitor_daily_stats (day, visits, unique_visitors)
+		VALUES (CURRENT_DATE, 1, $1)
+		ON CONFLICT (day) DO UPDATE SET
+			visits = visitor_daily_stats.visits + 1,
+			unique_visitors = visitor_daily_stats.unique_visitors + EXCLUDED.unique_visitors,
+			updated_at = NOW()
+	`
+	if _, err := tx.Exec(ctx, query, unique); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO visitor_daily_segments (day, dimension, value, visits, unique_visitors)
+		VALUES
+			(CURRENT_DATE, 'country', $1, 1, $5),
+			(CURRENT_DATE, 'browser', $2, 1, $5),
+			(CURRENT_DATE, 'os', $3, 1, $5),
+			(CURRENT_DATE, 'device', $4, 1, $5),
+			(CURRENT_DATE, 'source', $6, 1, $5)
+		ON CONFLICT (day, dimension, value) DO UPDATE SET
+			visits = visitor_daily_segments.visits + 1,
+			unique_visitors = visitor_daily_segments.unique_visitors + EXCLUDED.unique_visitors
+	`
+	if _, err := tx.Exec(ctx, query,
+		segmentValue(visitor.Country),
+		segmentValue(visitor.Browser),
+		segmentValue(visitor.OS),
+		segmentValue(visitor.Device),
+		unique,
+		sourceValue(visitor.Source),
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RollUp recomputes the rollups of the last days days from the raw visits and
+// returns how many days it rewrote
+func (r *VisitorRepository) RollUp(ctx context.Context, days int) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO visitor_daily_stats (day, visits, unique_visitors, bot_visits, bot_unique_visitors)
+		SELECT visited_at::date,
+			COUNT(*) FILTER (WHERE NOT is_bot),
+			COUNT(DISTINCT visitor_hash) FILTER (WHERE NOT is_bot),
+			COUNT(*) FILTER (WHERE is_bot),
+			COUNT(DISTINCT visitor_hash) FILTER (WHERE is_bot)
+		FROM visitors
+		WHERE visited_at >= CURRENT_DATE - $1::int
+		GROUP BY visited_at::date
+		ON CONFLICT (day) DO UPDATE SET
+			visits = EXCLUDED.visits,
+			unique_visitors = EXCLUDED.unique_visitors,
+			bot_visits = EXCLUDED.bot_visits,
+			bot_unique_visitors = EXCLUDED.bot_unique_visitors,
+			updated_at = NOW()
+	`
+	tag, err := tx.Exec(ctx, query, days)
+	if err != nil {
+		return 0, err
+	}
+
+	query = `
+		DELETE FROM visitor_daily_segments
+		WHERE day IN (SELECT DISTINCT visited_at::date FROM visitors WHERE visited_at >= CURRENT_DATE - $1::int)
+	`
+	if _, err := tx.Exec(ctx, query, days); err != nil {
+		return 0, err
+	}
+
+	query = `
+		INSERT INTO visitor_daily_segments (day, dimension, value, visits, unique_visitors)
+	` + visitorSegmentsQuery + `
+		WHERE v.visited_at >= CURRENT_DATE - $1::int
+		GROUP BY 1, 2, 3
+	`
+	if _, err := tx.Exec(ctx, query, days); err != nil {
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), tx.Commit(ctx)
+}
+
+// DeleteBefore deletes the raw visits of days more than days days ago. Whole
+// days go at once, so a rollup never sees half a day.
+func (r *VisitorRepository) DeleteBefore(ctx context.Context, days int) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM visitors WHERE visited_at < CURRENT_DATE - $1::int`, days)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DeleteOldSalts deletes the salts of past days, after which their visitor
+// hashes can no longer be matched to an address
+func (r *VisitorRepository) DeleteOldSalts(ctx context.Context) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM visitor_salts WHERE day < CURRENT_DATE`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
 }
 
 func (r *VisitorRepository) GetStats(ctx context.Context) (*models.VisitorStats, error) {
 	stats := &models.VisitorStats{}
 
 	// Get total count
-	countQuery := `SELECT COUNT(*) FROM visitors`
-	if err := r.pool.QueryRow(ctx, countQuery).Scan(&stats.TotalVisitors); err != nil {
+	var err error
+	if stats.TotalVisitors, err = r.GetTotalCount(ctx); err != nil {
 		return nil, err
 	}
 
@@ -43,10 +248,11 @@ func (r *VisitorRepository) GetStats(ctx context.Context) (*models.VisitorStats,
 	lastQuery := `
 		SELECT visited_at, COALESCE(city, ''), COALESCE(country, '')
 		FROM visitors
+		WHERE NOT is_bot
 		ORDER BY visited_at DESC
 		LIMIT 1
 	`
-	err := r.pool.QueryRow(ctx, lastQuery).Scan(
+	err = r.db.QueryRow(ctx, lastQuery).Scan(
 		&stats.LastVisitorDate,
 		&stats.LastVisitorCity,
 		&stats.LastVisitorCountry,
@@ -60,16 +266,107 @@ func (r *VisitorRepository) GetStats(ctx context.Context) (*models.VisitorStats,
 	return stats, nil
 }
 
-func (r *VisitorRepository) GetTodayCount(ctx context.Context) (int, error) {
-	var count int
-	query := `SELECT COUNT(*) FROM visitors WHERE DATE(visited_at) = CURRENT_DATE`
-	err := r.pool.QueryRow(ctx, query).Scan(&count)
-	return count, err
+// GetToday returns today's visits and unique visitors by people, and visits
+// by bots
+func (r *VisitorRepository) GetToday(ctx context.Context) (visits, uniqueVisitors, botVisits int, err error) {
+	query := `
+		SELECT COALESCE(SUM(visits), 0), COALESCE(SUM(unique_visitors), 0), COALESCE(SUM(bot_visits), 0)
+		FROM visitor_daily_stats
+		WHERE day = CURRENT_DATE
+	`
+	err = r.db.QueryRow(ctx, query).Scan(&visits, &uniqueVisitors, &botVisits)
+	return visits, uniqueVisitors, botVisits, err
+}
+
+// BotTotals returns the visits and unique visitors by bots from from to to.
+// Unique visitors are summed per day.
+func (r *VisitorRepository) BotTotals(ctx context.Context, from, to time.Time) (visits, uniqueVisitors int, err error) {
+	query := `
+		SELECT COALESCE(SUM(bot_visits), 0), COALESCE(SUM(bot_unique_visitors), 0)
+		FROM visitor_daily_stats
+		WHERE day BETWEEN $1::date AND $2::date
+	`
+	err = r.db.QueryRow(ctx, query, from, to).Scan(&visits, &uniqueVisitors)
+	return visits, uniqueVisitors, err
 }
 
 func (r *VisitorRepository) GetTotalCount(ctx context.Context) (int, error) {
 	var count int
-	query := `SELECT COUNT(*) FROM visitors`
-	err := r.pool.QueryRow(ctx, query).Scan(&count)
+	query := `SELECT COALESCE(SUM(visits), 0) FROM visitor_daily_stats`
+	err := r.db.QueryRow(ctx, query).Scan(&count)
 	return count, err
 }
+
+// ListSegments returns the top limit values of each dimension over the last
+// days days. Unique visitors are summed per day.
+func (r *VisitorRepository) ListSegments(ctx context.Context, days, limit int) ([]*models.VisitorSegment, error) {
+	query := `
+		SELECT dimension, value, visits, unique_visitors
+		FROM (
+			SELECT dimension, value, SUM(visits) AS visits, SUM(unique_visitors) AS unique_visitors,
+				ROW_NUMBER() OVER (PARTITION BY dimension ORDER BY SUM(visits) DESC, value) AS rank
+			FROM visitor_daily_segments
+			WHERE day > CURRENT_DATE - $1::int
+			GROUP BY dimension, value
+		) ranked
+		WHERE rank <= $2
+		ORDER BY dimension, visits DESC, value
+	`
+	rows, err := r.db.Query(ctx, query, days, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	segments := []*models.VisitorSegment{}
+	for rows.Next() {
+		segment := &models.VisitorSegment{}
+		if err := rows.Scan(&segment.Dimension, &segment.Value, &segment.Visits, &segment.UniqueVisitors); err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+	return segments, rows.Err()
+}
+
+// SegmentTotals returns the visits and unique visitors of each value of a
+// dimension from from to to, most visits first. Unique visitors are summed per
+// day.
+func (r *VisitorRepository) SegmentTotals(ctx context.Context, dimension string, from, to time.Time) ([]*models.VisitorSegment, error) {
+	query := `
+		SELECT dimension, value, SUM(visits) AS visits, SUM(unique_visitors)
+		FROM visitor_daily_segments
+		WHERE dimension = $1 AND day BETWEEN $2::date AND $3::date
+		GROUP BY dimension, value
+		ORDER BY visits DESC, value
+	`
+	rows, err := r.db.Query(ctx, query, dimension, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	segments := []*models.VisitorSegment{}
+	for rows.Next() {
+		segment := &models.VisitorSegment{}
+		if err := rows.Scan(&segment.Dimension, &segment.Value, &segment.Visits, &segment.UniqueVisitors); err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+	return segments, rows.Err()
+}
+
+func segmentValue(value string) string {
+	if value == "" {
+		return "Unknown"
+	}
+	return value
+}
+
+func sourceValue(value string) string {
+	if value == "" {
+		return models.AttributionUnknown
+	}
+	return value
+}
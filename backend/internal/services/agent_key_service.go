@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/config"
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/repository"
+)
+
+var (
+	ErrAgentKeyNotFound  = newError(KindNotFound, "AGENT_KEY_NOT_FOUND", "Agent key not found")
+	ErrAgentKeyInvalid   = newError(KindUnauthorized, "AGENT_KEY_INVALID", "Invalid agent token")
+	ErrAgentKeyInactive  = newError(KindUnauthorized, "AGENT_KEY_INACTIVE", "Agent token is revoked or expired")
+	ErrInvalidAgentScope = newError(KindInvalid, "INVALID_AGENT_SCOPE", "Invalid scopes. Allowed: "+strings.Join(models.AgentScopes, ", "))
+	// ErrCreatorNotFound is a creator named in a request that does not exist
+	ErrCreatorNotFound = newError(KindInvalid, "CREATOR_NOT_FOUND", "Creator not found")
+)
+
+const agentKeyPrefix = "zbk_"
+
+type AgentKeyService struct {
+	repos  *repository.Repositories
+	config config.AgentConfig
+}
+
+func NewAgentKeyService(repos *repository.Repositories, cfg config.AgentConfig) *AgentKeyService {
+	return &AgentKeyService{repos: repos, config: cfg}
+}
+
+// Issue creates a new key bound to a creator and returns it together with the
+// plaintext secret. Only the SHA-256 hash of the secret is persisted.
+func (s *AgentKeyService) Issue(ctx context.Context, req *models.AgentAPIKeyCreateRequest, adminID uuid.UUID) (*models.AgentAPIKey, string, error) {
+	creatorID, err := uuid.Parse(strings.TrimSpace(req.CreatorID))
+	if err != nil {
+		return nil, "", ErrCreatorNotFound
+	}
+	creator, err := s.repos.Creator.GetByID(ctx, creatorID)
+	if err != nil {
+		return nil, "", err
+	}
+	if creator == nil {
+		return nil, "", ErrCreatorNotFound
+	}
+
+	scopes, err := normalizeAgentScopes(req.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	plaintext, prefix, err := generateAgentKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &models.AgentAPIKey{
+		Name:      strings.TrimSpace(req.Name),
+		KeyPrefix: prefix,
+		KeyHash:   hashAgentKey(plaintext),
+		CreatorID: creator.ID,
+		Scopes:    scopes,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+	if adminID != uuid.Nil {
+		key.CreatedBy = &adminID
+	}
+
+	if err := s.repos.AgentKey.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	key, err = s.repos.AgentKey.GetByID(ctx, key.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	return key, plaintext, nil
+}
+
+// Authenticate resolves a presented token to an active key and its creator.
+// The legacy shared AGENT_API_TOKEN is still honoured when configured so that
+// existing agents keep working while they migrate to issued keys.
+func (s *AgentKeyService) Authenticate(ctx context.Context, token string) (*models.AgentAPIKey, *models.Creator, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, nil, ErrAgentKeyInvalid
+	}
+
+	if !strings.HasPrefix(token, agentKeyPrefix) {
+		return s.authenticateLegacy(ctx, token)
+	}
+
+	key, err := s.repos.AgentKey.GetByHash(ctx, hashAgentKey(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	if key == nil {
+		return nil, nil, ErrAgentKeyInvalid
+	}
+	if !key.IsActive(time.Now()) {
+		return nil, nil, ErrAgentKeyInactive
+	}
+
+	creator, err := s.repos.Creator.GetByID(ctx, key.CreatorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if creator == nil || !creator.IsActive {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	if err := s.repos.AgentKey.TouchLastUsed(ctx, key.ID); err != nil {
+		log.Printf("WARN failed to record agent key usage %s: %v", key.ID, err)
+	}
+
+	return key, creator, nil
+}
+
+func (s *AgentKeyService) authenticateLegacy(ctx context.Context, token string) (*models.AgentAPIKey, *models.Creator, error) {
+	expected := strings.TrimSpace(s.config.Token)
+	if expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		return nil, nil, ErrAgentKeyInvalid
+	}
+
+	creator, err := s.repos.Creator.GetByEmail(ctx, s.config.CreatorEmail)
+	if err != nil {
+		return nil, nil, err
+	}
+	if creator == nil || !creator.IsActive {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	key := &models.AgentAPIKey{
+		Name:         "legacy",
+		CreatorID:    creator.ID,
+		Scopes:       models.AgentScopes,
+		CreatorName:  creator.Name,
+		CreatorEmail: creator.Email,
+	}
+	return key, creator, nil
+}
+
+func (s *AgentKeyService) List(ctx context.Context) ([]*models.AgentAPIKey, error) {
+	return s.repos.AgentKey.List(ctx)
+}
+
+func (s *AgentKeyService) Revoke(ctx context.Context, id uuid.UUID) (*models.AgentAPIKey, error) {
+	key, err := s.repos.AgentKey.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrAgentKeyNotFound
+	}
+
+	if err := s.repos.AgentKey.Revoke(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repos.AgentKey.GetByID(ctx, id)
+}
+
+func normalizeAgentScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrInvalidAgentScope
+	}
+
+	seen := make(map[string]bool, len(scopes))
+	var normalized []string
+	for _, raw := range scopes {
+		scope := strings.ToLower(strings.TrimSpace(raw))
+		valid := false
+		for _, allowed := range models.AgentScopes {
+			if scope == allowed {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, ErrInvalidAgentScope
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
+
+func generateAgentKey() (plaintext, prefix string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(buf)
+	plaintext = agentKeyPrefix + secret
+	return plaintext, plaintext[:len(agentKeyPrefix)+8], nil
+}
+
+func hashAgentKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
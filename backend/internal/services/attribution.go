@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/net1io/zenbali/internal/models"
+)
+
+// Column sizes of the attribution fields
+const (
+	maxAttributionURLLength = 512
+	maxAttributionTagLength = 100
+)
+
+// referrerSources names the sites traffic is most often shared from. Keys
+// are registrable domains; subdomains such as l.instagram.com match too.
+var referrerSources = map[string]string{
+	"instagram.com":  "instagram",
+	"facebook.com":   "facebook",
+	"fb.com":         "facebook",
+	"fb.me":          "facebook",
+	"whatsapp.com":   "whatsapp",
+	"wa.me":          "whatsapp",
+	"t.co":           "twitter",
+	"twitter.com":    "twitter",
+	"x.com":          "twitter",
+	"tiktok.com":     "tiktok",
+	"youtube.com":    "youtube",
+	"linkedin.com":   "linkedin",
+	"lnkd.in":        "linkedin",
+	"bing.com":       "bing",
+	"duckduckgo.com": "duckduckgo",
+}
+
+// SourceReport breaks visitors, signups and paid postings from from to to,
+// inclusive, down by source
+func (s *VisitorService) SourceReport(ctx context.Context, from, to time.Time) (*models.SourceReport, error) {
+	if err := validStatsRange(from, to); err != nil {
+		return nil, err
+	}
+
+	visitors, err := s.repos.Visitor.SegmentTotals(ctx, models.VisitorDimensionSource, from, to)
+	if err != nil {
+		return nil, err
+	}
+	creators, err := s.repos.Creator.CreatorsBySource(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SourceReport{
+		From:     from.Format("2006-01-02"),
+		To:       to.Format("2006-01-02"),
+		Visitors: visitors,
+		Creators: creators,
+	}, nil
+}
+
+// normalizeAttribution cleans what the client reported and derives Source.
+// Referrers lose their query and fragment, which can carry personal data, and
+// the landing page keeps only its path. siteHost is our own host, whose
+// referrals are internal navigation.
+func normalizeAttribution(a models.Attribution, siteHost string) models.Attribution {
+	out := models.Attribution{
+		Referrer:    cleanReferrer(a.Referrer),
+		LandingPath: cleanLandingPath(a.LandingPath),
+		UTMSource:   cleanTag(a.UTMSource),
+		UTMMedium:   cleanTag(a.UTMMedium),
+		UTMCampaign: cleanTag(a.UTMCampaign),
+		UTMTerm:     cleanTag(a.UTMTerm),
+		UTMContent:  cleanTag(a.UTMContent),
+	}
+	out.Source = attributionSource(out, siteHost)
+	return out
+}
+
+// attributionSource is the UTM source if tagged, else the referring site
+func attributionSource(a models.Attribution, siteHost string) string {
+	if a.UTMSource != "" {
+		return strings.ToLower(a.UTMSource)
+	}
+	if a.Referrer == "" {
+		return models.AttributionDirect
+	}
+
+	ref, err := url.Parse(a.Referrer)
+	if err != nil {
+		return models.AttributionDirect
+	}
+	host := strings.TrimPrefix(strings.ToLower(ref.Hostname()), "www.")
+	if host == "" {
+		return models.AttributionDirect
+	}
+	if siteHost != "" && host == strings.TrimPrefix(strings.ToLower(siteHost), "www.") {
+		return models.AttributionInternal
+	}
+
+	for domain, source := range referrerSources {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return source
+		}
+	}
+	// Google has a domain per country
+	if strings.HasPrefix(host, "google.") || strings.Contains(host, ".google.") {
+		return "google"
+	}
+	return truncateRunes(host, maxAttributionTagLength)
+}
+
+func cleanReferrer(value string) string {
+	ref, err := url.Parse(strings.TrimSpace(value))
+	if err != nil || (ref.Scheme != "http" && ref.Scheme != "https") || ref.Host == "" {
+		return ""
+	}
+	ref.User = nil
+	ref.RawQuery = ""
+	ref.Fragment = ""
+	return truncateRunes(ref.String(), maxAttributionURLLength)
+}
+
+func cleanLandingPath(value string) string {
+	page, err := url.Parse(strings.TrimSpace(value))
+	if err != nil || !strings.HasPrefix(page.Path, "/") {
+		return ""
+	}
+	return truncateRunes(page.Path, maxAttributionURLLength)
+}
+
+func cleanTag(value string) string {
+	return truncateRunes(strings.TrimSpace(value), maxAttributionTagLength)
+}
+
+func truncateRunes(value string, max int) string {
+	if utf8.RuneCountInString(value) <= max {
+		return value
+	}
+	return string([]rune(value)[:max])
+}
+
+// siteHostOf returns the host of the site's base URL
+func siteHostOf(baseURL string) string {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+	return base.Hostname()
+}
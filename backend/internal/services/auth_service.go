@@ -14,20 +14,27 @@ import (
 )
 
 var (
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrAccountDisabled    = errors.New("account is disabled")
-	ErrEmailExists        = errors.New("email already registered")
+	ErrInvalidCredentials = newError(KindUnauthorized, "INVALID_CREDENTIALS", "Invalid email or password")
+	ErrAccountDisabled    = newError(KindForbidden, "ACCOUNT_DISABLED", "Account is disabled")
+	ErrEmailExists        = newError(KindConflict, "EMAIL_EXISTS", "Email already registered")
+	ErrAdminNotFound      = newError(KindNotFound, "ADMIN_NOT_FOUND", "Admin not found")
+	ErrPasswordTooShort   = newError(KindInvalid, "PASSWORD_TOO_SHORT", "Password must be at least 8 characters")
 )
 
+// MinPasswordLength is the shortest password an account may have
+const MinPasswordLength = 8
+
 type AuthService struct {
-	repos  *repository.Repositories
-	config config.JWTConfig
+	repos    *repository.Repositories
+	config   config.JWTConfig
+	siteHost string
 }
 
-func NewAuthService(repos *repository.Repositories, config config.JWTConfig) *AuthService {
+func NewAuthService(repos *repository.Repositories, config config.JWTConfig, baseURL string) *AuthService {
 	return &AuthService{
-		repos:  repos,
-		config: config,
+		repos:    repos,
+		config:   config,
+		siteHost: siteHostOf(baseURL),
 	}
 }
 
@@ -61,6 +68,7 @@ func (s *AuthService) RegisterCreator(ctx context.Context, req *models.CreatorRe
 		Mobile:           req.Mobile,
 		PasswordHash:     string(hash),
 		IsActive:         true,
+		Attribution:      normalizeAttribution(req.Attribution, s.siteHost),
 	}
 
 	if err := s.repos.Creator.Create(ctx, creator); err != nil {
@@ -193,3 +201,67 @@ func (s *AuthService) EnsureDefaultCreator(ctx context.Context, email, password
 		IsActive:         true,
 	})
 }
+
+// CreateAdmin adds an admin account
+func (s *AuthService) CreateAdmin(ctx context.Context, email, name, password string) (*models.Admin, error) {
+	if len(password) < MinPasswordLength {
+		return nil, ErrPasswordTooShort
+	}
+	existing, err := s.repos.Admin.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrEmailExists
+	}
+
+	hash, err := s.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	admin := &models.Admin{Email: email, Name: name, PasswordHash: hash, IsActive: true}
+	if err := s.repos.Admin.Create(ctx, admin); err != nil {
+		return nil, err
+	}
+	return admin, nil
+}
+
+// ResetAdminPassword sets the password of the admin with email
+func (s *AuthService) ResetAdminPassword(ctx context.Context, email, password string) error {
+	if len(password) < MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+	admin, err := s.repos.Admin.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if admin == nil {
+		return ErrAdminNotFound
+	}
+
+	hash, err := s.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return s.repos.Admin.UpdatePassword(ctx, admin.ID, hash)
+}
+
+// ResetCreatorPassword sets the password of the creator with email
+func (s *AuthService) ResetCreatorPassword(ctx context.Context, email, password string) error {
+	if len(password) < MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+	creator, err := s.repos.Creator.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if creator == nil {
+		return ErrCreatorNotFound
+	}
+
+	hash, err := s.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return s.repos.Creator.UpdatePassword(ctx, creator.ID, hash)
+}
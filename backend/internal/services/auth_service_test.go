@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/net1io/zenbali/internal/config"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+func newTestAuthService(t *testing.T) *AuthService {
+	t.Helper()
+	return NewAuthService(newTestRepos(t), config.JWTConfig{Secret: "test-secret", ExpiryHours: 1}, "https://zenbali.test")
+}
+
+func TestAuthServiceCreator(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestAuthService(t)
+
+	req := &models.CreatorRegisterRequest{
+		Name:     "Ketut",
+		Email:    "ketut@example.com",
+		Mobile:   "+628555",
+		Password: "sunrise-yoga",
+	}
+	creator, err := svc.RegisterCreator(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creator.PasswordHash == req.Password || !creator.IsActive {
+		t.Error("registered creator has a plain password or is inactive")
+	}
+	if _, err := svc.RegisterCreator(ctx, req); !errors.Is(err, ErrEmailExists) {
+		t.Errorf("registering twice: err = %v, want ErrEmailExists", err)
+	}
+
+	_, token, err := svc.LoginCreator(ctx, &models.CreatorLoginRequest{Email: req.Email, Password: req.Password})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := svc.ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != creator.ID || claims.UserType != "creator" {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	if _, _, err := svc.LoginCreator(ctx, &models.CreatorLoginRequest{Email: req.Email, Password: "wrong-password"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("wrong password: err = %v, want ErrInvalidCredentials", err)
+	}
+	if _, _, err := svc.LoginCreator(ctx, &models.CreatorLoginRequest{Email: "nobody@example.com", Password: req.Password}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("unknown email: err = %v, want ErrInvalidCredentials", err)
+	}
+
+	if err := svc.repos.Creator.UpdateStatus(ctx, creator.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := svc.LoginCreator(ctx, &models.CreatorLoginRequest{Email: req.Email, Password: req.Password}); !errors.Is(err, ErrAccountDisabled) {
+		t.Errorf("disabled account: err = %v, want ErrAccountDisabled", err)
+	}
+}
+
+func TestAuthServiceAdmin(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestAuthService(t)
+
+	if _, err := svc.CreateAdmin(ctx, "ops@example.com", "Ops", "short"); !errors.Is(err, ErrPasswordTooShort) {
+		t.Errorf("short password: err = %v, want ErrPasswordTooShort", err)
+	}
+	admin, err := svc.CreateAdmin(ctx, "ops@example.com", "Ops", "first-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateAdmin(ctx, "ops@example.com", "Ops", "first-password"); !errors.Is(err, ErrEmailExists) {
+		t.Errorf("creating twice: err = %v, want ErrEmailExists", err)
+	}
+
+	if err := svc.ResetAdminPassword(ctx, "ops@example.com", "second-password"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := svc.LoginAdmin(ctx, &models.AdminLoginRequest{Email: admin.Email, Password: "first-password"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("old password: err = %v, want ErrInvalidCredentials", err)
+	}
+	_, token, err := svc.LoginAdmin(ctx, &models.AdminLoginRequest{Email: admin.Email, Password: "second-password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims, err := svc.ValidateToken(token); err != nil || claims.UserType != "admin" {
+		t.Errorf("ValidateToken() = %+v, %v", claims, err)
+	}
+
+	if err := svc.ResetAdminPassword(ctx, "nobody@example.com", "second-password"); !errors.Is(err, ErrAdminNotFound) {
+		t.Errorf("unknown admin: err = %v, want ErrAdminNotFound", err)
+	}
+	if err := svc.ResetCreatorPassword(ctx, "nobody@example.com", "second-password"); !errors.Is(err, ErrCreatorNotFound) {
+		t.Errorf("unknown creator: err = %v, want ErrCreatorNotFound", err)
+	}
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/storage"
+)
+
+var (
+	ErrDirectUploadNotFound = newError(KindNotFound, "UPLOAD_NOT_FOUND", "Upload not found. Request a new upload URL and upload the file first")
+	ErrDirectUploadSize     = newError(KindInvalid, "UPLOAD_SIZE_MISMATCH", "Body size does not match the signed upload")
+)
+
+// directUploadPrefix is where direct uploads wait, unvalidated, until they
+// are confirmed. They are registered like any upload, so the sweeper deletes
+// the ones never confirmed.
+const directUploadPrefix = "staging/"
+
+// directUploadTypes maps the content types a client may declare to the file
+// extension they must be allowed under
+var directUploadTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/heic": ".heic",
+	"image/heif": ".heif",
+}
+
+// IsStagingKey reports whether key is an unconfirmed direct upload, or the
+// staging area itself, which must never be served
+func IsStagingKey(key string) bool {
+	return strings.HasPrefix(key+"/", directUploadPrefix)
+}
+
+// CreateDirectUpload registers a staging key and signs a URL the client can
+// PUT the image to. The declared size is enforced by the store.
+func (s *UploadService) CreateDirectUpload(ctx context.Context, req *models.DirectUploadRequest, owner models.UploadOwner) (*models.DirectUpload, error) {
+	contentType := strings.ToLower(strings.TrimSpace(req.ContentType))
+	ext, ok := directUploadTypes[contentType]
+	if !ok || !s.isAllowedExt(ext) {
+		return nil, ErrInvalidFileType
+	}
+	if req.SizeBytes <= 0 || req.SizeBytes > s.directMaxSize() {
+		return nil, ErrFileTooLarge
+	}
+
+	key := directUploadPrefix + uuid.New().String()
+	if s.uploads != nil {
+		upload := &models.Upload{
+			StorageKey: key,
+			Source:     owner.Source,
+			CreatorID:  owner.CreatorID,
+			AgentKeyID: owner.AgentKeyID,
+			SizeBytes:  req.SizeBytes,
+		}
+		if err := s.uploads.Create(ctx, upload); err != nil {
+			return nil, err
+		}
+	}
+
+	ttl := time.Duration(s.config.DirectURLTTLMinutes) * time.Minute
+	signed, err := s.store.SignPut(ctx, key, contentType, req.SizeBytes, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.DirectUpload{
+		Key:       key,
+		Method:    signed.Method,
+		URL:       signed.URL,
+		Headers:   signed.Headers,
+		ExpiresAt: signed.ExpiresAt,
+	}, nil
+}
+
+// ReceiveSignedPut stores the body of a signed PUT for the local backend,
+// which has no storage service of its own to send clients to
+func (s *UploadService) ReceiveSignedPut(ctx context.Context, query url.Values, contentType string, body io.Reader) error {
+	local, ok := s.store.(*storage.LocalStore)
+	if !ok {
+		return storage.ErrInvalidSignature
+	}
+
+	key, size, err := local.VerifyPut(query, contentType, time.Now())
+	if err != nil {
+		return err
+	}
+	if !IsStagingKey(key) {
+		return storage.ErrInvalidSignature
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, size+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) != size {
+		return ErrDirectUploadSize
+	}
+	return local.Put(ctx, key, bytes.NewReader(data), contentType)
+}
+
+// ConfirmDirectUpload validates an image the client uploaded to key and
+// stores its renditions like any other upload, returning the new key. The
+// staged object is removed whether or not it was a valid image.
+func (s *UploadService) ConfirmDirectUpload(ctx context.Context, key string, owner models.UploadOwner) (string, error) {
+	key = strings.TrimSpace(key)
+	if !IsStagingKey(key) {
+		return "", ErrDirectUploadNotFound
+	}
+
+	var staged *models.Upload
+	if s.uploads != nil {
+		upload, err := s.uploads.GetByKey(ctx, key)
+		if err != nil {
+			return "", err
+		}
+		if upload == nil || !ownsUpload(upload, owner) {
+			return "", ErrDirectUploadNotFound
+		}
+		staged = upload
+	}
+
+	reader, err := s.store.Open(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "", ErrDirectUploadNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	maxSize := s.directMaxSize()
+	data, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	reader.Close()
+	if err != nil {
+		return "", err
+	}
+
+	defer s.discardStaged(ctx, key, staged)
+	if int64(len(data)) > maxSize {
+		return "", ErrFileTooLarge
+	}
+	return s.saveImage(ctx, data, owner)
+}
+
+// discardStaged deletes a confirmed direct upload. A failure leaves it to the
+// sweeper.
+func (s *UploadService) discardStaged(ctx context.Context, key string, staged *models.Upload) {
+	if err := s.deleteKey(key); err != nil {
+		log.Printf("WARN failed to delete staged upload %s: %v", key, err)
+		return
+	}
+	if staged != nil {
+		if _, err := s.uploads.Delete(ctx, staged.ID); err != nil {
+			log.Printf("WARN failed to unregister staged upload %s: %v", key, err)
+		}
+	}
+}
+
+// ownsUpload reports whether owner may use an upload. Admins may use any.
+func ownsUpload(upload *models.Upload, owner models.UploadOwner) bool {
+	if owner.Source == models.UploadSourceAdmin {
+		return true
+	}
+	return upload.CreatorID != nil && owner.CreatorID != nil && *upload.CreatorID == *owner.CreatorID
+}
+
+func (s *UploadService) directMaxSize() int64 {
+	return int64(s.config.DirectMaxSizeMB) * 1024 * 1024
+}
@@ -0,0 +1,47 @@
+package services
+
+// Kind classifies a domain error. Handlers map each kind to an HTTP status.
+type Kind int
+
+const (
+	// KindInvalid is a request the service cannot act on as given
+	KindInvalid Kind = iota + 1
+	// KindUnauthorized is a missing or bad credential
+	KindUnauthorized
+	// KindForbidden is an action the caller may not take
+	KindForbidden
+	// KindNotFound is a resource that does not exist
+	KindNotFound
+	// KindConflict is a clash with the current state, such as a taken key
+	KindConflict
+)
+
+// Error is a domain error returned by the services. Code is stable and
+// machine-readable, so API clients can branch on it; Message is meant for
+// people and may change.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+}
+
+func newError(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is an error with the same code, so a copy made
+// by WithMessage still matches its sentinel under errors.Is
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithMessage returns a copy of e with a message for a particular request,
+// such as one naming the field at fault
+func (e *Error) WithMessage(message string) *Error {
+	return &Error{Kind: e.Kind, Code: e.Code, Message: message}
+}
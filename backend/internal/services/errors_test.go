@@ -0,0 +1,20 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorIs(t *testing.T) {
+	reworded := ErrFileTooLarge.WithMessage("image_url file too large")
+	if reworded.Error() != "image_url file too large" || ErrFileTooLarge.Error() != "File too large" {
+		t.Errorf("WithMessage gave %q and left the sentinel as %q", reworded, ErrFileTooLarge)
+	}
+	if !errors.Is(fmt.Errorf("saving: %w", reworded), ErrFileTooLarge) {
+		t.Error("a reworded, wrapped error does not match its sentinel")
+	}
+	if errors.Is(reworded, ErrInvalidFileType) {
+		t.Error("an error matches a sentinel with another code")
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+// duplicateTitleThreshold is the minimum similarity (0..1) at which two
+// normalised titles on the same date and location count as the same event.
+const duplicateTitleThreshold = 0.85
+
+// FindDuplicate returns an existing event on the same date and location whose
+// title is near-identical to title, or nil if there is none. Unpublished events
+// are only considered when they belong to creatorID.
+func (s *EventService) FindDuplicate(ctx context.Context, creatorID uuid.UUID, eventDate string, locationID int, title string) (*models.Event, error) {
+	date, err := time.Parse("2006-01-02", eventDate)
+	if err != nil {
+		return nil, ErrInvalidDate
+	}
+
+	candidates, _, err := s.repos.Event.List(ctx, models.EventListFilter{
+		LocationID:  locationID,
+		DateFrom:    date,
+		DateTo:      date,
+		IncludePast: true,
+		Limit:       100,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := NormalizeTitle(title)
+	var best *models.Event
+	bestScore := 0.0
+	for _, candidate := range candidates {
+		if !candidate.IsPublished && candidate.CreatorID != creatorID {
+			continue
+		}
+		score := TitleSimilarity(normalized, NormalizeTitle(candidate.Title))
+		if score >= duplicateTitleThreshold && score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	return best, nil
+}
+
+// IsNearDuplicateTitle reports whether two raw titles are close enough to be
+// treated as the same event.
+func IsNearDuplicateTitle(a, b string) bool {
+	return TitleSimilarity(NormalizeTitle(a), NormalizeTitle(b)) >= duplicateTitleThreshold
+}
+
+// NormalizeTitle lowercases a title, drops punctuation and collapses whitespace
+func NormalizeTitle(title string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(r)
+			space = false
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// TitleSimilarity returns 1 - (edit distance / longer length) for two
+// already-normalised titles.
+func TitleSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+var (
+	ErrEventImageNotFound = newError(KindNotFound, "IMAGE_NOT_FOUND", "Image not found")
+	ErrGalleryFull        = newError(KindConflict, "GALLERY_FULL", fmt.Sprintf("An event can have at most %d images", models.MaxEventImages))
+	ErrInvalidImageOrder  = newError(KindInvalid, "INVALID_IMAGE_ORDER", "image_ids must list every gallery image exactly once")
+	ErrImageTextTooLong   = newError(KindInvalid, "IMAGE_TEXT_TOO_LONG", "Caption and alt text must be at most 500 characters")
+)
+
+// maxImageTextLength matches the caption and alt_text columns
+const maxImageTextLength = 500
+
+// galleryEvent loads an event whose gallery the caller may change
+func (s *EventService) galleryEvent(ctx context.Context, id, creatorID uuid.UUID, isAdmin bool) (*models.Event, error) {
+	event, err := s.repos.Event.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, ErrEventNotFound
+	}
+	if !isAdmin && event.CreatorID != creatorID {
+		return nil, ErrNotEventOwner
+	}
+	return event, nil
+}
+
+// AuthorizeGallery checks that the caller may change an event's gallery
+func (s *EventService) AuthorizeGallery(ctx context.Context, id, creatorID uuid.UUID, isAdmin bool) error {
+	_, err := s.galleryEvent(ctx, id, creatorID, isAdmin)
+	return err
+}
+
+// AddImage appends an uploaded image to an event's gallery. The first image
+// becomes the cover. On error the caller still owns imageRef.
+func (s *EventService) AddImage(ctx context.Context, id, creatorID uuid.UUID, imageRef, caption, altText string, isAdmin bool) (*models.EventImage, error) {
+	if !validImageText(&caption) || !validImageText(&altText) {
+		return nil, ErrImageTextTooLong
+	}
+	if _, err := s.galleryEvent(ctx, id, creatorID, isAdmin); err != nil {
+		return nil, err
+	}
+
+	image := &models.EventImage{
+		EventID:  id,
+		ImageURL: imageRef,
+		Caption:  optionalText(caption),
+		AltText:  optionalText(altText),
+	}
+	added, err := s.repos.EventImage.Add(ctx, image, models.MaxEventImages)
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		return nil, ErrGalleryFull
+	}
+
+	if s.upload != nil {
+		if err := s.upload.Attach(ctx, imageRef, id); err != nil {
+			log.Printf("WARN failed to attach gallery image %s to event %s: %v", imageRef, id, err)
+		}
+	}
+	return image, nil
+}
+
+// UpdateImage edits an image's caption and alt text, or makes it the cover
+func (s *EventService) UpdateImage(ctx context.Context, id, imageID, creatorID uuid.UUID, req *models.EventImageUpdateRequest, isAdmin bool) (*models.EventImage, error) {
+	if !validImageText(req.Caption) || !validImageText(req.AltText) {
+		return nil, ErrImageTextTooLong
+	}
+	if _, err := s.galleryEvent(ctx, id, creatorID, isAdmin); err != nil {
+		return nil, err
+	}
+
+	image, err := s.repos.EventImage.GetByID(ctx, id, imageID)
+	if err != nil {
+		return nil, err
+	}
+	if image == nil {
+		return nil, ErrEventImageNotFound
+	}
+
+	if req.Caption != nil || req.AltText != nil {
+		if req.Caption != nil {
+			image.Caption = optionalText(*req.Caption)
+		}
+		if req.AltText != nil {
+			image.AltText = optionalText(*req.AltText)
+		}
+		if err := s.repos.EventImage.UpdateMeta(ctx, image); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.IsCover != nil && *req.IsCover && !image.IsCover {
+		if err := s.repos.EventImage.SetCover(ctx, id, imageID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.repos.EventImage.GetByID(ctx, id, imageID)
+}
+
+// ReorderImages puts the gallery in the order of imageIDs
+func (s *EventService) ReorderImages(ctx context.Context, id, creatorID uuid.UUID, imageIDs []uuid.UUID, isAdmin bool) ([]*models.EventImage, error) {
+	if _, err := s.galleryEvent(ctx, id, creatorID, isAdmin); err != nil {
+		return nil, err
+	}
+
+	reordered, err := s.repos.EventImage.Reorder(ctx, id, imageIDs)
+	if err != nil {
+		return nil, err
+	}
+	if !reordered {
+		return nil, ErrInvalidImageOrder
+	}
+
+	return s.repos.EventImage.ListByEvent(ctx, id)
+}
+
+// DeleteImage removes an image from the gallery and hands it to the sweeper.
+// If it was the cover, the next image takes its place.
+func (s *EventService) DeleteImage(ctx context.Context, id, imageID, creatorID uuid.UUID, isAdmin bool) error {
+	if _, err := s.galleryEvent(ctx, id, creatorID, isAdmin); err != nil {
+		return err
+	}
+
+	image, err := s.repos.EventImage.Delete(ctx, id, imageID)
+	if err != nil {
+		return err
+	}
+	if image == nil {
+		return ErrEventImageNotFound
+	}
+
+	s.releaseUnused(ctx, id, image.ImageURL)
+	return nil
+}
+
+// releaseUnused releases imageRef unless the event's gallery still shows it
+func (s *EventService) releaseUnused(ctx context.Context, eventID uuid.UUID, imageRef string) {
+	if s.upload == nil || imageRef == "" {
+		return
+	}
+	inUse, err := s.repos.EventImage.Contains(ctx, eventID, imageRef)
+	if err != nil {
+		log.Printf("WARN failed to check gallery of event %s: %v", eventID, err)
+		return
+	}
+	if inUse {
+		return
+	}
+	if err := s.upload.Release(ctx, imageRef); err != nil {
+		log.Printf("WARN failed to release image %s of event %s: %v", imageRef, eventID, err)
+	}
+}
+
+func validImageText(value *string) bool {
+	return value == nil || utf8.RuneCountInString(strings.TrimSpace(*value)) <= maxImageTextLength
+}
+
+func optionalText(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -2,8 +2,8 @@ package services
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,10 +12,21 @@ import (
 )
 
 var (
-	ErrEventNotFound = errors.New("event not found")
-	ErrNotEventOwner = errors.New("not the owner of this event")
-	ErrEventInPast   = errors.New("cannot modify past events")
-	ErrInvalidDate   = errors.New("invalid date format")
+	ErrEventNotFound = newError(KindNotFound, "EVENT_NOT_FOUND", "Event not found")
+	ErrNotEventOwner = newError(KindForbidden, "NOT_EVENT_OWNER", "Not authorized to access this event")
+	ErrEventInPast   = newError(KindInvalid, "EVENT_IN_PAST", "Cannot modify past events")
+	ErrInvalidDate   = newError(KindInvalid, "INVALID_DATE", "Invalid date format. Use YYYY-MM-DD")
+
+	ErrExternalIDExists = newError(KindConflict, "EXTERNAL_ID_EXISTS", "external_id is already used by another event")
+	ErrInvalidCreatorID = newError(KindInvalid, "INVALID_CREATOR_ID", "Invalid creator ID")
+	ErrInvalidPrice     = newError(KindInvalid, "INVALID_PRICE", "price_thousands must be between 0 and 100000")
+
+	// Agent payloads name their references and spell out time and duration
+	ErrUnknownLocation     = newError(KindInvalid, "UNKNOWN_LOCATION", "Unknown location")
+	ErrUnknownEventType    = newError(KindInvalid, "UNKNOWN_EVENT_TYPE", "Unknown event type")
+	ErrUnknownEntranceType = newError(KindInvalid, "UNKNOWN_ENTRANCE_TYPE", "Unknown entrance type")
+	ErrInvalidEventTime    = newError(KindInvalid, "INVALID_EVENT_TIME", "event_time must use HH:MM or HH:MM:SS format")
+	ErrInvalidDuration     = newError(KindInvalid, "INVALID_DURATION", "Invalid duration")
 )
 
 type EventService struct {
@@ -32,12 +43,28 @@ func resolveEntranceFee(entranceFee float64, priceThousands *int) (float64, erro
 		return entranceFee, nil
 	}
 	if *priceThousands < 0 || *priceThousands > 100000 {
-		return 0, fmt.Errorf("price_thousands must be between 0 and 100000")
+		return 0, ErrInvalidPrice
 	}
 	return float64(*priceThousands * 1000), nil
 }
 
 func (s *EventService) Create(ctx context.Context, creatorID uuid.UUID, req *models.EventCreateRequest) (*models.Event, error) {
+	event, err := s.PrepareCreate(ctx, creatorID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repos.Event.Create(ctx, event); err != nil {
+		return nil, err
+	}
+
+	// Fetch with joined fields
+	return s.repos.Event.GetByID(ctx, event.ID)
+}
+
+// PrepareCreate validates a create request and builds the event it would
+// insert, without writing anything.
+func (s *EventService) PrepareCreate(ctx context.Context, creatorID uuid.UUID, req *models.EventCreateRequest) (*models.Event, error) {
 	eventDate, err := time.Parse("2006-01-02", req.EventDate)
 	if err != nil {
 		return nil, ErrInvalidDate
@@ -78,6 +105,19 @@ func (s *EventService) Create(ctx context.Context, creatorID uuid.UUID, req *mod
 		notes = &req.Notes
 	}
 
+	var venue *string
+	if req.Venue != "" {
+		venue = &req.Venue
+	}
+
+	var externalID *string
+	if req.ExternalID != "" {
+		if err := s.ensureExternalIDAvailable(ctx, creatorID, uuid.Nil, req.ExternalID); err != nil {
+			return nil, err
+		}
+		externalID = &req.ExternalID
+	}
+
 	event := &models.Event{
 		CreatorID:            creatorID,
 		Title:                req.Title,
@@ -90,17 +130,35 @@ func (s *EventService) Create(ctx context.Context, creatorID uuid.UUID, req *mod
 		EntranceFee:          entranceFee,
 		ParticipantGroupType: participantGroupType,
 		LeadBy:               leadBy,
+		Venue:                venue,
 		ContactEmail:         req.ContactEmail,
 		ContactMobile:        contactMobile,
 		Notes:                notes,
+		ExternalID:           externalID,
 	}
 
-	if err := s.repos.Event.Create(ctx, event); err != nil {
+	return event, nil
+}
+
+// CreateBatch inserts prepared events in a single transaction; either all of
+// them are created or none are.
+func (s *EventService) CreateBatch(ctx context.Context, events []*models.Event) ([]*models.Event, error) {
+	if err := s.repos.Event.CreateBatch(ctx, events); err != nil {
 		return nil, err
 	}
 
-	// Fetch with joined fields
-	return s.repos.Event.GetByID(ctx, event.ID)
+	created := make([]*models.Event, 0, len(events))
+	for _, event := range events {
+		fetched, err := s.repos.Event.GetByID(ctx, event.ID)
+		if err != nil {
+			return nil, err
+		}
+		if fetched.ImageURL != nil {
+			s.AttachImage(ctx, fetched.ID, nil, *fetched.ImageURL)
+		}
+		created = append(created, fetched)
+	}
+	return created, nil
 }
 
 func (s *EventService) GetByID(ctx context.Context, id uuid.UUID) (*models.Event, error) {
@@ -114,6 +172,29 @@ func (s *EventService) GetByID(ctx context.Context, id uuid.UUID) (*models.Event
 	return event, nil
 }
 
+// GetByExternalID looks up a creator's event by the ID their source system uses
+func (s *EventService) GetByExternalID(ctx context.Context, creatorID uuid.UUID, externalID string) (*models.Event, error) {
+	event, err := s.repos.Event.GetByExternalID(ctx, creatorID, externalID)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, ErrEventNotFound
+	}
+	return event, nil
+}
+
+func (s *EventService) ensureExternalIDAvailable(ctx context.Context, creatorID, eventID uuid.UUID, externalID string) error {
+	existing, err := s.repos.Event.GetByExternalID(ctx, creatorID, externalID)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != eventID {
+		return ErrExternalIDExists
+	}
+	return nil
+}
+
 func (s *EventService) Update(ctx context.Context, id, creatorID uuid.UUID, req *models.EventUpdateRequest, isAdmin bool) (*models.Event, error) {
 	event, err := s.repos.Event.GetByID(ctx, id)
 	if err != nil {
@@ -183,6 +264,19 @@ func (s *EventService) Update(ctx context.Context, id, creatorID uuid.UUID, req
 	if req.LeadBy != "" {
 		event.LeadBy = &req.LeadBy
 	}
+	if req.Venue != "" {
+		event.Venue = &req.Venue
+	}
+	if req.ExternalID != nil {
+		if *req.ExternalID == "" {
+			event.ExternalID = nil
+		} else {
+			if err := s.ensureExternalIDAvailable(ctx, event.CreatorID, event.ID, *req.ExternalID); err != nil {
+				return nil, err
+			}
+			event.ExternalID = req.ExternalID
+		}
+	}
 
 	if err := s.repos.Event.Update(ctx, event); err != nil {
 		return nil, err
@@ -191,6 +285,139 @@ func (s *EventService) Update(ctx context.Context, id, creatorID uuid.UUID, req
 	return s.repos.Event.GetByID(ctx, id)
 }
 
+// UpdateWithImage updates an event and makes imageRef its cover in one
+// transaction, so a failure leaves both unchanged. An empty imageRef leaves
+// the image alone.
+func (s *EventService) UpdateWithImage(ctx context.Context, id, creatorID uuid.UUID, req *models.EventUpdateRequest, imageRef string) (*models.Event, error) {
+	var previous, replaced *string
+	err := s.repos.Tx.InTx(ctx, func(tx *repository.Repositories) error {
+		event, err := s.withRepos(tx).Update(ctx, id, creatorID, req, false)
+		if err != nil || imageRef == "" {
+			return err
+		}
+		previous = event.ImageURL
+
+		if err := tx.Event.UpdateAdminFields(ctx, id, &imageRef, nil, nil); err != nil {
+			return err
+		}
+		replaced, err = tx.EventImage.SyncCover(ctx, id, imageRef)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if imageRef != "" {
+		s.attachUpload(ctx, id, previous, replaced, imageRef)
+	}
+	return s.GetByID(ctx, id)
+}
+
+// AdminUpdate applies an admin's changes to an event in one transaction: its
+// fields, then its image and payment flags, then its owner. Nothing is
+// changed when a step fails.
+func (s *EventService) AdminUpdate(ctx context.Context, id uuid.UUID, req *models.AdminEventUpdateRequest) (*models.Event, error) {
+	var creatorID uuid.UUID
+	if req.CreatorID != "" {
+		parsed, err := uuid.Parse(req.CreatorID)
+		if err != nil {
+			return nil, ErrInvalidCreatorID
+		}
+		creatorID = parsed
+	}
+
+	var imageRef *string
+	if req.ImageURL != "" {
+		ref := strings.TrimSpace(req.ImageURL)
+		if s.upload != nil {
+			ref = s.upload.ImageRef(ref)
+		}
+		imageRef = &ref
+	}
+
+	var previous *string
+	err := s.repos.Tx.InTx(ctx, func(tx *repository.Repositories) error {
+		event, err := s.withRepos(tx).Update(ctx, id, uuid.Nil, &req.EventUpdateRequest, true)
+		if err != nil {
+			return err
+		}
+		previous = event.ImageURL
+
+		if imageRef != nil || req.IsPaid != nil || req.IsPublished != nil {
+			if err := tx.Event.UpdateAdminFields(ctx, id, imageRef, req.IsPaid, req.IsPublished); err != nil {
+				return err
+			}
+		}
+
+		if req.CreatorID != "" {
+			creator, err := tx.Creator.GetByID(ctx, creatorID)
+			if err != nil {
+				return err
+			}
+			if creator == nil {
+				return ErrCreatorNotFound
+			}
+			if err := tx.Event.UpdateCreator(ctx, id, creatorID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if imageRef != nil {
+		s.AttachImage(ctx, id, previous, *imageRef)
+	}
+	return s.GetByID(ctx, id)
+}
+
+// CreatePublished creates an event that is paid and published from the
+// start, as agent events are, with imageRef as its cover.
+func (s *EventService) CreatePublished(ctx context.Context, creatorID uuid.UUID, req *models.EventCreateRequest, imageRef string) (*models.Event, error) {
+	var image *string
+	if imageRef != "" {
+		image = &imageRef
+	}
+	isPaid := true
+	isPublished := true
+	return s.CreateWithAdminFields(ctx, creatorID, req, image, &isPaid, &isPublished)
+}
+
+// CreateWithAdminFields creates an event and sets the fields only admins may
+// set; nil fields keep their defaults. The event and its fields are written in
+// one transaction, so a failure leaves no event behind.
+func (s *EventService) CreateWithAdminFields(ctx context.Context, creatorID uuid.UUID, req *models.EventCreateRequest, imageRef *string, isPaid, isPublished *bool) (*models.Event, error) {
+	var id uuid.UUID
+	err := s.repos.Tx.InTx(ctx, func(tx *repository.Repositories) error {
+		event, err := s.withRepos(tx).Create(ctx, creatorID, req)
+		if err != nil {
+			return err
+		}
+		id = event.ID
+
+		if imageRef == nil && isPaid == nil && isPublished == nil {
+			return nil
+		}
+		return tx.Event.UpdateAdminFields(ctx, id, imageRef, isPaid, isPublished)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if imageRef != nil {
+		s.AttachImage(ctx, id, nil, *imageRef)
+	}
+	return s.GetByID(ctx, id)
+}
+
+// withRepos returns a copy of s that uses repos, such as the repositories of
+// a transaction
+func (s *EventService) withRepos(repos *repository.Repositories) *EventService {
+	return &EventService{repos: repos, upload: s.upload}
+}
+
 func (s *EventService) Delete(ctx context.Context, id, creatorID uuid.UUID, isAdmin bool) error {
 	event, err := s.repos.Event.GetByID(ctx, id)
 	if err != nil {
@@ -209,9 +436,18 @@ func (s *EventService) Delete(ctx context.Context, id, creatorID uuid.UUID, isAd
 		return err
 	}
 
-	if event.ImageURL != nil && s.upload != nil {
-		if err := s.upload.DeleteFile(*event.ImageURL); err != nil {
-			return err
+	if s.upload != nil {
+		refs := make(map[string]bool)
+		if event.ImageURL != nil && *event.ImageURL != "" {
+			refs[*event.ImageURL] = true
+		}
+		for _, image := range event.Gallery {
+			refs[image.ImageURL] = true
+		}
+		for ref := range refs {
+			if err := s.upload.Release(ctx, ref); err != nil {
+				log.Printf("WARN failed to release image of deleted event %s: %v", id, err)
+			}
 		}
 	}
 
@@ -232,18 +468,48 @@ func (s *EventService) UpdateImageURL(ctx context.Context, id, creatorID uuid.UU
 
 	if err := s.repos.Event.UpdateImageURL(ctx, id, imageURL); err != nil {
 		if s.upload != nil {
-			_ = s.upload.DeleteFile(imageURL)
+			_ = s.upload.Release(ctx, imageURL)
 		}
 		return err
 	}
 
-	if event.ImageURL != nil && *event.ImageURL != "" && *event.ImageURL != imageURL && s.upload != nil {
-		if err := s.upload.DeleteFile(*event.ImageURL); err != nil {
-			return err
-		}
+	s.AttachImage(ctx, id, event.ImageURL, imageURL)
+	return nil
+}
+
+// AttachImage makes imageRef, just written to events.image_url, the cover of
+// the event's gallery, records in the uploads registry that the event uses
+// it, and hands the image it replaced to the sweeper. Failures are only
+// logged: the sweeper reconciles the registry against events and galleries
+// before deleting anything.
+func (s *EventService) AttachImage(ctx context.Context, eventID uuid.UUID, previous *string, imageRef string) {
+	if imageRef == "" {
+		return
+	}
+	replaced, err := s.repos.EventImage.SyncCover(ctx, eventID, imageRef)
+	if err != nil {
+		log.Printf("WARN failed to set cover image of event %s: %v", eventID, err)
 	}
+	s.attachUpload(ctx, eventID, previous, replaced, imageRef)
+}
 
-	return nil
+// attachUpload records in the uploads registry that the event uses imageRef
+// and releases previous and replaced, the images imageRef took over from as
+// image_url and as cover, unless the gallery still shows them
+func (s *EventService) attachUpload(ctx context.Context, eventID uuid.UUID, previous, replaced *string, imageRef string) {
+	if s.upload == nil {
+		return
+	}
+	if err := s.upload.Attach(ctx, imageRef, eventID); err != nil {
+		log.Printf("WARN failed to attach image %s to event %s: %v", imageRef, eventID, err)
+	}
+	if previous != nil && *previous != imageRef {
+		s.releaseUnused(ctx, eventID, *previous)
+	}
+	// The cover can only differ from previous if the two had drifted apart
+	if replaced != nil && *replaced != imageRef && (previous == nil || *replaced != *previous) {
+		s.releaseUnused(ctx, eventID, *replaced)
+	}
 }
 
 func (s *EventService) ListPublic(ctx context.Context, filter models.EventListFilter) (*models.EventListResponse, error) {
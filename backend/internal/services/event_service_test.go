@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/repository"
+)
+
+func eventCreateRequest() *models.EventCreateRequest {
+	return &models.EventCreateRequest{
+		Title:                "Full Moon Sound Healing",
+		EventDate:            time.Now().AddDate(0, 0, 7).Format("2006-01-02"),
+		EventTime:            "19:30",
+		LocationID:           1,
+		EventTypeID:          2,
+		Duration:             "2 hours",
+		EntranceTypeID:       3,
+		ParticipantGroupType: "Everyone",
+		LeadBy:               "Wayan",
+		ContactEmail:         "healing@example.com",
+		ContactMobile:        "+628123",
+		Notes:                "Bring a mat",
+	}
+}
+
+func TestEventServiceCreate(t *testing.T) {
+	ctx := context.Background()
+	repos := newTestRepos(t)
+	creator := createTestCreator(t, repos, "creator@example.com")
+	svc := NewEventService(repos, nil)
+
+	req := eventCreateRequest()
+	price := 150
+	req.PriceThousands = &price
+	req.ExternalID = "cal-1"
+	event, err := svc.Create(ctx, creator.ID, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.LocationName != "Ubud" || event.EventTypeName != "Healing" || event.CreatorName != creator.Name {
+		t.Errorf("joined names = %q, %q, %q", event.LocationName, event.EventTypeName, event.CreatorName)
+	}
+	if event.EntranceFee != 150000 {
+		t.Errorf("EntranceFee = %v, want 150000 from price_thousands", event.EntranceFee)
+	}
+	if event.EventTime == nil || *event.EventTime != "19:30:00" {
+		t.Errorf("EventTime = %v, want 19:30:00", event.EventTime)
+	}
+	if event.IsPaid || event.IsPublished {
+		t.Error("new event is paid or published")
+	}
+
+	if _, err := svc.Create(ctx, creator.ID, req); !errors.Is(err, ErrExternalIDExists) {
+		t.Errorf("reusing external_id: err = %v, want ErrExternalIDExists", err)
+	}
+
+	req = eventCreateRequest()
+	req.EventDate = "07/01/2030"
+	if _, err := svc.Create(ctx, creator.ID, req); !errors.Is(err, ErrInvalidDate) {
+		t.Errorf("bad date: err = %v, want ErrInvalidDate", err)
+	}
+}
+
+func TestEventServiceUpdate(t *testing.T) {
+	ctx := context.Background()
+	repos := newTestRepos(t)
+	owner := createTestCreator(t, repos, "owner@example.com")
+	other := createTestCreator(t, repos, "other@example.com")
+	svc := NewEventService(repos, nil)
+
+	upcoming := createTestEvent(t, repos, owner.ID, 3)
+	past := createTestEvent(t, repos, owner.ID, -3)
+	fee := 50000.0
+	req := &models.EventUpdateRequest{Title: "Sunset Yoga", LocationID: 2, EntranceFee: &fee}
+
+	updated, err := svc.Update(ctx, upcoming.ID, owner.ID, req, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Title != "Sunset Yoga" || updated.LocationName != "Canggu" || updated.EntranceFee != fee {
+		t.Errorf("updated event = %q at %q for %v", updated.Title, updated.LocationName, updated.EntranceFee)
+	}
+	if updated.ContactEmail != upcoming.ContactEmail {
+		t.Errorf("ContactEmail = %q, fields left out of the request must be kept", updated.ContactEmail)
+	}
+
+	if _, err := svc.Update(ctx, upcoming.ID, other.ID, req, false); !errors.Is(err, ErrNotEventOwner) {
+		t.Errorf("other creator: err = %v, want ErrNotEventOwner", err)
+	}
+	if _, err := svc.Update(ctx, past.ID, owner.ID, req, false); !errors.Is(err, ErrEventInPast) {
+		t.Errorf("past event: err = %v, want ErrEventInPast", err)
+	}
+	if _, err := svc.Update(ctx, past.ID, other.ID, req, true); err != nil {
+		t.Errorf("admin updating a past event: %v", err)
+	}
+	if _, err := svc.Update(ctx, owner.ID, owner.ID, req, false); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("unknown event: err = %v, want ErrEventNotFound", err)
+	}
+}
+
+func TestEventServiceDelete(t *testing.T) {
+	ctx := context.Background()
+	repos := newTestRepos(t)
+	owner := createTestCreator(t, repos, "owner@example.com")
+	other := createTestCreator(t, repos, "other@example.com")
+	svc := NewEventService(repos, nil)
+	event := createTestEvent(t, repos, owner.ID, 3)
+
+	payment := &models.Payment{EventID: event.ID, CreatorID: owner.ID, AmountCents: 1000, Currency: "USD", Status: models.PaymentStatusPending}
+	if err := repos.Payment.Create(ctx, payment); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.Delete(ctx, event.ID, other.ID, false); !errors.Is(err, ErrNotEventOwner) {
+		t.Fatalf("other creator: err = %v, want ErrNotEventOwner", err)
+	}
+	if err := svc.Delete(ctx, event.ID, owner.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.GetByID(ctx, event.ID); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("GetByID after delete: err = %v, want ErrEventNotFound", err)
+	}
+	if got, _ := repos.Payment.GetByID(ctx, payment.ID); got != nil {
+		t.Error("payment of deleted event was kept")
+	}
+	if err := svc.Delete(ctx, event.ID, owner.ID, false); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("deleting twice: err = %v, want ErrEventNotFound", err)
+	}
+}
+
+func TestEventServiceListPublic(t *testing.T) {
+	ctx := context.Background()
+	repos := newTestRepos(t)
+	creator := createTestCreator(t, repos, "creator@example.com")
+	svc := NewEventService(repos, nil)
+
+	for i := 0; i < 3; i++ {
+		event := createTestEvent(t, repos, creator.ID, i+1)
+		if err := svc.PublishEvent(ctx, event.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	createTestEvent(t, repos, creator.ID, 1)
+
+	list, err := svc.ListPublic(ctx, models.EventListFilter{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 3 || list.TotalPages != 2 || len(list.Events) != 2 {
+		t.Fatalf("got %d of %d events on %d pages, want 2 of 3 on 2", len(list.Events), list.Total, list.TotalPages)
+	}
+	if !list.Events[0].EventDate.Before(list.Events[1].EventDate) {
+		t.Error("events not listed soonest first")
+	}
+
+	list, err = svc.ListPublic(ctx, models.EventListFilter{LocationID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 0 {
+		t.Errorf("location filter: got %d events, want 0", list.Total)
+	}
+}
+
+func TestEventServiceAdminUpdate(t *testing.T) {
+	ctx := context.Background()
+	repos := newTestRepos(t)
+	owner := createTestCreator(t, repos, "owner@example.com")
+	buyer := createTestCreator(t, repos, "buyer@example.com")
+	svc := NewEventService(repos, nil)
+	event := createTestEvent(t, repos, owner.ID, -1)
+
+	published := true
+	req := &models.AdminEventUpdateRequest{
+		EventUpdateRequest: models.EventUpdateRequest{Title: "Moved Yoga"},
+		CreatorID:          buyer.ID.String(),
+		IsPublished:        &published,
+	}
+	updated, err := svc.AdminUpdate(ctx, event.ID, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Title != "Moved Yoga" || updated.CreatorID != buyer.ID || !updated.IsPublished {
+		t.Errorf("updated event = %q by %s, published %t", updated.Title, updated.CreatorName, updated.IsPublished)
+	}
+
+	// A failing step undoes the steps before it
+	req.Title = "Never Saved"
+	published = false
+	for _, creatorID := range []string{"not-a-uuid", event.ID.String()} {
+		req.CreatorID = creatorID
+		if _, err := svc.AdminUpdate(ctx, event.ID, req); err == nil {
+			t.Fatalf("creator %s: no error", creatorID)
+		}
+		got, _ := svc.GetByID(ctx, event.ID)
+		if got.Title != "Moved Yoga" || !got.IsPublished || got.CreatorID != buyer.ID {
+			t.Errorf("creator %s: event changed to %q, published %t", creatorID, got.Title, got.IsPublished)
+		}
+	}
+}
+
+// failingPublishTx runs transactions whose UpdateAdminFields calls fail
+type failingPublishTx struct{ repository.Transactor }
+
+type failingPublishEvents struct{ repository.EventStore }
+
+func (failingPublishEvents) UpdateAdminFields(context.Context, uuid.UUID, *string, *bool, *bool) error {
+	return errors.New("publish failed")
+}
+
+func (t failingPublishTx) InTx(ctx context.Context, fn func(tx *repository.Repositories) error) error {
+	return t.Transactor.InTx(ctx, func(tx *repository.Repositories) error {
+		failing := *tx
+		failing.Event = failingPublishEvents{tx.Event}
+		return fn(&failing)
+	})
+}
+
+func TestEventServiceCreatePublished(t *testing.T) {
+	ctx := context.Background()
+	repos := newTestRepos(t)
+	creator := createTestCreator(t, repos, "agent@example.com")
+	svc := NewEventService(repos, nil)
+
+	event, err := svc.CreatePublished(ctx, creator.ID, eventCreateRequest(), "events/cover.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !event.IsPaid || !event.IsPublished || event.ImageURL == nil || *event.ImageURL != "events/cover.jpg" {
+		t.Errorf("created event paid %t, published %t, image %v", event.IsPaid, event.IsPublished, event.ImageURL)
+	}
+
+	// A failed publish leaves no unpublished event behind
+	repos.Tx = failingPublishTx{repos.Tx}
+	req := eventCreateRequest()
+	req.Title = "Never Saved"
+	if _, err := svc.CreatePublished(ctx, creator.ID, req, ""); err == nil {
+		t.Fatal("no error from failing publish")
+	}
+	_, total, err := repos.Event.List(ctx, models.EventListFilter{CreatorID: creator.ID, IncludePast: true, Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 {
+		t.Errorf("creator has %d events after a failed create, want 1", total)
+	}
+}
+
+func TestEventServiceCreateWithAdminFields(t *testing.T) {
+	ctx := context.Background()
+	repos := newTestRepos(t)
+	creator := createTestCreator(t, repos, "creator@example.com")
+	svc := NewEventService(repos, nil)
+
+	isPublished := true
+	event, err := svc.CreateWithAdminFields(ctx, creator.ID, eventCreateRequest(), nil, nil, &isPublished)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.IsPaid || !event.IsPublished || event.ImageURL != nil {
+		t.Errorf("created event paid %t, published %t, image %v; want only published", event.IsPaid, event.IsPublished, event.ImageURL)
+	}
+
+	// An admin create whose fields fail to save leaves no event behind
+	repos.Tx = failingPublishTx{repos.Tx}
+	image := "events/cover.jpg"
+	if _, err := svc.CreateWithAdminFields(ctx, creator.ID, eventCreateRequest(), &image, nil, nil); err == nil {
+		t.Fatal("no error from failing admin fields")
+	}
+	_, total, err := repos.Event.List(ctx, models.EventListFilter{CreatorID: creator.ID, IncludePast: true, Page: 1, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 {
+		t.Errorf("creator has %d events after a failed create, want 1", total)
+	}
+}
+
+func TestEventServiceUpdateWithImage(t *testing.T) {
+	ctx := context.Background()
+	repos := newTestRepos(t)
+	creator := createTestCreator(t, repos, "agent@example.com")
+	event := createTestEvent(t, repos, creator.ID, 5)
+	svc := NewEventService(repos, nil)
+
+	updated, err := svc.UpdateWithImage(ctx, event.ID, creator.ID, &models.EventUpdateRequest{Title: "Renamed"}, "events/cover.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Title != "Renamed" || updated.ImageURL == nil || *updated.ImageURL != "events/cover.jpg" {
+		t.Errorf("updated event %q with image %v", updated.Title, updated.ImageURL)
+	}
+	if len(updated.Gallery) != 1 || !updated.Gallery[0].IsCover || updated.Gallery[0].ImageURL != "events/cover.jpg" {
+		t.Errorf("gallery = %+v, want the new image as cover", updated.Gallery)
+	}
+
+	// A failure to store the image also undoes the field changes
+	repos.Tx = failingPublishTx{repos.Tx}
+	if _, err := svc.UpdateWithImage(ctx, event.ID, creator.ID, &models.EventUpdateRequest{Title: "Never Saved"}, "events/other.jpg"); err == nil {
+		t.Fatal("no error from failing image update")
+	}
+	stored, _ := repos.Event.GetByID(ctx, event.ID)
+	if stored.Title != "Renamed" || *stored.ImageURL != "events/cover.jpg" {
+		t.Errorf("failed update left the event %q with image %v", stored.Title, *stored.ImageURL)
+	}
+
+	if _, err := svc.UpdateWithImage(ctx, event.ID, uuid.New(), &models.EventUpdateRequest{Title: "Stolen"}, ""); !errors.Is(err, ErrNotEventOwner) {
+		t.Errorf("another creator's update: err = %v, want ErrNotEventOwner", err)
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/repository"
+	"github.com/net1io/zenbali/internal/useragent"
+)
+
+var (
+	ErrInvalidEventAction = newError(KindInvalid, "INVALID_EVENT_ACTION", "action must be view, email, whatsapp or share")
+	ErrInvalidStatsRange  = newError(KindInvalid, "INVALID_STATS_RANGE", fmt.Sprintf("from must not be after to, and the range must be at most %d days", MaxStatsRangeDays))
+)
+
+// MaxStatsRangeDays caps how many days one stats request may span
+const MaxStatsRangeDays = 366
+
+// EventStatsService records what visitors do on event pages and reports it to
+// creators. Visitors are told apart with the daily visitor hash, so each is
+// counted once per event, action and day.
+type EventStatsService struct {
+	repos   *repository.Repositories
+	visitor *VisitorService
+}
+
+func NewEventStatsService(repos *repository.Repositories, visitor *VisitorService) *EventStatsService {
+	return &EventStatsService{repos: repos, visitor: visitor}
+}
+
+// Track records an action on a published event's page. Actions by bots are
+// ignored.
+func (s *EventStatsService) Track(ctx context.Context, eventID uuid.UUID, action, ipAddress, userAgent string) error {
+	if !models.IsEventAction(action) {
+		return ErrInvalidEventAction
+	}
+
+	event, err := s.repos.Event.GetByID(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if event == nil || !event.IsPublished {
+		return ErrEventNotFound
+	}
+	if useragent.Bot(userAgent) != "" {
+		return nil
+	}
+
+	hash, err := s.visitor.visitorHash(ctx, ipAddress, userAgent)
+	if err != nil {
+		return err
+	}
+	_, err = s.repos.EventStats.Record(ctx, eventID, action, hash)
+	return err
+}
+
+// EventStats returns an event's daily stats from from to to, inclusive
+func (s *EventStatsService) EventStats(ctx context.Context, eventID, creatorID uuid.UUID, from, to time.Time) (*models.EventStatsResponse, error) {
+	if err := validStatsRange(from, to); err != nil {
+		return nil, err
+	}
+
+	event, err := s.repos.Event.GetByID(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, ErrEventNotFound
+	}
+	if event.CreatorID != creatorID {
+		return nil, ErrNotEventOwner
+	}
+
+	daily, err := s.repos.EventStats.EventDaily(ctx, eventID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.EventStatsResponse{
+		EventID: event.ID,
+		Title:   event.Title,
+		From:    from.Format("2006-01-02"),
+		To:      to.Format("2006-01-02"),
+		Totals:  sumStats(daily),
+		Daily:   daily,
+	}, nil
+}
+
+// CreatorStats sums the stats of all of a creator's events from from to to,
+// inclusive
+func (s *EventStatsService) CreatorStats(ctx context.Context, creatorID uuid.UUID, from, to time.Time) (*models.CreatorStatsResponse, error) {
+	if err := validStatsRange(from, to); err != nil {
+		return nil, err
+	}
+
+	daily, err := s.repos.EventStats.CreatorDaily(ctx, creatorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.repos.EventStats.CreatorEventTotals(ctx, creatorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.CreatorStatsResponse{
+		From:   from.Format("2006-01-02"),
+		To:     to.Format("2006-01-02"),
+		Totals: sumStats(daily),
+		Daily:  daily,
+		Events: events,
+	}, nil
+}
+
+func validStatsRange(from, to time.Time) error {
+	if to.Before(from) || to.Sub(from) >= MaxStatsRangeDays*24*time.Hour {
+		return ErrInvalidStatsRange
+	}
+	return nil
+}
+
+func sumStats(days []*models.EventStatsDay) models.EventStatsCounts {
+	var totals models.EventStatsCounts
+	for _, day := range days {
+		totals.Add(day.EventStatsCounts)
+	}
+	return totals
+}
@@ -2,8 +2,8 @@ package services
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"log"
 	"strings"
 
 	"github.com/google/uuid"
@@ -12,12 +12,13 @@ import (
 	"github.com/net1io/zenbali/internal/repository"
 	"github.com/stripe/stripe-go/v76"
 	"github.com/stripe/stripe-go/v76/checkout/session"
+	"github.com/stripe/stripe-go/v76/refund"
 )
 
 var (
-	ErrPaymentNotFound = errors.New("payment not found")
-	ErrAlreadyPaid     = errors.New("event already paid")
-	ErrSessionMismatch = errors.New("checkout session does not match event")
+	ErrPaymentNotFound = newError(KindNotFound, "PAYMENT_NOT_FOUND", "Payment not found")
+	ErrAlreadyPaid     = newError(KindConflict, "EVENT_ALREADY_PAID", "Event is already paid")
+	ErrSessionMismatch = newError(KindForbidden, "SESSION_MISMATCH", "Checkout session does not belong to this event")
 )
 
 type PaymentService struct {
@@ -132,17 +133,101 @@ func (s *PaymentService) HandleSuccessfulPayment(ctx context.Context, sessionID
 		return err
 	}
 
-	// Update payment status
-	if err := s.repos.Payment.UpdateStatus(ctx, payment.ID, models.PaymentStatusCompleted, sess.PaymentIntent.ID); err != nil {
+	// Complete the payment and publish the event together. The event stays
+	// locked until then, so a manual payment for it waits and finds it paid.
+	var superseded bool
+	err = s.repos.Tx.InTx(ctx, func(tx *repository.Repositories) error {
+		event, err := tx.Event.GetByIDForUpdate(ctx, payment.EventID)
+		if err != nil {
+			return err
+		}
+		if event == nil {
+			return ErrEventNotFound
+		}
+		// The webhook and the verify call can both get here
+		current, err := tx.Payment.GetByID(ctx, payment.ID)
+		if err != nil {
+			return err
+		}
+		if current != nil && (current.Status == models.PaymentStatusCompleted || current.Status == models.PaymentStatusRefunded) {
+			return nil
+		}
+		// Another payment got there first; completing this one as well
+		// would charge for the event twice
+		if event.IsPaid {
+			superseded = true
+			return nil
+		}
+		if err := tx.Payment.UpdateStatus(ctx, payment.ID, models.PaymentStatusCompleted, sess.PaymentIntent.ID); err != nil {
+			return err
+		}
+		return tx.Event.UpdatePaymentStatus(ctx, payment.EventID, true, true)
+	})
+	if err != nil || !superseded {
 		return err
 	}
+	return s.refundSuperseded(ctx, payment, sess.PaymentIntent.ID)
+}
 
-	// Publish the event
-	if err := s.repos.Event.UpdatePaymentStatus(ctx, payment.EventID, true, true); err != nil {
-		return err
+// refundSuperseded refunds a Stripe payment for an event that was paid for
+// by other means in the meantime. The refund is keyed on the payment, so a
+// retried webhook does not refund it twice.
+func (s *PaymentService) refundSuperseded(ctx context.Context, payment *models.Payment, paymentIntentID string) error {
+	params := &stripe.RefundParams{PaymentIntent: stripe.String(paymentIntentID)}
+	params.SetIdempotencyKey("superseded-" + payment.ID.String())
+	if _, err := refund.New(params); err != nil {
+		return fmt.Errorf("refund superseded payment: %w", err)
+	}
+	log.Printf("Refunded payment %s: event %s was already paid", payment.ID, payment.EventID)
+	return s.repos.Payment.UpdateStatus(ctx, payment.ID, models.PaymentStatusRefunded, paymentIntentID)
+}
+
+// RecordManualPayment records a payment confirmed outside Stripe, such as a
+// bank transfer, and publishes the event. reference identifies the transfer
+// and is stored in place of a payment intent ID. amountCents of 0 charges the
+// configured posting fee.
+func (s *PaymentService) RecordManualPayment(ctx context.Context, eventID uuid.UUID, amountCents int64, reference string) (*models.Payment, error) {
+	if amountCents == 0 {
+		amountCents = s.config.PriceCents
 	}
 
-	return nil
+	var payment *models.Payment
+	err := s.repos.Tx.InTx(ctx, func(tx *repository.Repositories) error {
+		// Locked so that a Stripe payment completing at the same time cannot
+		// also pay for the event between the check and the update
+		event, err := tx.Event.GetByIDForUpdate(ctx, eventID)
+		if err != nil {
+			return err
+		}
+		if event == nil {
+			return ErrEventNotFound
+		}
+		if event.IsPaid {
+			return ErrAlreadyPaid
+		}
+
+		payment = &models.Payment{
+			EventID:     event.ID,
+			CreatorID:   event.CreatorID,
+			AmountCents: int(amountCents),
+			Currency:    "USD",
+			Status:      models.PaymentStatusPending,
+		}
+		if err := tx.Payment.Create(ctx, payment); err != nil {
+			return err
+		}
+		if err := tx.Payment.UpdateStatus(ctx, payment.ID, models.PaymentStatusCompleted, reference); err != nil {
+			return err
+		}
+		payment.Status = models.PaymentStatusCompleted
+		payment.StripePaymentIntentID = reference
+
+		return tx.Event.UpdatePaymentStatus(ctx, event.ID, true, true)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
 }
 
 func (s *PaymentService) HandleFailedPayment(ctx context.Context, sessionID string) error {
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/config"
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/repository"
+	"github.com/stripe/stripe-go/v76"
+)
+
+// stripeStub records what the stubbed Stripe API was sent
+type stripeStub struct {
+	metadata map[string]string
+	// refunds holds the payment intent and idempotency key of each refund
+	refunds []string
+}
+
+// stubStripe points the Stripe client at a test server that creates checkout
+// sessions, reports them as paid and accepts refunds
+func stubStripe(t *testing.T) *stripeStub {
+	t.Helper()
+	stub := &stripeStub{metadata: make(map[string]string)}
+	metadata := stub.metadata
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/checkout/sessions":
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			for _, key := range []string{"event_id", "creator_id"} {
+				metadata[key] = r.PostForm.Get("metadata[" + key + "]")
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"id": "cs_test_1", "object": "checkout.session", "url": "https://checkout.stripe.test/cs_test_1",
+			})
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/checkout/sessions/"):
+			json.NewEncoder(w).Encode(map[string]any{
+				"id":             strings.TrimPrefix(r.URL.Path, "/v1/checkout/sessions/"),
+				"object":         "checkout.session",
+				"payment_intent": "pi_test_1",
+				"payment_status": "paid",
+				"status":         "complete",
+				"metadata":       metadata,
+			})
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/refunds":
+			if err := r.ParseForm(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			stub.refunds = append(stub.refunds, r.PostForm.Get("payment_intent")+" "+r.Header.Get("Idempotency-Key"))
+			json.NewEncoder(w).Encode(map[string]any{"id": "re_test_1", "object": "refund", "status": "succeeded"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	stripe.Key = "sk_test_stub"
+	stripe.SetBackend(stripe.APIBackend, stripe.GetBackendWithConfig(stripe.APIBackend, &stripe.BackendConfig{
+		URL:               stripe.String(srv.URL),
+		MaxNetworkRetries: stripe.Int64(0),
+		LeveledLogger:     &stripe.LeveledLogger{Level: stripe.LevelNull},
+	}))
+	t.Cleanup(func() { stripe.SetBackend(stripe.APIBackend, nil) })
+	return stub
+}
+
+func TestPaymentServiceCheckout(t *testing.T) {
+	ctx := context.Background()
+	metadata := stubStripe(t).metadata
+	repos := newTestRepos(t)
+	creator := createTestCreator(t, repos, "creator@example.com")
+	event := createTestEvent(t, repos, creator.ID, 5)
+	svc := NewPaymentService(repos, config.StripeConfig{PriceCents: 1000})
+
+	checkout, err := svc.CreateCheckoutSession(ctx, event, "https://zenbali.test/ok", "https://zenbali.test/cancel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkout.SessionID != "cs_test_1" || checkout.SessionURL == "" {
+		t.Errorf("checkout = %+v", checkout)
+	}
+	if metadata["event_id"] != event.ID.String() || metadata["creator_id"] != creator.ID.String() {
+		t.Errorf("session metadata = %v", metadata)
+	}
+
+	payment, err := repos.Payment.GetByStripeSessionID(ctx, "cs_test_1")
+	if err != nil || payment == nil {
+		t.Fatalf("pending payment not recorded: %v", err)
+	}
+	if payment.Status != models.PaymentStatusPending || payment.AmountCents != 1000 {
+		t.Errorf("payment = %s of %d cents, want pending of 1000", payment.Status, payment.AmountCents)
+	}
+
+	paid, err := svc.VerifyCheckoutSession(ctx, event, "cs_test_1")
+	if err != nil || !paid {
+		t.Fatalf("VerifyCheckoutSession() = %t, %v", paid, err)
+	}
+	payment, _ = repos.Payment.GetByID(ctx, payment.ID)
+	if payment.Status != models.PaymentStatusCompleted || payment.StripePaymentIntentID != "pi_test_1" {
+		t.Errorf("payment = %s with intent %q, want completed with pi_test_1", payment.Status, payment.StripePaymentIntentID)
+	}
+	event, _ = repos.Event.GetByID(ctx, event.ID)
+	if !event.IsPaid || !event.IsPublished {
+		t.Error("paid event not published")
+	}
+
+	if _, err := svc.CreateCheckoutSession(ctx, event, "", ""); !errors.Is(err, ErrAlreadyPaid) {
+		t.Errorf("paying twice: err = %v, want ErrAlreadyPaid", err)
+	}
+}
+
+func TestPaymentServiceSessionMismatch(t *testing.T) {
+	ctx := context.Background()
+	stubStripe(t)
+	repos := newTestRepos(t)
+	creator := createTestCreator(t, repos, "creator@example.com")
+	paidFor := createTestEvent(t, repos, creator.ID, 5)
+	other := createTestEvent(t, repos, creator.ID, 6)
+	svc := NewPaymentService(repos, config.StripeConfig{PriceCents: 1000})
+
+	if _, err := svc.CreateCheckoutSession(ctx, paidFor, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.VerifyCheckoutSession(ctx, other, "cs_test_1"); !errors.Is(err, ErrSessionMismatch) {
+		t.Errorf("err = %v, want ErrSessionMismatch", err)
+	}
+	if err := svc.HandleSuccessfulPayment(ctx, "cs_unknown"); !errors.Is(err, ErrPaymentNotFound) {
+		t.Errorf("unknown session: err = %v, want ErrPaymentNotFound", err)
+	}
+}
+
+func TestPaymentServiceRecordManualPayment(t *testing.T) {
+	ctx := context.Background()
+	repos := newTestRepos(t)
+	creator := createTestCreator(t, repos, "creator@example.com")
+	event := createTestEvent(t, repos, creator.ID, 5)
+	svc := NewPaymentService(repos, config.StripeConfig{PriceCents: 1000})
+
+	payment, err := svc.RecordManualPayment(ctx, event.ID, 0, "bank-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payment.AmountCents != 1000 || payment.StripePaymentIntentID != "bank-123" {
+		t.Errorf("payment = %d cents with reference %q", payment.AmountCents, payment.StripePaymentIntentID)
+	}
+
+	list, err := svc.ListAll(ctx, 1, 10, models.PaymentStatusCompleted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 || list.Payments[0].EventTitle != event.Title || list.Payments[0].CreatorName != creator.Name {
+		t.Errorf("completed payments = %d, first %+v", list.Total, list.Payments)
+	}
+
+	if _, err := svc.RecordManualPayment(ctx, event.ID, 500, "bank-124"); !errors.Is(err, ErrAlreadyPaid) {
+		t.Errorf("paying twice: err = %v, want ErrAlreadyPaid", err)
+	}
+	if _, err := svc.RecordManualPayment(ctx, creator.ID, 0, "bank-125"); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("unknown event: err = %v, want ErrEventNotFound", err)
+	}
+}
+
+// recordingTx runs transactions that note when they lock an event and when
+// they change a payment's status
+type recordingTx struct {
+	repository.Transactor
+	calls *[]string
+}
+
+type recordingEvents struct {
+	repository.EventStore
+	calls *[]string
+}
+
+type recordingPayments struct {
+	repository.PaymentStore
+	calls *[]string
+}
+
+func (e recordingEvents) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*models.Event, error) {
+	*e.calls = append(*e.calls, "lock event")
+	return e.EventStore.GetByIDForUpdate(ctx, id)
+}
+
+func (p recordingPayments) UpdateStatus(ctx context.Context, id uuid.UUID, status, paymentIntentID string) error {
+	*p.calls = append(*p.calls, "payment "+status)
+	return p.PaymentStore.UpdateStatus(ctx, id, status, paymentIntentID)
+}
+
+func (t recordingTx) InTx(ctx context.Context, fn func(tx *repository.Repositories) error) error {
+	return t.Transactor.InTx(ctx, func(tx *repository.Repositories) error {
+		recording := *tx
+		recording.Event = recordingEvents{tx.Event, t.calls}
+		recording.Payment = recordingPayments{tx.Payment, t.calls}
+		return fn(&recording)
+	})
+}
+
+func TestPaymentServiceLocksEvent(t *testing.T) {
+	ctx := context.Background()
+	stubStripe(t)
+	repos := newTestRepos(t)
+	creator := createTestCreator(t, repos, "creator@example.com")
+	viaStripe := createTestEvent(t, repos, creator.ID, 5)
+	viaBank := createTestEvent(t, repos, creator.ID, 6)
+	svc := NewPaymentService(repos, config.StripeConfig{PriceCents: 1000})
+	if _, err := svc.CreateCheckoutSession(ctx, viaStripe, "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	repos.Tx = recordingTx{repos.Tx, &calls}
+	check := func(what string, want ...string) {
+		t.Helper()
+		if strings.Join(calls, ", ") != strings.Join(want, ", ") {
+			t.Errorf("%s: calls = %v, want %v", what, calls, want)
+		}
+		calls = nil
+	}
+
+	if err := svc.HandleSuccessfulPayment(ctx, "cs_test_1"); err != nil {
+		t.Fatal(err)
+	}
+	check("Stripe payment", "lock event", "payment completed")
+	completed, _ := repos.Payment.GetByStripeSessionID(ctx, "cs_test_1")
+
+	// The webhook arriving after the verify call changes nothing
+	if err := svc.HandleSuccessfulPayment(ctx, "cs_test_1"); err != nil {
+		t.Fatal(err)
+	}
+	check("repeated Stripe payment", "lock event")
+	again, _ := repos.Payment.GetByStripeSessionID(ctx, "cs_test_1")
+	if !again.UpdatedAt.Equal(completed.UpdatedAt) {
+		t.Error("repeated completion rewrote the payment")
+	}
+
+	if _, err := svc.RecordManualPayment(ctx, viaBank.ID, 0, "bank-123"); err != nil {
+		t.Fatal(err)
+	}
+	check("manual payment", "lock event", "payment completed")
+	if _, err := svc.RecordManualPayment(ctx, viaStripe.ID, 0, "bank-124"); !errors.Is(err, ErrAlreadyPaid) {
+		t.Errorf("manual payment of a Stripe-paid event: err = %v, want ErrAlreadyPaid", err)
+	}
+	check("refused manual payment", "lock event")
+}
+
+func TestPaymentServiceStripeAfterManualPayment(t *testing.T) {
+	ctx := context.Background()
+	stub := stubStripe(t)
+	repos := newTestRepos(t)
+	creator := createTestCreator(t, repos, "creator@example.com")
+	event := createTestEvent(t, repos, creator.ID, 5)
+	svc := NewPaymentService(repos, config.StripeConfig{PriceCents: 1000})
+
+	// The creator starts a checkout, then pays by bank transfer before the
+	// Stripe payment completes
+	if _, err := svc.CreateCheckoutSession(ctx, event, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.RecordManualPayment(ctx, event.ID, 0, "bank-123"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := svc.HandleSuccessfulPayment(ctx, "cs_test_1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	late, _ := repos.Payment.GetByStripeSessionID(ctx, "cs_test_1")
+	if late.Status != models.PaymentStatusRefunded {
+		t.Errorf("late Stripe payment is %s, want refunded", late.Status)
+	}
+	if len(stub.refunds) != 1 || stub.refunds[0] != "pi_test_1 superseded-"+late.ID.String() {
+		t.Errorf("refunds = %v, want one of pi_test_1 keyed on the payment", stub.refunds)
+	}
+
+	list, err := svc.ListAll(ctx, 1, 10, models.PaymentStatusCompleted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 || list.Payments[0].StripePaymentIntentID != "bank-123" {
+		t.Errorf("completed payments = %d, want only the bank transfer", list.Total)
+	}
+}
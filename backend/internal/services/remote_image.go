@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/storage"
+)
+
+var (
+	ErrInvalidImageURL    = newError(KindInvalid, "INVALID_IMAGE_URL", "image_url must be an http(s) URL")
+	ErrRemoteImageBlocked = newError(KindInvalid, "IMAGE_URL_BLOCKED", "image_url host is not allowed")
+	ErrRemoteImageFetch   = newError(KindInvalid, "IMAGE_FETCH_FAILED", "Failed to download image_url")
+)
+
+const (
+	remoteImageTimeout      = 20 * time.Second
+	remoteImageMaxRedirects = 5
+)
+
+// Ranges that are not covered by the netip helpers but must never be reached
+// from a server-side fetch.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// HostedKey returns the storage key of a URL that already points at our own
+// storage, so the image can be used without a fetch. Only confirmed uploads
+// registered to owner qualify; for anything else hosted is false and the URL
+// is fetched like any other.
+func (s *UploadService) HostedKey(ctx context.Context, rawURL string, owner models.UploadOwner) (key string, hosted bool, err error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if !storage.IsURL(rawURL) || s.uploads == nil {
+		return "", false, nil
+	}
+	key, ok := s.store.KeyFromURL(rawURL)
+	if !ok || IsStagingKey(key) {
+		return "", false, nil
+	}
+	upload, err := s.uploads.GetByKey(ctx, key)
+	if err != nil {
+		return "", false, err
+	}
+	if upload == nil || !ownsUpload(upload, owner) {
+		return "", false, nil
+	}
+	return key, true, nil
+}
+
+// SaveRemoteImage downloads an image from a public http(s) URL and stores it
+// like an uploaded file, returning its storage key.
+func (s *UploadService) SaveRemoteImage(ctx context.Context, rawURL string, owner models.UploadOwner) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return "", ErrInvalidImageURL
+	}
+	if target.User != nil {
+		return "", ErrInvalidImageURL
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, remoteImageTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return "", ErrInvalidImageURL
+	}
+	req.Header.Set("Accept", "image/jpeg, image/png, image/webp, image/heic, image/heif")
+	req.Header.Set("User-Agent", "ZenBali-ImageFetcher/1.0")
+
+	resp, err := remoteImageClient(s.remoteIPAllowed).Do(req)
+	if err != nil {
+		if errors.Is(err, ErrRemoteImageBlocked) {
+			return "", ErrRemoteImageBlocked
+		}
+		return "", fmt.Errorf("%w: %v", ErrRemoteImageFetch, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: status %d", ErrRemoteImageFetch, resp.StatusCode)
+	}
+
+	maxSize := int64(s.config.MaxSizeMB * 1024 * 1024)
+	if resp.ContentLength > maxSize {
+		return "", ErrFileTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrRemoteImageFetch, err)
+	}
+	if int64(len(data)) > maxSize {
+		return "", ErrFileTooLarge
+	}
+
+	// saveImage identifies the file from its bytes, not the server's headers
+	return s.saveImage(ctx, data, owner)
+}
+
+func (s *UploadService) isAllowedExt(ext string) bool {
+	for _, allowed := range s.config.AllowedExt {
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteImageClient returns a client that only connects to addresses allowed
+// accepts. The check runs on the resolved IP at dial time, so it also covers
+// redirects and DNS names that resolve to internal hosts.
+func remoteImageClient(allowed func(netip.Addr) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return ErrRemoteImageBlocked
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !allowed(addr) {
+				return ErrRemoteImageBlocked
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: remoteImageTimeout,
+		Transport: &http.Transport{
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 10 * time.Second,
+			MaxIdleConns:          1,
+			DisableKeepAlives:     true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= remoteImageMaxRedirects {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return ErrInvalidImageURL
+			}
+			return nil
+		},
+	}
+}
+
+// IsPublicIP reports whether addr is a globally routable unicast address
+func IsPublicIP(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+	if addr.Is4() && addr == netip.AddrFrom4([4]byte{255, 255, 255, 255}) {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/repository"
+)
+
+var (
+	ErrInvalidReportInterval = newError(KindInvalid, "INVALID_REPORT_INTERVAL", "interval must be day, week or month")
+	ErrInvalidReportRange    = newError(KindInvalid, "INVALID_REPORT_RANGE", fmt.Sprintf("from must not be after to, and the range must span at most %d intervals", MaxReportPoints))
+)
+
+// MaxReportPoints caps how many intervals one time series may have
+const MaxReportPoints = 366
+
+// ReportService builds the admin dashboard's time series
+type ReportService struct {
+	repos *repository.Repositories
+}
+
+func NewReportService(repos *repository.Repositories) *ReportService {
+	return &ReportService{repos: repos}
+}
+
+// TimeSeries returns new events, new creators, paid postings, revenue and
+// visitors from from to to, inclusive, per interval. The totals are compared
+// with the range of the same length that ends the day before from.
+func (s *ReportService) TimeSeries(ctx context.Context, from, to time.Time, interval string) (*models.TimeSeriesReport, error) {
+	if !models.IsReportInterval(interval) {
+		return nil, ErrInvalidReportInterval
+	}
+	if to.Before(from) || reportPoints(from, to, interval) > MaxReportPoints {
+		return nil, ErrInvalidReportRange
+	}
+
+	previousTo := from.AddDate(0, 0, -1)
+	previousFrom := previousTo.Add(-to.Sub(from))
+
+	series, err := s.repos.Report.Series(ctx, from, to, interval)
+	if err != nil {
+		return nil, err
+	}
+	previousSeries, err := s.repos.Report.Series(ctx, previousFrom, previousTo, interval)
+	if err != nil {
+		return nil, err
+	}
+	funnel, err := s.repos.Report.Funnel(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	previousFunnel, err := s.repos.Report.Funnel(ctx, previousFrom, previousTo)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.TimeSeriesReport{
+		From:           from.Format("2006-01-02"),
+		To:             to.Format("2006-01-02"),
+		Interval:       interval,
+		PreviousFrom:   previousFrom.Format("2006-01-02"),
+		PreviousTo:     previousTo.Format("2006-01-02"),
+		Series:         series,
+		Totals:         sumReportPoints(series),
+		Previous:       sumReportPoints(previousSeries),
+		Funnel:         withRates(*funnel),
+		PreviousFunnel: withRates(*previousFunnel),
+	}
+	report.Change = models.ReportChange{
+		NewEvents:      percentChange(float64(report.Totals.NewEvents), float64(report.Previous.NewEvents)),
+		NewCreators:    percentChange(float64(report.Totals.NewCreators), float64(report.Previous.NewCreators)),
+		PaidPostings:   percentChange(float64(report.Totals.PaidPostings), float64(report.Previous.PaidPostings)),
+		Revenue:        percentChange(report.Totals.Revenue, report.Previous.Revenue),
+		Visitors:       percentChange(float64(report.Totals.Visitors), float64(report.Previous.Visitors)),
+		UniqueVisitors: percentChange(float64(report.Totals.UniqueVisitors), float64(report.Previous.UniqueVisitors)),
+	}
+	return report, nil
+}
+
+// reportPoints counts the intervals from from to to, stopping once past the
+// cap
+func reportPoints(from, to time.Time, interval string) int {
+	points := 0
+	for start := truncateInterval(from, interval); !start.After(to) && points <= MaxReportPoints; start = nextInterval(start, interval) {
+		points++
+	}
+	return points
+}
+
+// truncateInterval returns the first day of the interval t is in, as
+// Postgres's date_trunc does
+func truncateInterval(t time.Time, interval string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case models.ReportIntervalWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case models.ReportIntervalMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	default:
+		return day
+	}
+}
+
+func nextInterval(start time.Time, interval string) time.Time {
+	switch interval {
+	case models.ReportIntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case models.ReportIntervalMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+func sumReportPoints(points []*models.ReportPoint) models.ReportTotals {
+	var totals models.ReportTotals
+	for _, point := range points {
+		totals.Add(point.ReportTotals)
+	}
+	return totals
+}
+
+func withRates(funnel models.ConversionFunnel) models.ConversionFunnel {
+	funnel.PostedRate = rate(funnel.Posted, funnel.Registered)
+	funnel.PaidRate = rate(funnel.Paid, funnel.Posted)
+	funnel.OverallRate = rate(funnel.Paid, funnel.Registered)
+	return funnel
+}
+
+// rate is part as a percentage of whole, to one decimal
+func rate(part, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(whole)*1000) / 10
+}
+
+// percentChange is the change from previous to current as a percentage, to
+// one decimal, or nil if previous is 0
+func percentChange(current, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := math.Round((current-previous)/previous*1000) / 10
+	return &change
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/net1io/zenbali/internal/models"
+)
+
+func TestReportPoints(t *testing.T) {
+	day := func(s string) time.Time {
+		t.Helper()
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		from, to string
+		interval string
+		want     int
+	}{
+		{"2024-03-01", "2024-03-01", models.ReportIntervalDay, 1},
+		{"2024-03-01", "2024-03-31", models.ReportIntervalDay, 31},
+		// Friday to the next Monday spans two weeks
+		{"2024-03-01", "2024-03-04", models.ReportIntervalWeek, 2},
+		{"2024-01-31", "2024-03-01", models.ReportIntervalMonth, 3},
+		{"2020-01-01", "2029-12-31", models.ReportIntervalMonth, 120},
+		{"2020-01-01", "2029-12-31", models.ReportIntervalDay, MaxReportPoints + 1},
+	}
+
+	for _, tt := range tests {
+		if got := reportPoints(day(tt.from), day(tt.to), tt.interval); got != tt.want {
+			t.Errorf("reportPoints(%s, %s, %s) = %d, want %d", tt.from, tt.to, tt.interval, got, tt.want)
+		}
+	}
+}
+
+func TestPercentChangeAndRates(t *testing.T) {
+	if got := percentChange(5, 0); got != nil {
+		t.Errorf("percentChange from 0 = %v, want nil", *got)
+	}
+	if got := percentChange(15, 10); got == nil || *got != 50 {
+		t.Errorf("percentChange(15, 10) = %v, want 50", got)
+	}
+	if got := percentChange(1, 3); got == nil || *got != -66.7 {
+		t.Errorf("percentChange(1, 3) = %v, want -66.7", got)
+	}
+
+	funnel := withRates(models.ConversionFunnel{Registered: 8, Posted: 4, Paid: 1})
+	if funnel.PostedRate != 50 || funnel.PaidRate != 25 || funnel.OverallRate != 12.5 {
+		t.Errorf("withRates() = %+v", funnel)
+	}
+	if empty := withRates(models.ConversionFunnel{}); empty.OverallRate != 0 {
+		t.Errorf("withRates() of no creators = %+v", empty)
+	}
+}
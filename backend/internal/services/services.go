@@ -2,9 +2,14 @@ package services
 
 // Services holds all service instances
 type Services struct {
-	Auth    *AuthService
-	Event   *EventService
-	Payment *PaymentService
-	Upload  *UploadService
-	Visitor *VisitorService
+	Auth             *AuthService
+	AgentKey         *AgentKeyService
+	Event            *EventService
+	EventStats       *EventStatsService
+	Payment          *PaymentService
+	Report           *ReportService
+	Upload           *UploadService
+	UploadSweeper    *UploadSweeper
+	Visitor          *VisitorService
+	VisitorRetention *VisitorRetention
 }
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/repository"
+	"github.com/net1io/zenbali/internal/repository/memory"
+)
+
+// newTestRepos returns repositories backed by a fresh in-memory database
+func newTestRepos(t *testing.T) *repository.Repositories {
+	t.Helper()
+	return memory.New().Repositories()
+}
+
+func createTestCreator(t *testing.T, repos *repository.Repositories, email string) *models.Creator {
+	t.Helper()
+	creator := &models.Creator{Name: "Made", Email: email, Mobile: "+628111", PasswordHash: "x"}
+	if err := repos.Creator.Create(context.Background(), creator); err != nil {
+		t.Fatal(err)
+	}
+	return creator
+}
+
+// createTestEvent stores an unpaid event of creatorID days days from today
+func createTestEvent(t *testing.T, repos *repository.Repositories, creatorID uuid.UUID, days int) *models.Event {
+	t.Helper()
+	event := &models.Event{
+		CreatorID:      creatorID,
+		Title:          "Sunrise Yoga",
+		EventDate:      time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, days),
+		LocationID:     1,
+		EventTypeID:    1,
+		EntranceTypeID: 1,
+		ContactEmail:   "yoga@example.com",
+	}
+	if err := repos.Event.Create(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
@@ -1,49 +1,52 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"errors"
-	"fmt"
 	"io"
 	"mime/multipart"
-	"os"
-	"path"
+	"net/netip"
 	"path/filepath"
 	"strings"
 	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/google/uuid"
 	"github.com/net1io/zenbali/internal/config"
+	"github.com/net1io/zenbali/internal/imaging"
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/repository"
+	"github.com/net1io/zenbali/internal/storage"
 )
 
 var (
-	ErrFileTooLarge    = errors.New("file too large")
-	ErrInvalidFileType = errors.New("invalid file type")
+	ErrFileTooLarge    = newError(KindInvalid, "FILE_TOO_LARGE", "File too large")
+	ErrInvalidFileType = newError(KindInvalid, "INVALID_FILE_TYPE", "Invalid file type. Allowed: jpg, jpeg, png, webp, heic, heif")
+	ErrImageDimensions = newError(KindInvalid, "IMAGE_TOO_LARGE", "Image dimensions too large")
+	ErrHEICUnsupported = newError(KindInvalid, "HEIC_UNSUPPORTED", "HEIC images cannot be converted on this server. Please upload a JPEG, PNG or WebP")
 )
 
 type UploadService struct {
-	config    config.UploadConfig
-	gcsClient *storage.Client
+	config  config.UploadConfig
+	store   storage.BlobStore
+	uploads repository.UploadStore
+	// remoteIPAllowed decides which addresses SaveRemoteImage may connect to
+	remoteIPAllowed func(netip.Addr) bool
 }
 
-func NewUploadService(ctx context.Context, cfg config.UploadConfig) (*UploadService, error) {
-	service := &UploadService{config: cfg}
-
-	if cfg.Backend != "gcs" {
-		return service, nil
-	}
-
-	client, err := storage.NewClient(ctx)
+// NewUploadService creates the service for the configured backend. uploads
+// may be nil, in which case stored files are not tracked in the registry.
+func NewUploadService(ctx context.Context, cfg config.UploadConfig, uploads repository.UploadStore) (*UploadService, error) {
+	store, err := storage.New(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("create GCS client: %w", err)
+		return nil, err
 	}
-
-	service.gcsClient = client
-	return service, nil
+	return &UploadService{config: cfg, store: store, uploads: uploads, remoteIPAllowed: IsPublicIP}, nil
 }
 
-func (s *UploadService) SaveEventImage(file multipart.File, header *multipart.FileHeader) (string, error) {
+// SaveEventImage validates an uploaded image, stores its renditions and
+// returns the storage key of the full-size JPEG
+func (s *UploadService) SaveEventImage(ctx context.Context, file multipart.File, header *multipart.FileHeader, owner models.UploadOwner) (string, error) {
 	maxSize := int64(s.config.MaxSizeMB * 1024 * 1024)
 	if header.Size > maxSize {
 		return "", ErrFileTooLarge
@@ -61,123 +64,185 @@ func (s *UploadService) SaveEventImage(file multipart.File, header *multipart.Fi
 		return "", ErrInvalidFileType
 	}
 
-	filename := fmt.Sprintf("%s%s", uuid.New().String(), ext)
-	if s.config.Backend == "gcs" {
-		return s.saveToGCS(file, header, filename)
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) > maxSize {
+		return "", ErrFileTooLarge
 	}
 
-	return s.saveToLocal(file, filename)
+	return s.saveImage(ctx, data, owner)
 }
 
-func (s *UploadService) saveToLocal(file multipart.File, filename string) (string, error) {
-	filePath := filepath.Join(s.config.Dir, filename)
-
-	dst, err := os.Create(filePath)
+// saveImage stores every rendition of an image, registers the upload and
+// returns the key of the full-size JPEG, which the other renditions' keys are
+// derived from.
+func (s *UploadService) saveImage(ctx context.Context, data []byte, owner models.UploadOwner) (string, error) {
+	// The format is taken from the bytes; the client's name and Content-Type
+	// are never trusted
+	info, err := imaging.Validate(data)
 	if err != nil {
-		return "", err
+		return "", uploadImageError(err)
 	}
-	defer dst.Close()
-
-	if _, err := io.Copy(dst, file); err != nil {
-		_ = os.Remove(filePath)
-		return "", err
+	if !s.isAllowedExt(imaging.Extension(info.Format)) {
+		return "", ErrInvalidFileType
 	}
 
-	return "/uploads/" + filename, nil
-}
+	renditions, err := imaging.Process(data)
+	if err != nil {
+		return "", uploadImageError(err)
+	}
 
-func (s *UploadService) saveToGCS(file multipart.File, header *multipart.FileHeader, filename string) (string, error) {
-	if s.gcsClient == nil {
-		return "", errors.New("GCS client not configured")
+	id := uuid.New().String()
+	var fullKey string
+	var saved []string
+	var size int64
+	discard := func() {
+		for _, k := range saved {
+			_ = s.deleteKey(k)
+		}
+	}
+	for _, rendition := range renditions {
+		key := imaging.FileName(id, rendition.Variant, rendition.Format)
+		if err := s.save(bytes.NewReader(rendition.Data), key, rendition.ContentType()); err != nil {
+			discard()
+			return "", err
+		}
+		saved = append(saved, key)
+		size += int64(len(rendition.Data))
+		if rendition.Variant == imaging.VariantFull && rendition.Format == imaging.FormatJPEG {
+			fullKey = key
+		}
 	}
 
-	objectName := s.objectName(filename)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	if s.uploads != nil {
+		upload := &models.Upload{
+			StorageKey: fullKey,
+			Source:     owner.Source,
+			CreatorID:  owner.CreatorID,
+			AgentKeyID: owner.AgentKeyID,
+			SizeBytes:  size,
+		}
+		if err := s.uploads.Create(ctx, upload); err != nil {
+			discard()
+			return "", err
+		}
+	}
 
-	writer := s.gcsClient.Bucket(s.config.GCSBucket).Object(objectName).NewWriter(ctx)
-	writer.ContentType = header.Header.Get("Content-Type")
-	writer.CacheControl = "public, max-age=31536000"
+	return fullKey, nil
+}
 
-	if _, err := io.Copy(writer, file); err != nil {
-		_ = writer.Close()
-		return "", err
+// Attach records that an event now uses the image ref points at. References
+// outside our storage are ignored.
+func (s *UploadService) Attach(ctx context.Context, ref string, eventID uuid.UUID) error {
+	key, ok := s.Key(ref)
+	if !ok || s.uploads == nil {
+		return nil
 	}
+	return s.uploads.Attach(ctx, key, eventID)
+}
 
-	if err := writer.Close(); err != nil {
-		return "", err
+// Release marks an image as no longer used by its event. The files are kept
+// for the grace period and then removed by the sweeper, which also retries
+// deletes that fail. Without a registry the files are deleted immediately.
+func (s *UploadService) Release(ctx context.Context, ref string) error {
+	key, ok := s.Key(ref)
+	if !ok {
+		return nil
 	}
+	if s.uploads == nil {
+		return s.DeleteFile(key)
+	}
+	return s.uploads.Detach(ctx, key)
+}
+
+func uploadImageError(err error) error {
+	switch {
+	case errors.Is(err, imaging.ErrImageDimensions):
+		return ErrImageDimensions
+	case errors.Is(err, imaging.ErrHEIFDecoderUnavailable):
+		return ErrHEICUnsupported
+	case errors.Is(err, imaging.ErrUnsupportedImage), errors.Is(err, imaging.ErrPolyglotImage):
+		return ErrInvalidFileType
+	}
+	return err
+}
 
-	return s.gcsPublicURL(objectName), nil
+func (s *UploadService) save(file io.Reader, key, contentType string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return s.store.Put(ctx, key, file, contentType)
 }
 
-func (s *UploadService) DeleteFile(imageURL string) error {
-	if imageURL == "" {
+// DeleteFile removes a stored image together with all of its renditions. ref
+// may be a key or, for references written before keys, one of our URLs;
+// anything else is not ours and is left alone.
+func (s *UploadService) DeleteFile(ref string) error {
+	key, ok := s.Key(ref)
+	if !ok {
 		return nil
 	}
 
-	if strings.HasPrefix(imageURL, "/uploads/") {
-		filename := strings.TrimPrefix(imageURL, "/uploads/")
-		filePath := filepath.Join(s.config.Dir, filename)
-		if _, err := os.Stat(filePath); os.IsNotExist(err) {
-			return nil
-		}
-		return os.Remove(filePath)
+	keys := imaging.RenditionURLs(key)
+	if len(keys) == 0 {
+		return s.deleteKey(key)
 	}
 
-	objectName, ok := s.gcsObjectNameFromURL(imageURL)
-	if !ok || s.gcsClient == nil {
-		return nil
+	var firstErr error
+	for _, k := range keys {
+		if err := s.deleteKey(k); err != nil && firstErr == nil {
+			firstErr = err
+		}
 	}
+	return firstErr
+}
 
+func (s *UploadService) deleteKey(key string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
-
-	err := s.gcsClient.Bucket(s.config.GCSBucket).Object(objectName).Delete(ctx)
-	if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
-		return err
-	}
-
-	return nil
+	return s.store.Delete(ctx, key)
 }
 
-func (s *UploadService) GetMaxSizeMB() int {
-	return s.config.MaxSizeMB
+// Key returns the storage key an image reference points at. ok is false for
+// URLs that are not in our store.
+func (s *UploadService) Key(ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", false
+	}
+	if !storage.IsURL(ref) {
+		return ref, true
+	}
+	return s.store.KeyFromURL(ref)
 }
 
-func (s *UploadService) GetAllowedExtensions() []string {
-	return s.config.AllowedExt
+// ImageRef normalises an image reference for storage: our own URLs become
+// keys, anything else is kept as given
+func (s *UploadService) ImageRef(ref string) string {
+	if key, ok := s.Key(ref); ok {
+		return key
+	}
+	return strings.TrimSpace(ref)
 }
 
-func (s *UploadService) objectName(filename string) string {
-	prefix := strings.Trim(strings.TrimSpace(s.config.GCSPrefix), "/")
-	if prefix == "" {
-		return filename
+// URL returns the public URL for an image reference. Keys are resolved
+// against the store; URLs are returned unchanged.
+func (s *UploadService) URL(ref string) string {
+	if ref == "" || storage.IsURL(ref) {
+		return ref
 	}
-	return path.Join(prefix, filename)
+	return s.store.URL(ref)
 }
 
-func (s *UploadService) gcsPublicURL(objectName string) string {
-	base := strings.TrimSuffix(strings.TrimSpace(s.config.GCSPublicBase), "/")
-	if base == "" {
-		base = "https://storage.googleapis.com/" + s.config.GCSBucket
-	}
-	return base + "/" + objectName
+func (s *UploadService) GetMaxSizeMB() int {
+	return s.config.MaxSizeMB
 }
 
-func (s *UploadService) gcsObjectNameFromURL(imageURL string) (string, bool) {
-	publicBase := strings.TrimSuffix(strings.TrimSpace(s.config.GCSPublicBase), "/")
-	defaultBase := "https://storage.googleapis.com/" + s.config.GCSBucket
-	gsPrefix := "gs://" + s.config.GCSBucket + "/"
+func (s *UploadService) GetDirectMaxSizeMB() int {
+	return s.config.DirectMaxSizeMB
+}
 
-	switch {
-	case publicBase != "" && strings.HasPrefix(imageURL, publicBase+"/"):
-		return strings.TrimPrefix(imageURL, publicBase+"/"), true
-	case strings.HasPrefix(imageURL, defaultBase+"/"):
-		return strings.TrimPrefix(imageURL, defaultBase+"/"), true
-	case strings.HasPrefix(imageURL, gsPrefix):
-		return strings.TrimPrefix(imageURL, gsPrefix), true
-	default:
-		return "", false
-	}
+func (s *UploadService) GetAllowedExtensions() []string {
+	return s.config.AllowedExt
 }
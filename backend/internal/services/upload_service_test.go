@@ -0,0 +1,159 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/net1io/zenbali/internal/config"
+	"github.com/net1io/zenbali/internal/models"
+)
+
+func newLocalUploadService(t *testing.T) *UploadService {
+	t.Helper()
+	service, err := NewUploadService(context.Background(), config.UploadConfig{
+		Backend:             "local",
+		Dir:                 t.TempDir(),
+		MaxSizeMB:           5,
+		AllowedExt:          []string{".jpg", ".jpeg", ".png", ".webp"},
+		DirectMaxSizeMB:     1,
+		DirectURLTTLMinutes: 15,
+		DirectUploadURL:     "http://localhost:8080/api/uploads/direct",
+		DirectUploadSecret:  "secret",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+func uploadFile(t *testing.T, name string, data []byte) (multipart.File, *multipart.FileHeader) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return file, &multipart.FileHeader{Filename: name, Size: int64(len(data))}
+}
+
+func TestSaveEventImageRejectsDisguisedFiles(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewNRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		data     []byte
+		want     error
+	}{
+		{"html with image extension", "event.jpg", []byte("<html><script>alert(1)</script></html>"), ErrInvalidFileType},
+		{"png with appended script", "event.png", append(pngData.Bytes(), []byte("<script>alert(1)</script>")...), ErrInvalidFileType},
+		{"disallowed extension", "event.svg", pngData.Bytes(), ErrInvalidFileType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newLocalUploadService(t)
+			file, header := uploadFile(t, tt.filename, tt.data)
+			if _, err := service.SaveEventImage(context.Background(), file, header, models.UploadOwner{Source: models.UploadSourceCreator}); err != tt.want {
+				t.Fatalf("SaveEventImage() error = %v, want %v", err, tt.want)
+			}
+			entries, _ := os.ReadDir(service.config.Dir)
+			if len(entries) != 0 {
+				t.Fatalf("rejected upload left %d files behind", len(entries))
+			}
+		})
+	}
+}
+
+func TestSaveEventImageStoresDetectedFormat(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewNRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+
+	// A PNG named .jpg is accepted: the bytes decide what it is, not the name
+	service := newLocalUploadService(t)
+	file, header := uploadFile(t, "event.jpg", pngData.Bytes())
+	key, err := service.SaveEventImage(context.Background(), file, header, models.UploadOwner{Source: models.UploadSourceCreator})
+	if err != nil {
+		t.Fatalf("SaveEventImage() error = %v", err)
+	}
+	if !strings.HasSuffix(key, "-full.jpg") || strings.Contains(key, "/") {
+		t.Fatalf("SaveEventImage() = %q, want key <id>-full.jpg", key)
+	}
+	if url := service.URL(key); url != "/uploads/"+key {
+		t.Fatalf("URL(%q) = %q", key, url)
+	}
+
+	entries, _ := os.ReadDir(service.config.Dir)
+	if len(entries) != 6 {
+		t.Fatalf("stored %d files, want 6 renditions", len(entries))
+	}
+}
+
+func TestDirectUploadRoundTrip(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewNRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	service := newLocalUploadService(t)
+	owner := models.UploadOwner{Source: models.UploadSourceCreator}
+
+	if _, err := service.CreateDirectUpload(ctx, &models.DirectUploadRequest{ContentType: "text/html", SizeBytes: 10}, owner); err != ErrInvalidFileType {
+		t.Fatalf("CreateDirectUpload(text/html) error = %v, want ErrInvalidFileType", err)
+	}
+	if _, err := service.CreateDirectUpload(ctx, &models.DirectUploadRequest{ContentType: "image/png", SizeBytes: 2 << 20}, owner); err != ErrFileTooLarge {
+		t.Fatalf("CreateDirectUpload(2MB) error = %v, want ErrFileTooLarge", err)
+	}
+
+	upload, err := service.CreateDirectUpload(ctx, &models.DirectUploadRequest{
+		ContentType: "image/png",
+		SizeBytes:   int64(pngData.Len()),
+	}, owner)
+	if err != nil {
+		t.Fatalf("CreateDirectUpload() error = %v", err)
+	}
+	if _, err := service.ConfirmDirectUpload(ctx, upload.Key, owner); err != ErrDirectUploadNotFound {
+		t.Fatalf("ConfirmDirectUpload() before upload error = %v, want ErrDirectUploadNotFound", err)
+	}
+
+	signed, err := url.Parse(upload.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	short := bytes.NewReader(pngData.Bytes()[:10])
+	if err := service.ReceiveSignedPut(ctx, signed.Query(), "image/png", short); err != ErrDirectUploadSize {
+		t.Fatalf("ReceiveSignedPut(short body) error = %v, want ErrDirectUploadSize", err)
+	}
+	if err := service.ReceiveSignedPut(ctx, signed.Query(), upload.Headers["Content-Type"], bytes.NewReader(pngData.Bytes())); err != nil {
+		t.Fatalf("ReceiveSignedPut() error = %v", err)
+	}
+
+	key, err := service.ConfirmDirectUpload(ctx, upload.Key, owner)
+	if err != nil {
+		t.Fatalf("ConfirmDirectUpload() error = %v", err)
+	}
+	if !strings.HasSuffix(key, "-full.jpg") || IsStagingKey(key) {
+		t.Fatalf("ConfirmDirectUpload() = %q, want a rendition key", key)
+	}
+	if _, err := os.Stat(filepath.Join(service.config.Dir, upload.Key)); !os.IsNotExist(err) {
+		t.Fatalf("staged upload was not removed: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/repository"
+)
+
+// uploadSweepBatch caps how many orphans one sweep deletes, so a backlog is
+// worked off over several runs rather than in one long one
+const uploadSweepBatch = 500
+
+// UploadSweeper deletes uploads that no event uses once their grace period
+// has passed: images uploaded but never attached, and images replaced or left
+// behind by deleted events.
+type UploadSweeper struct {
+	repos  *repository.Repositories
+	upload *UploadService
+	grace  time.Duration
+}
+
+func NewUploadSweeper(repos *repository.Repositories, upload *UploadService, grace time.Duration) *UploadSweeper {
+	return &UploadSweeper{repos: repos, upload: upload, grace: grace}
+}
+
+// Sweep deletes orphaned uploads, or with dryRun only reports what it would
+// delete.
+func (s *UploadSweeper) Sweep(ctx context.Context, dryRun bool) (*models.UploadSweepReport, error) {
+	report := &models.UploadSweepReport{
+		DryRun:  dryRun,
+		Cutoff:  time.Now().Add(-s.grace),
+		Uploads: []*models.Upload{},
+	}
+
+	if !dryRun {
+		reattached, err := s.repos.Upload.Reattach(ctx)
+		if err != nil {
+			return nil, err
+		}
+		report.Reattached = reattached
+	}
+
+	orphans, err := s.repos.Upload.ListOrphans(ctx, report.Cutoff, uploadSweepBatch)
+	if err != nil {
+		return nil, err
+	}
+	report.Orphans = len(orphans)
+
+	for _, upload := range orphans {
+		report.Uploads = append(report.Uploads, upload)
+		if dryRun {
+			report.Bytes += upload.SizeBytes
+			continue
+		}
+
+		// Drop the registry row first: if the upload was attached since it
+		// was listed, the row stays and so do the files
+		deleted, err := s.repos.Upload.Delete(ctx, upload.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !deleted {
+			continue
+		}
+
+		if err := s.upload.DeleteFile(upload.StorageKey); err != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", upload.StorageKey, err))
+			// Re-register so a later sweep retries
+			if err := s.repos.Upload.Detach(ctx, upload.StorageKey); err != nil {
+				log.Printf("WARN failed to re-register upload %s: %v", upload.StorageKey, err)
+			}
+			continue
+		}
+		report.Deleted++
+		report.Bytes += upload.SizeBytes
+	}
+
+	return report, nil
+}
+
+// Run sweeps every interval until ctx is cancelled
+func (s *UploadSweeper) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := s.Sweep(ctx, false)
+			if err != nil {
+				log.Printf("ERROR upload sweep failed: %v", err)
+				continue
+			}
+			if report.Deleted > 0 || report.Failed > 0 {
+				log.Printf("Upload sweep deleted %d orphaned uploads (%d bytes), %d failed", report.Deleted, report.Bytes, report.Failed)
+			}
+		}
+	}
+}
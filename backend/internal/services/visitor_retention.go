@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/repository"
+)
+
+// visitorRollupDays is how far back each run recomputes rollups. Only today's
+// and yesterday's change, but a week covers any downtime of the job.
+const visitorRollupDays = 7
+
+// VisitorRetention recomputes the daily visitor rollups from raw visits, then
+// deletes visits older than the retention period, the salts of past days and
+// the event interactions hashed with them.
+type VisitorRetention struct {
+	repos *repository.Repositories
+	// retentionDays of 0 keeps raw visits forever
+	retentionDays int
+}
+
+func NewVisitorRetention(repos *repository.Repositories, retentionDays int) *VisitorRetention {
+	return &VisitorRetention{repos: repos, retentionDays: retentionDays}
+}
+
+// RunOnce rolls up and prunes once
+func (s *VisitorRetention) RunOnce(ctx context.Context) (*models.VisitorRetentionReport, error) {
+	report := &models.VisitorRetentionReport{}
+
+	days := visitorRollupDays
+	if s.retentionDays > 0 && s.retentionDays < days {
+		days = s.retentionDays
+	}
+	rolledUp, err := s.repos.Visitor.RollUp(ctx, days)
+	if err != nil {
+		return nil, err
+	}
+	report.DaysRolledUp = rolledUp
+
+	if s.retentionDays > 0 {
+		deleted, err := s.repos.Visitor.DeleteBefore(ctx, s.retentionDays)
+		if err != nil {
+			return nil, err
+		}
+		report.VisitsDeleted = deleted
+	}
+
+	salts, err := s.repos.Visitor.DeleteOldSalts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.SaltsDeleted = salts
+
+	interactions, err := s.repos.EventStats.DeleteOldInteractions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.InteractionsDeleted = interactions
+
+	return report, nil
+}
+
+// Run runs the job now and then every interval until ctx is cancelled
+func (s *VisitorRetention) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := s.RunOnce(ctx)
+		if err != nil {
+			log.Printf("ERROR visitor retention failed: %v", err)
+		} else if report.VisitsDeleted > 0 {
+			log.Printf("Visitor retention deleted %d visits older than %d days", report.VisitsDeleted, s.retentionDays)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
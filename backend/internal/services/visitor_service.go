@@ -2,70 +2,136 @@ package services
 
 import (
 	"context"
-	"encoding/json"
-	"net/http"
-	"strings"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/netip"
 	"time"
 
+	"github.com/net1io/zenbali/internal/geoip"
 	"github.com/net1io/zenbali/internal/models"
 	"github.com/net1io/zenbali/internal/repository"
+	"github.com/net1io/zenbali/internal/useragent"
 )
 
 type VisitorService struct {
 	repos *repository.Repositories
+	// geo is nil when no GeoIP database is configured or it failed to open
+	geo *geoip.Locator
+	// siteHost is our own host, whose referrals are internal navigation
+	siteHost string
 }
 
-func NewVisitorService(repos *repository.Repositories) *VisitorService {
-	return &VisitorService{repos: repos}
+func NewVisitorService(repos *repository.Repositories, geo *geoip.Locator, baseURL string) *VisitorService {
+	return &VisitorService{repos: repos, geo: geo, siteHost: siteHostOf(baseURL)}
 }
 
-func (s *VisitorService) TrackVisitor(ctx context.Context, ipAddress, userAgent string) error {
-	// Get location from IP (using free IP-API service)
+// TrackVisitor records a visit. Neither the address nor the user agent is
+// stored: the pair is hashed with today's salt to count unique visitors, and
+// the user agent is reduced to browser, OS and device class. The attribution
+// the page reported is cleaned and stored with the visit. Visits by crawlers
+// and scripts are flagged, and counted apart from people's.
+func (s *VisitorService) TrackVisitor(ctx context.Context, ipAddress, userAgent string, attribution models.Attribution) error {
 	country, city := s.getLocationFromIP(ipAddress)
 
+	hash, err := s.visitorHash(ctx, ipAddress, userAgent)
+	if err != nil {
+		return err
+	}
+	client := useragent.Parse(userAgent)
+
 	visitor := &models.Visitor{
-		IPAddress: ipAddress,
-		UserAgent: userAgent,
-		Country:   country,
-		City:      city,
+		VisitorHash: hash,
+		Browser:     client.Browser,
+		OS:          client.OS,
+		Device:      client.Device,
+		Country:     country,
+		City:        city,
+		Attribution: normalizeAttribution(attribution, s.siteHost),
+	}
+	if bot := useragent.Bot(userAgent); bot != "" {
+		visitor.IsBot = true
+		visitor.Browser = bot
 	}
 
 	return s.repos.Visitor.Create(ctx, visitor)
 }
 
+// visitorHash identifies a visitor for the current day only
+func (s *VisitorService) visitorHash(ctx context.Context, ipAddress, userAgent string) (string, error) {
+	candidate := make([]byte, 32)
+	if _, err := rand.Read(candidate); err != nil {
+		return "", err
+	}
+	salt, err := s.repos.Visitor.DailySalt(ctx, candidate)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(ipAddress + "\n" + userAgent))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// BotReport returns the visits by bots from from to to, inclusive, by bot
+func (s *VisitorService) BotReport(ctx context.Context, from, to time.Time) (*models.BotReport, error) {
+	if err := validStatsRange(from, to); err != nil {
+		return nil, err
+	}
+
+	visits, uniqueVisitors, err := s.repos.Visitor.BotTotals(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	bots, err := s.repos.Visitor.SegmentTotals(ctx, models.VisitorDimensionBot, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.BotReport{
+		From:           from.Format("2006-01-02"),
+		To:             to.Format("2006-01-02"),
+		Visits:         visits,
+		UniqueVisitors: uniqueVisitors,
+		Bots:           bots,
+	}, nil
+}
+
 func (s *VisitorService) GetStats(ctx context.Context) (*models.VisitorStats, error) {
 	return s.repos.Visitor.GetStats(ctx)
 }
 
+// getLocationFromIP looks the address up in the local GeoIP database.
+// Loopback, private and other non-routable addresses are reported as Local.
 func (s *VisitorService) getLocationFromIP(ip string) (country, city string) {
-	// Skip for localhost/private IPs
-	if ip == "" || ip == "127.0.0.1" || ip == "::1" || strings.HasPrefix(ip, "192.168.") || strings.HasPrefix(ip, "10.") {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "Unknown", "Unknown"
+	}
+	if !IsPublicIP(addr) {
 		return "Local", "Local"
 	}
-
-	// Use free IP geolocation API
-	client := &http.Client{Timeout: 2 * time.Second}
-	resp, err := client.Get("http://ip-api.com/json/" + ip + "?fields=country,city")
-	if err != nil {
+	if s.geo == nil {
 		return "Unknown", "Unknown"
 	}
-	defer resp.Body.Close()
 
-	var result struct {
-		Country string `json:"country"`
-		City    string `json:"city"`
+	loc, found, err := s.geo.Lookup(addr)
+	if err != nil {
+		log.Printf("WARN GeoIP lookup failed for %s: %v", addr, err)
+		return "Unknown", "Unknown"
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if !found {
 		return "Unknown", "Unknown"
 	}
 
-	if result.Country == "" {
-		result.Country = "Unknown"
+	country, city = loc.Country, loc.City
+	if country == "" {
+		country = "Unknown"
 	}
-	if result.City == "" {
-		result.City = "Unknown"
+	if city == "" {
+		city = "Unknown"
 	}
-
-	return result.Country, result.City
+	return country, city
 }
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// GCSStore keeps files in a Google Cloud Storage bucket
+type GCSStore struct {
+	client     *storage.Client
+	bucket     string
+	prefix     string
+	publicBase string
+}
+
+func NewGCSStore(ctx context.Context, bucket, prefix, publicBase string) (*GCSStore, error) {
+	if bucket == "" {
+		return nil, errors.New("GCS bucket not configured")
+	}
+
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create GCS client: %w", err)
+	}
+
+	return &GCSStore{
+		client:     client,
+		bucket:     bucket,
+		prefix:     prefix,
+		publicBase: strings.TrimSuffix(strings.TrimSpace(publicBase), "/"),
+	}, nil
+}
+
+func (s *GCSStore) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	writer := s.client.Bucket(s.bucket).Object(objectName(s.prefix, key)).NewWriter(ctx)
+	writer.ContentType = contentType
+	writer.CacheControl = cacheControl
+
+	if _, err := io.Copy(writer, r); err != nil {
+		_ = writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
+func (s *GCSStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	reader, err := s.client.Bucket(s.bucket).Object(objectName(s.prefix, key)).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, ErrNotFound
+	}
+	return reader, err
+}
+
+func (s *GCSStore) Delete(ctx context.Context, key string) error {
+	err := s.client.Bucket(s.bucket).Object(objectName(s.prefix, key)).Delete(ctx)
+	if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		return err
+	}
+	return nil
+}
+
+// SignPut issues a V4 signed URL. The client library signs with the service
+// account key, or through the IAM signBlob API when running on GCP.
+func (s *GCSStore) SignPut(_ context.Context, key, contentType string, size int64, expires time.Duration) (*SignedUpload, error) {
+	expiresAt := time.Now().Add(expires).Truncate(time.Second)
+	// GCS rejects uploads outside the signed length range
+	lengthRange := fmt.Sprintf("%d,%d", size, size)
+
+	signed, err := s.client.Bucket(s.bucket).SignedURL(objectName(s.prefix, key), &storage.SignedURLOptions{
+		Scheme:      storage.SigningSchemeV4,
+		Method:      http.MethodPut,
+		Expires:     expiresAt,
+		ContentType: contentType,
+		Headers:     []string{"x-goog-content-length-range:" + lengthRange},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sign GCS upload: %w", err)
+	}
+
+	return &SignedUpload{
+		Method: http.MethodPut,
+		URL:    signed,
+		Headers: map[string]string{
+			"Content-Type":                contentType,
+			"X-Goog-Content-Length-Range": lengthRange,
+		},
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (s *GCSStore) URL(key string) string {
+	return s.baseURL() + "/" + objectName(s.prefix, key)
+}
+
+func (s *GCSStore) KeyFromURL(url string) (string, bool) {
+	defaultBase := "https://storage.googleapis.com/" + s.bucket
+	gsPrefix := "gs://" + s.bucket
+
+	for _, base := range []string{s.publicBase, defaultBase, gsPrefix} {
+		if base != "" && strings.HasPrefix(url, base+"/") {
+			return keyFromObjectName(s.prefix, strings.TrimPrefix(url, base+"/"))
+		}
+	}
+	return "", false
+}
+
+func (s *GCSStore) baseURL() string {
+	if s.publicBase != "" {
+		return s.publicBase
+	}
+	return "https://storage.googleapis.com/" + s.bucket
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalStoreSignedPut(t *testing.T) {
+	store := NewLocalStore(t.TempDir())
+	store.EnableSignedPuts("http://localhost:8080/api/uploads/direct", []byte("secret"))
+
+	signed, err := store.SignPut(context.Background(), "staging/abc", "image/jpeg", 1024, 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed.Headers["Content-Type"] != "image/jpeg" {
+		t.Fatalf("Headers = %v", signed.Headers)
+	}
+	parsed, err := url.Parse(signed.URL)
+	if err != nil || parsed.Path != "/api/uploads/direct" {
+		t.Fatalf("URL = %q", signed.URL)
+	}
+
+	query := parsed.Query()
+	key, size, err := store.VerifyPut(query, "image/jpeg", time.Now())
+	if err != nil || key != "staging/abc" || size != 1024 {
+		t.Fatalf("VerifyPut() = %q, %d, %v", key, size, err)
+	}
+
+	tests := map[string]func() (url.Values, string, time.Time){
+		"other content type": func() (url.Values, string, time.Time) { return query, "text/html", time.Now() },
+		"expired":            func() (url.Values, string, time.Time) { return query, "image/jpeg", time.Now().Add(time.Hour) },
+		"other key": func() (url.Values, string, time.Time) {
+			q := url.Values{}
+			for k, v := range query {
+				q[k] = v
+			}
+			q.Set("key", "staging/other")
+			return q, "image/jpeg", time.Now()
+		},
+		"larger size": func() (url.Values, string, time.Time) {
+			q := url.Values{}
+			for k, v := range query {
+				q[k] = v
+			}
+			q.Set("size", "999999")
+			return q, "image/jpeg", time.Now()
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			q, contentType, now := tt()
+			if _, _, err := store.VerifyPut(q, contentType, now); !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("VerifyPut() error = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+func TestLocalStoreOpen(t *testing.T) {
+	store := NewLocalStore(t.TempDir())
+	if _, err := store.Open(context.Background(), "staging/missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open(missing) error = %v, want ErrNotFound", err)
+	}
+
+	if err := store.Put(context.Background(), "staging/abc", strings.NewReader("data"), ""); err != nil {
+		t.Fatal(err)
+	}
+	r, err := store.Open(context.Background(), "staging/abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if data, _ := io.ReadAll(r); string(data) != "data" {
+		t.Fatalf("Open() read %q", data)
+	}
+}
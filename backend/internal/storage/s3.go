@@ -0,0 +1,335 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Options configures an S3-compatible store such as AWS S3, MinIO or
+// Cloudflare R2
+type S3Options struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PublicBase is the URL objects are served from, e.g. a CDN or R2 custom
+	// domain. It defaults to the bucket URL on the endpoint.
+	PublicBase string
+	// PathStyle addresses the bucket as endpoint/bucket rather than as a
+	// bucket.endpoint subdomain. MinIO and R2 need it.
+	PathStyle bool
+}
+
+// S3Store talks to the S3 REST API directly with SigV4-signed requests
+type S3Store struct {
+	opts     S3Options
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+func NewS3Store(opts S3Options) (*S3Store, error) {
+	if opts.Bucket == "" || opts.AccessKeyID == "" || opts.SecretAccessKey == "" {
+		return nil, errors.New("S3 bucket and credentials must be configured")
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = "https://s3.amazonaws.com"
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+
+	endpoint, err := url.Parse(strings.TrimSuffix(opts.Endpoint, "/"))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", opts.Endpoint)
+	}
+	opts.PublicBase = strings.TrimSuffix(strings.TrimSpace(opts.PublicBase), "/")
+
+	return &S3Store{
+		opts:     opts,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 60 * time.Second},
+		now:      time.Now,
+	}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	// SigV4 signs the payload hash, so the body is buffered; uploads are
+	// already size-limited images
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Cache-Control", cacheControl)
+	return s.do(req, body)
+}
+
+func (s *S3Store) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, nil, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	case resp.StatusCode/100 != 2:
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("s3 GET %s: status %d: %s", req.URL.Path, resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	// S3 answers 204 whether or not the object existed
+	return s.do(req, nil)
+}
+
+// SignPut presigns a PUT. Content-Length is signed, so S3 rejects a body of
+// any other size.
+func (s *S3Store) SignPut(_ context.Context, key, contentType string, size int64, expires time.Duration) (*SignedUpload, error) {
+	now := s.now()
+	headers := map[string]string{
+		"Content-Type":   contentType,
+		"Content-Length": fmt.Sprintf("%d", size),
+	}
+	return &SignedUpload{
+		Method:    http.MethodPut,
+		URL:       s.presign(http.MethodPut, key, headers, expires, now),
+		Headers:   headers,
+		ExpiresAt: now.Add(expires).Truncate(time.Second),
+	}, nil
+}
+
+func (s *S3Store) URL(key string) string {
+	return s.baseURL() + "/" + encodePath(objectName(s.opts.Prefix, key))
+}
+
+func (s *S3Store) KeyFromURL(rawURL string) (string, bool) {
+	base := s.baseURL() + "/"
+	if !strings.HasPrefix(rawURL, base) {
+		return "", false
+	}
+	name, err := url.PathUnescape(strings.TrimPrefix(rawURL, base))
+	if err != nil {
+		return "", false
+	}
+	return keyFromObjectName(s.opts.Prefix, name)
+}
+
+func (s *S3Store) baseURL() string {
+	if s.opts.PublicBase != "" {
+		return s.opts.PublicBase
+	}
+	return s.bucketURL()
+}
+
+func (s *S3Store) bucketURL() string {
+	if s.opts.PathStyle {
+		return s.endpoint.String() + "/" + s.opts.Bucket
+	}
+	return s.endpoint.Scheme + "://" + s.opts.Bucket + "." + s.endpoint.Host + s.endpoint.Path
+}
+
+func (s *S3Store) objectURL(key string) string {
+	return s.bucketURL() + "/" + encodePath(objectName(s.opts.Prefix, key))
+}
+
+func (s *S3Store) do(req *http.Request, body []byte) error {
+	s.sign(req, body, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("s3 %s %s: status %d: %s", req.Method, req.URL.Path, resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header covering the
+// host, every header already set on req and the payload hash
+func (s *S3Store) sign(req *http.Request, body []byte, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	canonicalHeaders, signedHeaders := canonicalHeaderList(headers)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		encodePath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope, signature := s.signature(now, canonicalRequest)
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.opts.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+// presign returns a URL with SigV4 query authentication for method on key.
+// The request must carry headers exactly; the payload is not signed.
+func (s *S3Store) presign(method, key string, headers map[string]string, expires time.Duration, now time.Time) string {
+	now = now.UTC()
+	objectURL, _ := url.Parse(s.objectURL(key))
+
+	signing := map[string]string{"host": objectURL.Host}
+	for name, value := range headers {
+		signing[strings.ToLower(name)] = strings.TrimSpace(value)
+	}
+	canonicalHeaders, signedHeaders := canonicalHeaderList(signing)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", s.opts.AccessKeyID+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", fmt.Sprintf("%d", int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", signedHeaders)
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		encodePath(objectURL.Path),
+		canonicalQuery(query),
+		canonicalHeaders,
+		signedHeaders,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	_, signature := s.signature(now, canonicalRequest)
+	return objectURL.String() + "?" + canonicalQuery(query) + "&X-Amz-Signature=" + signature
+}
+
+func (s *S3Store) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.opts.Region + "/s3/aws4_request"
+}
+
+// signature signs a canonical request made at now and returns its scope
+func (s *S3Store) signature(now time.Time, canonicalRequest string) (scope, signature string) {
+	scope = s.scope(now)
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format("20060102T150405Z"),
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.opts.SecretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.opts.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return scope, hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalHeaderList formats lower-cased headers for a canonical request
+func canonicalHeaderList(headers map[string]string) (canonical, signed string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + headers[name] + "\n")
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		vs := append([]string(nil), values[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, encodeURIComponent(k)+"="+encodeURIComponent(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// encodePath percent-encodes everything but unreserved characters and
+// slashes, as SigV4 requires
+func encodePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = encodeURIComponent(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func encodeURIComponent(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
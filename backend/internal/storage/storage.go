@@ -0,0 +1,102 @@
+// Package storage keeps uploaded files in a blob store. Files are addressed by
+// key; the database stores keys and public URLs are derived from them, so the
+// backend can change without rewriting every reference.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/net1io/zenbali/internal/config"
+)
+
+// ErrNotFound is returned by Open for a key that does not exist
+var ErrNotFound = errors.New("object not found")
+
+// Cache policy for stored objects. Keys are never reused, so objects can be
+// cached indefinitely.
+const cacheControl = "public, max-age=31536000"
+
+// BlobStore stores and serves files by key
+type BlobStore interface {
+	// Put stores the contents of r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Open reads the object stored under key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// SignPut returns a request that lets a client store exactly size bytes
+	// of contentType under key, without credentials, until it expires
+	SignPut(ctx context.Context, key, contentType string, size int64, expires time.Duration) (*SignedUpload, error)
+	// URL returns the public URL of key
+	URL(key string) string
+	// KeyFromURL recovers the key from a URL this store produced, including
+	// URLs written before references were stored as keys
+	KeyFromURL(url string) (string, bool)
+}
+
+// SignedUpload is a pre-authorised request that stores one object. The
+// client must send every header exactly as given.
+type SignedUpload struct {
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// New returns the store selected by cfg.Backend
+func New(ctx context.Context, cfg config.UploadConfig) (BlobStore, error) {
+	switch cfg.Backend {
+	case "", "local":
+		store := NewLocalStore(cfg.Dir)
+		store.EnableSignedPuts(cfg.DirectUploadURL, []byte(cfg.DirectUploadSecret))
+		return store, nil
+	case "gcs":
+		return NewGCSStore(ctx, cfg.GCSBucket, cfg.GCSPrefix, cfg.GCSPublicBase)
+	case "s3":
+		return NewS3Store(S3Options{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			Prefix:          cfg.S3Prefix,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			PublicBase:      cfg.S3PublicBase,
+			PathStyle:       cfg.S3PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown upload backend %q", cfg.Backend)
+	}
+}
+
+// IsURL reports whether ref is a URL or path rather than a key. Keys never
+// contain a scheme and never start with a slash.
+func IsURL(ref string) bool {
+	return strings.HasPrefix(ref, "/") || strings.Contains(ref, "://")
+}
+
+// objectName joins a configured prefix and a key
+func objectName(prefix, key string) string {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return key
+	}
+	return path.Join(prefix, key)
+}
+
+// keyFromObjectName strips the configured prefix from an object name
+func keyFromObjectName(prefix, name string) (string, bool) {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix != "" {
+		if !strings.HasPrefix(name, prefix+"/") {
+			return "", false
+		}
+		name = strings.TrimPrefix(name, prefix+"/")
+	}
+	return name, name != ""
+}
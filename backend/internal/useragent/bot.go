@@ -0,0 +1,66 @@
+package useragent
+
+import "strings"
+
+// OtherBot names automated clients that match no known crawler
+const OtherBot = "Other bot"
+
+// crawlers are clients that announce themselves. Link preview fetchers are
+// here too: they load a page when someone shares it, not when anyone reads it.
+var crawlers = []token{
+	{"Googlebot", "Googlebot"},
+	{"AdsBot-Google", "Googlebot"},
+	{"Google-InspectionTool", "Googlebot"},
+	{"bingbot", "Bingbot"},
+	{"DuckDuckBot", "DuckDuckBot"},
+	{"YandexBot", "YandexBot"},
+	{"Baiduspider", "Baiduspider"},
+	{"Applebot", "Applebot"},
+	{"AhrefsBot", "AhrefsBot"},
+	{"SemrushBot", "SemrushBot"},
+	{"GPTBot", "GPTBot"},
+	{"ClaudeBot", "ClaudeBot"},
+	{"facebookexternalhit", "Facebook preview"},
+	{"Twitterbot", "Twitterbot"},
+	{"WhatsApp/", "WhatsApp preview"},
+	{"TelegramBot", "Telegram preview"},
+	{"Slackbot", "Slackbot"},
+	{"Discordbot", "Discordbot"},
+	{"UptimeRobot", "UptimeRobot"},
+	{"Pingdom", "Pingdom"},
+	{"HeadlessChrome", "Headless Chrome"},
+	{"Lighthouse", "Lighthouse"},
+	{"curl/", "curl"},
+	{"Wget/", "Wget"},
+	{"python-requests", "Python"},
+	{"python-urllib", "Python"},
+	{"Go-http-client", "Go"},
+	{"node-fetch", "Node.js"},
+	{"axios/", "Node.js"},
+	{"okhttp", "OkHttp"},
+}
+
+// botMarkers are lower-case substrings that give away other automated
+// clients. "bot" alone is not one: phone models such as Cubot contain it.
+var botMarkers = []string{"bot/", "bot;", "bot)", "bot-", "crawler", "spider", "+http", "monitor"}
+
+// Bot returns the name of the crawler or script that sent a User-Agent, or ""
+// if it looks like a person's browser. An empty User-Agent is a bot: every
+// browser sends one.
+func Bot(ua string) string {
+	ua = strings.TrimSpace(ua)
+	if ua == "" {
+		return OtherBot
+	}
+	if name := match(ua, crawlers); name != "Other" {
+		return name
+	}
+
+	lower := strings.ToLower(ua)
+	for _, marker := range botMarkers {
+		if strings.Contains(lower, marker) {
+			return OtherBot
+		}
+	}
+	return ""
+}
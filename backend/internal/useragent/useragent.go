@@ -0,0 +1,108 @@
+// Package useragent reduces User-Agent headers to coarse browser, operating
+// system and device classes, so the header itself need not be stored.
+package useragent
+
+import "strings"
+
+// Device classes
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceOther   = "other"
+)
+
+// Unknown is reported for every field of an empty User-Agent
+const Unknown = "Unknown"
+
+// Info is what a User-Agent says about the client
+type Info struct {
+	Browser string
+	OS      string
+	Device  string
+}
+
+// token is a substring that identifies a browser or OS. The first match wins,
+// so more specific tokens come first: Edge and Opera also claim to be Chrome,
+// and Chrome claims to be Safari.
+type token struct {
+	contains string
+	name     string
+}
+
+var browsers = []token{
+	{"Edg/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"Edge/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Opera", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Instagram", "Instagram"},
+	{"FBAN/", "Facebook"},
+	{"FBAV/", "Facebook"},
+	{"CriOS/", "Chrome"},
+	{"FxiOS/", "Firefox"},
+	{"Firefox/", "Firefox"},
+	{"Chromium/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Version/", "Safari"},
+	{"MSIE ", "Internet Explorer"},
+	{"Trident/", "Internet Explorer"},
+}
+
+var systems = []token{
+	{"Windows", "Windows"},
+	{"iPad", "iPadOS"},
+	{"iPhone", "iOS"},
+	{"iPod", "iOS"},
+	{"Android", "Android"},
+	{"CrOS", "ChromeOS"},
+	{"Macintosh", "macOS"},
+	{"Mac OS X", "macOS"},
+	{"Linux", "Linux"},
+}
+
+// Parse classifies a User-Agent header. Fields it cannot place are "Other".
+func Parse(ua string) Info {
+	ua = strings.TrimSpace(ua)
+	if ua == "" {
+		return Info{Browser: Unknown, OS: Unknown, Device: DeviceOther}
+	}
+
+	info := Info{
+		Browser: match(ua, browsers),
+		OS:      match(ua, systems),
+	}
+	if info.Browser == "Safari" && !strings.Contains(ua, "Safari/") {
+		// Version/ alone is not enough; Opera Presto uses it too
+		info.Browser = "Other"
+	}
+	info.Device = device(ua, info.OS)
+	return info
+}
+
+func match(ua string, tokens []token) string {
+	for _, t := range tokens {
+		if strings.Contains(ua, t.contains) {
+			return t.name
+		}
+	}
+	return "Other"
+}
+
+func device(ua, os string) string {
+	switch {
+	case os == "iPadOS" || strings.Contains(ua, "Tablet"):
+		return DeviceTablet
+	// Android phones say Mobile; Android tablets do not
+	case os == "Android" && !strings.Contains(ua, "Mobile"):
+		return DeviceTablet
+	case strings.Contains(ua, "Mobi") || os == "iOS" || strings.Contains(ua, "Windows Phone"):
+		return DeviceMobile
+	case os == "Windows" || os == "macOS" || os == "Linux" || os == "ChromeOS":
+		return DeviceDesktop
+	default:
+		return DeviceOther
+	}
+}
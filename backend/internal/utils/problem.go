@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/net1io/zenbali/internal/validation"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// CodeValidationFailed is the code of a request body that broke its
+// validation rules
+const CodeValidationFailed = "VALIDATION_FAILED"
+
+// ProblemDetails is an RFC 7807 error body. Problems are not given type URIs,
+// so Type is always about:blank and Title is the status text; Code is the
+// extension member clients branch on.
+type ProblemDetails struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code"`
+	// Errors lists the fields of a request that failed validation
+	Errors validation.Errors `json:"errors,omitempty"`
+	// Data is the resource the problem is about, such as the event a
+	// duplicate matched
+	Data interface{} `json:"data,omitempty"`
+}
+
+// WriteProblem sends p as application/problem+json, filling in its type and
+// title
+func WriteProblem(w http.ResponseWriter, p *ProblemDetails) {
+	p.Type = "about:blank"
+	p.Title = http.StatusText(p.Status)
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Problem sends a problem response with a code of its own
+func Problem(w http.ResponseWriter, status int, code, detail string) {
+	WriteProblem(w, &ProblemDetails{Status: status, Code: code, Detail: detail})
+}
+
+// StatusCode is the code of a problem that has none of its own, the status
+// text in upper snake case, such as NOT_FOUND
+func StatusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "ERROR"
+	}
+	return strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
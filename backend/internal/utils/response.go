@@ -3,13 +3,14 @@ package utils
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/net1io/zenbali/internal/validation"
 )
 
 // Response represents a standard API response
 type Response struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
 	Message string      `json:"message,omitempty"`
 }
 
@@ -36,12 +37,10 @@ func Created(w http.ResponseWriter, data interface{}) {
 	})
 }
 
-// Error sends an error response
+// Error sends a problem response with message as the detail and a code
+// derived from status. Prefer Problem where the problem has its own code.
 func Error(w http.ResponseWriter, status int, message string) {
-	JSON(w, status, Response{
-		Success: false,
-		Error:   message,
-	})
+	Problem(w, status, StatusCode(status), message)
 }
 
 // BadRequest sends a 400 error
@@ -73,6 +72,24 @@ func NotFound(w http.ResponseWriter, message string) {
 	Error(w, http.StatusNotFound, message)
 }
 
+// Conflict sends a 409 error
+func Conflict(w http.ResponseWriter, message string) {
+	if message == "" {
+		message = "Conflict"
+	}
+	Error(w, http.StatusConflict, message)
+}
+
+// ValidationFailed sends a 422 error listing the fields that failed
+func ValidationFailed(w http.ResponseWriter, errs validation.Errors) {
+	WriteProblem(w, &ProblemDetails{
+		Status: http.StatusUnprocessableEntity,
+		Code:   CodeValidationFailed,
+		Detail: "Validation failed",
+		Errors: errs,
+	})
+}
+
 // InternalError sends a 500 error
 func InternalError(w http.ResponseWriter, message string) {
 	if message == "" {
@@ -93,3 +110,17 @@ func Message(w http.ResponseWriter, message string) {
 func ParseJSON(r *http.Request, v interface{}) error {
 	return json.NewDecoder(r.Body).Decode(v)
 }
+
+// DecodeJSON parses the JSON request body into v and validates it against its
+// validate tags. On failure it sends a 400 or 422 and returns false.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := ParseJSON(r, v); err != nil {
+		BadRequest(w, "Invalid request body")
+		return false
+	}
+	if errs := validation.Struct(v); errs != nil {
+		ValidationFailed(w, errs)
+		return false
+	}
+	return true
+}
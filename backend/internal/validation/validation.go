@@ -0,0 +1,216 @@
+// Package validation checks request models against their validate struct
+// tags. Fields are reported under their JSON names, with the rule that failed
+// and a message the API can return as-is.
+//
+// The rules are a subset of the go-playground/validator tags:
+//
+//	required   not the zero value; strings must not be blank
+//	omitempty  skip the remaining rules when the field is empty or nil
+//	omitnil    skip the remaining rules when the field is a nil pointer, so
+//	           "required" on a partial update means "not blank when sent"
+//	min=n      length of a string (in characters) or slice, or value of a number
+//	max=n      as min, as an upper bound
+//	email      a bare email address
+//	uuid       a UUID
+//	oneof=a b  one of the space-separated values
+//
+// Pointers are checked against the value they point to. Embedded structs are
+// checked as if their fields were declared inline.
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// FieldError is one failed rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors lists every failed rule of a request
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	return strings.Join(e.Messages(), "; ")
+}
+
+// Messages returns the message of each error
+func (e Errors) Messages() []string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Message
+	}
+	return messages
+}
+
+// Struct validates v, a struct or a pointer to one, and returns the fields
+// that fail their rules, or nil. It panics on a tag it does not understand,
+// as that is a bug in the model rather than in the request.
+func Struct(v interface{}) Errors {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validation: %T is not a struct", v))
+	}
+	var errs Errors
+	validateStruct(rv, &errs)
+	return errs
+}
+
+func validateStruct(rv reflect.Value, errs *Errors) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && jsonName(sf) == sf.Name {
+			validateStruct(rv.Field(i), errs)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get("validate")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		if fe := validateField(jsonName(sf), rv.Field(i), tag); fe != nil {
+			*errs = append(*errs, *fe)
+		}
+	}
+}
+
+// validateField returns the first rule of tag that value fails
+func validateField(field string, value reflect.Value, tag string) *FieldError {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			break
+		}
+		value = value.Elem()
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		if value.Kind() == reflect.Ptr && name != "required" && name != "omitempty" && name != "omitnil" {
+			// Only the presence rules apply to a nil pointer
+			continue
+		}
+		switch name {
+		case "omitnil":
+			if value.Kind() == reflect.Ptr {
+				return nil
+			}
+		case "omitempty":
+			if isEmpty(value) {
+				return nil
+			}
+		case "required":
+			if isEmpty(value) {
+				return &FieldError{field, name, field + " is required"}
+			}
+		case "min", "max":
+			if fe := checkBound(field, value, name, param); fe != nil {
+				return fe
+			}
+		case "email":
+			if s := stringOf(field, value, name); s != "" && !isEmail(s) {
+				return &FieldError{field, name, field + " must be a valid email address"}
+			}
+		case "uuid":
+			if s := stringOf(field, value, name); s != "" {
+				if _, err := uuid.Parse(s); err != nil {
+					return &FieldError{field, name, field + " must be a valid UUID"}
+				}
+			}
+		case "oneof":
+			options := strings.Fields(param)
+			if s := stringOf(field, value, name); !contains(options, s) {
+				return &FieldError{field, name, fmt.Sprintf("%s must be one of: %s", field, strings.Join(options, ", "))}
+			}
+		default:
+			panic(fmt.Sprintf("validation: unknown rule %q on %s", rule, field))
+		}
+	}
+	return nil
+}
+
+func checkBound(field string, value reflect.Value, rule, param string) *FieldError {
+	bound, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validation: bad %s=%q on %s", rule, param, field))
+	}
+
+	var actual float64
+	var unit string
+	switch value.Kind() {
+	case reflect.String:
+		actual, unit = float64(utf8.RuneCountInString(value.String())), " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		actual, unit = float64(value.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		actual = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		actual = value.Float()
+	default:
+		panic(fmt.Sprintf("validation: %s does not apply to %s of kind %s", rule, field, value.Kind()))
+	}
+
+	if rule == "min" && actual < bound {
+		return &FieldError{field, rule, fmt.Sprintf("%s must be at least %s%s", field, param, unit)}
+	}
+	if rule == "max" && actual > bound {
+		return &FieldError{field, rule, fmt.Sprintf("%s must be at most %s%s", field, param, unit)}
+	}
+	return nil
+}
+
+// stringOf returns the string value checks like email apply to
+func stringOf(field string, value reflect.Value, rule string) string {
+	if value.Kind() == reflect.String {
+		return value.String()
+	}
+	panic(fmt.Sprintf("validation: %s does not apply to %s of kind %s", rule, field, value.Kind()))
+}
+
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+func isEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+func contains(options []string, s string) bool {
+	for _, option := range options {
+		if option == s {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonName is the name encoding/json uses for the field
+func jsonName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+	return name
+}
@@ -0,0 +1,133 @@
+package validation_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/net1io/zenbali/internal/handlers"
+	"github.com/net1io/zenbali/internal/models"
+	"github.com/net1io/zenbali/internal/validation"
+)
+
+type base struct {
+	Name string `json:"name" validate:"required,min=2,max=5"`
+}
+
+type request struct {
+	base
+	Email   string   `json:"email,omitempty" validate:"omitempty,email"`
+	ID      string   `json:"id" validate:"omitempty,uuid"`
+	Count   int      `json:"count" validate:"min=0,max=10"`
+	Tags    []string `json:"tags" validate:"required,max=2"`
+	Kind    string   `json:"kind" validate:"required,oneof=a b"`
+	Note    *string  `json:"note" validate:"omitnil,required,max=3"`
+	Price   *int     `json:"price" validate:"min=0"`
+	Ignored string   `json:"ignored"`
+}
+
+func TestStruct(t *testing.T) {
+	blank, long, negative := "  ", "four", -1
+
+	tests := []struct {
+		name string
+		req  request
+		want []string // field:rule of each error
+	}{
+		{
+			"valid",
+			request{base: base{"Ketut"}, Email: "k@example.com", ID: "7f2c1c9e-5a7d-4b0e-9c55-3f7c2a1d9e10", Count: 10, Tags: []string{"x"}, Kind: "b"},
+			nil,
+		},
+		{
+			"empty",
+			request{},
+			[]string{"name:required", "tags:required", "kind:required"},
+		},
+		{
+			"blank strings are missing",
+			request{base: base{"   "}, Tags: []string{"x"}, Kind: "a", Note: &blank},
+			[]string{"name:required", "note:required"},
+		},
+		{
+			"bounds",
+			request{base: base{"Wayan Sari"}, Count: 11, Tags: []string{"x", "y", "z"}, Kind: "a", Note: &long, Price: &negative},
+			[]string{"name:max", "count:max", "tags:max", "note:max", "price:min"},
+		},
+		{
+			"formats",
+			request{base: base{"Ketut"}, Email: "Ketut <k@example.com>", ID: "42", Tags: []string{"x"}, Kind: "c"},
+			[]string{"email:email", "id:uuid", "kind:oneof"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, fe := range validation.Struct(&tt.req) {
+				got = append(got, fe.Field+":"+fe.Rule)
+				if fe.Message == "" {
+					t.Errorf("%s:%s has no message", fe.Field, fe.Rule)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("errors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessages(t *testing.T) {
+	errs := validation.Struct(&request{base: base{"K"}, Tags: []string{"x"}, Kind: "d"})
+	want := []string{"name must be at least 2 characters", "kind must be one of: a, b"}
+	if !reflect.DeepEqual(errs.Messages(), want) {
+		t.Errorf("messages = %q, want %q", errs.Messages(), want)
+	}
+}
+
+// TestRequestModels checks every request model only uses rules the package
+// understands, which would otherwise panic on the first request
+func TestRequestModels(t *testing.T) {
+	for _, req := range []interface{}{
+		&models.CreatorRegisterRequest{},
+		&models.CreatorLoginRequest{},
+		&models.CreatorUpdateRequest{},
+		&models.AdminCreatorCreateRequest{},
+		&models.AdminCreatorUpdateRequest{},
+		&models.AdminLoginRequest{},
+		&models.EventCreateRequest{},
+		&models.EventUpdateRequest{},
+		&models.AdminEventCreateRequest{},
+		&models.AdminEventUpdateRequest{},
+		&models.EventImageUpdateRequest{},
+		&models.EventImageOrderRequest{},
+		&models.EventInteractionRequest{},
+		&models.DirectUploadRequest{},
+		&models.DirectUploadConfirmRequest{},
+		&models.AgentAPIKeyCreateRequest{},
+		&models.LocationRequest{},
+		&models.EventTypeRequest{},
+		&models.EntranceTypeRequest{},
+		&models.TrackVisitorRequest{},
+		&handlers.AgentEventCreateRequest{},
+		&handlers.AgentEventUpdateRequest{},
+		&handlers.AgentEventImageRequest{},
+	} {
+		validation.Struct(req)
+		// Rules after omitnil only run on pointers that are set
+		validation.Struct(withPointersSet(req))
+	}
+}
+
+// withPointersSet returns a copy of the struct req points to with every nil
+// pointer field pointing at a zero value
+func withPointersSet(req interface{}) interface{} {
+	v := reflect.New(reflect.TypeOf(req).Elem())
+	v.Elem().Set(reflect.ValueOf(req).Elem())
+	for i := 0; i < v.Elem().NumField(); i++ {
+		field := v.Elem().Field(i)
+		if field.Kind() == reflect.Ptr && field.IsNil() && field.CanSet() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+	}
+	return v.Interface()
+}
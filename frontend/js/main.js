@@ -61,7 +61,11 @@ const API = {
             const data = await response.json();
 
             if (!response.ok) {
-                throw new Error(data.error || 'Request failed');
+                // Validation failures list a message per field
+                if (data.errors && data.errors.length) {
+                    throw new Error(data.errors.map(e => e.message).join('. '));
+                }
+                throw new Error(data.detail || 'Request failed');
             }
 
             return data;
@@ -111,7 +115,7 @@ const API = {
         const data = await response.json();
 
         if (!response.ok) {
-            throw new Error(data.error || 'Upload failed');
+            throw new Error(data.detail || 'Upload failed');
         }
 
         return data;
@@ -62,7 +62,11 @@ const API = {
             const data = await response.json();
 
             if (!response.ok) {
-                throw new Error(data.error || 'Request failed');
+                // Validation failures list a message per field
+                if (data.errors && data.errors.length) {
+                    throw new Error(data.errors.map(e => e.message).join('. '));
+                }
+                throw new Error(data.detail || 'Request failed');
             }
 
             return data;
@@ -112,7 +116,7 @@ const API = {
         const data = await response.json();
 
         if (!response.ok) {
-            throw new Error(data.error || 'Upload failed');
+            throw new Error(data.detail || 'Upload failed');
         }
 
         return data;
@@ -291,17 +295,63 @@ const Modal = {
     }
 };
 
+// Attribution: where this page view came from
+const ATTRIBUTION_KEY = 'zenbali_attribution';
+const UTM_PARAMS = ['utm_source', 'utm_medium', 'utm_campaign', 'utm_term', 'utm_content'];
+
+function currentAttribution() {
+    const params = new URLSearchParams(window.location.search);
+    const attribution = {
+        referrer: document.referrer,
+        landing_path: window.location.pathname
+    };
+    UTM_PARAMS.forEach(name => {
+        attribution[name] = params.get(name) || '';
+    });
+    return attribution;
+}
+
+// Keeps where the visitor first arrived from, so a signup is credited to it
+// rather than to our own pages
+function firstTouchAttribution() {
+    try {
+        const stored = localStorage.getItem(ATTRIBUTION_KEY);
+        if (stored) return JSON.parse(stored);
+
+        const attribution = currentAttribution();
+        const internal = attribution.referrer &&
+            new URL(attribution.referrer).host === window.location.host;
+        if (!internal || attribution.utm_source) {
+            localStorage.setItem(ATTRIBUTION_KEY, JSON.stringify(attribution));
+        }
+        return attribution;
+    } catch (error) {
+        return currentAttribution();
+    }
+}
+
 // Visitor Tracking
 async function trackVisitor() {
+    firstTouchAttribution();
     try {
         await API.post('/visitors', {
-            user_agent: navigator.userAgent
+            user_agent: navigator.userAgent,
+            ...currentAttribution()
         });
     } catch (error) {
         console.log('Visitor tracking failed:', error);
     }
 }
 
+// Event page actions: view, email, whatsapp or share
+async function trackEventAction(eventId, action) {
+    try {
+        await API.post(`/events/${eventId}/interactions`, { action });
+    } catch (error) {
+        console.log('Event tracking failed:', error);
+    }
+}
+
 async function loadVisitorStats() {
     try {
         const response = await API.get('/visitors/stats');
@@ -1,8 +0,0 @@
--- Entrance Types
-INSERT INTO entrance_types (name, slug) VALUES
-('Free', 'free'),
-('Prepaid Online', 'prepaid-online'),
-('Pay at Site', 'pay-at-site'),
-('Donation-based', 'donation-based'),
-('By Registration Only', 'by-registration-only'),
-('Members Only', 'members-only');
\ No newline at end of file
@@ -55,29 +55,16 @@ done
 
 echo "✅ Database is ready!"
 
-# Check if database is initialized
-echo "🔍 Checking database initialization..."
-TABLE_COUNT=$(docker exec -i zenbali-postgres psql -U "$DB_USER" -d "$DB_NAME" -t -c "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = 'public';" 2>/dev/null || echo "0")
-
-if [ "$TABLE_COUNT" -lt 5 ]; then
-    echo "📦 Initializing database with migrations..."
-
-    cd backend
-
-    # Run migrations
-    cat internal/database/migrations/001_init.up.sql | docker exec -i zenbali-postgres psql -U "$DB_USER" -d "$DB_NAME" > /dev/null 2>&1
-    echo "   ✓ Schema migration applied"
-
-    cat internal/database/migrations/002_seed_data.up.sql | docker exec -i zenbali-postgres psql -U "$DB_USER" -d "$DB_NAME" > /dev/null 2>&1
-    echo "   ✓ Seed data loaded"
-
-    cat internal/database/migrations/003_add_participant_group_and_lead_by.up.sql | docker exec -i zenbali-postgres psql -U "$DB_USER" -d "$DB_NAME" > /dev/null 2>&1
-    echo "   ✓ New fields migration applied"
-
-    cd ..
-else
-    echo "✅ Database already initialized"
+# Apply pending migrations
+echo "📦 Applying database migrations..."
+cd backend
+if ! go run ./cmd/migrate up; then
+    echo "❌ Migrations failed. If the schema has no migration history and could"
+    echo "   not be recognised, run 'go run ./cmd/migrate baseline <version>' once;"
+    echo "   see readme.md."
+    exit 1
 fi
+cd ..
 
 # Start the backend server
 echo "🚀 Starting backend server..."